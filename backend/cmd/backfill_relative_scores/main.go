@@ -0,0 +1,124 @@
+package main
+
+import (
+	"backend/internal/database"
+	"backend/internal/models"
+	"backend/internal/repository"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+)
+
+// This script computes the Peer and Baseline scores of every score on a
+// resolved forecast, filling in scores from before they were kept at
+// resolution. Running it again recomputes them.
+// Run with: go run cmd/backfill_relative_scores/main.go
+
+func main() {
+	log.Println("Starting relative score backfill...")
+
+	db, err := database.NewDB(os.Getenv("DB_CONNECTION_STRING"))
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	forecastRepo := repository.NewForecastRepository(db)
+	pointRepo := repository.NewForecastPointRepository(db)
+	scoreRepo := repository.NewScoreRepository(db)
+	userRepo := repository.NewUserRepository(db)
+
+	status := "resolved"
+	forecasts, err := forecastRepo.GetForecasts(ctx, models.ForecastFilters{Status: &status})
+	if err != nil {
+		log.Fatalf("Failed to get resolved forecasts: %v", err)
+	}
+
+	// the crowd's score is compared with every user's, see models.SetRelativeScores
+	var crowdID int64
+	crowd, err := userRepo.GetUserByUsername(ctx, models.CrowdUsername)
+	switch {
+	case err == nil && crowd.Role == models.RoleBot && crowd.Password == "":
+		crowdID = crowd.ID
+	case err != nil && !errors.Is(err, sql.ErrNoRows):
+		log.Fatalf("Failed to look up the crowd user: %v", err)
+	}
+
+	log.Printf("Found %d resolved forecasts", len(forecasts))
+	if len(forecasts) == 0 {
+		log.Println("No forecasts to backfill. Exiting.")
+		return
+	}
+
+	fmt.Printf("About to backfill the relative scores of %d forecasts. Continue? (y/n): ", len(forecasts))
+	var response string
+	fmt.Scanln(&response)
+	if response != "y" && response != "Y" {
+		log.Println("Backfill cancelled.")
+		return
+	}
+
+	successCount := 0
+	errorCount := 0
+
+	for i, forecast := range forecasts {
+		if i%10 == 0 {
+			log.Printf("Progress: %d/%d", i, len(forecasts))
+		}
+		if forecast.Resolution == nil || !forecast.Resolution.IsScored() {
+			continue
+		}
+
+		id := forecast.ID
+		points, err := pointRepo.GetForecastPoints(ctx, models.PointFilters{ForecastID: &id})
+		if err != nil {
+			log.Printf("Error fetching points for forecast %d: %v", id, err)
+			errorCount++
+			continue
+		}
+		stored, err := scoreRepo.GetScores(ctx, models.ScoreFilters{ForecastID: &id})
+		if err != nil {
+			log.Printf("Error fetching scores for forecast %d: %v", id, err)
+			errorCount++
+			continue
+		}
+		if len(points) == 0 || len(stored) == 0 {
+			continue
+		}
+
+		scores := make([]*models.Scores, len(stored))
+		for j := range stored {
+			scores[j] = &stored[j]
+		}
+		if err := models.SetRelativeScores(forecast, points, scores, crowdID); err != nil {
+			log.Printf("Error calculating relative scores for forecast %d: %v", id, err)
+			errorCount++
+			continue
+		}
+
+		failed := false
+		for _, score := range scores {
+			if err := scoreRepo.UpdateScore(ctx, score); err != nil {
+				log.Printf("Error updating score %d: %v", score.ID, err)
+				failed = true
+			}
+		}
+		if failed {
+			errorCount++
+			continue
+		}
+		successCount++
+	}
+
+	log.Printf("Backfill complete!")
+	log.Printf("Forecasts updated: %d", successCount)
+	log.Printf("Errors: %d", errorCount)
+
+	if errorCount > 0 {
+		os.Exit(1)
+	}
+}
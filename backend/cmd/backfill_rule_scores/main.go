@@ -0,0 +1,167 @@
+package main
+
+import (
+	"backend/internal/database"
+	"backend/internal/models"
+	"backend/internal/repository"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+)
+
+// This script computes the scores under every registered scoring rule of the
+// scores of resolved binary and multiple choice forecasts, filling in scores
+// from before they were kept and adding rules registered since. Running it
+// again recomputes them.
+// Run with: go run cmd/backfill_rule_scores/main.go
+
+func main() {
+	log.Println("Starting rule score backfill...")
+
+	db, err := database.NewDB(os.Getenv("DB_CONNECTION_STRING"))
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	forecastRepo := repository.NewForecastRepository(db)
+	pointRepo := repository.NewForecastPointRepository(db)
+	scoreRepo := repository.NewScoreRepository(db)
+	userRepo := repository.NewUserRepository(db)
+
+	status := "resolved"
+	forecasts, err := forecastRepo.GetForecasts(ctx, models.ForecastFilters{Status: &status})
+	if err != nil {
+		log.Fatalf("Failed to get resolved forecasts: %v", err)
+	}
+
+	// the crowd has no points of its own, its scores pool everyone's
+	var crowdID int64
+	crowd, err := userRepo.GetUserByUsername(ctx, models.CrowdUsername)
+	switch {
+	case err == nil && crowd.Role == models.RoleBot && crowd.Password == "":
+		crowdID = crowd.ID
+	case err != nil && !errors.Is(err, sql.ErrNoRows):
+		log.Fatalf("Failed to look up the crowd user: %v", err)
+	}
+
+	log.Printf("Found %d resolved forecasts", len(forecasts))
+	if len(forecasts) == 0 {
+		log.Println("No forecasts to backfill. Exiting.")
+		return
+	}
+
+	fmt.Printf("About to backfill the rule scores of %d forecasts. Continue? (y/n): ", len(forecasts))
+	var response string
+	fmt.Scanln(&response)
+	if response != "y" && response != "Y" {
+		log.Println("Backfill cancelled.")
+		return
+	}
+
+	successCount := 0
+	errorCount := 0
+
+	for i, forecast := range forecasts {
+		if i%10 == 0 {
+			log.Printf("Progress: %d/%d", i, len(forecasts))
+		}
+		if forecast.Resolution == nil || !forecast.Resolution.IsScored() || forecast.IsContinuous() {
+			continue
+		}
+
+		id := forecast.ID
+		points, err := pointRepo.GetForecastPoints(ctx, models.PointFilters{ForecastID: &id})
+		if err != nil {
+			log.Printf("Error fetching points for forecast %d: %v", id, err)
+			errorCount++
+			continue
+		}
+		scores, err := scoreRepo.GetScores(ctx, models.ScoreFilters{ForecastID: &id})
+		if err != nil {
+			log.Printf("Error fetching scores for forecast %d: %v", id, err)
+			errorCount++
+			continue
+		}
+		if len(points) == 0 || len(scores) == 0 {
+			continue
+		}
+
+		failed := false
+		for _, score := range scores {
+			rules, err := ruleScores(forecast, points, score.UserID, score.UserID == crowdID && crowdID != 0)
+			if err != nil {
+				log.Printf("Error calculating rule scores of score %d: %v", score.ID, err)
+				failed = true
+				continue
+			}
+			score.Rules = rules
+			if err := scoreRepo.UpdateScore(ctx, &score); err != nil {
+				log.Printf("Error updating score %d: %v", score.ID, err)
+				failed = true
+			}
+		}
+		if failed {
+			errorCount++
+			continue
+		}
+		successCount++
+	}
+
+	log.Printf("Backfill complete!")
+	log.Printf("Forecasts updated: %d", successCount)
+	log.Printf("Errors: %d", errorCount)
+
+	if errorCount > 0 {
+		os.Exit(1)
+	}
+}
+
+// ruleScores scores the user's points, or the default community forecast for
+// the crowd, as they are scored at resolution
+func ruleScores(forecast *models.Forecast, points []*models.ForecastPoint, userID int64, isCrowd bool) ([]models.RuleScore, error) {
+	var series []models.AggregatePoint
+	if isCrowd {
+		var err error
+		series, err = models.AggregatePoints(points, len(forecast.Options), nil, models.AggregateOptions{Method: models.DefaultAggregateMethod})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if forecast.IsMultipleChoice() {
+		outcome, err := forecast.ParseOptionResolution(string(*forecast.Resolution))
+		if err != nil {
+			return nil, err
+		}
+		choicePoints := models.ChoicePoints(series)
+		if !isCrowd {
+			for _, p := range points {
+				if p.UserID == userID {
+					choicePoints = append(choicePoints, models.ChoicePoint{Probabilities: p.Probabilities, CreatedAt: p.CreatedAt})
+				}
+			}
+		}
+		score, err := models.CalcMultipleChoiceScore(choicePoints, outcome, len(forecast.Options), userID, forecast.ID, forecast.CreatedAt, forecast.ClosingDate, forecast.ResolvedAt)
+		return score.Rules, err
+	}
+
+	outcome, err := forecast.Resolution.BinaryOutcome(forecast.ResolutionValue)
+	if err != nil {
+		return nil, err
+	}
+	timePoints := models.TimePoints(series)
+	if !isCrowd {
+		for _, p := range points {
+			if p.UserID == userID {
+				timePoints = append(timePoints, models.TimePoint{PointForecast: p.PointForecast, CreatedAt: p.CreatedAt})
+			}
+		}
+	}
+	score, err := models.CalcBinaryScore(timePoints, outcome, userID, forecast.ID, forecast.CreatedAt, forecast.ClosingDate, forecast.ResolvedAt)
+	return score.Rules, err
+}
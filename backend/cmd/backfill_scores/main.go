@@ -38,10 +38,13 @@ func main() {
 		FROM scores s
 		JOIN forecasts f ON s.forecast_id = f.id
 		WHERE f.resolved IS NOT NULL
+		AND f.question_type = 'binary'
+		AND s.user_id NOT IN (SELECT id FROM users WHERE username = $1)
 		ORDER BY s.id
 	`
 
-	rows, err := db.QueryContext(ctx, query)
+	// the crowd has no points of its own, its scores pool everyone's
+	rows, err := db.QueryContext(ctx, query, models.CrowdUsername)
 	if err != nil {
 		log.Fatalf("Failed to query scores: %v", err)
 	}
@@ -129,25 +132,31 @@ func main() {
 		}
 
 		// Get the resolution outcome
-		var resolutionStr *string
-		resolutionQuery := `SELECT resolution FROM forecasts WHERE id = $1`
-		err = db.QueryRowContext(ctx, resolutionQuery, scoreToBackfill.ForecastID).Scan(&resolutionStr)
+		var resolution *models.Resolution
+		var resolutionValue *float64
+		resolutionQuery := `SELECT resolution, resolution_value FROM forecasts WHERE id = $1`
+		err = db.QueryRowContext(ctx, resolutionQuery, scoreToBackfill.ForecastID).Scan(&resolution, &resolutionValue)
 		if err != nil {
 			log.Printf("Error fetching resolution for forecast %d: %v", scoreToBackfill.ForecastID, err)
 			errorCount++
 			continue
 		}
 
-		if resolutionStr == nil || *resolutionStr == "-" {
+		if resolution == nil || !resolution.IsScored() {
 			log.Printf("Forecast %d has no valid resolution, skipping score %d", scoreToBackfill.ForecastID, scoreToBackfill.ScoreID)
 			errorCount++
 			continue
 		}
 
-		outcome := *resolutionStr == "1"
+		outcome, err := resolution.BinaryOutcome(resolutionValue)
+		if err != nil {
+			log.Printf("Forecast %d has no binary outcome, skipping score %d: %v", scoreToBackfill.ForecastID, scoreToBackfill.ScoreID, err)
+			errorCount++
+			continue
+		}
 
 		// Recalculate scores
-		recalculatedScore, err := models.CalcForecastScore(
+		recalculatedScore, err := models.CalcBinaryScore(
 			points,
 			outcome,
 			scoreToBackfill.UserID,
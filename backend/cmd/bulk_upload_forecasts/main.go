@@ -3,7 +3,9 @@ package main
 import (
 	"backend/internal/database"
 	"context"
+	"database/sql"
 	"encoding/csv"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -15,7 +17,7 @@ import (
 // This script bulk uploads forecasts from a CSV file
 // CSV format: question,category,resolution_criteria,user_id,closing_date
 // - question: The forecast question (required)
-// - category: The forecast category (required)
+// - category: The name of an existing top-level category (required)
 // - resolution_criteria: Criteria for resolution (required)
 // - user_id: The user ID who owns the forecast (required)
 // - closing_date: When the forecast closes, RFC3339 format e.g. 2025-12-31T23:59:59Z (optional)
@@ -183,9 +185,13 @@ func parseCSV(path string) ([]ForecastCSV, error) {
 }
 
 func insertForecast(ctx context.Context, db *database.DB, f ForecastCSV) (int64, error) {
+	// the category is the existing top-level category of that name, as for
+	// forecasts created through the API with a category name
 	query := `
-		INSERT INTO forecasts (question, category, created, user_id, resolution_criteria, closing_date)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		INSERT INTO forecasts (question, category, category_id, created, user_id, resolution_criteria, closing_date)
+		SELECT $1, c.name, c.id, $3, $4, $5, $6
+		FROM categories c
+		WHERE c.parent_id IS NULL AND lower(c.name) = lower(trim($2))
 		RETURNING id
 	`
 
@@ -199,6 +205,9 @@ func insertForecast(ctx context.Context, db *database.DB, f ForecastCSV) (int64,
 		f.ClosingDate,
 	).Scan(&id)
 
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("category %q not found", f.Category)
+	}
 	if err != nil {
 		return 0, err
 	}
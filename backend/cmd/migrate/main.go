@@ -0,0 +1,80 @@
+package main
+
+import (
+	"backend/internal/database"
+	"context"
+	"log"
+	"os"
+	"strconv"
+)
+
+// This script applies, reverts or lists the embedded schema migrations
+// Commands:
+// - up: apply every pending migration
+// - down [n]: revert the last n applied migrations (default 1)
+// - status: list migrations and when they were applied
+//
+// Run with: go run cmd/migrate/main.go <up|down|status>
+
+func main() {
+	if len(os.Args) < 2 {
+		log.Fatal("Usage: go run cmd/migrate/main.go <up|down [n]|status>")
+	}
+
+	db, err := database.NewDB(os.Getenv("DB_CONNECTION_STRING"))
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+
+	switch os.Args[1] {
+	case "up":
+		applied, err := db.MigrateUp(ctx)
+		for _, m := range applied {
+			log.Printf("Applied %04d_%s", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+		if len(applied) == 0 {
+			log.Println("Database is up to date.")
+		}
+
+	case "down":
+		steps := 1
+		if len(os.Args) > 2 {
+			steps, err = strconv.Atoi(os.Args[2])
+			if err != nil || steps < 1 {
+				log.Fatalf("Invalid number of steps '%s'", os.Args[2])
+			}
+		}
+		reverted, err := db.MigrateDown(ctx, steps)
+		for _, m := range reverted {
+			log.Printf("Reverted %04d_%s", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+		if len(reverted) == 0 {
+			log.Println("No applied migrations to revert.")
+		}
+
+	case "status":
+		statuses, err := db.MigrationStatus(ctx)
+		if err != nil {
+			log.Fatalf("Failed to get migration status: %v", err)
+		}
+		for _, s := range statuses {
+			if s.AppliedAt != nil {
+				log.Printf("%04d_%s applied at %s", s.Version, s.Name, s.AppliedAt.Format("2006-01-02 15:04:05"))
+			} else {
+				log.Printf("%04d_%s pending", s.Version, s.Name)
+			}
+		}
+
+	default:
+		log.Fatalf("Unknown command '%s', expected up, down or status", os.Args[1])
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"backend/internal/database"
+	"backend/internal/models"
+	"backend/internal/repository"
+	"context"
+	"log"
+	"os"
+)
+
+// This script sets a user's role, e.g. to promote the first admin who can
+// then manage roles through PUT /api/admin/users/role
+// Roles: admin, forecaster, bot
+//
+// Run with: go run cmd/set_role/main.go <username> <role>
+
+func main() {
+	if len(os.Args) < 3 {
+		log.Fatal("Usage: go run cmd/set_role/main.go <username> <admin|forecaster|bot>")
+	}
+
+	role, err := models.ParseRole(os.Args[2])
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	db, err := database.NewDB(os.Getenv("DB_CONNECTION_STRING"))
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	users := repository.NewUserRepository(db)
+
+	user, err := users.GetUserByUsername(ctx, os.Args[1])
+	if err != nil {
+		log.Fatalf("Failed to find user '%s': %v", os.Args[1], err)
+	}
+
+	if err := users.UpdateRole(ctx, user.ID, role); err != nil {
+		log.Fatalf("Failed to set role: %v", err)
+	}
+	log.Printf("User '%s' is now %s; the role applies from their next login", user.Username, role)
+}
@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"backend/internal/models"
 	"context"
 	"net/http"
 	"strings"
@@ -10,27 +11,129 @@ type contextKey string
 
 const UserContextKey contextKey = "user"
 
-func AuthMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		authHeader := r.Header.Get("Authorization")
-		if authHeader == "" {
-			http.Error(w, "Authorization header required", http.StatusUnauthorized)
-			return
+// APIKeyHeader carries an API key in place of a bearer token
+const APIKeyHeader = "X-API-Key"
+
+// KeyAuthenticator resolves an API key to the claims of the user it belongs to
+type KeyAuthenticator interface {
+	AuthenticateKey(ctx context.Context, key string) (*Claims, error)
+}
+
+// AuthMiddleware accepts either a bearer JWT, checked against the sessions, or
+// an API key in the X-API-Key header, and stores the resulting claims in the
+// request context
+func AuthMiddleware(keys KeyAuthenticator, sessions Sessions) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if apiKey := r.Header.Get(APIKeyHeader); apiKey != "" {
+				claims, err := keys.AuthenticateKey(r.Context(), apiKey)
+				if err != nil {
+					http.Error(w, "Invalid API key", http.StatusUnauthorized)
+					return
+				}
+
+				ctx := context.WithValue(r.Context(), UserContextKey, claims)
+				next.ServeHTTP(w, r.WithContext(ctx))
+				return
+			}
+
+			authHeader := r.Header.Get("Authorization")
+			if authHeader == "" {
+				http.Error(w, "Authorization header required", http.StatusUnauthorized)
+				return
+			}
+
+			tokenString := strings.TrimPrefix(authHeader, "Bearer ")
+			if tokenString == authHeader {
+				http.Error(w, "Invalid token format", http.StatusUnauthorized)
+				return
+			}
+
+			claims, err := ValidateToken(r.Context(), tokenString, sessions)
+			if err != nil {
+				http.Error(w, "Invalid token", http.StatusUnauthorized)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), UserContextKey, claims)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// OptionalAuth authenticates requests that carry credentials as AuthMiddleware
+// does, rejecting invalid ones, and lets anonymous requests through without
+// claims
+func OptionalAuth(keys KeyAuthenticator, sessions Sessions) func(http.Handler) http.Handler {
+	authenticated := AuthMiddleware(keys, sessions)
+	return func(next http.Handler) http.Handler {
+		withClaims := authenticated(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get(APIKeyHeader) == "" && r.Header.Get("Authorization") == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			withClaims.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireRole wraps a handler behind AuthMiddleware so that only users with
+// one of the roles can call it
+func RequireRole(roles ...models.Role) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := r.Context().Value(UserContextKey).(*Claims)
+			if !ok {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			for _, role := range roles {
+				if claims.Role == role {
+					next(w, r)
+					return
+				}
+			}
+			http.Error(w, "Forbidden", http.StatusForbidden)
+		}
+	}
+}
+
+// RequireScope wraps a handler behind AuthMiddleware so that API keys can
+// only call it when they carry the scope
+func RequireScope(scope models.Scope) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := r.Context().Value(UserContextKey).(*Claims)
+			if !ok {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			if !claims.HasScope(scope) {
+				http.Error(w, "API key is missing scope "+string(scope), http.StatusForbidden)
+				return
+			}
+			next(w, r)
 		}
+	}
+}
 
-		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
-		if tokenString == authHeader {
-			http.Error(w, "Invalid token format", http.StatusUnauthorized)
+// RequireToken wraps a handler behind AuthMiddleware so that it cannot be
+// called with an API key, e.g. to stop a scoped key minting an unscoped one
+func RequireToken(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := r.Context().Value(UserContextKey).(*Claims)
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
 			return
 		}
 
-		claims, err := ValidateToken(tokenString)
-		if err != nil {
-			http.Error(w, "Invalid token", http.StatusUnauthorized)
+		if claims.APIKeyID != 0 {
+			http.Error(w, "This route needs a login token, not an API key", http.StatusForbidden)
 			return
 		}
-
-		ctx := context.WithValue(r.Context(), UserContextKey, claims)
-		next.ServeHTTP(w, r.WithContext(ctx))
-	})
+		next(w, r)
+	}
 }
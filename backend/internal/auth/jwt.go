@@ -1,6 +1,10 @@
 package auth
 
 import (
+	"backend/internal/models"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"time"
@@ -8,18 +12,53 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// DefaultAccessTokenTTL is how long access tokens last unless configured;
+// clients renew them with their refresh token
+const DefaultAccessTokenTTL = 15 * time.Minute
+
 var (
 	ErrInvalidToken   = errors.New("invalid token")
+	ErrRevokedToken   = errors.New("token has been revoked")
 	ErrNotInitialized = errors.New("auth package not initialized")
 	secretKey         []byte
+	accessTokenTTL    = DefaultAccessTokenTTL
 )
 
+// Sessions is the server-side state tokens are checked against
+type Sessions interface {
+	// TokenVersion returns the user's current token version. Tokens issued
+	// with an older version, e.g. before a password change, are rejected.
+	TokenVersion(ctx context.Context, userID int64) (int, error)
+	// IsTokenRevoked reports whether the token with the jti was logged out
+	IsTokenRevoked(ctx context.Context, jti string) (bool, error)
+}
+
 type Claims struct {
-	UserID   int64  `json:"user_id"`
-	Username string `json:"username"`
+	UserID   int64       `json:"user_id"`
+	Username string      `json:"username"`
+	Role     models.Role `json:"role"`
+	// Scopes limits a request made with an API key; tokens carry none
+	Scopes   models.Scopes `json:"scopes,omitempty"`
+	APIKeyID int64         `json:"-"`
+	// TokenVersion must match the user's for the token to be valid
+	TokenVersion int `json:"token_version"`
 	jwt.RegisteredClaims
 }
 
+// HasScope reports whether the claims allow the scope. Claims without scopes
+// allow everything the role does.
+func (c *Claims) HasScope(scope models.Scope) bool {
+	if len(c.Scopes) == 0 {
+		return true
+	}
+	for _, s := range c.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
 // Init initializes the auth package with the JWT secret.
 // Must be called before using GenerateToken or ValidateToken.
 func Init(secret []byte) error {
@@ -30,25 +69,52 @@ func Init(secret []byte) error {
 	return nil
 }
 
-func GenerateToken(userID int64, username string) (string, error) {
+// SetAccessTokenTTL changes how long newly issued access tokens last
+func SetAccessTokenTTL(ttl time.Duration) error {
+	if ttl <= 0 {
+		return fmt.Errorf("access token TTL must be positive, got %v", ttl)
+	}
+	accessTokenTTL = ttl
+	return nil
+}
+
+// GenerateToken issues an access token with a unique jti, so it can be revoked
+// on its own, and returns it along with when it expires
+func GenerateToken(userID int64, username string, role models.Role, tokenVersion int) (string, time.Time, error) {
 	if secretKey == nil {
-		return "", ErrNotInitialized
+		return "", time.Time{}, ErrNotInitialized
 	}
 
+	jti := make([]byte, 16)
+	if _, err := rand.Read(jti); err != nil {
+		return "", time.Time{}, err
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(accessTokenTTL)
 	claims := Claims{
-		UserID:   userID,
-		Username: username,
+		UserID:       userID,
+		Username:     username,
+		Role:         role,
+		TokenVersion: tokenVersion,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ID:        hex.EncodeToString(jti),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(now),
 		},
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(secretKey)
+	signed, err := token.SignedString(secretKey)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return signed, expiresAt, nil
 }
 
-func ValidateToken(tokenString string) (*Claims, error) {
+// ValidateToken checks the token's signature and expiry, and then that it was
+// not revoked by a logout or a password change
+func ValidateToken(ctx context.Context, tokenString string, sessions Sessions) (*Claims, error) {
 	if secretKey == nil {
 		return nil, ErrNotInitialized
 	}
@@ -65,5 +131,29 @@ func ValidateToken(tokenString string) (*Claims, error) {
 		return nil, ErrInvalidToken
 	}
 
-	return token.Claims.(*Claims), nil
+	claims := token.Claims.(*Claims)
+	// tokens issued before roles existed belong to forecasters
+	if claims.Role == "" {
+		claims.Role = models.RoleForecaster
+	}
+
+	version, err := sessions.TokenVersion(ctx, claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if claims.TokenVersion != version {
+		return nil, ErrRevokedToken
+	}
+
+	// tokens issued before jtis existed cannot be revoked on their own
+	if claims.ID != "" {
+		revoked, err := sessions.IsTokenRevoked(ctx, claims.ID)
+		if err != nil {
+			return nil, err
+		}
+		if revoked {
+			return nil, ErrRevokedToken
+		}
+	}
+	return claims, nil
 }
@@ -1,60 +1,81 @@
+// Package cache caches the results of the services' queries. MemoryCache
+// keeps entries in process; RedisCache shares them between instances of the
+// backend and SyncedCache keeps them in process but invalidates them on every
+// instance, so either can be used when running more than one.
 package cache
 
 import (
 	"strings"
-	"sync"
+	"time"
 )
 
-type CacheItem struct {
-	Value any
-}
+// separator splits keys into the segments prefix invalidation works on, e.g.
+// "point:list:user:1:2" falls under "point:", "point:list:" and so on
+const separator = ":"
 
-type Cache struct {
-	items map[string]CacheItem
-	mu    sync.RWMutex
-}
+const (
+	// DefaultMaxEntries bounds the caches made by NewCache
+	DefaultMaxEntries = 10000
+	// DefaultMaxBytes bounds the estimated size of the caches made by NewCache
+	DefaultMaxBytes = 64 << 20
+	// DefaultTTL is how long entries set without a TTL live in caches made by NewCache
+	DefaultTTL = time.Hour
+)
 
-func NewCache() *Cache {
-	return &Cache{
-		items: make(map[string]CacheItem),
-	}
+// Cache is implemented by each cache backend. Values read back from a backend
+// that serializes them have the type they were set with, if it was registered
+// with Register, and are otherwise treated as missing.
+type Cache interface {
+	Get(key string) (any, bool)
+	// Set stores the value for the cache's default TTL
+	Set(key string, value any)
+	// SetWithTTL stores the value until the TTL passes, or for good if it is zero
+	SetWithTTL(key string, value any, ttl time.Duration)
+	// SetWithTags stores the value for the default TTL, or until one of the
+	// tags, naming the data the value was built from, is invalidated
+	SetWithTags(key string, value any, tags ...string)
+	// InvalidateTags deletes every entry set with one of the tags
+	InvalidateTags(tags ...string)
+	Delete(key string)
+	// DeleteByPrefix deletes every key starting with the prefix. It is
+	// cheapest for prefixes ending in the separator, like "score:".
+	DeleteByPrefix(prefix string)
+	Stats() Stats
 }
 
-func (c *Cache) Set(key string, value any) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	c.items[key] = CacheItem{
-		Value: value,
-	}
+// Options configures a cache. Zero values mean no bound and no expiry.
+type Options struct {
+	MaxEntries int
+	// MaxBytes bounds the entries' estimated size, see MemoryCache
+	MaxBytes   int64
+	DefaultTTL time.Duration
 }
 
-func (c *Cache) Get(key string) (interface{}, bool) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	item, found := c.items[key]
-	if !found {
-		return nil, false
-	}
-
-	return item.Value, true
-}
-
-func (c *Cache) Delete(key string) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	delete(c.items, key)
+// Stats are a cache's counters since it was created
+type Stats struct {
+	Hits   uint64 `json:"hits"`
+	Misses uint64 `json:"misses"`
+	// Evictions counts entries dropped to stay within MaxEntries or MaxBytes
+	Evictions uint64 `json:"evictions"`
+	// Expirations counts entries dropped because their TTL passed
+	Expirations uint64 `json:"expirations"`
+	// Errors counts failed calls to Redis
+	Errors  uint64 `json:"errors"`
+	Entries int    `json:"entries"`
+	// Bytes is the estimated size of the entries kept in memory
+	Bytes int64 `json:"bytes,omitempty"`
 }
 
-func (c *Cache) DeleteByPrefix(prefix string) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	for key := range c.items {
-		if strings.HasPrefix(key, prefix) {
-			delete(c.items, key)
+// prefixesOf returns the separator-terminated prefixes of the key
+func prefixesOf(key string) []string {
+	var prefixes []string
+	for i := 0; i < len(key); {
+		j := strings.Index(key[i:], separator)
+		if j < 0 {
+			break
 		}
+		i += j + len(separator)
+		prefixes = append(prefixes, key[:i])
 	}
+	return prefixes
 }
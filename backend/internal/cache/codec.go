@@ -0,0 +1,76 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// registry maps the types of cached values to names and back, so backends
+// that serialize values can restore their type
+var registry = struct {
+	sync.RWMutex
+	names map[reflect.Type]string
+	types map[string]reflect.Type
+}{
+	names: make(map[reflect.Type]string),
+	types: make(map[string]reflect.Type),
+}
+
+// Register allows values of the same types as the examples to be cached by
+// backends that serialize them. Values are stored as JSON, so they read back
+// the way the API would return them.
+func Register(examples ...any) {
+	registry.Lock()
+	defer registry.Unlock()
+
+	for _, example := range examples {
+		t := reflect.TypeOf(example)
+		registry.names[t] = t.String()
+		registry.types[t.String()] = t
+	}
+}
+
+// envelope is the serialized form of a value
+type envelope struct {
+	Type  string          `json:"type"`
+	Value json.RawMessage `json:"value"`
+	// Tags are the versions of the value's tags when it was set
+	Tags map[string]string `json:"tags,omitempty"`
+}
+
+func encode(value any, tags map[string]string) ([]byte, error) {
+	registry.RLock()
+	name, found := registry.names[reflect.TypeOf(value)]
+	registry.RUnlock()
+	if !found {
+		return nil, fmt.Errorf("cache: type %T is not registered", value)
+	}
+
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(envelope{Type: name, Value: raw, Tags: tags})
+}
+
+func decode(data []byte) (any, map[string]string, error) {
+	var e envelope
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, nil, err
+	}
+
+	registry.RLock()
+	t, found := registry.types[e.Type]
+	registry.RUnlock()
+	if !found {
+		return nil, nil, fmt.Errorf("cache: type %s is not registered", e.Type)
+	}
+
+	value := reflect.New(t)
+	if err := json.Unmarshal(e.Value, value.Interface()); err != nil {
+		return nil, nil, err
+	}
+	return value.Elem().Interface(), e.Tags, nil
+}
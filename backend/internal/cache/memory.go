@@ -0,0 +1,274 @@
+package cache
+
+import (
+	"container/list"
+	"encoding/json"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type CacheItem struct {
+	Value any
+	// ExpiresAt is zero for entries that never expire
+	ExpiresAt time.Time
+}
+
+func (i CacheItem) expired(now time.Time) bool {
+	return !i.ExpiresAt.IsZero() && now.After(i.ExpiresAt)
+}
+
+// entry is an element of the LRU list
+type entry struct {
+	key  string
+	item CacheItem
+	tags []string
+	// size is the entry's estimated size, counted when MaxBytes is set
+	size int64
+}
+
+// MemoryCache is an in-process LRU cache with per-entry TTLs. Expired entries
+// are dropped when they are read or reach the back of the list. Besides
+// MaxEntries it can hold the entries within MaxBytes, estimating each by the
+// length of its key and JSON encoding when it is set, which tracks the size of
+// the structs the services cache but is not their exact footprint.
+type MemoryCache struct {
+	opts Options
+	// bytes is the estimated size of the entries, when MaxBytes is set
+	bytes int64
+
+	items map[string]*list.Element
+	// lru has the most recently used entry at the front
+	lru *list.List
+	// prefixes maps each separator-terminated prefix of a key to the keys
+	// under it, so DeleteByPrefix does not scan every key
+	prefixes map[string]map[string]struct{}
+	// tags maps each tag to the keys set with it
+	tags map[string]map[string]struct{}
+	mu   sync.Mutex
+
+	hits        atomic.Uint64
+	misses      atomic.Uint64
+	evictions   atomic.Uint64
+	expirations atomic.Uint64
+}
+
+// NewCache creates a MemoryCache with DefaultMaxEntries, DefaultMaxBytes and
+// DefaultTTL
+func NewCache() *MemoryCache {
+	return NewMemoryCache(Options{
+		MaxEntries: DefaultMaxEntries,
+		MaxBytes:   DefaultMaxBytes,
+		DefaultTTL: DefaultTTL,
+	})
+}
+
+func NewMemoryCache(opts Options) *MemoryCache {
+	return &MemoryCache{
+		opts:     opts,
+		items:    make(map[string]*list.Element),
+		lru:      list.New(),
+		prefixes: make(map[string]map[string]struct{}),
+		tags:     make(map[string]map[string]struct{}),
+	}
+}
+
+// Set stores the value for the cache's default TTL
+func (c *MemoryCache) Set(key string, value any) {
+	c.SetWithTTL(key, value, c.opts.DefaultTTL)
+}
+
+// SetWithTTL stores the value until the TTL passes, or for good if it is zero
+func (c *MemoryCache) SetWithTTL(key string, value any, ttl time.Duration) {
+	c.set(key, value, ttl, nil)
+}
+
+// SetWithTags stores the value for the default TTL, or until one of its tags is
+// invalidated
+func (c *MemoryCache) SetWithTags(key string, value any, tags ...string) {
+	c.set(key, value, c.opts.DefaultTTL, tags)
+}
+
+func (c *MemoryCache) set(key string, value any, ttl time.Duration, tags []string) {
+	var size int64
+	if c.opts.MaxBytes > 0 {
+		size = entrySize(key, value)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	item := CacheItem{Value: value}
+	if ttl > 0 {
+		item.ExpiresAt = time.Now().Add(ttl)
+	}
+
+	// replacing an entry replaces its tags too
+	c.remove(key)
+	if c.opts.MaxBytes > 0 && size > c.opts.MaxBytes {
+		// it would evict every other entry and still not fit
+		c.evictions.Add(1)
+		return
+	}
+	c.items[key] = c.lru.PushFront(&entry{key: key, item: item, tags: tags, size: size})
+	c.bytes += size
+	c.index(key, tags)
+
+	for (c.opts.MaxEntries > 0 && c.lru.Len() > c.opts.MaxEntries) || (c.opts.MaxBytes > 0 && c.bytes > c.opts.MaxBytes) {
+		oldest := c.lru.Back().Value.(*entry)
+		if oldest.item.expired(time.Now()) {
+			c.expirations.Add(1)
+		} else {
+			c.evictions.Add(1)
+		}
+		c.remove(oldest.key)
+	}
+}
+
+func (c *MemoryCache) Get(key string) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, found := c.items[key]
+	if !found {
+		c.misses.Add(1)
+		return nil, false
+	}
+
+	e := el.Value.(*entry)
+	if e.item.expired(time.Now()) {
+		c.remove(key)
+		c.expirations.Add(1)
+		c.misses.Add(1)
+		return nil, false
+	}
+
+	c.lru.MoveToFront(el)
+	c.hits.Add(1)
+	return e.item.Value, true
+}
+
+func (c *MemoryCache) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.remove(key)
+}
+
+// DeleteByPrefix deletes every key starting with the prefix. Prefixes ending
+// in the separator, like "score:", are looked up in the index; any other
+// prefix falls back to scanning the keys.
+func (c *MemoryCache) DeleteByPrefix(prefix string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if strings.HasSuffix(prefix, separator) {
+		for key := range c.prefixes[prefix] {
+			c.remove(key)
+		}
+		return
+	}
+
+	for key := range c.items {
+		if strings.HasPrefix(key, prefix) {
+			c.remove(key)
+		}
+	}
+}
+
+// InvalidateTags deletes the entries set with any of the tags
+func (c *MemoryCache) InvalidateTags(tags ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, tag := range tags {
+		for key := range c.tags[tag] {
+			c.remove(key)
+		}
+	}
+}
+
+// Clear deletes every entry
+func (c *MemoryCache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.items = make(map[string]*list.Element)
+	c.lru.Init()
+	c.bytes = 0
+	c.prefixes = make(map[string]map[string]struct{})
+	c.tags = make(map[string]map[string]struct{})
+}
+
+// Stats returns a snapshot of the cache's counters
+func (c *MemoryCache) Stats() Stats {
+	c.mu.Lock()
+	entries, bytes := c.lru.Len(), c.bytes
+	c.mu.Unlock()
+
+	return Stats{
+		Hits:        c.hits.Load(),
+		Misses:      c.misses.Load(),
+		Evictions:   c.evictions.Load(),
+		Expirations: c.expirations.Load(),
+		Entries:     entries,
+		Bytes:       bytes,
+	}
+}
+
+// remove drops the key from the list, map and index. The caller holds mu.
+func (c *MemoryCache) remove(key string) {
+	el, found := c.items[key]
+	if !found {
+		return
+	}
+
+	e := el.Value.(*entry)
+	c.lru.Remove(el)
+	delete(c.items, key)
+	c.bytes -= e.size
+	for _, prefix := range prefixesOf(key) {
+		unindex(c.prefixes, prefix, key)
+	}
+	for _, tag := range e.tags {
+		unindex(c.tags, tag, key)
+	}
+}
+
+// entrySize estimates the memory an entry holds by the length of its key and
+// JSON encoding. Values that cannot be encoded count as their key.
+func entrySize(key string, value any) int64 {
+	size := int64(len(key))
+	if raw, err := json.Marshal(value); err == nil {
+		size += int64(len(raw))
+	}
+	return size
+}
+
+// index adds the key under each of its prefixes and tags. The caller holds mu.
+func (c *MemoryCache) index(key string, tags []string) {
+	for _, prefix := range prefixesOf(key) {
+		addIndex(c.prefixes, prefix, key)
+	}
+	for _, tag := range tags {
+		addIndex(c.tags, tag, key)
+	}
+}
+
+func addIndex(index map[string]map[string]struct{}, name string, key string) {
+	keys, found := index[name]
+	if !found {
+		keys = make(map[string]struct{})
+		index[name] = keys
+	}
+	keys[key] = struct{}{}
+}
+
+func unindex(index map[string]map[string]struct{}, name string, key string) {
+	keys := index[name]
+	delete(keys, key)
+	if len(keys) == 0 {
+		delete(index, name)
+	}
+}
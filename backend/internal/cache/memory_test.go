@@ -0,0 +1,186 @@
+package cache
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMemoryCache_SetGetDelete(t *testing.T) {
+	c := NewCache()
+
+	c.Set("users", []string{"alice"})
+	if value, found := c.Get("users"); !found || value.([]string)[0] != "alice" {
+		t.Fatalf("Expected to get the value back, got %v, %v", value, found)
+	}
+
+	c.Delete("users")
+	if _, found := c.Get("users"); found {
+		t.Error("Expected a deleted key to be missing")
+	}
+
+	stats := c.Stats()
+	if stats.Hits != 1 || stats.Misses != 1 || stats.Entries != 0 {
+		t.Errorf("Expected 1 hit, 1 miss and no entries, got %+v", stats)
+	}
+}
+
+func TestMemoryCache_TTL(t *testing.T) {
+	c := NewMemoryCache(Options{DefaultTTL: time.Millisecond})
+
+	c.Set("short", 1)
+	c.SetWithTTL("forever", 2, 0)
+	time.Sleep(5 * time.Millisecond)
+
+	if _, found := c.Get("short"); found {
+		t.Error("Expected the entry to expire")
+	}
+	if _, found := c.Get("forever"); !found {
+		t.Error("Expected an entry without a TTL to stay")
+	}
+	if stats := c.Stats(); stats.Expirations != 1 || stats.Entries != 1 {
+		t.Errorf("Expected 1 expiration and 1 entry left, got %+v", stats)
+	}
+}
+
+func TestMemoryCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	c := NewMemoryCache(Options{MaxEntries: 2})
+
+	c.Set("a", 1)
+	c.Set("b", 2)
+	c.Get("a")
+	c.Set("c", 3)
+
+	if _, found := c.Get("b"); found {
+		t.Error("Expected the least recently used entry to be evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, found := c.Get(key); !found {
+			t.Errorf("Expected %s to stay", key)
+		}
+	}
+	if stats := c.Stats(); stats.Evictions != 1 || stats.Entries != 2 {
+		t.Errorf("Expected 1 eviction and 2 entries, got %+v", stats)
+	}
+}
+
+func TestMemoryCache_EvictsWithinMaxBytes(t *testing.T) {
+	// each entry is a one-byte key and an eight-letter string, ten bytes quoted
+	c := NewMemoryCache(Options{MaxBytes: 25})
+
+	c.Set("a", "aaaaaaaa")
+	c.Set("b", "bbbbbbbb")
+	if stats := c.Stats(); stats.Bytes != 22 || stats.Entries != 2 {
+		t.Fatalf("Expected 2 entries of 22 bytes, got %+v", stats)
+	}
+	c.Set("c", "cccccccc")
+	if _, found := c.Get("a"); found {
+		t.Error("Expected the oldest entry to be evicted to fit the new one")
+	}
+
+	// replacing an entry counts its new size only
+	c.Set("b", "b")
+	if stats := c.Stats(); stats.Bytes != 15 || stats.Entries != 2 || stats.Evictions != 1 {
+		t.Errorf("Expected 2 entries of 15 bytes after 1 eviction, got %+v", stats)
+	}
+
+	// an entry larger than the budget is not kept
+	c.Set("big", strings.Repeat("x", 30))
+	if _, found := c.Get("big"); found {
+		t.Error("Expected an entry over the budget not to be cached")
+	}
+	if stats := c.Stats(); stats.Entries != 2 {
+		t.Errorf("Expected the other entries to stay, got %+v", stats)
+	}
+
+	c.Clear()
+	if stats := c.Stats(); stats.Bytes != 0 {
+		t.Errorf("Expected no bytes after clearing, got %+v", stats)
+	}
+}
+
+func TestMemoryCache_DeleteByPrefix(t *testing.T) {
+	c := NewCache()
+
+	for i := 0; i < 3; i++ {
+		c.Set(fmt.Sprintf("point:list:user:%d:%d", i, i), i)
+	}
+	c.Set("point:all", 0)
+	c.Set("score:all", 0)
+	c.Set("scores", 0)
+
+	c.DeleteByPrefix("point:list:")
+	for i := 0; i < 3; i++ {
+		if _, found := c.Get(fmt.Sprintf("point:list:user:%d:%d", i, i)); found {
+			t.Errorf("Expected point:list:user:%d:%d to be deleted", i, i)
+		}
+	}
+	if _, found := c.Get("point:all"); !found {
+		t.Error("Expected keys outside the prefix to stay")
+	}
+
+	// prefixes that do not end in the separator still work
+	c.DeleteByPrefix("score")
+	for _, key := range []string{"score:all", "scores"} {
+		if _, found := c.Get(key); found {
+			t.Errorf("Expected %s to be deleted", key)
+		}
+	}
+
+	c.DeleteByPrefix("point:")
+	if stats := c.Stats(); stats.Entries != 0 {
+		t.Errorf("Expected no entries left, got %+v", stats)
+	}
+	if len(c.prefixes) != 0 {
+		t.Errorf("Expected the prefix index to be empty, got %v", c.prefixes)
+	}
+}
+
+func TestMemoryCache_InvalidateTags(t *testing.T) {
+	c := NewCache()
+
+	c.SetWithTags("forecast:detail:1", 1, "forecast:1")
+	c.SetWithTags("forecast:list:open", 0, "forecasts")
+	c.SetWithTags("score:all", 0, "scores", "forecasts")
+	c.Set("users", 0)
+
+	c.InvalidateTags("forecasts")
+	for _, key := range []string{"forecast:list:open", "score:all"} {
+		if _, found := c.Get(key); found {
+			t.Errorf("Expected %s to be invalidated", key)
+		}
+	}
+	for _, key := range []string{"forecast:detail:1", "users"} {
+		if _, found := c.Get(key); !found {
+			t.Errorf("Expected %s to stay", key)
+		}
+	}
+
+	// setting a key again replaces its tags
+	c.SetWithTags("forecast:detail:1", 2, "forecast:2")
+	c.InvalidateTags("forecast:1")
+	if _, found := c.Get("forecast:detail:1"); !found {
+		t.Error("Expected a replaced entry to drop its old tags")
+	}
+	c.InvalidateTags("forecast:2")
+	if _, found := c.Get("forecast:detail:1"); found {
+		t.Error("Expected the entry to be invalidated by its new tag")
+	}
+
+	c.Delete("users")
+	if len(c.tags) != 0 {
+		t.Errorf("Expected the tag index to be empty, got %v", c.tags)
+	}
+}
+
+func TestPrefixesOf(t *testing.T) {
+	got := prefixesOf("point:list:user:1:2")
+	want := []string{"point:", "point:list:", "point:list:user:", "point:list:user:1:"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+	if got := prefixesOf("users"); len(got) != 0 {
+		t.Errorf("Expected no prefixes for a key without separators, got %v", got)
+	}
+}
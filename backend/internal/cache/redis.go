@@ -0,0 +1,296 @@
+package cache
+
+import (
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultNamespace prefixes the Redis keys of caches made without one
+const DefaultNamespace = "forecasts:cache:"
+
+// RedisCache stores entries in Redis, so every instance of the backend sees
+// the same entries and invalidations. Keys are stored under the version of
+// each of their separator-terminated prefixes, and DeleteByPrefix bumps the
+// version, orphaning the old entries until their TTL passes. Entries keep the
+// versions their tags had when they were set, and InvalidateTags bumps them,
+// so reads of older entries miss. Errors are logged and counted, and turn
+// reads into misses.
+type RedisCache struct {
+	client     *redisClient
+	namespace  string
+	defaultTTL time.Duration
+
+	hits   atomic.Uint64
+	misses atomic.Uint64
+	errors atomic.Uint64
+}
+
+// NewRedisCache creates a cache on the Redis described by the options. Nothing is
+// dialed until the cache is first used.
+func NewRedisCache(redis RedisOptions, namespace string, defaultTTL time.Duration) *RedisCache {
+	if namespace == "" {
+		namespace = DefaultNamespace
+	}
+	return &RedisCache{
+		client:     newRedisClient(redis),
+		namespace:  namespace,
+		defaultTTL: defaultTTL,
+	}
+}
+
+func (c *RedisCache) Get(key string) (any, bool) {
+	dataKey, err := c.dataKey(key)
+	if err != nil {
+		c.fail("get", key, err)
+		c.misses.Add(1)
+		return nil, false
+	}
+
+	reply, err := c.client.do("GET", dataKey)
+	if err != nil {
+		c.fail("get", key, err)
+		c.misses.Add(1)
+		return nil, false
+	}
+	data, ok := reply.([]byte)
+	if !ok {
+		c.misses.Add(1)
+		return nil, false
+	}
+
+	value, tags, err := decode(data)
+	if err != nil {
+		c.fail("decode", key, err)
+		c.misses.Add(1)
+		return nil, false
+	}
+
+	if len(tags) > 0 {
+		names := make([]string, 0, len(tags))
+		for tag := range tags {
+			names = append(names, tag)
+		}
+		current, err := c.tagVersions(names)
+		if err != nil {
+			c.fail("get", key, err)
+			c.misses.Add(1)
+			return nil, false
+		}
+		for tag, version := range tags {
+			if current[tag] != version {
+				c.misses.Add(1)
+				return nil, false
+			}
+		}
+	}
+
+	c.hits.Add(1)
+	return value, true
+}
+
+func (c *RedisCache) Set(key string, value any) {
+	c.SetWithTTL(key, value, c.defaultTTL)
+}
+
+func (c *RedisCache) SetWithTTL(key string, value any, ttl time.Duration) {
+	c.set(key, value, ttl, nil)
+}
+
+func (c *RedisCache) SetWithTags(key string, value any, tags ...string) {
+	versions, err := c.tagVersions(tags)
+	if err != nil {
+		c.fail("set", key, err)
+		return
+	}
+	c.set(key, value, c.defaultTTL, versions)
+}
+
+func (c *RedisCache) set(key string, value any, ttl time.Duration, tags map[string]string) {
+	data, err := encode(value, tags)
+	if err != nil {
+		c.fail("encode", key, err)
+		return
+	}
+
+	dataKey, err := c.dataKey(key)
+	if err != nil {
+		c.fail("set", key, err)
+		return
+	}
+
+	args := []string{"SET", dataKey, string(data)}
+	if ttl > 0 {
+		args = append(args, "PX", strconv.FormatInt(ttl.Milliseconds(), 10))
+	}
+	if _, err := c.client.do(args...); err != nil {
+		c.fail("set", key, err)
+	}
+}
+
+func (c *RedisCache) Delete(key string) {
+	dataKey, err := c.dataKey(key)
+	if err != nil {
+		c.fail("delete", key, err)
+		return
+	}
+
+	if _, err := c.client.do("DEL", dataKey); err != nil {
+		c.fail("delete", key, err)
+	}
+}
+
+// DeleteByPrefix bumps the version of prefixes ending in the separator. Any
+// other prefix is deleted by scanning the namespace for matching keys.
+func (c *RedisCache) DeleteByPrefix(prefix string) {
+	if strings.HasSuffix(prefix, separator) {
+		if _, err := c.client.do("INCR", c.versionKey(prefix)); err != nil {
+			c.fail("delete prefix", prefix, err)
+		}
+		return
+	}
+
+	pattern := c.namespace + "k:" + escapeGlob(prefix) + "*"
+	cursor := "0"
+	for {
+		reply, err := c.client.do("SCAN", cursor, "MATCH", pattern, "COUNT", "100")
+		if err != nil {
+			c.fail("delete prefix", prefix, err)
+			return
+		}
+
+		page, ok := reply.([]any)
+		if !ok || len(page) != 2 {
+			c.fail("delete prefix", prefix, redisError("unexpected SCAN reply"))
+			return
+		}
+		next, _ := page[0].([]byte)
+		keys, _ := page[1].([]any)
+
+		if len(keys) > 0 {
+			args := []string{"DEL"}
+			for _, k := range keys {
+				if b, ok := k.([]byte); ok {
+					args = append(args, string(b))
+				}
+			}
+			if _, err := c.client.do(args...); err != nil {
+				c.fail("delete prefix", prefix, err)
+				return
+			}
+		}
+
+		cursor = string(next)
+		if cursor == "0" || cursor == "" {
+			return
+		}
+	}
+}
+
+// InvalidateTags bumps the version of each tag
+func (c *RedisCache) InvalidateTags(tags ...string) {
+	for _, tag := range tags {
+		if _, err := c.client.do("INCR", c.tagKey(tag)); err != nil {
+			c.fail("invalidate tag", tag, err)
+		}
+	}
+}
+
+// Stats returns this instance's hits, misses and errors. Entries, evictions
+// and expirations are up to Redis and are not tracked.
+func (c *RedisCache) Stats() Stats {
+	return Stats{
+		Hits:   c.hits.Load(),
+		Misses: c.misses.Load(),
+		Errors: c.errors.Load(),
+	}
+}
+
+func (c *RedisCache) Close() error {
+	return c.client.Close()
+}
+
+// dataKey is the Redis key of the key under the current versions of its
+// prefixes
+func (c *RedisCache) dataKey(key string) (string, error) {
+	prefixes := prefixesOf(key)
+	if len(prefixes) == 0 {
+		return c.namespace + "k:" + key + "@", nil
+	}
+
+	args := []string{"MGET"}
+	for _, prefix := range prefixes {
+		args = append(args, c.versionKey(prefix))
+	}
+	reply, err := c.client.do(args...)
+	if err != nil {
+		return "", err
+	}
+
+	return c.namespace + "k:" + key + "@" + strings.Join(versionsOf(reply, len(prefixes)), "."), nil
+}
+
+// tagVersions returns the current version of each tag
+func (c *RedisCache) tagVersions(tags []string) (map[string]string, error) {
+	if len(tags) == 0 {
+		return nil, nil
+	}
+
+	args := []string{"MGET"}
+	for _, tag := range tags {
+		args = append(args, c.tagKey(tag))
+	}
+	reply, err := c.client.do(args...)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make(map[string]string, len(tags))
+	for i, version := range versionsOf(reply, len(tags)) {
+		versions[tags[i]] = version
+	}
+	return versions, nil
+}
+
+// versionsOf reads an MGET reply of n version counters, which start at "0"
+func versionsOf(reply any, n int) []string {
+	values, _ := reply.([]any)
+	versions := make([]string, n)
+	for i := range versions {
+		versions[i] = "0"
+		if i < len(values) {
+			if b, ok := values[i].([]byte); ok {
+				versions[i] = string(b)
+			}
+		}
+	}
+	return versions
+}
+
+func (c *RedisCache) versionKey(prefix string) string {
+	return c.namespace + "v:" + prefix
+}
+
+func (c *RedisCache) tagKey(tag string) string {
+	return c.namespace + "t:" + tag
+}
+
+func (c *RedisCache) fail(op string, key string, err error) {
+	c.errors.Add(1)
+	slog.Warn("redis cache error", slog.String("op", op), slog.String("cache_key", key), slog.String("error", err.Error()))
+}
+
+// escapeGlob escapes the characters Redis patterns treat specially
+func escapeGlob(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch r {
+		case '*', '?', '[', ']', '\\':
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
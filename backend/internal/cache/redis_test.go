@@ -0,0 +1,284 @@
+package cache
+
+import (
+	"backend/internal/cache/redistest"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+)
+
+type cachedThing struct {
+	Name  string   `json:"name"`
+	Score *float64 `json:"score"`
+}
+
+func init() {
+	Register([]cachedThing{}, &cachedThing{})
+}
+
+// redisOptions points at REDIS_ADDR when it is set, to test against a real
+// Redis, and otherwise at an in-process stand-in
+func redisOptions(t *testing.T) RedisOptions {
+	if addr := os.Getenv("REDIS_ADDR"); addr != "" {
+		return RedisOptions{Addr: addr, Password: os.Getenv("REDIS_PASSWORD")}
+	}
+	return RedisOptions{Addr: redistest.NewServer(t).Addr()}
+}
+
+// testNamespace keeps tests against a real Redis apart
+func testNamespace(t *testing.T) string {
+	return fmt.Sprintf("test:%s:%d:", t.Name(), time.Now().UnixNano())
+}
+
+// eventually retries the check until it passes or five seconds have gone by,
+// for invalidations that arrive asynchronously
+func eventually(t *testing.T, check func() bool, msg string) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !check() {
+		if time.Now().After(deadline) {
+			t.Fatal(msg)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestRedisCache_RoundTrip(t *testing.T) {
+	c := NewRedisCache(redisOptions(t), testNamespace(t), time.Minute)
+	defer c.Close()
+
+	score := 0.25
+	c.Set("thing:list", []cachedThing{{Name: "a", Score: &score}, {Name: "b"}})
+	value, found := c.Get("thing:list")
+	if !found {
+		t.Fatal("Expected to get the value back")
+	}
+	things, ok := value.([]cachedThing)
+	if !ok || len(things) != 2 || *things[0].Score != 0.25 || things[1].Score != nil {
+		t.Fatalf("Expected the value to keep its type and contents, got %#v", value)
+	}
+
+	c.Set("thing:one", &cachedThing{Name: "c"})
+	if value, _ := c.Get("thing:one"); value.(*cachedThing).Name != "c" {
+		t.Errorf("Expected a pointer value back, got %#v", value)
+	}
+
+	// values of unregistered types are not cached
+	c.Set("unregistered", map[string]int{"a": 1})
+	if _, found := c.Get("unregistered"); found {
+		t.Error("Expected a value of an unregistered type to be missing")
+	}
+
+	c.Delete("thing:list")
+	if _, found := c.Get("thing:list"); found {
+		t.Error("Expected a deleted key to be missing")
+	}
+
+	stats := c.Stats()
+	if stats.Hits != 2 || stats.Misses != 2 || stats.Errors != 1 {
+		t.Errorf("Expected 2 hits, 2 misses and 1 error, got %+v", stats)
+	}
+}
+
+func TestRedisCache_TTL(t *testing.T) {
+	c := NewRedisCache(redisOptions(t), testNamespace(t), 0)
+	defer c.Close()
+
+	c.SetWithTTL("short", []cachedThing{}, 20*time.Millisecond)
+	c.Set("forever", []cachedThing{})
+	time.Sleep(50 * time.Millisecond)
+
+	if _, found := c.Get("short"); found {
+		t.Error("Expected the entry to expire")
+	}
+	if _, found := c.Get("forever"); !found {
+		t.Error("Expected an entry without a TTL to stay")
+	}
+}
+
+func TestRedisCache_DeleteByPrefixAcrossInstances(t *testing.T) {
+	opts, namespace := redisOptions(t), testNamespace(t)
+	a := NewRedisCache(opts, namespace, time.Minute)
+	b := NewRedisCache(opts, namespace, time.Minute)
+	defer a.Close()
+	defer b.Close()
+
+	for i := 0; i < 3; i++ {
+		a.Set(fmt.Sprintf("score:user:%d", i), []cachedThing{})
+	}
+	a.Set("scores", []cachedThing{})
+	a.Set("point:all", []cachedThing{})
+
+	if _, found := b.Get("score:user:1"); !found {
+		t.Fatal("Expected instances to share entries")
+	}
+
+	b.DeleteByPrefix("score:")
+	for i := 0; i < 3; i++ {
+		if _, found := a.Get(fmt.Sprintf("score:user:%d", i)); found {
+			t.Errorf("Expected score:user:%d to be deleted on the other instance", i)
+		}
+	}
+	if _, found := a.Get("scores"); !found {
+		t.Error("Expected keys outside the prefix to stay")
+	}
+
+	// entries set after the prefix is deleted are read again
+	a.Set("score:user:1", []cachedThing{})
+	if _, found := b.Get("score:user:1"); !found {
+		t.Error("Expected a new entry under a deleted prefix to be found")
+	}
+
+	// prefixes that do not end in the separator are scanned for
+	b.DeleteByPrefix("score")
+	for _, key := range []string{"scores", "score:user:1"} {
+		if _, found := a.Get(key); found {
+			t.Errorf("Expected %s to be deleted", key)
+		}
+	}
+	if _, found := a.Get("point:all"); !found {
+		t.Error("Expected keys outside the prefix to stay")
+	}
+}
+
+func TestRedisCache_InvalidateTagsAcrossInstances(t *testing.T) {
+	opts, namespace := redisOptions(t), testNamespace(t)
+	a := NewRedisCache(opts, namespace, time.Minute)
+	b := NewRedisCache(opts, namespace, time.Minute)
+	defer a.Close()
+	defer b.Close()
+
+	a.SetWithTags("forecast:list:open", []cachedThing{}, "forecasts")
+	a.SetWithTags("score:all", []cachedThing{}, "scores", "forecasts")
+	a.SetWithTags("forecast:detail:1", &cachedThing{}, "forecast:1")
+
+	b.InvalidateTags("forecasts")
+	for _, key := range []string{"forecast:list:open", "score:all"} {
+		if _, found := a.Get(key); found {
+			t.Errorf("Expected %s to be invalidated on the other instance", key)
+		}
+	}
+	if _, found := a.Get("forecast:detail:1"); !found {
+		t.Error("Expected entries without the tag to stay")
+	}
+
+	// entries set after the tag is invalidated are read again
+	a.SetWithTags("score:all", []cachedThing{}, "scores", "forecasts")
+	if _, found := b.Get("score:all"); !found {
+		t.Error("Expected a new entry with an invalidated tag to be found")
+	}
+	if stats := a.Stats(); stats.Errors != 0 {
+		t.Errorf("Expected no errors, got %+v", stats)
+	}
+}
+
+func TestRedisCache_Auth(t *testing.T) {
+	server := redistest.NewServer(t)
+	server.Password = "secret"
+
+	c := NewRedisCache(RedisOptions{Addr: server.Addr(), Password: "wrong"}, "", time.Minute)
+	c.Set("key", []cachedThing{})
+	if stats := c.Stats(); stats.Errors != 1 {
+		t.Errorf("Expected a wrong password to fail, got %+v", stats)
+	}
+
+	c = NewRedisCache(RedisOptions{Addr: server.Addr(), Password: "secret"}, "", time.Minute)
+	c.Set("key", []cachedThing{})
+	if _, found := c.Get("key"); !found {
+		t.Error("Expected the cache to work with the right password")
+	}
+}
+
+func TestRedisCache_Reconnects(t *testing.T) {
+	server := redistest.NewServer(t)
+	c := NewRedisCache(RedisOptions{Addr: server.Addr()}, "", time.Minute)
+	defer c.Close()
+
+	c.Set("key", []cachedThing{})
+	server.DropConnections()
+
+	// the first command after the drop fails and the next redials
+	c.Get("key")
+	if _, found := c.Get("key"); !found {
+		t.Error("Expected the cache to redial after losing its connection")
+	}
+}
+
+func TestSyncedCache_Invalidates(t *testing.T) {
+	opts := redisOptions(t)
+	channel := testNamespace(t)
+	a, err := NewSyncedCache(NewCache(), opts, channel)
+	if err != nil {
+		t.Fatalf("Error creating synced cache: %v", err)
+	}
+	defer a.Close()
+	b, err := NewSyncedCache(NewCache(), opts, channel)
+	if err != nil {
+		t.Fatalf("Error creating synced cache: %v", err)
+	}
+	defer b.Close()
+
+	for _, c := range []*SyncedCache{a, b} {
+		c.Set("users", 1)
+		c.Set("score:all", 1)
+		c.Set("score:user:1", 1)
+		c.SetWithTags("forecast:list:open", 1, "forecasts")
+	}
+
+	a.Delete("users")
+	eventually(t, func() bool {
+		_, found := b.Get("users")
+		return !found
+	}, "Expected a delete to reach the other instance")
+
+	a.DeleteByPrefix("score:")
+	eventually(t, func() bool {
+		_, foundAll := b.Get("score:all")
+		_, foundUser := b.Get("score:user:1")
+		return !foundAll && !foundUser
+	}, "Expected a prefix delete to reach the other instance")
+
+	a.InvalidateTags("forecasts")
+	eventually(t, func() bool {
+		_, found := b.Get("forecast:list:open")
+		return !found
+	}, "Expected a tag invalidation to reach the other instance")
+
+	// values stay in process, so any type can be cached
+	a.Set("unregistered", map[string]int{"a": 1})
+	if _, found := a.Get("unregistered"); !found {
+		t.Error("Expected a synced cache to keep values of any type")
+	}
+}
+
+func TestSyncedCache_ClearsAfterResubscribing(t *testing.T) {
+	server := redistest.NewServer(t)
+	c, err := NewSyncedCache(NewCache(), RedisOptions{Addr: server.Addr()}, "")
+	if err != nil {
+		t.Fatalf("Error creating synced cache: %v", err)
+	}
+	defer c.Close()
+
+	c.Set("users", 1)
+	server.DropConnections()
+
+	eventually(t, func() bool {
+		_, found := c.Get("users")
+		return !found
+	}, "Expected the cache to be cleared after resubscribing")
+	if stats := c.Stats(); stats.Errors == 0 {
+		t.Errorf("Expected the dropped subscription to be counted, got %+v", stats)
+	}
+}
+
+func TestNewSyncedCache_FailsWithoutRedis(t *testing.T) {
+	server := redistest.NewServer(t)
+	addr := server.Addr()
+	server.Close()
+
+	if _, err := NewSyncedCache(NewCache(), RedisOptions{Addr: addr}, ""); err == nil {
+		t.Error("Expected creating a synced cache without a Redis to fail")
+	}
+}
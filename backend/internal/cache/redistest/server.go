@@ -0,0 +1,332 @@
+// Package redistest runs an in-process server speaking enough of the Redis
+// protocol to test the Redis cache backends without a Redis: PING, AUTH, GET,
+// SET with PX, DEL, MGET, INCR, SCAN with MATCH, PUBLISH and SUBSCRIBE.
+package redistest
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type value struct {
+	data      string
+	expiresAt time.Time
+}
+
+// Server is an in-process Redis stand-in
+type Server struct {
+	// Password, when set, must be sent with AUTH before other commands
+	Password string
+
+	listener net.Listener
+
+	mu          sync.Mutex
+	values      map[string]value
+	subscribers map[string]map[*conn]struct{}
+	conns       map[*conn]struct{}
+}
+
+type conn struct {
+	net.Conn
+	// mu serializes replies, which PUBLISH on other connections also write
+	mu     sync.Mutex
+	w      *bufio.Writer
+	authed bool
+}
+
+// NewServer starts a server on a local port and stops it when the test ends
+func NewServer(t testing.TB) *Server {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Error starting redis stand-in: %v", err)
+	}
+
+	s := &Server{
+		listener:    listener,
+		values:      make(map[string]value),
+		subscribers: make(map[string]map[*conn]struct{}),
+		conns:       make(map[*conn]struct{}),
+	}
+	go s.serve()
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Addr is the address to dial
+func (s *Server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// Close stops the server and drops every connection
+func (s *Server) Close() {
+	s.listener.Close()
+	s.DropConnections()
+}
+
+// DropConnections closes the open connections, e.g. to test reconnecting
+func (s *Server) DropConnections() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for c := range s.conns {
+		c.Close()
+	}
+}
+
+// Keys returns the keys that have not expired
+func (s *Server) Keys() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var keys []string
+	for key := range s.values {
+		if _, ok := s.get(key); ok {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+func (s *Server) serve() {
+	for {
+		nc, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		c := &conn{Conn: nc, w: bufio.NewWriter(nc)}
+		s.mu.Lock()
+		s.conns[c] = struct{}{}
+		s.mu.Unlock()
+		go s.handle(c)
+	}
+}
+
+func (s *Server) handle(c *conn) {
+	defer func() {
+		c.Close()
+		s.mu.Lock()
+		delete(s.conns, c)
+		for _, subs := range s.subscribers {
+			delete(subs, c)
+		}
+		s.mu.Unlock()
+	}()
+
+	r := bufio.NewReader(c)
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+		s.dispatch(c, args)
+	}
+}
+
+func (s *Server) dispatch(c *conn, args []string) {
+	name := strings.ToUpper(args[0])
+	if name == "AUTH" {
+		if len(args) == 2 && args[1] == s.Password {
+			c.authed = true
+			c.reply("+OK\r\n")
+		} else {
+			c.reply("-WRONGPASS invalid password\r\n")
+		}
+		return
+	}
+	if s.Password != "" && !c.authed {
+		c.reply("-NOAUTH Authentication required.\r\n")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch name {
+	case "PING":
+		c.reply("+PONG\r\n")
+	case "GET":
+		if v, ok := s.get(args[1]); ok {
+			c.reply(bulk(v.data))
+		} else {
+			c.reply("$-1\r\n")
+		}
+	case "SET":
+		v := value{data: args[2]}
+		if len(args) == 5 && strings.ToUpper(args[3]) == "PX" {
+			ms, err := strconv.Atoi(args[4])
+			if err != nil {
+				c.reply("-ERR value is not an integer or out of range\r\n")
+				return
+			}
+			v.expiresAt = time.Now().Add(time.Duration(ms) * time.Millisecond)
+		}
+		s.values[args[1]] = v
+		c.reply("+OK\r\n")
+	case "DEL":
+		deleted := 0
+		for _, key := range args[1:] {
+			if _, ok := s.get(key); ok {
+				deleted++
+			}
+			delete(s.values, key)
+		}
+		c.reply(fmt.Sprintf(":%d\r\n", deleted))
+	case "MGET":
+		var b strings.Builder
+		fmt.Fprintf(&b, "*%d\r\n", len(args)-1)
+		for _, key := range args[1:] {
+			if v, ok := s.get(key); ok {
+				b.WriteString(bulk(v.data))
+			} else {
+				b.WriteString("$-1\r\n")
+			}
+		}
+		c.reply(b.String())
+	case "INCR":
+		v, _ := s.get(args[1])
+		n, err := strconv.ParseInt(v.data, 10, 64)
+		if v.data != "" && err != nil {
+			c.reply("-ERR value is not an integer or out of range\r\n")
+			return
+		}
+		n++
+		s.values[args[1]] = value{data: strconv.FormatInt(n, 10), expiresAt: v.expiresAt}
+		c.reply(fmt.Sprintf(":%d\r\n", n))
+	case "SCAN":
+		// everything is returned in one page
+		pattern := "*"
+		for i := 2; i+1 < len(args); i += 2 {
+			if strings.ToUpper(args[i]) == "MATCH" {
+				pattern = args[i+1]
+			}
+		}
+		var keys []string
+		for key := range s.values {
+			if _, ok := s.get(key); ok && match(pattern, key) {
+				keys = append(keys, key)
+			}
+		}
+		var b strings.Builder
+		fmt.Fprintf(&b, "*2\r\n%s*%d\r\n", bulk("0"), len(keys))
+		for _, key := range keys {
+			b.WriteString(bulk(key))
+		}
+		c.reply(b.String())
+	case "PUBLISH":
+		message := fmt.Sprintf("*3\r\n%s%s%s", bulk("message"), bulk(args[1]), bulk(args[2]))
+		for sub := range s.subscribers[args[1]] {
+			sub.reply(message)
+		}
+		c.reply(fmt.Sprintf(":%d\r\n", len(s.subscribers[args[1]])))
+	case "SUBSCRIBE":
+		for i, channel := range args[1:] {
+			if s.subscribers[channel] == nil {
+				s.subscribers[channel] = make(map[*conn]struct{})
+			}
+			s.subscribers[channel][c] = struct{}{}
+			c.reply(fmt.Sprintf("*3\r\n%s%s:%d\r\n", bulk("subscribe"), bulk(channel), i+1))
+		}
+	default:
+		c.reply(fmt.Sprintf("-ERR unknown command '%s'\r\n", args[0]))
+	}
+}
+
+// get returns the key's value unless it expired, dropping it if it did. The
+// caller holds mu.
+func (s *Server) get(key string) (value, bool) {
+	v, ok := s.values[key]
+	if !ok {
+		return value{}, false
+	}
+	if !v.expiresAt.IsZero() && time.Now().After(v.expiresAt) {
+		delete(s.values, key)
+		return value{}, false
+	}
+	return v, true
+}
+
+func (c *conn) reply(s string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.w.WriteString(s)
+	c.w.Flush()
+}
+
+func bulk(s string) string {
+	return fmt.Sprintf("$%d\r\n%s\r\n", len(s), s)
+}
+
+// readCommand reads a command sent as an array of bulk strings
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(line, "*") {
+		return nil, fmt.Errorf("unexpected command %q", line)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+	if err != nil || n < 1 {
+		return nil, fmt.Errorf("unexpected command %q", line)
+	}
+
+	args := make([]string, n)
+	for i := range args {
+		header, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(header, "$")))
+		if err != nil {
+			return nil, err
+		}
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+		args[i] = string(data[:size])
+	}
+	return args, nil
+}
+
+// match reports whether the key matches a Redis pattern using *, ? and
+// backslash escapes
+func match(pattern string, key string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for i := len(key); i >= 0; i-- {
+				if match(pattern[1:], key[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if len(key) == 0 {
+				return false
+			}
+		case '\\':
+			if len(pattern) > 1 {
+				pattern = pattern[1:]
+			}
+			fallthrough
+		default:
+			if len(key) == 0 || key[0] != pattern[0] {
+				return false
+			}
+		}
+		pattern, key = pattern[1:], key[1:]
+	}
+	return len(key) == 0
+}
@@ -0,0 +1,193 @@
+package cache
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RedisOptions is where to find Redis
+type RedisOptions struct {
+	Addr     string
+	Password string
+	// Timeout bounds dialing and each command, defaulting to a second
+	Timeout time.Duration
+}
+
+func (o RedisOptions) timeout() time.Duration {
+	if o.Timeout <= 0 {
+		return time.Second
+	}
+	return o.Timeout
+}
+
+// redisError is an error reply, as opposed to a failed connection
+type redisError string
+
+func (e redisError) Error() string {
+	return string(e)
+}
+
+// respConn is a connection speaking RESP2, the Redis protocol. Replies are
+// strings for simple strings, int64s for integers, []byte or nil for bulk
+// strings, []any or nil for arrays and redisError for errors.
+type respConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+	w    *bufio.Writer
+}
+
+func dialRESP(opts RedisOptions) (*respConn, error) {
+	conn, err := net.DialTimeout("tcp", opts.Addr, opts.timeout())
+	if err != nil {
+		return nil, err
+	}
+
+	c := &respConn{conn: conn, r: bufio.NewReader(conn), w: bufio.NewWriter(conn)}
+	if opts.Password != "" {
+		conn.SetDeadline(time.Now().Add(opts.timeout()))
+		if _, err := c.do("AUTH", opts.Password); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("redis auth: %w", err)
+		}
+	}
+	return c, nil
+}
+
+// do sends a command and reads its reply. Error replies are returned as errors.
+func (c *respConn) do(args ...string) (any, error) {
+	if err := c.write(args...); err != nil {
+		return nil, err
+	}
+	reply, err := c.read()
+	if err != nil {
+		return nil, err
+	}
+	if e, ok := reply.(redisError); ok {
+		return nil, e
+	}
+	return reply, nil
+}
+
+func (c *respConn) write(args ...string) error {
+	fmt.Fprintf(c.w, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(c.w, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	return c.w.Flush()
+}
+
+func (c *respConn) read() (any, error) {
+	line, err := c.readLine()
+	if err != nil {
+		return nil, err
+	}
+	if len(line) == 0 {
+		return nil, errors.New("redis: empty reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return redisError(line[1:]), nil
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		data := make([]byte, n+2)
+		if _, err := io.ReadFull(c.r, data); err != nil {
+			return nil, err
+		}
+		return data[:n], nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		items := make([]any, n)
+		for i := range items {
+			if items[i], err = c.read(); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("redis: unexpected reply %q", line)
+	}
+}
+
+func (c *respConn) readLine() (string, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	if len(line) < 2 || line[len(line)-2] != '\r' {
+		return "", fmt.Errorf("redis: malformed line %q", line)
+	}
+	return line[:len(line)-2], nil
+}
+
+func (c *respConn) Close() error {
+	return c.conn.Close()
+}
+
+// redisClient sends commands over one connection, redialing after it fails
+type redisClient struct {
+	opts RedisOptions
+	mu   sync.Mutex
+	conn *respConn
+}
+
+func newRedisClient(opts RedisOptions) *redisClient {
+	return &redisClient{opts: opts}
+}
+
+func (c *redisClient) do(args ...string) (any, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conn == nil {
+		conn, err := dialRESP(c.opts)
+		if err != nil {
+			return nil, err
+		}
+		c.conn = conn
+	}
+
+	c.conn.conn.SetDeadline(time.Now().Add(c.opts.timeout()))
+	reply, err := c.conn.do(args...)
+	var replyErr redisError
+	if err != nil && !errors.As(err, &replyErr) {
+		// the connection may be mid-reply, so it cannot be reused
+		c.conn.Close()
+		c.conn = nil
+	}
+	return reply, err
+}
+
+func (c *redisClient) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
@@ -0,0 +1,221 @@
+package cache
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultChannel is the Redis channel invalidations are published on
+const DefaultChannel = "forecasts:cache:invalidate"
+
+// resubscribeDelay is how long to wait before redialing a failed subscription
+const resubscribeDelay = time.Second
+
+const (
+	opDelete         = "del"
+	opDeleteByPrefix = "prefix"
+	opInvalidateTags = "tags"
+)
+
+// SyncedCache keeps entries in a MemoryCache and publishes each Delete,
+// DeleteByPrefix and InvalidateTags on a Redis channel, applying the ones other instances publish.
+// Invalidations published while the subscription is down are missed, so the
+// cache is cleared whenever it resubscribes.
+type SyncedCache struct {
+	*MemoryCache
+
+	client  *redisClient
+	redis   RedisOptions
+	channel string
+	// node tells this instance's invalidations apart from the others'
+	node string
+
+	errors atomic.Uint64
+
+	mu     sync.Mutex
+	sub    *respConn
+	closed bool
+	done   chan struct{}
+}
+
+// NewSyncedCache creates a cache that subscribes to the channel on the Redis
+// described by the options, and keeps resubscribing until it is closed
+func NewSyncedCache(local *MemoryCache, redis RedisOptions, channel string) (*SyncedCache, error) {
+	if channel == "" {
+		channel = DefaultChannel
+	}
+
+	node := make([]byte, 8)
+	if _, err := rand.Read(node); err != nil {
+		return nil, err
+	}
+
+	c := &SyncedCache{
+		MemoryCache: local,
+		client:      newRedisClient(redis),
+		redis:       redis,
+		channel:     channel,
+		node:        hex.EncodeToString(node),
+		done:        make(chan struct{}),
+	}
+
+	// subscribe once up front so a misconfigured Redis fails at startup
+	sub, err := c.subscribe()
+	if err != nil {
+		return nil, err
+	}
+	go c.listen(sub)
+	return c, nil
+}
+
+func (c *SyncedCache) Delete(key string) {
+	c.MemoryCache.Delete(key)
+	c.publish(opDelete, key)
+}
+
+func (c *SyncedCache) DeleteByPrefix(prefix string) {
+	c.MemoryCache.DeleteByPrefix(prefix)
+	c.publish(opDeleteByPrefix, prefix)
+}
+
+func (c *SyncedCache) InvalidateTags(tags ...string) {
+	c.MemoryCache.InvalidateTags(tags...)
+	c.publish(opInvalidateTags, strings.Join(tags, "\n"))
+}
+
+func (c *SyncedCache) Stats() Stats {
+	stats := c.MemoryCache.Stats()
+	stats.Errors = c.errors.Load()
+	return stats
+}
+
+// Close stops the subscription
+func (c *SyncedCache) Close() error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil
+	}
+	c.closed = true
+	if c.sub != nil {
+		c.sub.Close()
+	}
+	c.mu.Unlock()
+
+	<-c.done
+	return c.client.Close()
+}
+
+func (c *SyncedCache) publish(op string, arg string) {
+	message := strings.Join([]string{c.node, op, arg}, "\n")
+	if _, err := c.client.do("PUBLISH", c.channel, message); err != nil {
+		c.errors.Add(1)
+		slog.Warn("failed to publish cache invalidation", slog.String("op", op), slog.String("cache_key", arg), slog.String("error", err.Error()))
+	}
+}
+
+func (c *SyncedCache) subscribe() (*respConn, error) {
+	sub, err := dialRESP(c.redis)
+	if err != nil {
+		return nil, err
+	}
+
+	sub.conn.SetDeadline(time.Now().Add(c.redis.timeout()))
+	if err := sub.write("SUBSCRIBE", c.channel); err != nil {
+		sub.Close()
+		return nil, err
+	}
+	if _, err := sub.read(); err != nil {
+		sub.Close()
+		return nil, err
+	}
+	// messages can be far apart
+	sub.conn.SetDeadline(time.Time{})
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		sub.Close()
+		return nil, net.ErrClosed
+	}
+	c.sub = sub
+	return sub, nil
+}
+
+// listen applies the invalidations on the subscription until it fails, and
+// then resubscribes until the cache is closed
+func (c *SyncedCache) listen(sub *respConn) {
+	defer close(c.done)
+
+	for {
+		err := c.receive(sub)
+		sub.Close()
+
+		if c.isClosed() {
+			return
+		}
+		c.errors.Add(1)
+		slog.Warn("cache invalidation subscription failed", slog.String("error", err.Error()))
+
+		for {
+			time.Sleep(resubscribeDelay)
+			if c.isClosed() {
+				return
+			}
+			if sub, err = c.subscribe(); err == nil {
+				break
+			}
+			c.errors.Add(1)
+		}
+		// invalidations may have been missed while unsubscribed
+		c.MemoryCache.Clear()
+	}
+}
+
+func (c *SyncedCache) receive(sub *respConn) error {
+	for {
+		reply, err := sub.read()
+		if err != nil {
+			return err
+		}
+
+		// pushed messages are ["message", channel, payload]
+		parts, ok := reply.([]any)
+		if !ok || len(parts) != 3 {
+			continue
+		}
+		if kind, _ := parts[0].([]byte); string(kind) != "message" {
+			continue
+		}
+		payload, _ := parts[2].([]byte)
+		c.apply(string(payload))
+	}
+}
+
+func (c *SyncedCache) apply(message string) {
+	fields := strings.SplitN(message, "\n", 3)
+	if len(fields) != 3 || fields[0] == c.node {
+		return
+	}
+
+	switch fields[1] {
+	case opDelete:
+		c.MemoryCache.Delete(fields[2])
+	case opDeleteByPrefix:
+		c.MemoryCache.DeleteByPrefix(fields[2])
+	case opInvalidateTags:
+		c.MemoryCache.InvalidateTags(strings.Split(fields[2], "\n")...)
+	}
+}
+
+func (c *SyncedCache) isClosed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closed
+}
@@ -1,18 +1,40 @@
 package config
 
 import (
+	"backend/internal/models"
 	"context"
 	"fmt"
 	"os"
+	"strconv"
+	"time"
 
 	secretmanager "cloud.google.com/go/secretmanager/apiv1"
 	"cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
 )
 
 type Config struct {
-	JWTSecret      []byte
-	AllowedOrigin  string
-	DBConnString   string
+	JWTSecret     []byte
+	AllowedOrigin string
+	DBConnString  string
+	// AutoMigrate applies pending schema migrations on startup (AUTO_MIGRATE=true)
+	AutoMigrate bool
+	// AccessTokenTTL is how long access tokens last (ACCESS_TOKEN_TTL, e.g. 15m)
+	AccessTokenTTL time.Duration
+	// CacheMaxEntries bounds the cache (CACHE_MAX_ENTRIES)
+	CacheMaxEntries int
+	// CacheMaxBytes bounds the estimated size of the in-memory cache
+	// (CACHE_MAX_BYTES, 0 for no bound)
+	CacheMaxBytes int64
+	// CacheTTL is how long cached entries live (CACHE_TTL, e.g. 1h)
+	CacheTTL time.Duration
+	// CacheBackend is memory, redis or synced (CACHE_BACKEND); the latter two
+	// are for running more than one instance
+	CacheBackend  string
+	RedisAddr     string
+	RedisPassword string
+	// LifetimePrior is held for users before their first point in lifetime
+	// scores (LIFETIME_PRIOR, a probability or crowd)
+	LifetimePrior models.LifetimePrior
 }
 
 // Load loads configuration from environment variables and Google Secret Manager.
@@ -21,8 +43,48 @@ func Load(ctx context.Context) (*Config, error) {
 	cfg := &Config{
 		AllowedOrigin: getEnvOrDefault("ALLOWED_ORIGIN", "https://www.samuelsforecasts.com"),
 		DBConnString:  os.Getenv("DB_CONNECTION_STRING"),
+		AutoMigrate:   os.Getenv("AUTO_MIGRATE") == "true",
 	}
 
+	accessTokenTTL, err := time.ParseDuration(getEnvOrDefault("ACCESS_TOKEN_TTL", "15m"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid ACCESS_TOKEN_TTL: %w", err)
+	}
+	cfg.AccessTokenTTL = accessTokenTTL
+
+	cacheMaxEntries, err := strconv.Atoi(getEnvOrDefault("CACHE_MAX_ENTRIES", "10000"))
+	if err != nil || cacheMaxEntries < 0 {
+		return nil, fmt.Errorf("invalid CACHE_MAX_ENTRIES: %q", os.Getenv("CACHE_MAX_ENTRIES"))
+	}
+	cfg.CacheMaxEntries = cacheMaxEntries
+
+	cacheMaxBytes, err := strconv.ParseInt(getEnvOrDefault("CACHE_MAX_BYTES", "67108864"), 10, 64)
+	if err != nil || cacheMaxBytes < 0 {
+		return nil, fmt.Errorf("invalid CACHE_MAX_BYTES: %q", os.Getenv("CACHE_MAX_BYTES"))
+	}
+	cfg.CacheMaxBytes = cacheMaxBytes
+
+	cacheTTL, err := time.ParseDuration(getEnvOrDefault("CACHE_TTL", "1h"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid CACHE_TTL: %w", err)
+	}
+	cfg.CacheTTL = cacheTTL
+
+	cfg.CacheBackend = getEnvOrDefault("CACHE_BACKEND", "memory")
+	switch cfg.CacheBackend {
+	case "memory", "redis", "synced":
+	default:
+		return nil, fmt.Errorf("invalid CACHE_BACKEND %q, expected memory, redis or synced", cfg.CacheBackend)
+	}
+	cfg.RedisAddr = getEnvOrDefault("REDIS_ADDR", "localhost:6379")
+
+	lifetimePrior, err := models.ParseLifetimePrior(getEnvOrDefault("LIFETIME_PRIOR", "0.5"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid LIFETIME_PRIOR: %w", err)
+	}
+	cfg.LifetimePrior = lifetimePrior
+	cfg.RedisPassword = os.Getenv("REDIS_PASSWORD")
+
 	// For local development, allow using environment variables directly
 	if os.Getenv("USE_LOCAL_SECRETS") == "true" {
 		jwtSecret := os.Getenv("JWT_SECRET")
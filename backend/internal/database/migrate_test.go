@@ -0,0 +1,22 @@
+package database
+
+import "testing"
+
+func TestLoadMigrations(t *testing.T) {
+	migrations, err := LoadMigrations()
+	if err != nil {
+		t.Fatalf("Error loading migrations: %v", err)
+	}
+	if len(migrations) == 0 {
+		t.Fatal("Expected embedded migrations, got none")
+	}
+
+	for i, m := range migrations {
+		if m.Version != int64(i+1) {
+			t.Errorf("Migration %s has version %d, want %d (versions must be sequential)", m.Name, m.Version, i+1)
+		}
+		if m.Up == "" || m.Down == "" {
+			t.Errorf("Migration %d_%s must have both an up and a down script", m.Version, m.Name)
+		}
+	}
+}
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS scores;
+DROP TABLE IF EXISTS points;
+DROP TABLE IF EXISTS forecasts;
+DROP TABLE IF EXISTS users;
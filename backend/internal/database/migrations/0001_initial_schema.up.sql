@@ -0,0 +1,48 @@
+-- Baseline schema. Uses IF NOT EXISTS so databases created before migrations
+-- existed are adopted without changes.
+CREATE TABLE IF NOT EXISTS users (
+    id BIGSERIAL PRIMARY KEY,
+    username VARCHAR(255) NOT NULL UNIQUE,
+    password VARCHAR(255) NOT NULL,
+    created TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS forecasts (
+    id BIGSERIAL PRIMARY KEY,
+    question TEXT,
+    category TEXT,
+    created TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    user_id BIGINT NOT NULL REFERENCES users(id),
+    resolution_criteria TEXT,
+    closing_date TIMESTAMP,
+    resolution TEXT,
+    resolved TIMESTAMP,
+    comment TEXT
+);
+
+CREATE TABLE IF NOT EXISTS points (
+    id BIGSERIAL PRIMARY KEY,
+    forecast_id BIGINT NOT NULL REFERENCES forecasts(id),
+    user_id BIGINT NOT NULL REFERENCES users(id),
+    point_forecast DOUBLE PRECISION NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    created TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS scores (
+    id BIGSERIAL PRIMARY KEY,
+    brier_score DOUBLE PRECISION NOT NULL,
+    log2_score DOUBLE PRECISION NOT NULL,
+    logn_score DOUBLE PRECISION NOT NULL,
+    brier_score_time_weighted DOUBLE PRECISION NOT NULL DEFAULT 0,
+    log2_score_time_weighted DOUBLE PRECISION NOT NULL DEFAULT 0,
+    logn_score_time_weighted DOUBLE PRECISION NOT NULL DEFAULT 0,
+    user_id BIGINT NOT NULL REFERENCES users(id),
+    forecast_id BIGINT NOT NULL REFERENCES forecasts(id),
+    created TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS points_forecast_id_idx ON points (forecast_id);
+CREATE INDEX IF NOT EXISTS points_user_id_created_idx ON points (user_id, created);
+CREATE INDEX IF NOT EXISTS scores_forecast_id_idx ON scores (forecast_id);
+CREATE INDEX IF NOT EXISTS scores_user_id_idx ON scores (user_id);
@@ -0,0 +1,8 @@
+ALTER TABLE scores DROP COLUMN IF EXISTS crps_time_weighted;
+ALTER TABLE scores DROP COLUMN IF EXISTS crps;
+ALTER TABLE points DROP COLUMN IF EXISTS distribution;
+ALTER TABLE forecasts DROP COLUMN IF EXISTS range_max;
+ALTER TABLE forecasts DROP COLUMN IF EXISTS range_min;
+ALTER TABLE points DROP COLUMN IF EXISTS probabilities;
+ALTER TABLE forecasts DROP COLUMN IF EXISTS options;
+ALTER TABLE forecasts DROP COLUMN IF EXISTS question_type;
@@ -0,0 +1,11 @@
+-- multiple-choice questions
+ALTER TABLE forecasts ADD COLUMN IF NOT EXISTS question_type TEXT NOT NULL DEFAULT 'binary';
+ALTER TABLE forecasts ADD COLUMN IF NOT EXISTS options JSONB;
+ALTER TABLE points ADD COLUMN IF NOT EXISTS probabilities JSONB;
+
+-- numeric and date questions
+ALTER TABLE forecasts ADD COLUMN IF NOT EXISTS range_min DOUBLE PRECISION;
+ALTER TABLE forecasts ADD COLUMN IF NOT EXISTS range_max DOUBLE PRECISION;
+ALTER TABLE points ADD COLUMN IF NOT EXISTS distribution JSONB;
+ALTER TABLE scores ADD COLUMN IF NOT EXISTS crps DOUBLE PRECISION;
+ALTER TABLE scores ADD COLUMN IF NOT EXISTS crps_time_weighted DOUBLE PRECISION;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS scores_forecast_id_user_id_key;
+CREATE INDEX IF NOT EXISTS scores_forecast_id_idx ON scores (forecast_id);
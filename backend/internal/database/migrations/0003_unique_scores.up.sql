@@ -0,0 +1,10 @@
+-- one score per user and forecast, so re-running a resolution cannot
+-- duplicate rows. Keep the most recent score where duplicates already exist.
+DELETE FROM scores s
+USING scores newer
+WHERE s.forecast_id = newer.forecast_id
+  AND s.user_id = newer.user_id
+  AND s.id < newer.id;
+
+DROP INDEX IF EXISTS scores_forecast_id_idx;
+CREATE UNIQUE INDEX IF NOT EXISTS scores_forecast_id_user_id_key ON scores (forecast_id, user_id);
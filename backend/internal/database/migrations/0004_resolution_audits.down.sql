@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS archived_scores;
+DROP TABLE IF EXISTS resolution_audits;
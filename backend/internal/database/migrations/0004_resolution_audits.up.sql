@@ -0,0 +1,34 @@
+-- every resolve, unresolve and re-resolve of a forecast
+CREATE TABLE IF NOT EXISTS resolution_audits (
+    id BIGSERIAL PRIMARY KEY,
+    forecast_id BIGINT NOT NULL REFERENCES forecasts(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL REFERENCES users(id),
+    action TEXT NOT NULL,
+    previous_resolution TEXT,
+    previous_resolved TIMESTAMP,
+    resolution TEXT,
+    comment TEXT,
+    created TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS resolution_audits_forecast_id_idx ON resolution_audits (forecast_id);
+
+-- scores replaced when a forecast is unresolved, kept for the audit trail
+CREATE TABLE IF NOT EXISTS archived_scores (
+    id BIGINT PRIMARY KEY,
+    brier_score DOUBLE PRECISION NOT NULL,
+    log2_score DOUBLE PRECISION NOT NULL,
+    logn_score DOUBLE PRECISION NOT NULL,
+    brier_score_time_weighted DOUBLE PRECISION NOT NULL,
+    log2_score_time_weighted DOUBLE PRECISION NOT NULL,
+    logn_score_time_weighted DOUBLE PRECISION NOT NULL,
+    crps DOUBLE PRECISION,
+    crps_time_weighted DOUBLE PRECISION,
+    user_id BIGINT NOT NULL,
+    forecast_id BIGINT NOT NULL,
+    created TIMESTAMP NOT NULL,
+    audit_id BIGINT NOT NULL REFERENCES resolution_audits(id) ON DELETE CASCADE,
+    archived TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS archived_scores_forecast_id_idx ON archived_scores (forecast_id);
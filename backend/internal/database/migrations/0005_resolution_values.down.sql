@@ -0,0 +1,4 @@
+ALTER TABLE resolution_audits DROP COLUMN resolution_value;
+ALTER TABLE resolution_audits DROP COLUMN previous_resolution_value;
+
+ALTER TABLE forecasts DROP COLUMN resolution_value;
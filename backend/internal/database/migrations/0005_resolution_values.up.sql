@@ -0,0 +1,5 @@
+-- Probabilistic resolutions store their fractional outcome next to the code
+ALTER TABLE forecasts ADD COLUMN resolution_value DOUBLE PRECISION;
+
+ALTER TABLE resolution_audits ADD COLUMN previous_resolution_value DOUBLE PRECISION;
+ALTER TABLE resolution_audits ADD COLUMN resolution_value DOUBLE PRECISION;
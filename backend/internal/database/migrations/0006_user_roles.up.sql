@@ -0,0 +1,4 @@
+-- Existing users keep full access as forecasters; admins are promoted with
+-- cmd/set_role
+ALTER TABLE users ADD COLUMN role TEXT NOT NULL DEFAULT 'forecaster'
+    CHECK (role IN ('admin', 'forecaster', 'bot'));
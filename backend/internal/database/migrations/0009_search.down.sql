@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS points_reason_vector_idx;
+DROP INDEX IF EXISTS forecasts_search_vector_idx;
+
+ALTER TABLE points DROP COLUMN reason_vector;
+ALTER TABLE forecasts DROP COLUMN search_vector;
@@ -0,0 +1,14 @@
+-- full-text search over forecasts, weighted by where the text is found, and
+-- over the reasons given with forecast points
+ALTER TABLE forecasts ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('english', coalesce(question, '')), 'A') ||
+    setweight(to_tsvector('english', coalesce(resolution_criteria, '')), 'B') ||
+    setweight(to_tsvector('english', coalesce(comment, '')), 'C')
+) STORED;
+
+ALTER TABLE points ADD COLUMN reason_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('english', reason), 'D')
+) STORED;
+
+CREATE INDEX IF NOT EXISTS forecasts_search_vector_idx ON forecasts USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS points_reason_vector_idx ON points USING GIN (reason_vector);
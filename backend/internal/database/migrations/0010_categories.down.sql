@@ -0,0 +1,5 @@
+ALTER TABLE forecasts DROP COLUMN category_id;
+
+DROP TABLE IF EXISTS forecast_tags;
+DROP TABLE IF EXISTS tags;
+DROP TABLE IF EXISTS categories;
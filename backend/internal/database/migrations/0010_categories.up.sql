@@ -0,0 +1,53 @@
+-- a tree of categories replacing the free-text forecasts.category, which is
+-- kept as the name of the forecast's category
+CREATE TABLE IF NOT EXISTS categories (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    parent_id BIGINT REFERENCES categories(id),
+    created TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- names are unique among siblings, ignoring case
+CREATE UNIQUE INDEX IF NOT EXISTS categories_parent_name_idx ON categories (COALESCE(parent_id, 0), lower(name));
+
+-- tag names are stored lowercased
+CREATE TABLE IF NOT EXISTS tags (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE
+);
+
+CREATE TABLE IF NOT EXISTS forecast_tags (
+    forecast_id BIGINT NOT NULL REFERENCES forecasts(id) ON DELETE CASCADE,
+    tag_id BIGINT NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    PRIMARY KEY (forecast_id, tag_id)
+);
+
+CREATE INDEX IF NOT EXISTS forecast_tags_tag_id_idx ON forecast_tags (tag_id);
+
+ALTER TABLE forecasts ADD COLUMN category_id BIGINT REFERENCES categories(id);
+CREATE INDEX IF NOT EXISTS forecasts_category_id_idx ON forecasts (category_id);
+
+-- every distinct category string becomes a top-level category, spelled as its
+-- first use
+INSERT INTO categories (name)
+SELECT DISTINCT ON (lower(trim(category))) trim(category)
+FROM forecasts
+WHERE trim(coalesce(category, '')) <> ''
+ORDER BY lower(trim(category)), id;
+
+UPDATE forecasts f
+SET category_id = c.id, category = c.name
+FROM categories c
+WHERE c.parent_id IS NULL AND lower(c.name) = lower(trim(f.category));
+
+-- forecasts were left out of the stale forecasts list when their category
+-- contained "personal"; they keep that through a tag
+INSERT INTO tags (name)
+SELECT 'personal'
+WHERE EXISTS (SELECT 1 FROM forecasts WHERE lower(category) LIKE '%personal%' AND lower(category) <> 'personal')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO forecast_tags (forecast_id, tag_id)
+SELECT f.id, t.id
+FROM forecasts f, tags t
+WHERE t.name = 'personal' AND lower(f.category) LIKE '%personal%' AND lower(f.category) <> 'personal';
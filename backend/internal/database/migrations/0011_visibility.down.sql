@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS forecast_shares;
+
+ALTER TABLE forecasts DROP COLUMN visibility;
@@ -0,0 +1,27 @@
+-- who may see a forecast: everyone (public), anyone with its id (unlisted),
+-- its owner (private), or its owner and the users it is shared with (shared)
+ALTER TABLE forecasts ADD COLUMN visibility TEXT NOT NULL DEFAULT 'public'
+    CHECK (visibility IN ('public', 'unlisted', 'private', 'shared'));
+
+CREATE TABLE IF NOT EXISTS forecast_shares (
+    forecast_id BIGINT NOT NULL REFERENCES forecasts(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    PRIMARY KEY (forecast_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS forecast_shares_user_id_idx ON forecast_shares (user_id);
+
+-- personal forecasts were only kept out of the stale forecasts list; they
+-- become private
+WITH RECURSIVE personal_categories AS (
+    SELECT id FROM categories WHERE lower(name) = 'personal'
+    UNION ALL
+    SELECT c.id FROM categories c JOIN personal_categories pc ON c.parent_id = pc.id
+)
+UPDATE forecasts
+SET visibility = 'private'
+WHERE category_id IN (SELECT id FROM personal_categories)
+OR id IN (
+    SELECT ft.forecast_id FROM forecast_tags ft JOIN tags t ON t.id = ft.tag_id
+    WHERE t.name = 'personal'
+);
@@ -0,0 +1,11 @@
+-- every edit of an open forecast's question, resolution criteria or closing
+-- date, with what it changed
+CREATE TABLE IF NOT EXISTS forecast_revisions (
+    id BIGSERIAL PRIMARY KEY,
+    forecast_id BIGINT NOT NULL REFERENCES forecasts(id) ON DELETE CASCADE,
+    version INTEGER NOT NULL,
+    user_id BIGINT NOT NULL REFERENCES users(id),
+    changes JSONB NOT NULL,
+    created TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (forecast_id, version)
+);
@@ -0,0 +1,4 @@
+ALTER TABLE archived_scores DROP COLUMN IF EXISTS baseline_score;
+ALTER TABLE archived_scores DROP COLUMN IF EXISTS peer_score;
+ALTER TABLE scores DROP COLUMN IF EXISTS baseline_score;
+ALTER TABLE scores DROP COLUMN IF EXISTS peer_score;
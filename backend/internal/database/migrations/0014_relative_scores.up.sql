@@ -0,0 +1,5 @@
+-- Peer and Baseline scores, null on scores from before until backfilled
+ALTER TABLE scores ADD COLUMN IF NOT EXISTS peer_score DOUBLE PRECISION;
+ALTER TABLE scores ADD COLUMN IF NOT EXISTS baseline_score DOUBLE PRECISION;
+ALTER TABLE archived_scores ADD COLUMN IF NOT EXISTS peer_score DOUBLE PRECISION;
+ALTER TABLE archived_scores ADD COLUMN IF NOT EXISTS baseline_score DOUBLE PRECISION;
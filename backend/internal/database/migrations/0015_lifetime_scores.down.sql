@@ -0,0 +1,4 @@
+ALTER TABLE archived_scores DROP COLUMN IF EXISTS logn_score_lifetime;
+ALTER TABLE archived_scores DROP COLUMN IF EXISTS brier_score_lifetime;
+ALTER TABLE scores DROP COLUMN IF EXISTS logn_score_lifetime;
+ALTER TABLE scores DROP COLUMN IF EXISTS brier_score_lifetime;
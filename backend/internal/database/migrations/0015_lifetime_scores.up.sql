@@ -0,0 +1,6 @@
+-- time-weighted scores over the whole life of the forecast, null on scores
+-- from before they were kept and on continuous forecasts
+ALTER TABLE scores ADD COLUMN IF NOT EXISTS brier_score_lifetime DOUBLE PRECISION;
+ALTER TABLE scores ADD COLUMN IF NOT EXISTS logn_score_lifetime DOUBLE PRECISION;
+ALTER TABLE archived_scores ADD COLUMN IF NOT EXISTS brier_score_lifetime DOUBLE PRECISION;
+ALTER TABLE archived_scores ADD COLUMN IF NOT EXISTS logn_score_lifetime DOUBLE PRECISION;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS archived_rule_scores;
+DROP TABLE IF EXISTS rule_scores;
@@ -0,0 +1,18 @@
+-- scores under each registered scoring rule, see models.ScoringRule, so a new
+-- rule needs no new columns
+CREATE TABLE IF NOT EXISTS rule_scores (
+    score_id BIGINT NOT NULL REFERENCES scores(id) ON DELETE CASCADE,
+    rule TEXT NOT NULL,
+    score DOUBLE PRECISION NOT NULL,
+    score_time_weighted DOUBLE PRECISION NOT NULL,
+    PRIMARY KEY (score_id, rule)
+);
+
+-- rule scores of the archived scores
+CREATE TABLE IF NOT EXISTS archived_rule_scores (
+    score_id BIGINT NOT NULL REFERENCES archived_scores(id) ON DELETE CASCADE,
+    rule TEXT NOT NULL,
+    score DOUBLE PRECISION NOT NULL,
+    score_time_weighted DOUBLE PRECISION NOT NULL,
+    PRIMARY KEY (score_id, rule)
+);
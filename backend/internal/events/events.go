@@ -0,0 +1,109 @@
+// Package events is an in-process bus for domain events. Services publish what
+// changed once it is committed, and subscribers react to it, e.g. by
+// invalidating the cache entries built from the changed data.
+package events
+
+import (
+	"backend/internal/logger"
+	"context"
+	"log/slog"
+	"sync"
+)
+
+// Event is something that happened in the domain. Subscribers switch on the
+// concrete type.
+type Event interface {
+	Name() string
+}
+
+type ForecastCreated struct {
+	ForecastID int64
+	UserID     int64
+}
+
+type ForecastUpdated struct {
+	ForecastID int64
+}
+
+type ForecastDeleted struct {
+	ForecastID int64
+}
+
+// ForecastResolved is published when a forecast is resolved, unresolved or
+// re-resolved
+type ForecastResolved struct {
+	ForecastID int64
+}
+
+// ForecastVisibilityChanged is published when who may see a forecast changes
+type ForecastVisibilityChanged struct {
+	ForecastID int64
+}
+
+// CategoryChanged is published when a category is renamed, moved or deleted
+type CategoryChanged struct {
+	CategoryID int64
+}
+
+type PointCreated struct {
+	ForecastID int64
+	UserID     int64
+}
+
+// ScoreChanged is published when scores are written, archived or deleted. The
+// forecast is 0 when it is not known.
+type ScoreChanged struct {
+	ForecastID int64
+}
+
+// UserChanged is published when a user is created or their role changes
+type UserChanged struct {
+	UserID int64
+}
+
+type UserDeleted struct {
+	UserID int64
+}
+
+func (ForecastCreated) Name() string           { return "forecast.created" }
+func (ForecastUpdated) Name() string           { return "forecast.updated" }
+func (ForecastDeleted) Name() string           { return "forecast.deleted" }
+func (ForecastResolved) Name() string          { return "forecast.resolved" }
+func (ForecastVisibilityChanged) Name() string { return "forecast.visibility_changed" }
+func (CategoryChanged) Name() string           { return "category.changed" }
+func (PointCreated) Name() string              { return "point.created" }
+func (ScoreChanged) Name() string              { return "score.changed" }
+func (UserChanged) Name() string               { return "user.changed" }
+func (UserDeleted) Name() string               { return "user.deleted" }
+
+// Handler reacts to an event. It runs in the publisher's goroutine.
+type Handler func(ctx context.Context, e Event)
+
+// Bus delivers each published event to every subscriber, in the order they
+// subscribed, before Publish returns
+type Bus struct {
+	mu       sync.RWMutex
+	handlers []Handler
+}
+
+func NewBus() *Bus {
+	return &Bus{}
+}
+
+func (b *Bus) Subscribe(h Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.handlers = append(b.handlers, h)
+}
+
+func (b *Bus) Publish(ctx context.Context, e Event) {
+	b.mu.RLock()
+	handlers := b.handlers
+	b.mu.RUnlock()
+
+	logger.FromContext(ctx).Info("publishing event", slog.String("event", e.Name()), slog.Any("payload", e))
+	for _, h := range handlers {
+		h(ctx, e)
+	}
+}
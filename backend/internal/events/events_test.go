@@ -0,0 +1,31 @@
+package events
+
+import (
+	"context"
+	"testing"
+)
+
+func TestBus_DeliversToSubscribersInOrder(t *testing.T) {
+	bus := NewBus()
+	var got []string
+	for _, name := range []string{"first", "second"} {
+		name := name
+		bus.Subscribe(func(ctx context.Context, e Event) {
+			got = append(got, name+":"+e.Name())
+		})
+	}
+
+	bus.Publish(context.Background(), PointCreated{ForecastID: 1, UserID: 2})
+	bus.Publish(context.Background(), ScoreChanged{ForecastID: 1})
+
+	want := []string{"first:point.created", "second:point.created", "first:score.changed", "second:score.changed"}
+	if len(got) != len(want) {
+		t.Fatalf("Expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Expected %v, got %v", want, got)
+			break
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"backend/internal/auth"
+	"backend/internal/logger"
+	"backend/internal/models"
+	"backend/internal/services"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+type APIKeyHandler struct {
+	service *services.APIKeyService
+}
+
+func NewAPIKeyHandler(s *services.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{service: s}
+}
+
+// CreateAPIKey returns the new key once; only its prefix is shown afterwards
+func (h *APIKeyHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+
+	claims, ok := r.Context().Value(auth.UserContextKey).(*auth.Claims)
+	if !ok {
+		log.Error("unauthorized")
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var request struct {
+		Name   string        `json:"name"`
+		Scopes models.Scopes `json:"scopes"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		log.Error("invalid request body", slog.String("error", err.Error()))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	key, apiKey, err := h.service.CreateAPIKey(r.Context(), claims.UserID, request.Name, request.Scopes)
+	if err != nil {
+		log.Error("failed to create API key", slog.String("error", err.Error()))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, map[string]any{
+		"key":     key,
+		"api_key": apiKey,
+	})
+}
+
+func (h *APIKeyHandler) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+
+	claims, ok := r.Context().Value(auth.UserContextKey).(*auth.Claims)
+	if !ok {
+		log.Error("unauthorized")
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	keys, err := h.service.ListAPIKeys(r.Context(), claims.UserID)
+	if err != nil {
+		log.Error("failed to list API keys", slog.String("error", err.Error()))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, keys)
+}
+
+func (h *APIKeyHandler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+
+	claims, ok := r.Context().Value(auth.UserContextKey).(*auth.Claims)
+	if !ok {
+		log.Error("unauthorized")
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+	if err != nil {
+		log.Error("invalid API key ID", slog.String("error", err.Error()))
+		http.Error(w, "Invalid API key ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.RevokeAPIKey(r.Context(), id, claims.UserID); err != nil {
+		log.Error("failed to revoke API key", slog.Int64("id", id), slog.String("error", err.Error()))
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "API key not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, "API key revoked")
+}
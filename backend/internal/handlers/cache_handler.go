@@ -0,0 +1,19 @@
+package handlers
+
+import (
+	"backend/internal/cache"
+	"net/http"
+)
+
+type CacheHandler struct {
+	cache cache.Cache
+}
+
+func NewCacheHandler(c cache.Cache) *CacheHandler {
+	return &CacheHandler{cache: c}
+}
+
+// GetStats returns the cache's hit, miss and eviction counters
+func (h *CacheHandler) GetStats(w http.ResponseWriter, r *http.Request) {
+	respondJSON(w, http.StatusOK, h.cache.Stats())
+}
@@ -4,6 +4,7 @@ import (
 	"backend/internal/logger"
 	"backend/internal/models"
 	"backend/internal/services"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"strconv"
@@ -12,25 +13,32 @@ import (
 )
 
 type CalibrationHandler struct {
-	service *services.CalibrationService
+	service    *services.CalibrationService
+	categories *services.CategoryService
 }
 
-func NewCalibrationHandler(s *services.CalibrationService) *CalibrationHandler {
-	return &CalibrationHandler{service: s}
+func NewCalibrationHandler(s *services.CalibrationService, categories *services.CategoryService) *CalibrationHandler {
+	return &CalibrationHandler{service: s, categories: categories}
 }
 
 func (h *CalibrationHandler) GetCalibration(w http.ResponseWriter, r *http.Request) {
 	log := logger.FromContext(r.Context())
 
-	filters, err := parseCalibrationFilters(r)
+	filters, err := parseCalibrationFilters(r, h.categories)
 	if err != nil {
 		log.Error("invalid filter parameter", slog.String("error", err.Error()))
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	opts, err := parseCalibrationOptions(r)
+	if err != nil {
+		log.Error("invalid calibration option", slog.String("error", err.Error()))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	log.Info("getting calibration data", slog.Any("filters", filters))
-	data, err := h.service.GetCalibrationData(r.Context(), filters)
+	data, err := h.service.GetCalibrationData(r.Context(), filters, opts)
 	if err != nil {
 		log.Error("failed to get calibration data", slog.String("error", err.Error()))
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -43,15 +51,21 @@ func (h *CalibrationHandler) GetCalibration(w http.ResponseWriter, r *http.Reque
 func (h *CalibrationHandler) GetCalibrationByUsers(w http.ResponseWriter, r *http.Request) {
 	log := logger.FromContext(r.Context())
 
-	filters, err := parseCalibrationFilters(r)
+	filters, err := parseCalibrationFilters(r, h.categories)
 	if err != nil {
 		log.Error("invalid filter parameter", slog.String("error", err.Error()))
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	opts, err := parseCalibrationOptions(r)
+	if err != nil {
+		log.Error("invalid calibration option", slog.String("error", err.Error()))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	log.Info("getting calibration data by users", slog.Any("filters", filters))
-	data, err := h.service.GetCalibrationDataByUsers(r.Context(), filters)
+	data, err := h.service.GetCalibrationDataByUsers(r.Context(), filters, opts)
 	if err != nil {
 		log.Error("failed to get calibration data by users", slog.String("error", err.Error()))
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -61,7 +75,71 @@ func (h *CalibrationHandler) GetCalibrationByUsers(w http.ResponseWriter, r *htt
 	respondJSON(w, http.StatusOK, data)
 }
 
-func parseCalibrationFilters(r *http.Request) (models.CalibrationFilters, error) {
+// GetCalibrationMetrics returns the Brier score decomposition and calibration
+// error of the points matching the calibration filters
+func (h *CalibrationHandler) GetCalibrationMetrics(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+
+	filters, err := parseCalibrationFilters(r, h.categories)
+	if err != nil {
+		log.Error("invalid filter parameter", slog.String("error", err.Error()))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	opts, err := parseCalibrationOptions(r)
+	if err != nil {
+		log.Error("invalid calibration option", slog.String("error", err.Error()))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	metrics, err := h.service.GetCalibrationMetrics(r.Context(), filters, opts)
+	if err != nil {
+		log.Error("failed to get calibration metrics", slog.String("error", err.Error()))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, metrics)
+}
+
+// parseCalibrationOptions reads binning, bins, edges, points, interval and
+// confidence
+func parseCalibrationOptions(r *http.Request) (models.CalibrationOptions, error) {
+	queryParams := r.URL.Query()
+	opts := models.CalibrationOptions{
+		Binning:  models.CalibrationBinning(queryParams.Get("binning")),
+		Points:   models.CalibrationPointSelection(queryParams.Get("points")),
+		Interval: models.CalibrationInterval(queryParams.Get("interval")),
+	}
+
+	if binsStr := queryParams.Get("bins"); binsStr != "" {
+		bins, err := strconv.Atoi(binsStr)
+		if err != nil {
+			return opts, fmt.Errorf("invalid bins: %w", err)
+		}
+		opts.Bins = bins
+	}
+	if edgesStr := queryParams.Get("edges"); edgesStr != "" {
+		for _, edgeStr := range strings.Split(edgesStr, ",") {
+			edge, err := strconv.ParseFloat(strings.TrimSpace(edgeStr), 64)
+			if err != nil {
+				return opts, fmt.Errorf("invalid edges: %w", err)
+			}
+			opts.Edges = append(opts.Edges, edge)
+		}
+	}
+	if confidenceStr := queryParams.Get("confidence"); confidenceStr != "" {
+		confidence, err := strconv.ParseFloat(confidenceStr, 64)
+		if err != nil {
+			return opts, fmt.Errorf("invalid confidence: %w", err)
+		}
+		opts.Confidence = confidence
+	}
+	return opts, opts.Validate()
+}
+
+func parseCalibrationFilters(r *http.Request, categories *services.CategoryService) (models.CalibrationFilters, error) {
 	queryParams := r.URL.Query()
 	var filters models.CalibrationFilters
 
@@ -74,11 +152,11 @@ func parseCalibrationFilters(r *http.Request) (models.CalibrationFilters, error)
 		filters.UserID = &userID
 	}
 
-	categoryStr := queryParams.Get("category")
-	if categoryStr != "" {
-		category := strings.ToLower(categoryStr)
-		filters.Category = &category
+	categoryIDs, err := parseCategories(r, categories)
+	if err != nil {
+		return filters, err
 	}
+	filters.CategoryIDs = categoryIDs
 
 	startDateStr := queryParams.Get("start_date")
 	if startDateStr != "" {
@@ -98,5 +176,6 @@ func parseCalibrationFilters(r *http.Request) (models.CalibrationFilters, error)
 		filters.EndDate = &endDate
 	}
 
+	filters.Viewer = viewer(r)
 	return filters, nil
 }
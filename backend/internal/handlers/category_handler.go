@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"backend/internal/logger"
+	"backend/internal/models"
+	"backend/internal/services"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+type CategoryHandler struct {
+	service *services.CategoryService
+}
+
+func NewCategoryHandler(s *services.CategoryService) *CategoryHandler {
+	return &CategoryHandler{service: s}
+}
+
+// ListCategories returns the category tree
+func (h *CategoryHandler) ListCategories(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+
+	categories, err := h.service.GetCategoryTree(r.Context())
+	if err != nil {
+		log.Error("failed to get categories", slog.String("error", err.Error()))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	respondJSON(w, http.StatusOK, categories)
+}
+
+func (h *CategoryHandler) ListTags(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+
+	tags, err := h.service.GetTags(r.Context())
+	if err != nil {
+		log.Error("failed to get tags", slog.String("error", err.Error()))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	respondJSON(w, http.StatusOK, tags)
+}
+
+func (h *CategoryHandler) CreateCategory(w http.ResponseWriter, r *http.Request) {
+	var category models.Category
+	if err := json.NewDecoder(r.Body).Decode(&category); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	category.ID = 0
+
+	if err := h.service.CreateCategory(r.Context(), &category); err != nil {
+		respondCategoryError(w, r, err)
+		return
+	}
+	respondJSON(w, http.StatusCreated, category)
+}
+
+// UpdateCategory renames or moves a category; a missing parent_id moves it to
+// the top level
+func (h *CategoryHandler) UpdateCategory(w http.ResponseWriter, r *http.Request) {
+	var category models.Category
+	if err := json.NewDecoder(r.Body).Decode(&category); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if category.ID == 0 {
+		http.Error(w, "category id is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.UpdateCategory(r.Context(), &category); err != nil {
+		respondCategoryError(w, r, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, category)
+}
+
+func (h *CategoryHandler) DeleteCategory(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid category id", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.DeleteCategory(r.Context(), id); err != nil {
+		respondCategoryError(w, r, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, "category deleted")
+}
+
+// respondCategoryError maps the errors of category changes to their statuses
+func respondCategoryError(w http.ResponseWriter, r *http.Request, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, models.ErrCategoryNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, models.ErrCategoryExists), errors.Is(err, models.ErrCategoryInUse):
+		status = http.StatusConflict
+	case errors.Is(err, models.ErrCategoryCycle), errors.Is(err, models.ErrCategoryNameRequired):
+		status = http.StatusBadRequest
+	}
+	logger.FromContext(r.Context()).Error("category change failed", slog.String("error", err.Error()))
+	http.Error(w, err.Error(), status)
+}
+
+// parseCategories reads the category_id or category (a name) query parameter
+// into the ids of the matching categories, and of their subcategories when
+// include_subcategories is true. It returns nil when neither is given.
+func parseCategories(r *http.Request, categories *services.CategoryService) ([]int64, error) {
+	query := r.URL.Query()
+
+	var id *int64
+	if idStr := query.Get("category_id"); idStr != "" {
+		categoryID, err := strconv.ParseInt(idStr, 10, 64)
+		if err != nil {
+			return nil, errors.New("invalid category_id")
+		}
+		id = &categoryID
+	}
+
+	descendants := false
+	if s := query.Get("include_subcategories"); s != "" {
+		var err error
+		if descendants, err = strconv.ParseBool(s); err != nil {
+			return nil, errors.New("invalid include_subcategories")
+		}
+	}
+
+	return categories.CategoryIDs(r.Context(), id, query.Get("category"), descendants)
+}
@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"backend/internal/auth"
+	"backend/internal/logger"
+	"backend/internal/models"
+	"backend/internal/services"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+type CommentHandler struct {
+	service *services.CommentService
+}
+
+func NewCommentHandler(s *services.CommentService) *CommentHandler {
+	return &CommentHandler{service: s}
+}
+
+// ListComments returns the comments of a forecast, oldest first unless a page
+// is asked for. Replies name their parent in parent_id.
+func (h *CommentHandler) ListComments(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+
+	idStr := r.PathValue("id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		log.Error("invalid forecast ID", slog.String("error", err.Error()), slog.String("id", idStr))
+		http.Error(w, "Invalid forecast ID", http.StatusBadRequest)
+		return
+	}
+
+	page, err := parsePage(r, models.SortCreated)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	fields, err := parseFields[models.Comment](r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	comments, err := h.service.GetComments(r.Context(), models.CommentFilters{ForecastID: id, Page: page}, *viewer(r))
+	if err != nil {
+		respondCommentError(w, r, err)
+		return
+	}
+	respondList(w, comments, page, fields)
+}
+
+func (h *CommentHandler) CreateComment(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.UserContextKey).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var comment models.Comment
+	if err := json.NewDecoder(r.Body).Decode(&comment); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if comment.ForecastID == 0 {
+		http.Error(w, "forecast_id is required", http.StatusBadRequest)
+		return
+	}
+	comment = models.Comment{ForecastID: comment.ForecastID, ParentID: comment.ParentID, Body: comment.Body, UserID: claims.UserID}
+
+	if err := h.service.CreateComment(r.Context(), &comment); err != nil {
+		respondCommentError(w, r, err)
+		return
+	}
+	respondJSON(w, http.StatusCreated, comment)
+}
+
+// UpdateComment replaces the body of one of the user's comments
+func (h *CommentHandler) UpdateComment(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.UserContextKey).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var request struct {
+		ID   int64  `json:"id"`
+		Body string `json:"body"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if request.ID == 0 {
+		http.Error(w, "comment id is required", http.StatusBadRequest)
+		return
+	}
+
+	comment, err := h.service.UpdateComment(r.Context(), claims.UserID, request.ID, request.Body)
+	if err != nil {
+		respondCommentError(w, r, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, comment)
+}
+
+func (h *CommentHandler) DeleteComment(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.UserContextKey).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid comment id", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.DeleteComment(r.Context(), claims.UserID, id); err != nil {
+		respondCommentError(w, r, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, "comment deleted")
+}
+
+// respondCommentError maps the errors of comments to their statuses
+func respondCommentError(w http.ResponseWriter, r *http.Request, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, models.ErrForecastNotFound), errors.Is(err, models.ErrCommentNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, models.ErrNotCommentAuthor):
+		status = http.StatusForbidden
+	case errors.Is(err, models.ErrCommentDeleted):
+		status = http.StatusConflict
+	case errors.Is(err, models.ErrCommentEmpty), errors.Is(err, models.ErrCommentTooLong),
+		errors.Is(err, models.ErrParentNotFound), errors.Is(err, models.ErrInvalidCursor):
+		status = http.StatusBadRequest
+	}
+	logger.FromContext(r.Context()).Error("comment request failed", slog.String("error", err.Error()))
+	http.Error(w, err.Error(), status)
+}
@@ -5,19 +5,24 @@ import (
 	"backend/internal/logger"
 	"backend/internal/models"
 	"backend/internal/services"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"slices"
 	"strconv"
+	"strings"
 )
 
 type ForecastHandler struct {
-	service *services.ForecastService
+	service    *services.ForecastService
+	categories *services.CategoryService
 }
 
-func NewForecastHandler(s *services.ForecastService) *ForecastHandler {
-	return &ForecastHandler{service: s}
+func NewForecastHandler(s *services.ForecastService, categories *services.CategoryService) *ForecastHandler {
+	return &ForecastHandler{service: s, categories: categories}
 }
 
 // handlers for lists of forecasts
@@ -57,9 +62,33 @@ func (h *ForecastHandler) ListForecasts(w http.ResponseWriter, r *http.Request)
 		filters.Status = &status
 	}
 
-	category := queryParams.Get("category")
-	if category != "" {
-		filters.Category = &category
+	categoryIDs, err := parseCategories(r, h.categories)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	filters.CategoryIDs = categoryIDs
+
+	tag := queryParams.Get("tag")
+	if tag != "" {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		filters.Tag = &tag
+	}
+
+	page, err := parsePage(r, models.SortCreated, models.SortClosingDate, models.SortQuestion)
+	if err != nil {
+		log.Warn("invalid page", slog.String("error", err.Error()))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	filters.Page = page
+	filters.Viewer = viewer(r)
+
+	fields, err := parseFields[models.Forecast](r)
+	if err != nil {
+		log.Warn("invalid fields", slog.String("error", err.Error()))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
 	log.Info("getting forecasts", slog.Any("filters", filters))
@@ -70,7 +99,55 @@ func (h *ForecastHandler) ListForecasts(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	respondJSON(w, http.StatusOK, forecasts)
+	respondList(w, forecasts, page, fields)
+}
+
+// Search handles full-text searches of forecasts and their points' reasons
+func (h *ForecastHandler) Search(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+
+	queryParams := r.URL.Query()
+	filters := models.SearchFilters{Query: queryParams.Get("q")}
+	if strings.TrimSpace(filters.Query) == "" {
+		http.Error(w, "q is required", http.StatusBadRequest)
+		return
+	}
+
+	status := queryParams.Get("status")
+	if status != "" {
+		validStatuses := []string{"open", "resolved", "closed"}
+		if !slices.Contains(validStatuses, status) {
+			http.Error(w, "invalid status", http.StatusBadRequest)
+			return
+		}
+		filters.Status = &status
+	}
+
+	categoryIDs, err := parseCategories(r, h.categories)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	filters.CategoryIDs = categoryIDs
+
+	if limitStr := queryParams.Get("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit < 1 || limit > models.MaxSearchLimit {
+			http.Error(w, fmt.Sprintf("limit must be between 1 and %d", models.MaxSearchLimit), http.StatusBadRequest)
+			return
+		}
+		filters.Limit = limit
+	}
+	filters.Viewer = viewer(r)
+
+	results, err := h.service.Search(r.Context(), filters)
+	if err != nil {
+		log.Error("error searching forecasts", slog.String("error", err.Error()))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, results)
 }
 
 func (h *ForecastHandler) GetForecast(w http.ResponseWriter, r *http.Request) {
@@ -86,7 +163,11 @@ func (h *ForecastHandler) GetForecast(w http.ResponseWriter, r *http.Request) {
 	}
 
 	log.Info("getting forecast by ID", slog.Int64("id", id))
-	forecast, err := h.service.GetForecastByID(r.Context(), id)
+	forecast, err := h.service.GetVisibleForecast(r.Context(), id, *viewer(r))
+	if errors.Is(err, models.ErrForecastNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
 	if err != nil {
 		log.Error("error getting forecast by ID", slog.String("error", err.Error()), slog.Int64("id", id))
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -112,17 +193,32 @@ func (h *ForecastHandler) CreateForecast(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	if forecast.Question == "" || forecast.ResolutionCriteria == "" || forecast.Category == "" {
+	if forecast.Question == "" || forecast.ResolutionCriteria == "" || (forecast.Category == "" && forecast.CategoryID == nil) {
 		log.Error("question, resolution criteria, category, and closing date are required")
 		http.Error(w, "Question, resolution criteria, category, and closing date are required", http.StatusBadRequest)
 		return
 	}
 
+	if err := forecast.Validate(); err != nil {
+		log.Error("invalid forecast", slog.String("error", err.Error()))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := forecast.ValidateVisibility(); err != nil {
+		log.Error("invalid visibility", slog.String("error", err.Error()))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	// Set user ID from claims
 	forecast.UserID = claims.UserID
 
 	log.Info("creating forecast", slog.Any("forecast", forecast))
 	err := h.service.CreateForecast(r.Context(), &forecast)
+	if errors.Is(err, models.ErrCategoryNotFound) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		log.Error("failed to create forecast", slog.String("error", err.Error()))
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -132,7 +228,236 @@ func (h *ForecastHandler) CreateForecast(w http.ResponseWriter, r *http.Request)
 	respondJSON(w, http.StatusCreated, "forecast created")
 }
 
+// CategorizeForecast moves one of the user's forecasts to another category and
+// replaces its tags
+func (h *ForecastHandler) CategorizeForecast(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+
+	claims, ok := r.Context().Value(auth.UserContextKey).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var request struct {
+		ForecastID int64    `json:"forecast_id"`
+		CategoryID int64    `json:"category_id"`
+		Tags       []string `json:"tags"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if request.ForecastID == 0 || request.CategoryID == 0 {
+		http.Error(w, "forecast_id and category_id are required", http.StatusBadRequest)
+		return
+	}
+
+	err := h.service.CategorizeForecast(r.Context(), claims.UserID, request.ForecastID, request.CategoryID, request.Tags)
+	if err != nil {
+		log.Error("failed to categorize forecast", slog.String("error", err.Error()))
+		if errors.Is(err, models.ErrCategoryNotFound) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, "forecast categorized")
+}
+
+// EditForecast changes the question, resolution criteria or closing date of
+// one of the user's open forecasts, returning the revision it made
+func (h *ForecastHandler) EditForecast(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+
+	claims, ok := r.Context().Value(auth.UserContextKey).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var edit models.ForecastEdit
+	if err := json.NewDecoder(r.Body).Decode(&edit); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if edit.ForecastID == 0 {
+		http.Error(w, "forecast_id is required", http.StatusBadRequest)
+		return
+	}
+
+	revision, err := h.service.EditForecast(r.Context(), claims.UserID, edit)
+	if err != nil {
+		log.Error("failed to edit forecast", slog.String("error", err.Error()))
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, models.ErrForecastResolved):
+			status = http.StatusConflict
+		case errors.Is(err, models.ErrNoChanges), errors.Is(err, models.ErrQuestionRequired),
+			errors.Is(err, models.ErrClosingDateEarlier), errors.Is(err, models.ErrClosingDateInThePast):
+			status = http.StatusBadRequest
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, revision)
+}
+
+// GetForecastHistory returns the edits of a forecast, oldest first
+func (h *ForecastHandler) GetForecastHistory(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+
+	idStr := r.PathValue("id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		log.Error("invalid forecast ID", slog.String("error", err.Error()), slog.String("id", idStr))
+		http.Error(w, "Invalid forecast ID", http.StatusBadRequest)
+		return
+	}
+
+	revisions, err := h.service.GetForecastHistory(r.Context(), id, *viewer(r))
+	if errors.Is(err, models.ErrForecastNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Error("failed to get forecast history", slog.String("error", err.Error()), slog.Int64("id", id))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, revisions)
+}
+
+// GetAggregate returns the community forecast pooling every user's latest
+// point, chosen with the method, extremize and weighted query parameters
+func (h *ForecastHandler) GetAggregate(w http.ResponseWriter, r *http.Request) {
+	id, opts, ok := parseAggregateRequest(w, r)
+	if !ok {
+		return
+	}
+
+	aggregate, err := h.service.GetAggregate(r.Context(), id, *viewer(r), opts)
+	if err != nil {
+		respondAggregateError(w, r, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, aggregate)
+}
+
+// GetAggregateSeries returns the community forecast after every point, as
+// GetAggregate pools it
+func (h *ForecastHandler) GetAggregateSeries(w http.ResponseWriter, r *http.Request) {
+	id, opts, ok := parseAggregateRequest(w, r)
+	if !ok {
+		return
+	}
+
+	series, err := h.service.GetAggregateSeries(r.Context(), id, *viewer(r), opts)
+	if err != nil {
+		respondAggregateError(w, r, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, series)
+}
+
+// parseAggregateRequest reads the forecast id and the aggregate options,
+// responding with an error when they are invalid
+func parseAggregateRequest(w http.ResponseWriter, r *http.Request) (int64, models.AggregateOptions, bool) {
+	var opts models.AggregateOptions
+
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid forecast ID", http.StatusBadRequest)
+		return 0, opts, false
+	}
+
+	query := r.URL.Query()
+	opts.Method = models.AggregateMethod(query.Get("method"))
+	if s := query.Get("extremize"); s != "" {
+		if opts.Extremize, err = strconv.ParseFloat(s, 64); err != nil {
+			http.Error(w, "invalid extremize", http.StatusBadRequest)
+			return 0, opts, false
+		}
+	}
+	if s := query.Get("weighted"); s != "" {
+		if opts.Weighted, err = strconv.ParseBool(s); err != nil {
+			http.Error(w, "invalid weighted", http.StatusBadRequest)
+			return 0, opts, false
+		}
+	}
+	if err := opts.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return 0, opts, false
+	}
+	return id, opts, true
+}
+
+func respondAggregateError(w http.ResponseWriter, r *http.Request, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, models.ErrForecastNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, models.ErrAggregateUnsupported):
+		status = http.StatusBadRequest
+	default:
+		logger.FromContext(r.Context()).Error("failed to aggregate forecast", slog.String("error", err.Error()))
+	}
+	http.Error(w, err.Error(), status)
+}
+
+// SetVisibility changes who may see one of the user's forecasts
+func (h *ForecastHandler) SetVisibility(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+
+	claims, ok := r.Context().Value(auth.UserContextKey).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var request struct {
+		ForecastID int64             `json:"forecast_id"`
+		Visibility models.Visibility `json:"visibility"`
+		SharedWith []int64           `json:"shared_with"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	check := models.Forecast{Visibility: request.Visibility, SharedWith: request.SharedWith}
+	if request.ForecastID == 0 || request.Visibility == "" {
+		http.Error(w, "forecast_id and visibility are required", http.StatusBadRequest)
+		return
+	}
+	if err := check.ValidateVisibility(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err := h.service.SetVisibility(r.Context(), claims.UserID, request.ForecastID, request.Visibility, request.SharedWith)
+	if err != nil {
+		log.Error("failed to set forecast visibility", slog.String("error", err.Error()))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, "forecast visibility set")
+}
+
 func (h *ForecastHandler) DeleteForecast(w http.ResponseWriter, r *http.Request) {
+	h.deleteForecast(w, r, h.service.DeleteForecast)
+}
+
+// AdminDeleteForecast deletes any user's forecast
+func (h *ForecastHandler) AdminDeleteForecast(w http.ResponseWriter, r *http.Request) {
+	h.deleteForecast(w, r, h.service.AdminDeleteForecast)
+}
+
+func (h *ForecastHandler) deleteForecast(w http.ResponseWriter, r *http.Request, deleteForecast func(ctx context.Context, id int64, user_id int64) error) {
 	claims, ok := r.Context().Value(auth.UserContextKey).(*auth.Claims)
 	if !ok {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
@@ -149,7 +474,7 @@ func (h *ForecastHandler) DeleteForecast(w http.ResponseWriter, r *http.Request)
 	}
 
 	// Use UserID from claims
-	if err := h.service.DeleteForecast(r.Context(), request.ForecastID, claims.UserID); err != nil {
+	if err := deleteForecast(r.Context(), request.ForecastID, claims.UserID); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -158,6 +483,15 @@ func (h *ForecastHandler) DeleteForecast(w http.ResponseWriter, r *http.Request)
 }
 
 func (h *ForecastHandler) ResolveForecast(w http.ResponseWriter, r *http.Request) {
+	h.resolveForecast(w, r, h.service.ResolveForecast)
+}
+
+// AdminResolveForecast resolves any user's forecast
+func (h *ForecastHandler) AdminResolveForecast(w http.ResponseWriter, r *http.Request) {
+	h.resolveForecast(w, r, h.service.AdminResolveForecast)
+}
+
+func (h *ForecastHandler) resolveForecast(w http.ResponseWriter, r *http.Request, resolve func(ctx context.Context, user_id int64, id int64, resolution string, value *float64, comment string) error) {
 	log := logger.FromContext(r.Context())
 
 	claims, ok := r.Context().Value(auth.UserContextKey).(*auth.Claims)
@@ -168,9 +502,10 @@ func (h *ForecastHandler) ResolveForecast(w http.ResponseWriter, r *http.Request
 	}
 
 	var resolution struct {
-		ID         int64  `json:"id"`
-		Resolution string `json:"resolution"`
-		Comment    string `json:"comment"`
+		ID              int64    `json:"id"`
+		Resolution      string   `json:"resolution"`
+		ResolutionValue *float64 `json:"resolution_value"`
+		Comment         string   `json:"comment"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&resolution); err != nil {
@@ -182,19 +517,109 @@ func (h *ForecastHandler) ResolveForecast(w http.ResponseWriter, r *http.Request
 	userID := claims.UserID
 
 	log.Info("resolving forecast", slog.Any("resolution", resolution))
-	if err := h.service.ResolveForecast(r.Context(),
+	if err := resolve(r.Context(),
 		userID,
 		resolution.ID,
 		resolution.Resolution,
+		resolution.ResolutionValue,
 		resolution.Comment); err != nil {
 		log.Error("failed to resolve forecast", slog.String("error", err.Error()))
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), resolutionStatus(err))
 		return
 	}
 
 	respondJSON(w, http.StatusOK, "forecast resolved")
 }
 
+// UnresolveForecast reopens a resolved forecast, or re-resolves it when a new
+// resolution is given
+func (h *ForecastHandler) UnresolveForecast(w http.ResponseWriter, r *http.Request) {
+	h.unresolveForecast(w, r, h.service.UnresolveForecast)
+}
+
+// AdminUnresolveForecast unresolves or re-resolves any user's forecast
+func (h *ForecastHandler) AdminUnresolveForecast(w http.ResponseWriter, r *http.Request) {
+	h.unresolveForecast(w, r, h.service.AdminUnresolveForecast)
+}
+
+func (h *ForecastHandler) unresolveForecast(w http.ResponseWriter, r *http.Request, unresolve func(ctx context.Context, user_id int64, id int64, resolution *string, value *float64, comment string) error) {
+	log := logger.FromContext(r.Context())
+
+	claims, ok := r.Context().Value(auth.UserContextKey).(*auth.Claims)
+	if !ok {
+		log.Error("unauthorized")
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var request struct {
+		ID              int64    `json:"id"`
+		Resolution      *string  `json:"resolution"`
+		ResolutionValue *float64 `json:"resolution_value"`
+		Comment         string   `json:"comment"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		log.Error("invalid request body", slog.String("error", err.Error()))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	log.Info("unresolving forecast", slog.Any("request", request))
+	if err := unresolve(r.Context(),
+		claims.UserID,
+		request.ID,
+		request.Resolution,
+		request.ResolutionValue,
+		request.Comment); err != nil {
+		log.Error("failed to unresolve forecast", slog.String("error", err.Error()))
+		http.Error(w, err.Error(), resolutionStatus(err))
+		return
+	}
+
+	if request.Resolution != nil {
+		respondJSON(w, http.StatusOK, "forecast re-resolved")
+		return
+	}
+	respondJSON(w, http.StatusOK, "forecast unresolved")
+}
+
+// resolutionStatus maps the errors of resolving and unresolving to their
+// statuses
+func resolutionStatus(err error) int {
+	if errors.Is(err, models.ErrInvalidResolution) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+func (h *ForecastHandler) GetResolutionAudits(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+
+	idStr := r.URL.Query().Get("forecast_id")
+
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		log.Error("invalid forecast ID", slog.String("error", err.Error()), slog.String("forecast_id", idStr))
+		http.Error(w, "Invalid forecast ID", http.StatusBadRequest)
+		return
+	}
+
+	log.Info("getting resolution audits", slog.Int64("id", id))
+	audits, err := h.service.GetResolutionAudits(r.Context(), id, *viewer(r))
+	if errors.Is(err, models.ErrForecastNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Error("error getting resolution audits", slog.String("error", err.Error()), slog.Int64("id", id))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, audits)
+}
+
 func (h *ForecastHandler) GetStaleAndNewForecasts(w http.ResponseWriter, r *http.Request) {
 	log := logger.FromContext(r.Context())
 
@@ -207,7 +632,30 @@ func (h *ForecastHandler) GetStaleAndNewForecasts(w http.ResponseWriter, r *http
 	}
 
 	log.Info("getting stale and new forecasts", slog.Int64("user_id", userID))
-	forecasts, err := h.service.GetStaleAndNewForecasts(r.Context(), userID)
+	forecasts, err := h.service.GetStaleAndNewForecasts(r.Context(), userID, *viewer(r))
+	if err != nil {
+		log.Error("failed to get stale and new forecasts", slog.String("error", err.Error()))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, forecasts)
+}
+
+// GetOwnStaleAndNewForecasts is GetStaleAndNewForecasts for the caller, so
+// agents with an API key need not know their user ID
+func (h *ForecastHandler) GetOwnStaleAndNewForecasts(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context())
+
+	claims, ok := r.Context().Value(auth.UserContextKey).(*auth.Claims)
+	if !ok {
+		log.Error("unauthorized")
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	log.Info("getting stale and new forecasts", slog.Int64("user_id", claims.UserID))
+	forecasts, err := h.service.GetStaleAndNewForecasts(r.Context(), claims.UserID, claims.UserID)
 	if err != nil {
 		log.Error("failed to get stale and new forecasts", slog.String("error", err.Error()))
 		http.Error(w, err.Error(), http.StatusInternalServerError)
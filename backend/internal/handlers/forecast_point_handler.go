@@ -7,6 +7,7 @@ import (
 	"backend/internal/services"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"log/slog"
 	"net/http"
 	"strconv"
@@ -97,6 +98,22 @@ func (h *ForecastPointHandler) ListForecastPoints(w http.ResponseWriter, r *http
 		filters.CreatedDirection = &createdDirectionString
 	}
 
+	page, err := parsePage(r, models.SortCreated)
+	if err != nil {
+		log.Error("invalid page", slog.String("error", err.Error()))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	filters.Page = page
+	filters.Viewer = viewer(r)
+
+	fields, err := parseFields[models.ForecastPoint](r)
+	if err != nil {
+		log.Error("invalid fields", slog.String("error", err.Error()))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	log.Info("getting forecast points", slog.Any("filters", filters))
 	points, err := h.service.GetForecastPoints(r.Context(), filters)
 	if err != nil {
@@ -110,7 +127,7 @@ func (h *ForecastPointHandler) ListForecastPoints(w http.ResponseWriter, r *http
 		return
 	}
 
-	respondJSON(w, http.StatusOK, points)
+	respondList(w, points, page, fields)
 }
 
 func (h *ForecastPointHandler) CreateForecastPoint(w http.ResponseWriter, r *http.Request) {
@@ -136,6 +153,10 @@ func (h *ForecastPointHandler) CreateForecastPoint(w http.ResponseWriter, r *htt
 
 	log.Info("creating forecast point", slog.Any("point", point))
 	err := h.service.CreateForecastPoint(r.Context(), &point)
+	if errors.Is(err, models.ErrForecastNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
 	if err != nil {
 		log.Error("failed to create forecast point", slog.String("error", err.Error()))
 		http.Error(w, err.Error(), http.StatusInternalServerError)
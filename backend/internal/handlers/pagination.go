@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"backend/internal/models"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// parsePage reads the limit, cursor, sort and order query parameters of a
+// list sortable by the given keys, the first being the default. It returns nil
+// when none is given, for lists that return every row.
+func parsePage(r *http.Request, sorts ...string) (*models.Page, error) {
+	query := r.URL.Query()
+	limitStr, cursorStr := query.Get("limit"), query.Get("cursor")
+	sort, order := query.Get("sort"), strings.ToLower(query.Get("order"))
+	if limitStr == "" && cursorStr == "" && sort == "" && order == "" {
+		return nil, nil
+	}
+
+	page := &models.Page{Limit: models.DefaultPageLimit, Sort: sorts[0], Desc: true}
+	if limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit < 1 || limit > models.MaxPageLimit {
+			return nil, fmt.Errorf("limit must be between 1 and %d", models.MaxPageLimit)
+		}
+		page.Limit = limit
+	}
+	if sort != "" {
+		if !slices.Contains(sorts, sort) {
+			return nil, fmt.Errorf("sort must be one of %s", strings.Join(sorts, ", "))
+		}
+		page.Sort = sort
+	}
+	switch order {
+	case "", "desc":
+	case "asc":
+		page.Desc = false
+	default:
+		return nil, fmt.Errorf("order must be asc or desc")
+	}
+
+	if cursorStr != "" {
+		cursor, err := models.DecodeCursor(cursorStr)
+		if err != nil {
+			return nil, err
+		}
+		// the cursor carries its ordering, which an explicit one must match
+		if (sort != "" && sort != cursor.Sort) || (order != "" && page.Desc != cursor.Desc) || !slices.Contains(sorts, cursor.Sort) {
+			return nil, models.ErrInvalidCursor
+		}
+		page.Sort, page.Desc, page.After = cursor.Sort, cursor.Desc, cursor
+	}
+	return page, nil
+}
+
+// parseFields reads the fields query parameter, the JSON fields of T to keep
+// in each item of a list. It returns nil when every field is wanted.
+func parseFields[T any](r *http.Request) ([]string, error) {
+	fieldsStr := r.URL.Query().Get("fields")
+	if fieldsStr == "" {
+		return nil, nil
+	}
+
+	known := jsonFields(reflect.TypeFor[T]())
+	var fields []string
+	for _, field := range strings.Split(fieldsStr, ",") {
+		field = strings.TrimSpace(field)
+		if !slices.Contains(known, field) {
+			return nil, fmt.Errorf("unknown field %q", field)
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// respondList writes the rows, as a page with its next cursor when one was
+// asked for, keeping only the selected fields of each row
+func respondList[T models.Sortable](w http.ResponseWriter, rows []T, page *models.Page, fields []string) {
+	if page == nil {
+		if fields == nil {
+			respondJSON(w, http.StatusOK, rows)
+			return
+		}
+		respondJSON(w, http.StatusOK, selectFields(rows, fields))
+		return
+	}
+
+	paged := models.NewPaged(rows, *page)
+	if fields == nil {
+		respondJSON(w, http.StatusOK, paged)
+		return
+	}
+	respondJSON(w, http.StatusOK, models.Paged[map[string]json.RawMessage]{
+		Items:      selectFields(paged.Items, fields),
+		NextCursor: paged.NextCursor,
+	})
+}
+
+// selectFields returns the rows as JSON objects with only the given fields
+func selectFields[T any](rows []T, fields []string) []map[string]json.RawMessage {
+	selected := make([]map[string]json.RawMessage, 0, len(rows))
+	for _, row := range rows {
+		data, _ := json.Marshal(row)
+		var all map[string]json.RawMessage
+		json.Unmarshal(data, &all)
+
+		kept := make(map[string]json.RawMessage, len(fields))
+		for _, field := range fields {
+			if value, ok := all[field]; ok {
+				kept[field] = value
+			}
+		}
+		selected = append(selected, kept)
+	}
+	return selected
+}
+
+// jsonFields lists the JSON field names of a struct, or of the struct a
+// pointer points to
+func jsonFields(t reflect.Type) []string {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	var fields []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Anonymous {
+			fields = append(fields, jsonFields(f.Type)...)
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "" || name == "-" || !f.IsExported() {
+			continue
+		}
+		fields = append(fields, name)
+	}
+	return fields
+}
@@ -9,16 +9,16 @@ import (
 	"log/slog"
 	"net/http"
 	"strconv"
-	"strings"
 	"time"
 )
 
 type ScoreHandler struct {
-	service *services.ScoreService
+	service    *services.ScoreService
+	categories *services.CategoryService
 }
 
-func NewScoreHandler(s *services.ScoreService) *ScoreHandler {
-	return &ScoreHandler{service: s}
+func NewScoreHandler(s *services.ScoreService, categories *services.CategoryService) *ScoreHandler {
+	return &ScoreHandler{service: s, categories: categories}
 }
 
 // Depending on the request parameters, this handler returns scores for a user_id, a forecast_id, or both
@@ -51,14 +51,27 @@ func (h *ScoreHandler) GetScores(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	log.Info("getting scores", slog.Int64("user_id", userID), slog.Int64("forecast_id", forecastID))
-	scores, err := h.service.GetScores(r.Context(), userID, forecastID)
+	page, err := parsePage(r, models.SortCreated)
+	if err != nil {
+		log.Error("invalid page", slog.String("error", err.Error()))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	fields, err := parseFields[models.Scores](r)
+	if err != nil {
+		log.Error("invalid fields", slog.String("error", err.Error()))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	log.Info("getting scores", slog.Int64("user_id", userID), slog.Int64("forecast_id", forecastID), slog.Any("page", page))
+	scores, err := h.service.GetScores(r.Context(), userID, forecastID, page, viewer(r))
 	if err != nil {
 		log.Error("failed to get scores", slog.String("error", err.Error()))
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	respondJSON(w, http.StatusOK, scores)
+	respondList(w, scores, page, fields)
 
 }
 
@@ -125,7 +138,7 @@ func (h *ScoreHandler) DeleteScore(w http.ResponseWriter, r *http.Request) {
 func (h *ScoreHandler) GetAverageScores(w http.ResponseWriter, r *http.Request) {
 	log := logger.FromContext(r.Context())
 	log.Info("getting average scores")
-	scores, err := h.service.GetAverageScores(r.Context())
+	scores, err := h.service.GetAverageScores(r.Context(), viewer(r))
 	if err != nil {
 		log.Error("failed to get average scores", slog.String("error", err.Error()))
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -164,11 +177,11 @@ func (h *ScoreHandler) GetAggregateScores(w http.ResponseWriter, r *http.Request
 		forecastIDPtr = &forecastID
 	}
 
-	var categoryPtr *string
-	categorystr := queryParams.Get("category")
-	if categorystr != "" {
-		category := strings.ToLower(categorystr)
-		categoryPtr = &category
+	categoryIDs, err := parseCategories(r, h.categories)
+	if err != nil {
+		log.Error("invalid category", slog.String("error", err.Error()))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
 	var startDatePtr *time.Time
@@ -195,8 +208,15 @@ func (h *ScoreHandler) GetAggregateScores(w http.ResponseWriter, r *http.Request
 		endDatePtr = &endDate
 	}
 
-	log.Info("getting aggregate scores", slog.Any("user_id", userIDPtr), slog.Any("forecast_id", forecastIDPtr), slog.Any("category", categoryPtr), slog.Any("start_date", startDatePtr), slog.Any("end_date", endDatePtr))
-	scores, err := h.service.GetAggregateScores(r.Context(), userIDPtr, forecastIDPtr, categoryPtr, startDatePtr, endDatePtr)
+	rule, err := parseScoringRule(r)
+	if err != nil {
+		log.Error("invalid rule", slog.String("error", err.Error()))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	log.Info("getting aggregate scores", slog.Any("user_id", userIDPtr), slog.Any("forecast_id", forecastIDPtr), slog.Any("category_ids", categoryIDs), slog.Any("start_date", startDatePtr), slog.Any("end_date", endDatePtr), slog.Any("rule", rule))
+	scores, err := h.service.GetAggregateScores(r.Context(), userIDPtr, forecastIDPtr, categoryIDs, startDatePtr, endDatePtr, rule, viewer(r))
 	if err != nil {
 		log.Error("failed to get aggregate scores", slog.String("error", err.Error()))
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -210,11 +230,11 @@ func (h *ScoreHandler) GetAggregateScoresGroupedByUsers(w http.ResponseWriter, r
 
 	queryParams := r.URL.Query()
 
-	var categoryPtr *string
-	categorystr := queryParams.Get("category")
-	if categorystr != "" {
-		category := strings.ToLower(categorystr)
-		categoryPtr = &category
+	categoryIDs, err := parseCategories(r, h.categories)
+	if err != nil {
+		log.Error("invalid category", slog.String("error", err.Error()))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
 	var startDatePtr *time.Time
@@ -241,8 +261,15 @@ func (h *ScoreHandler) GetAggregateScoresGroupedByUsers(w http.ResponseWriter, r
 		endDatePtr = &endDate
 	}
 
-	log.Info("getting aggregate scores grouped by users", slog.Any("category", categoryPtr), slog.Any("start_date", startDatePtr), slog.Any("end_date", endDatePtr))
-	scores, err := h.service.GetAggregateScoresGroupedByUsers(r.Context(), categoryPtr, startDatePtr, endDatePtr)
+	rule, err := parseScoringRule(r)
+	if err != nil {
+		log.Error("invalid rule", slog.String("error", err.Error()))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	log.Info("getting aggregate scores grouped by users", slog.Any("category_ids", categoryIDs), slog.Any("start_date", startDatePtr), slog.Any("end_date", endDatePtr), slog.Any("rule", rule))
+	scores, err := h.service.GetAggregateScoresGroupedByUsers(r.Context(), categoryIDs, startDatePtr, endDatePtr, rule, viewer(r))
 	if err != nil {
 		log.Error("failed to get aggregate scores grouped by users", slog.String("error", err.Error()))
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -250,3 +277,24 @@ func (h *ScoreHandler) GetAggregateScoresGroupedByUsers(w http.ResponseWriter, r
 	}
 	respondJSON(w, http.StatusOK, scores)
 }
+
+// ListScoringRules lists the scoring rules the aggregates can average with rule=
+func (h *ScoreHandler) ListScoringRules(w http.ResponseWriter, r *http.Request) {
+	var rules []models.ScoringRuleInfo
+	for _, rule := range models.ScoringRules() {
+		rules = append(rules, models.ScoringRuleInfo{Name: rule.Name(), LowerIsBetter: rule.LowerIsBetter()})
+	}
+	respondJSON(w, http.StatusOK, rules)
+}
+
+// parseScoringRule reads the rule parameter, nil when missing
+func parseScoringRule(r *http.Request) (*string, error) {
+	name := r.URL.Query().Get("rule")
+	if name == "" {
+		return nil, nil
+	}
+	if _, err := models.GetScoringRule(name); err != nil {
+		return nil, err
+	}
+	return &name, nil
+}
@@ -5,16 +5,18 @@ import (
 	"backend/internal/models"
 	"backend/internal/services"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 )
 
 type UserHandler struct {
-	service *services.UserService
+	service  *services.UserService
+	sessions *services.SessionService
 }
 
-func NewUserHandler(s *services.UserService) *UserHandler {
-	return &UserHandler{service: s}
+func NewUserHandler(s *services.UserService, sessions *services.SessionService) *UserHandler {
+	return &UserHandler{service: s, sessions: sessions}
 }
 
 func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
@@ -65,8 +67,8 @@ func (h *UserHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Only allow users to delete their own account or require admin role
-	if userID != claims.UserID {
+	// Only allow users to delete their own account unless they are an admin
+	if userID != claims.UserID && claims.Role != models.RoleAdmin {
 		http.Error(w, "Forbidden", http.StatusForbidden)
 		return
 	}
@@ -153,19 +155,85 @@ func (h *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Generate JWT token
-	token, err := auth.GenerateToken(user.ID, user.Username)
+	session, err := h.sessions.CreateSession(r.Context(), user)
 	if err != nil {
 		http.Error(w, "Error generating token", http.StatusInternalServerError)
 		return
 	}
 
-	respondJSON(w, http.StatusOK, map[string]string{
-		"token":    token,
-		"username": user.Username,
+	respondJSON(w, http.StatusOK, map[string]any{
+		"token":         session.AccessToken,
+		"refresh_token": session.RefreshToken,
+		"expires_at":    session.ExpiresAt,
+		"username":      user.Username,
+		"role":          user.Role,
 	})
 }
 
+// Refresh swaps a refresh token for a new access and refresh token
+func (h *UserHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	var refreshRequest struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&refreshRequest); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if refreshRequest.RefreshToken == "" {
+		http.Error(w, "Refresh token is required", http.StatusBadRequest)
+		return
+	}
+
+	session, err := h.sessions.Refresh(r.Context(), refreshRequest.RefreshToken)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidRefreshToken) {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, session)
+}
+
+// Logout revokes the access token it is called with and the refresh token in
+// the body, or with everywhere set every token the user holds
+func (h *UserHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(auth.UserContextKey).(*auth.Claims)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var logoutRequest struct {
+		RefreshToken string `json:"refresh_token"`
+		Everywhere   bool   `json:"everywhere"`
+	}
+
+	// the body is optional
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&logoutRequest); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	err := h.sessions.Logout(r.Context(), claims, logoutRequest.RefreshToken, logoutRequest.Everywhere)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidRefreshToken) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]string{"message": "Logged out successfully"})
+}
+
 // AdminResetPassword allows resetting a user's password without knowing the old one
 func (h *UserHandler) AdminResetPassword(w http.ResponseWriter, r *http.Request) {
 	var resetRequest struct {
@@ -191,3 +259,29 @@ func (h *UserHandler) AdminResetPassword(w http.ResponseWriter, r *http.Request)
 
 	respondJSON(w, http.StatusOK, map[string]string{"message": "Password reset successfully"})
 }
+
+// AdminSetRole changes a user's role
+func (h *UserHandler) AdminSetRole(w http.ResponseWriter, r *http.Request) {
+	var roleRequest struct {
+		UserID int64  `json:"user_id"`
+		Role   string `json:"role"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&roleRequest); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	role, err := models.ParseRole(roleRequest.Role)
+	if err != nil || roleRequest.UserID == 0 {
+		http.Error(w, "User ID and a valid role are required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.SetRole(r.Context(), roleRequest.UserID, role); err != nil {
+		http.Error(w, "Failed to set role: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]string{"message": "Role updated successfully"})
+}
@@ -0,0 +1,18 @@
+package handlers
+
+import (
+	"backend/internal/auth"
+	"backend/internal/models"
+	"net/http"
+)
+
+// viewer returns the id of the user calling a public route, which is 0 for
+// anonymous callers and API keys that cannot read forecasts. Filters use it to
+// hide the forecasts the caller may not see.
+func viewer(r *http.Request) *int64 {
+	var id int64
+	if claims, ok := r.Context().Value(auth.UserContextKey).(*auth.Claims); ok && claims.HasScope(models.ScopeForecastsRead) {
+		id = claims.UserID
+	}
+	return &id
+}
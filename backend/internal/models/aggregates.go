@@ -0,0 +1,306 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// AggregateMethod is how the latest points of every forecaster are pooled into
+// the community forecast
+type AggregateMethod string
+
+const (
+	AggregateMean   AggregateMethod = "mean"
+	AggregateMedian AggregateMethod = "median"
+	// AggregateGeoMeanOdds averages the log-odds, giving confident forecasters
+	// more say than the mean does
+	AggregateGeoMeanOdds AggregateMethod = "geo_mean_odds"
+	// AggregateExtremized scales the average log-odds by an extremizing
+	// factor, making up for forecasters drawing on the same information
+	AggregateExtremized AggregateMethod = "extremized"
+)
+
+const (
+	// DefaultAggregateMethod pools the community forecast shown by default
+	// and scored at resolution
+	DefaultAggregateMethod = AggregateGeoMeanOdds
+	// DefaultExtremizingFactor is the extremizing factor when none is given
+	DefaultExtremizingFactor = 1.5
+	// CrowdUsername names the pseudo-user the community forecast is scored as
+	CrowdUsername = "crowd"
+	// MinCrowdForecasters is how many users the community forecast pools
+	// before it is scored, as the aggregate of one user is their own forecast
+	MinCrowdForecasters = 2
+)
+
+// aggregateEpsilon keeps pooled probabilities within 0 and 1, so extremized
+// aggregates can still be scored
+const aggregateEpsilon = 1e-6
+
+// brierWeightSmoothing keeps a handful of lucky forecasts from giving a user
+// an outsized weight
+const brierWeightSmoothing = 0.05
+
+// uninformedBrier is the Brier score of always forecasting 50%, the weight
+// given to users without scores
+const uninformedBrier = 0.25
+
+var (
+	ErrAggregateUnsupported = errors.New("aggregates are only computed for binary and multiple choice forecasts")
+	ErrUsernameReserved     = fmt.Errorf("username %q is reserved", CrowdUsername)
+)
+
+// AggregateOptions chooses how points are pooled
+type AggregateOptions struct {
+	Method AggregateMethod
+	// Extremize is the extremizing factor of extremized aggregates
+	Extremize float64
+	// Weighted weighs users by their historical Brier score
+	Weighted bool
+}
+
+// Validate checks the options, defaulting the method and the extremizing factor
+func (o *AggregateOptions) Validate() error {
+	switch o.Method {
+	case "":
+		o.Method = DefaultAggregateMethod
+	case AggregateMean, AggregateMedian, AggregateGeoMeanOdds, AggregateExtremized:
+	default:
+		return fmt.Errorf("unknown aggregate method %q", o.Method)
+	}
+	if o.Method != AggregateExtremized {
+		if o.Extremize != 0 {
+			return errors.New("only extremized aggregates take extremize")
+		}
+		return nil
+	}
+	if o.Extremize == 0 {
+		o.Extremize = DefaultExtremizingFactor
+	}
+	if math.IsNaN(o.Extremize) || math.IsInf(o.Extremize, 0) || o.Extremize <= 0 {
+		return errors.New("extremize must be a positive number")
+	}
+	return nil
+}
+
+// AggregatePoint is the community forecast as of one point
+type AggregatePoint struct {
+	// PointForecast is the pooled probability of a binary forecast
+	PointForecast *float64 `json:"point_forecast,omitempty"`
+	// Probabilities are the pooled probabilities of a multiple choice forecast
+	Probabilities Probabilities `json:"probabilities,omitempty"`
+	// Forecasters is how many users' points were pooled
+	Forecasters int       `json:"forecasters"`
+	CreatedAt   time.Time `json:"created"`
+}
+
+// Aggregate is the current community forecast
+type Aggregate struct {
+	ForecastID int64           `json:"forecast_id"`
+	Method     AggregateMethod `json:"method"`
+	Extremize  float64         `json:"extremize,omitempty"`
+	Weighted   bool            `json:"weighted"`
+	AggregatePoint
+}
+
+// AggregateSeries is the community forecast after every point
+type AggregateSeries struct {
+	ForecastID int64            `json:"forecast_id"`
+	Method     AggregateMethod  `json:"method"`
+	Extremize  float64          `json:"extremize,omitempty"`
+	Weighted   bool             `json:"weighted"`
+	Points     []AggregatePoint `json:"points"`
+}
+
+// BrierWeights weighs each user by the inverse of their average Brier score on
+// questions of the type. Multiple choice Brier scores run up to 2, so they are
+// halved to weigh on the scale of binary ones. Users missing from scores weigh
+// as much as someone always forecasting 50%.
+func BrierWeights(scores []UserScores, questionType QuestionType) map[int64]float64 {
+	weights := make(map[int64]float64, len(scores))
+	for _, s := range scores {
+		brier := s.BrierScore
+		if questionType == QuestionTypeMultipleChoice {
+			brier /= 2
+		}
+		weights[s.UserID] = brierWeight(brier)
+	}
+	return weights
+}
+
+func brierWeight(brier float64) float64 {
+	return 1 / (brier + brierWeightSmoothing)
+}
+
+// AggregatePoints pools the latest point of every user after each of the
+// points, so the last aggregate is the current one. Binary forecasts have no
+// options. Users missing from weights, when given, weigh as an uninformed
+// forecaster.
+func AggregatePoints(points []*ForecastPoint, optionCount int, weights map[int64]float64, opts AggregateOptions) ([]AggregatePoint, error) {
+	sorted := make([]*ForecastPoint, len(points))
+	copy(sorted, points)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].CreatedAt.Before(sorted[j].CreatedAt)
+	})
+
+	latest := make(map[int64]*ForecastPoint)
+	var users []int64
+	series := make([]AggregatePoint, 0, len(sorted))
+	for _, p := range sorted {
+		if _, ok := latest[p.UserID]; !ok {
+			users = append(users, p.UserID)
+		}
+		latest[p.UserID] = p
+
+		current := make([]*ForecastPoint, len(users))
+		userWeights := make([]float64, len(users))
+		for i, userID := range users {
+			current[i] = latest[userID]
+			userWeights[i] = 1
+			if weights != nil {
+				w, ok := weights[userID]
+				if !ok {
+					w = brierWeight(uninformedBrier)
+				}
+				userWeights[i] = w
+			}
+		}
+
+		aggregate, err := poolPoints(current, optionCount, userWeights, opts)
+		if err != nil {
+			return nil, err
+		}
+		aggregate.CreatedAt = p.CreatedAt
+		series = append(series, aggregate)
+	}
+	return series, nil
+}
+
+// poolPoints pools one point per user
+func poolPoints(points []*ForecastPoint, optionCount int, weights []float64, opts AggregateOptions) (AggregatePoint, error) {
+	aggregate := AggregatePoint{Forecasters: len(points)}
+	values := make([]float64, len(points))
+
+	if optionCount == 0 {
+		for i, p := range points {
+			values[i] = p.PointForecast
+		}
+		pooled, err := pool(values, weights, opts)
+		if err != nil {
+			return AggregatePoint{}, err
+		}
+		pooled = clampProbability(pooled)
+		aggregate.PointForecast = &pooled
+		return aggregate, nil
+	}
+
+	// options are pooled one at a time, then brought back to a sum of 1
+	probabilities := make(Probabilities, optionCount)
+	var sum float64
+	for option := range probabilities {
+		for i, p := range points {
+			if len(p.Probabilities) != optionCount {
+				return AggregatePoint{}, fmt.Errorf("forecast point %d has %d probabilities, expected %d", p.ID, len(p.Probabilities), optionCount)
+			}
+			values[i] = p.Probabilities[option]
+		}
+		pooled, err := pool(values, weights, opts)
+		if err != nil {
+			return AggregatePoint{}, err
+		}
+		probabilities[option] = clampProbability(pooled)
+		sum += probabilities[option]
+	}
+	for option := range probabilities {
+		probabilities[option] /= sum
+	}
+	aggregate.Probabilities = probabilities
+	return aggregate, nil
+}
+
+// pool combines probabilities with the given weights
+func pool(values []float64, weights []float64, opts AggregateOptions) (float64, error) {
+	var total float64
+	for _, w := range weights {
+		total += w
+	}
+	if len(values) == 0 || total <= 0 {
+		return 0, errors.New("nothing to aggregate")
+	}
+
+	switch opts.Method {
+	case AggregateMean:
+		var sum float64
+		for i, v := range values {
+			sum += weights[i] * v
+		}
+		return sum / total, nil
+	case AggregateMedian:
+		return weightedMedian(values, weights, total), nil
+	case AggregateGeoMeanOdds, AggregateExtremized:
+		var sum float64
+		for i, v := range values {
+			v = clampProbability(v)
+			sum += weights[i] * math.Log(v/(1-v))
+		}
+		logOdds := sum / total
+		if opts.Method == AggregateExtremized {
+			logOdds *= opts.Extremize
+		}
+		return 1 / (1 + math.Exp(-logOdds)), nil
+	}
+	return 0, fmt.Errorf("unknown aggregate method %q", opts.Method)
+}
+
+// weightedMedian returns the value splitting the weights in half, averaging
+// the two middle values when they split them exactly
+func weightedMedian(values []float64, weights []float64, total float64) float64 {
+	order := make([]int, len(values))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return values[order[a]] < values[order[b]]
+	})
+
+	var cumulative float64
+	for k, i := range order {
+		cumulative += weights[i]
+		if math.Abs(cumulative-total/2) <= 1e-12*total && k+1 < len(order) {
+			return (values[i] + values[order[k+1]]) / 2
+		}
+		if cumulative > total/2 {
+			return values[i]
+		}
+	}
+	return values[order[len(order)-1]]
+}
+
+func clampProbability(p float64) float64 {
+	return math.Min(math.Max(p, aggregateEpsilon), 1-aggregateEpsilon)
+}
+
+// TimePoints turns a binary aggregate series into points to score
+func TimePoints(series []AggregatePoint) []TimePoint {
+	points := make([]TimePoint, 0, len(series))
+	for _, a := range series {
+		if a.PointForecast != nil {
+			points = append(points, TimePoint{PointForecast: *a.PointForecast, CreatedAt: a.CreatedAt})
+		}
+	}
+	return points
+}
+
+// ChoicePoints turns a multiple choice aggregate series into points to score
+func ChoicePoints(series []AggregatePoint) []ChoicePoint {
+	points := make([]ChoicePoint, 0, len(series))
+	for _, a := range series {
+		if a.Probabilities != nil {
+			points = append(points, ChoicePoint{Probabilities: a.Probabilities, CreatedAt: a.CreatedAt})
+		}
+	}
+	return points
+}
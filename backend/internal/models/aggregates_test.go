@@ -0,0 +1,148 @@
+package models
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestPool(t *testing.T) {
+	values := []float64{0.2, 0.5, 0.9}
+	equal := []float64{1, 1, 1}
+	odds := math.Cbrt(0.2 / 0.8 * 0.5 / 0.5 * 0.9 / 0.1)
+	extremized := math.Pow(odds, 2)
+
+	for _, c := range []struct {
+		name    string
+		opts    AggregateOptions
+		weights []float64
+		want    float64
+	}{
+		{"mean", AggregateOptions{Method: AggregateMean}, equal, (0.2 + 0.5 + 0.9) / 3},
+		{"weighted mean", AggregateOptions{Method: AggregateMean}, []float64{2, 1, 1}, (0.4 + 0.5 + 0.9) / 4},
+		{"median", AggregateOptions{Method: AggregateMedian}, equal, 0.5},
+		{"weighted median", AggregateOptions{Method: AggregateMedian}, []float64{1, 1, 3}, 0.9},
+		{"even median", AggregateOptions{Method: AggregateMedian}, []float64{1, 1, 2}, 0.7},
+		{"geometric mean of odds", AggregateOptions{Method: AggregateGeoMeanOdds}, equal, odds / (1 + odds)},
+		{"extremized", AggregateOptions{Method: AggregateExtremized, Extremize: 2}, equal, extremized / (1 + extremized)},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := pool(values, c.weights, c.opts)
+			if err != nil {
+				t.Fatalf("Error pooling: %v", err)
+			}
+			if math.Abs(got-c.want) > 1e-9 {
+				t.Errorf("Expected %v, got %v", c.want, got)
+			}
+		})
+	}
+}
+
+func TestAggregatePoints(t *testing.T) {
+	start := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	points := []*ForecastPoint{
+		{UserID: 2, PointForecast: 0.8, CreatedAt: start.Add(2 * time.Hour)},
+		{UserID: 1, PointForecast: 0.2, CreatedAt: start},
+		{UserID: 1, PointForecast: 0.4, CreatedAt: start.Add(3 * time.Hour)},
+	}
+
+	series, err := AggregatePoints(points, 0, nil, AggregateOptions{Method: AggregateMean})
+	if err != nil {
+		t.Fatalf("Error aggregating: %v", err)
+	}
+	want := []struct {
+		p           float64
+		forecasters int
+	}{{0.2, 1}, {0.5, 2}, {0.6, 2}}
+	if len(series) != len(want) {
+		t.Fatalf("Expected %d aggregates, got %d", len(want), len(series))
+	}
+	for i, w := range want {
+		if math.Abs(*series[i].PointForecast-w.p) > 1e-9 || series[i].Forecasters != w.forecasters {
+			t.Errorf("Aggregate %d: expected %v from %d forecasters, got %v from %d", i, w.p, w.forecasters, *series[i].PointForecast, series[i].Forecasters)
+		}
+	}
+	if !series[2].CreatedAt.Equal(start.Add(3 * time.Hour)) {
+		t.Errorf("Expected the last aggregate at the last point, got %v", series[2].CreatedAt)
+	}
+
+	// user 1 has a perfect record, user 2 has none and weighs as an
+	// uninformed forecaster
+	weighted, err := AggregatePoints(points, 0, BrierWeights([]UserScores{{ScoreMetrics: ScoreMetrics{BrierScore: 0}, UserID: 1}}, QuestionTypeBinary), AggregateOptions{Method: AggregateMean})
+	if err != nil {
+		t.Fatalf("Error aggregating: %v", err)
+	}
+	w1, w2 := 1/0.05, 1/0.3
+	if got, want := *weighted[2].PointForecast, (0.4*w1+0.8*w2)/(w1+w2); math.Abs(got-want) > 1e-9 {
+		t.Errorf("Expected the weighted mean %v, got %v", want, got)
+	}
+
+	// a multiple choice Brier score of 0.5 weighs as a binary one of 0.25
+	scores := []UserScores{{ScoreMetrics: ScoreMetrics{BrierScore: 0.5}, UserID: 1}}
+	if got, want := BrierWeights(scores, QuestionTypeMultipleChoice)[1], 1/0.3; math.Abs(got-want) > 1e-9 {
+		t.Errorf("Expected the halved multiple choice weight %v, got %v", want, got)
+	}
+}
+
+func TestAggregatePoints_MultipleChoice(t *testing.T) {
+	start := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	points := []*ForecastPoint{
+		{UserID: 1, Probabilities: Probabilities{0.6, 0.3, 0.1}, CreatedAt: start},
+		{UserID: 2, Probabilities: Probabilities{0.2, 0.5, 0.3}, CreatedAt: start.Add(time.Hour)},
+	}
+
+	for _, method := range []AggregateMethod{AggregateMean, AggregateMedian, AggregateGeoMeanOdds, AggregateExtremized} {
+		opts := AggregateOptions{Method: method}
+		if err := opts.Validate(); err != nil {
+			t.Fatalf("Error validating options: %v", err)
+		}
+		series, err := AggregatePoints(points, 3, nil, opts)
+		if err != nil {
+			t.Fatalf("%s: error aggregating: %v", method, err)
+		}
+		last := series[len(series)-1]
+		if last.PointForecast != nil {
+			t.Errorf("%s: expected no binary point forecast", method)
+		}
+		if err := ValidateProbabilities(last.Probabilities, 3); err != nil {
+			t.Errorf("%s: expected valid probabilities, got %v: %v", method, last.Probabilities, err)
+		}
+	}
+
+	if _, err := AggregatePoints(points, 2, nil, AggregateOptions{Method: AggregateMean}); err == nil {
+		t.Error("Expected an error for points with the wrong number of options")
+	}
+}
+
+func TestAggregatePoints_Extremes(t *testing.T) {
+	points := []*ForecastPoint{{UserID: 1, PointForecast: 0.999}}
+	series, err := AggregatePoints(points, 0, nil, AggregateOptions{Method: AggregateExtremized, Extremize: 10})
+	if err != nil {
+		t.Fatalf("Error aggregating: %v", err)
+	}
+	if err := ValidateProbability(*series[0].PointForecast); err != nil {
+		t.Errorf("Expected a scorable aggregate, got %v", *series[0].PointForecast)
+	}
+}
+
+func TestAggregateOptions_Validate(t *testing.T) {
+	opts := AggregateOptions{}
+	if err := opts.Validate(); err != nil || opts.Method != DefaultAggregateMethod {
+		t.Errorf("Expected the default method, got %q: %v", opts.Method, err)
+	}
+	opts = AggregateOptions{Method: AggregateExtremized}
+	if err := opts.Validate(); err != nil || opts.Extremize != DefaultExtremizingFactor {
+		t.Errorf("Expected the default extremizing factor, got %v: %v", opts.Extremize, err)
+	}
+
+	for _, invalid := range []AggregateOptions{
+		{Method: "mode"},
+		{Method: AggregateMean, Extremize: 2},
+		{Method: AggregateExtremized, Extremize: -1},
+		{Method: AggregateExtremized, Extremize: math.NaN()},
+	} {
+		if err := invalid.Validate(); err == nil {
+			t.Errorf("Expected an error for %+v", invalid)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Scope limits what an API key can do. A key without scopes can do anything
+// its user's role allows.
+type Scope string
+
+const (
+	ScopeForecastsRead  Scope = "forecasts:read"
+	ScopeForecastsWrite Scope = "forecasts:write"
+	ScopePointsWrite    Scope = "points:write"
+	ScopeScoresWrite    Scope = "scores:write"
+	ScopeCommentsWrite  Scope = "comments:write"
+	ScopeAdmin          Scope = "admin"
+)
+
+// Scopes is the scope list of an API key, stored as a JSON array
+type Scopes []Scope
+
+func (s Scopes) Value() (driver.Value, error) {
+	if s == nil {
+		return nil, nil
+	}
+	return json.Marshal(s)
+}
+
+func (s *Scopes) Scan(src any) error {
+	return scanJSON(src, s)
+}
+
+// Validate rejects unknown scopes
+func (s Scopes) Validate() error {
+	for _, scope := range s {
+		switch scope {
+		case ScopeForecastsRead, ScopeForecastsWrite, ScopePointsWrite, ScopeScoresWrite, ScopeCommentsWrite, ScopeAdmin:
+		default:
+			return fmt.Errorf("unknown scope %q", scope)
+		}
+	}
+	return nil
+}
+
+// APIKey is a long-lived credential for bots and agents. Only the hash of the
+// key is stored; the key itself is shown once, when it is created.
+type APIKey struct {
+	ID         int64      `json:"id"`
+	UserID     int64      `json:"user_id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	KeyHash    string     `json:"-"`
+	Scopes     Scopes     `json:"scopes,omitempty"`
+	CreatedAt  time.Time  `json:"created"`
+	LastUsedAt *time.Time `json:"last_used,omitempty"`
+	RevokedAt  *time.Time `json:"revoked,omitempty"`
+}
@@ -1,6 +1,14 @@
 package models
 
-import "time"
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"sort"
+	"strings"
+	"time"
+)
 
 // CalibrationBucket represents a single probability bucket with calibration data
 type CalibrationBucket struct {
@@ -9,6 +17,10 @@ type CalibrationBucket struct {
 	PredictionCount int     `json:"prediction_count"`
 	AvgPrediction   float64 `json:"avg_prediction"`
 	ActualRate      float64 `json:"actual_rate"`
+	// ActualRateLow and ActualRateHigh bound the confidence interval of the
+	// actual rate, which is wide for sparse buckets
+	ActualRateLow  *float64 `json:"actual_rate_low,omitempty"`
+	ActualRateHigh *float64 `json:"actual_rate_high,omitempty"`
 }
 
 // CalibrationData contains overall calibration data
@@ -28,8 +40,399 @@ type UserCalibrationData struct {
 
 // CalibrationFilters contains filter options for calibration queries
 type CalibrationFilters struct {
-	UserID    *int64
-	Category  *string
-	StartDate *time.Time
-	EndDate   *time.Time
+	UserID *int64
+	// CategoryIDs keeps forecasts in one of the categories, when not nil
+	CategoryIDs []int64
+	StartDate   *time.Time
+	EndDate     *time.Time
+	// Viewer keeps the forecasts the user, or an anonymous caller when 0, can
+	// view. Nil does not filter.
+	Viewer *int64
+	// Bins is how many equal-width buckets the calibration queries group
+	// points in, DefaultCalibrationBins when 0
+	Bins int
+}
+
+// CalibrationPoint is a point on a resolved binary forecast with its outcome
+type CalibrationPoint struct {
+	UserID        int64
+	ForecastID    int64
+	PointForecast float64
+	// Outcome is 1 when the forecast resolved yes and 0 when it resolved no
+	Outcome     float64
+	CreatedAt   time.Time
+	ClosingDate *time.Time
+	ResolvedAt  time.Time
+}
+
+// CalibrationBinning is how points are grouped into buckets
+type CalibrationBinning string
+
+const (
+	// CalibrationBinningEqual splits 0 to 1 into buckets of the same width
+	CalibrationBinningEqual CalibrationBinning = "equal"
+	// CalibrationBinningQuantile puts about as many points in each bucket
+	CalibrationBinningQuantile CalibrationBinning = "quantile"
+	// CalibrationBinningCustom uses the given bucket edges
+	CalibrationBinningCustom CalibrationBinning = "custom"
+)
+
+// CalibrationPointSelection is which points count towards the calibration
+type CalibrationPointSelection string
+
+const (
+	CalibrationPointsAll CalibrationPointSelection = "all"
+	// CalibrationPointsLast keeps each user's last point on each forecast
+	CalibrationPointsLast CalibrationPointSelection = "last"
+	// CalibrationPointsTimeWeighted weighs each point by the share of the
+	// scoring window it was held for, as time-weighted scores do, so each
+	// user's forecast on a question counts once
+	CalibrationPointsTimeWeighted CalibrationPointSelection = "time_weighted"
+)
+
+// CalibrationInterval is how the confidence interval of a bucket's actual
+// rate is computed
+type CalibrationInterval string
+
+const (
+	CalibrationIntervalWilson    CalibrationInterval = "wilson"
+	CalibrationIntervalBootstrap CalibrationInterval = "bootstrap"
+	CalibrationIntervalNone      CalibrationInterval = "none"
+)
+
+const (
+	DefaultCalibrationBins       = 10
+	MaxCalibrationBins           = 100
+	DefaultCalibrationConfidence = 0.95
+	// bootstrapResamples is how many times the bootstrap resamples a bucket
+	bootstrapResamples = 1000
+)
+
+// CalibrationOptions chooses how the calibration buckets are built
+type CalibrationOptions struct {
+	Binning CalibrationBinning
+	// Bins is the number of equal or quantile buckets
+	Bins int
+	// Edges are the bucket boundaries of custom binning, from 0 to 1
+	Edges      []float64
+	Points     CalibrationPointSelection
+	Interval   CalibrationInterval
+	Confidence float64
+}
+
+// Validate checks the options and fills in the defaults: ten equal buckets of
+// every point, with 95% Wilson intervals. Edges alone choose custom binning.
+func (o *CalibrationOptions) Validate() error {
+	if o.Binning == "" {
+		o.Binning = CalibrationBinningEqual
+		if len(o.Edges) > 0 {
+			o.Binning = CalibrationBinningCustom
+		}
+	}
+	switch o.Binning {
+	case CalibrationBinningEqual, CalibrationBinningQuantile:
+		if len(o.Edges) > 0 {
+			return errors.New("only custom binning takes edges")
+		}
+		if o.Bins == 0 {
+			o.Bins = DefaultCalibrationBins
+		}
+		if o.Bins < 1 || o.Bins > MaxCalibrationBins {
+			return fmt.Errorf("bins must be between 1 and %d", MaxCalibrationBins)
+		}
+	case CalibrationBinningCustom:
+		if o.Bins != 0 {
+			return errors.New("custom binning takes edges instead of bins")
+		}
+		if len(o.Edges) < 2 || o.Edges[0] != 0 || o.Edges[len(o.Edges)-1] != 1 {
+			return errors.New("custom edges must run from 0 to 1")
+		}
+		for i := 1; i < len(o.Edges); i++ {
+			if !(o.Edges[i] > o.Edges[i-1]) {
+				return errors.New("custom edges must be increasing")
+			}
+		}
+		if len(o.Edges)-1 > MaxCalibrationBins {
+			return fmt.Errorf("at most %d buckets", MaxCalibrationBins)
+		}
+	default:
+		return fmt.Errorf("unknown binning %q", o.Binning)
+	}
+
+	switch o.Points {
+	case "":
+		o.Points = CalibrationPointsAll
+	case CalibrationPointsAll, CalibrationPointsLast, CalibrationPointsTimeWeighted:
+	default:
+		return fmt.Errorf("unknown point selection %q", o.Points)
+	}
+
+	switch o.Interval {
+	case "":
+		o.Interval = CalibrationIntervalWilson
+	case CalibrationIntervalWilson, CalibrationIntervalBootstrap, CalibrationIntervalNone:
+	default:
+		return fmt.Errorf("unknown interval %q", o.Interval)
+	}
+	if o.Confidence == 0 {
+		o.Confidence = DefaultCalibrationConfidence
+	}
+	if !(o.Confidence > 0 && o.Confidence < 1) {
+		return errors.New("confidence must be between 0 and 1")
+	}
+	return nil
+}
+
+// Key names the options in cache keys
+func (o CalibrationOptions) Key() string {
+	edges := make([]string, len(o.Edges))
+	for i, e := range o.Edges {
+		edges[i] = fmt.Sprint(e)
+	}
+	return fmt.Sprintf("%s:%d:%s:%s:%s:%g", o.Binning, o.Bins, strings.Join(edges, ","), o.Points, o.Interval, o.Confidence)
+}
+
+// Grouped reports whether the calibration queries can group the points
+// themselves: equal buckets of every point, without resampling
+func (o CalibrationOptions) Grouped() bool {
+	return o.Binning == CalibrationBinningEqual && o.Points == CalibrationPointsAll && o.Interval != CalibrationIntervalBootstrap
+}
+
+// weightedPoint is a point with its weight in the calibration
+type weightedPoint struct {
+	CalibrationPoint
+	weight float64
+}
+
+// selectPoints keeps and weighs the points the options count
+func selectPoints(points []CalibrationPoint, selection CalibrationPointSelection) []weightedPoint {
+	if selection == CalibrationPointsAll || selection == "" {
+		selected := make([]weightedPoint, len(points))
+		for i, p := range points {
+			selected[i] = weightedPoint{p, 1}
+		}
+		return selected
+	}
+
+	type key struct{ userID, forecastID int64 }
+	groups := make(map[key][]CalibrationPoint)
+	var order []key
+	for _, p := range points {
+		k := key{p.UserID, p.ForecastID}
+		if _, ok := groups[k]; !ok {
+			order = append(order, k)
+		}
+		groups[k] = append(groups[k], p)
+	}
+
+	var selected []weightedPoint
+	for _, k := range order {
+		group := groups[k]
+		sort.SliceStable(group, func(i, j int) bool {
+			return group[i].CreatedAt.Before(group[j].CreatedAt)
+		})
+		if selection == CalibrationPointsLast {
+			selected = append(selected, weightedPoint{group[len(group)-1], 1})
+			continue
+		}
+		createdAts := make([]time.Time, len(group))
+		for i, p := range group {
+			createdAts[i] = p.CreatedAt
+		}
+		resolvedAt := group[0].ResolvedAt
+		weights := timeWeights(createdAts, scoringCloseDate(group[0].ClosingDate, &resolvedAt))
+		for i, p := range group {
+			selected = append(selected, weightedPoint{p, weights[i]})
+		}
+	}
+	return selected
+}
+
+// bucketEdges returns the boundaries of the buckets the options split the
+// points in
+func bucketEdges(points []weightedPoint, opts CalibrationOptions) []float64 {
+	switch opts.Binning {
+	case CalibrationBinningCustom:
+		return opts.Edges
+	case CalibrationBinningQuantile:
+		values := make([]float64, len(points))
+		for i, p := range points {
+			values[i] = p.PointForecast
+		}
+		sort.Float64s(values)
+		edges := []float64{0}
+		for k := 1; k < opts.Bins && len(values) > 0; k++ {
+			// points equal to an edge fall in the bucket it starts
+			if edge := values[k*len(values)/opts.Bins]; edge > edges[len(edges)-1] {
+				edges = append(edges, edge)
+			}
+		}
+		return append(edges, 1)
+	}
+	edges := make([]float64, opts.Bins+1)
+	for i := range edges {
+		edges[i] = float64(i) / float64(opts.Bins)
+	}
+	return edges
+}
+
+// bucketIndex returns the bucket the forecast falls in. Equal buckets are
+// found as the calibration queries find them, by flooring p * bins.
+func bucketIndex(p float64, edges []float64, opts CalibrationOptions) int {
+	last := len(edges) - 2
+	if opts.Binning == CalibrationBinningEqual {
+		return max(0, min(int(math.Floor(p*float64(opts.Bins))), last))
+	}
+	i := sort.Search(len(edges), func(i int) bool { return edges[i] > p }) - 1
+	return max(0, min(i, last))
+}
+
+// calibrationBuckets groups the points, skipping empty buckets, and returns the
+// weight of each bucket
+func calibrationBuckets(points []weightedPoint, opts CalibrationOptions) ([]CalibrationBucket, []float64) {
+	edges := bucketEdges(points, opts)
+	grouped := make([][]weightedPoint, len(edges)-1)
+	for _, p := range points {
+		i := bucketIndex(p.PointForecast, edges, opts)
+		grouped[i] = append(grouped[i], p)
+	}
+
+	buckets := []CalibrationBucket{}
+	var weights []float64
+	for i, group := range grouped {
+		var weight, forecast, outcome float64
+		for _, p := range group {
+			weight += p.weight
+			forecast += p.weight * p.PointForecast
+			outcome += p.weight * p.Outcome
+		}
+		if len(group) == 0 || weight <= 0 {
+			continue
+		}
+		bucket := CalibrationBucket{
+			BucketStart:     edges[i],
+			BucketEnd:       edges[i+1],
+			PredictionCount: len(group),
+			AvgPrediction:   forecast / weight,
+			ActualRate:      outcome / weight,
+		}
+		switch opts.Interval {
+		case CalibrationIntervalWilson:
+			bucket.ActualRateLow, bucket.ActualRateHigh = wilsonInterval(bucket.ActualRate, effectiveCount(group), opts.Confidence)
+		case CalibrationIntervalBootstrap:
+			bucket.ActualRateLow, bucket.ActualRateHigh = bootstrapInterval(group, opts.Confidence, uint64(i))
+		}
+		buckets = append(buckets, bucket)
+		weights = append(weights, weight)
+	}
+	return buckets, weights
+}
+
+// Calibrate buckets the points as the options say
+func Calibrate(points []CalibrationPoint, opts CalibrationOptions) CalibrationData {
+	selected := selectPoints(points, opts.Points)
+	buckets, _ := calibrationBuckets(selected, opts)
+	return CalibrationData{
+		Buckets:          buckets,
+		TotalPredictions: len(selected),
+		TotalForecasts:   countForecasts(selected),
+	}
+}
+
+// CalibrateByUsers buckets each user's points as the options say, ordered by
+// user
+func CalibrateByUsers(points []CalibrationPoint, opts CalibrationOptions) []UserCalibrationData {
+	byUser := make(map[int64][]CalibrationPoint)
+	for _, p := range points {
+		byUser[p.UserID] = append(byUser[p.UserID], p)
+	}
+
+	result := make([]UserCalibrationData, 0, len(byUser))
+	for userID, userPoints := range byUser {
+		data := Calibrate(userPoints, opts)
+		result = append(result, UserCalibrationData{
+			UserID:           userID,
+			Buckets:          data.Buckets,
+			TotalPredictions: data.TotalPredictions,
+			TotalForecasts:   data.TotalForecasts,
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].UserID < result[j].UserID
+	})
+	return result
+}
+
+// SetWilsonIntervals adds the options' Wilson intervals to buckets grouped by
+// the calibration queries, where every point weighs the same
+func SetWilsonIntervals(buckets []CalibrationBucket, opts CalibrationOptions) {
+	if opts.Interval != CalibrationIntervalWilson {
+		return
+	}
+	for i := range buckets {
+		b := &buckets[i]
+		b.ActualRateLow, b.ActualRateHigh = wilsonInterval(b.ActualRate, float64(b.PredictionCount), opts.Confidence)
+	}
+}
+
+func countForecasts(points []weightedPoint) int {
+	forecasts := make(map[int64]bool)
+	for _, p := range points {
+		forecasts[p.ForecastID] = true
+	}
+	return len(forecasts)
+}
+
+// effectiveCount is Kish's effective sample size of weighted points, their
+// number when they weigh the same
+func effectiveCount(points []weightedPoint) float64 {
+	var sum, squares float64
+	for _, p := range points {
+		sum += p.weight
+		squares += p.weight * p.weight
+	}
+	if squares == 0 {
+		return 0
+	}
+	return sum * sum / squares
+}
+
+// wilsonInterval is the Wilson score interval of a rate observed over n trials
+func wilsonInterval(rate float64, n float64, confidence float64) (*float64, *float64) {
+	if n <= 0 {
+		return nil, nil
+	}
+	z := math.Sqrt2 * math.Erfinv(confidence)
+	denominator := 1 + z*z/n
+	center := (rate + z*z/(2*n)) / denominator
+	half := z / denominator * math.Sqrt(rate*(1-rate)/n+z*z/(4*n*n))
+	low, high := math.Max(0, center-half), math.Min(1, center+half)
+	return &low, &high
+}
+
+// bootstrapInterval is the percentile interval of the rate of points drawn
+// with replacement from the bucket. The seed keeps intervals the same between
+// requests.
+func bootstrapInterval(points []weightedPoint, confidence float64, seed uint64) (*float64, *float64) {
+	rng := rand.New(rand.NewPCG(uint64(len(points)), seed))
+	rates := make([]float64, 0, bootstrapResamples)
+	for range bootstrapResamples {
+		var weight, outcome float64
+		for range points {
+			p := points[rng.IntN(len(points))]
+			weight += p.weight
+			outcome += p.weight * p.Outcome
+		}
+		if weight > 0 {
+			rates = append(rates, outcome/weight)
+		}
+	}
+	if len(rates) == 0 {
+		return nil, nil
+	}
+	sort.Float64s(rates)
+	alpha := (1 - confidence) / 2
+	low := rates[int(alpha*float64(len(rates)-1))]
+	high := rates[int(math.Ceil((1-alpha)*float64(len(rates)-1)))]
+	return &low, &high
 }
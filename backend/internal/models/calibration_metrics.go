@@ -0,0 +1,104 @@
+package models
+
+import "math"
+
+// logisticMaxIterations bounds the Newton steps of the calibration fit, which
+// diverges when the forecasts separate the outcomes perfectly
+const logisticMaxIterations = 50
+
+// CalibrationMetrics summarizes how well point forecasts match their outcomes
+type CalibrationMetrics struct {
+	// BrierScore is the mean squared error of the points
+	BrierScore float64 `json:"brier_score"`
+	// Reliability, Resolution and Uncertainty are the Murphy decomposition of
+	// the Brier score over the calibration buckets: Brier is about
+	// Reliability - Resolution + Uncertainty, the difference being the spread
+	// of the forecasts within each bucket
+	Reliability float64 `json:"reliability"`
+	Resolution  float64 `json:"resolution"`
+	Uncertainty float64 `json:"uncertainty"`
+	// ExpectedCalibrationError is the gap between the average forecast and
+	// the outcome rate of each bucket, weighted by its points
+	ExpectedCalibrationError float64 `json:"expected_calibration_error"`
+	// CalibrationSlope and CalibrationIntercept fit the log-odds of the outcome
+	// to the log-odds of the forecast by logistic regression; a calibrated
+	// forecaster has a slope of 1 and an intercept of 0. They are missing
+	// when the points cannot be fit, such as when every outcome is the same.
+	CalibrationSlope     *float64 `json:"calibration_slope"`
+	CalibrationIntercept *float64 `json:"calibration_intercept"`
+	TotalPredictions     int      `json:"total_predictions"`
+	TotalForecasts       int      `json:"total_forecasts"`
+}
+
+// CalcCalibrationMetrics computes the calibration metrics of the points, counted
+// and bucketed as the options say
+func CalcCalibrationMetrics(points []CalibrationPoint, opts CalibrationOptions) CalibrationMetrics {
+	var metrics CalibrationMetrics
+	selected := selectPoints(points, opts.Points)
+	var total, baseRate float64
+	for _, p := range selected {
+		total += p.weight
+	}
+	if total <= 0 {
+		return metrics
+	}
+
+	for _, p := range selected {
+		metrics.BrierScore += p.weight * (p.PointForecast - p.Outcome) * (p.PointForecast - p.Outcome)
+		baseRate += p.weight * p.Outcome
+	}
+	metrics.BrierScore /= total
+	baseRate /= total
+	metrics.TotalPredictions = len(selected)
+	metrics.TotalForecasts = countForecasts(selected)
+	metrics.Uncertainty = baseRate * (1 - baseRate)
+
+	buckets, weights := calibrationBuckets(selected, opts)
+	for i, b := range buckets {
+		share := weights[i] / total
+		gap := b.AvgPrediction - b.ActualRate
+		metrics.Reliability += share * gap * gap
+		metrics.Resolution += share * (b.ActualRate - baseRate) * (b.ActualRate - baseRate)
+		metrics.ExpectedCalibrationError += share * math.Abs(gap)
+	}
+
+	if intercept, slope, ok := fitCalibration(selected); ok {
+		metrics.CalibrationIntercept, metrics.CalibrationSlope = &intercept, &slope
+	}
+	return metrics
+}
+
+// fitCalibration fits P(outcome) = 1 / (1 + exp(-(a + b*logit(p)))) by Newton's
+// method, reporting whether it converged
+func fitCalibration(points []weightedPoint) (a float64, b float64, ok bool) {
+	xs := make([]float64, len(points))
+	for i, p := range points {
+		q := clampProbability(p.PointForecast)
+		xs[i] = math.Log(q / (1 - q))
+	}
+
+	for iteration := 0; iteration < logisticMaxIterations; iteration++ {
+		// gradient and Hessian of the log-likelihood
+		var ga, gb, haa, hab, hbb float64
+		for i, p := range points {
+			predicted := 1 / (1 + math.Exp(-(a + b*xs[i])))
+			w := p.weight * predicted * (1 - predicted)
+			ga += p.weight * (p.Outcome - predicted)
+			gb += p.weight * (p.Outcome - predicted) * xs[i]
+			haa += w
+			hab += w * xs[i]
+			hbb += w * xs[i] * xs[i]
+		}
+		det := haa*hbb - hab*hab
+		if det <= 1e-12 || math.IsNaN(det) {
+			return 0, 0, false
+		}
+		da := (hbb*ga - hab*gb) / det
+		db := (haa*gb - hab*ga) / det
+		a, b = a+da, b+db
+		if math.Abs(da) < 1e-10 && math.Abs(db) < 1e-10 {
+			return a, b, true
+		}
+	}
+	return 0, 0, false
+}
@@ -0,0 +1,76 @@
+package models
+
+import (
+	"math"
+	"testing"
+)
+
+// calibrationPoints makes four points at each forecast, one or three of which
+// resolved yes
+func calibrationPoints(low float64, high float64) []CalibrationPoint {
+	var points []CalibrationPoint
+	for i, outcome := range []float64{1, 0, 0, 0} {
+		points = append(points, CalibrationPoint{ForecastID: int64(i), PointForecast: low, Outcome: outcome})
+	}
+	for i, outcome := range []float64{1, 1, 1, 0} {
+		points = append(points, CalibrationPoint{ForecastID: int64(i), PointForecast: high, Outcome: outcome})
+	}
+	return points
+}
+
+// defaultCalibrationOptions are the options of a request without any
+func defaultCalibrationOptions(t *testing.T) CalibrationOptions {
+	t.Helper()
+	var opts CalibrationOptions
+	if err := opts.Validate(); err != nil {
+		t.Fatalf("Failed to validate the default options: %v", err)
+	}
+	return opts
+}
+
+func TestCalcCalibrationMetrics(t *testing.T) {
+	opts := defaultCalibrationOptions(t)
+	for _, c := range []struct {
+		name                    string
+		points                  []CalibrationPoint
+		brier, reliability, ece float64
+		slope                   float64
+	}{
+		{"calibrated", calibrationPoints(0.25, 0.75), 0.1875, 0, 0, 1},
+		{"overconfident", calibrationPoints(0.1, 0.9), 0.21, 0.0225, 0.15, 0.5},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			m := CalcCalibrationMetrics(c.points, opts)
+			for name, got := range map[string][2]float64{
+				"brier score":       {m.BrierScore, c.brier},
+				"reliability":       {m.Reliability, c.reliability},
+				"resolution":        {m.Resolution, 0.0625},
+				"uncertainty":       {m.Uncertainty, 0.25},
+				"calibration error": {m.ExpectedCalibrationError, c.ece},
+			} {
+				if math.Abs(got[0]-got[1]) > 1e-9 {
+					t.Errorf("Expected %s %v, got %v", name, got[1], got[0])
+				}
+			}
+			// every point of a bucket has the same forecast, so the
+			// decomposition is exact
+			if sum := m.Reliability - m.Resolution + m.Uncertainty; math.Abs(sum-m.BrierScore) > 1e-9 {
+				t.Errorf("Expected the decomposition to sum to the Brier score %v, got %v", m.BrierScore, sum)
+			}
+			if m.CalibrationSlope == nil || math.Abs(*m.CalibrationSlope-c.slope) > 1e-6 || math.Abs(*m.CalibrationIntercept) > 1e-6 {
+				t.Errorf("Expected a slope of %v and no intercept, got %v and %v", c.slope, m.CalibrationSlope, m.CalibrationIntercept)
+			}
+			if m.TotalPredictions != 8 || m.TotalForecasts != 4 {
+				t.Errorf("Expected 8 predictions on 4 forecasts, got %d and %d", m.TotalPredictions, m.TotalForecasts)
+			}
+		})
+	}
+
+	same := []CalibrationPoint{{PointForecast: 0.3, Outcome: 1}, {PointForecast: 0.8, Outcome: 1}}
+	if m := CalcCalibrationMetrics(same, opts); m.CalibrationSlope != nil || m.Uncertainty != 0 {
+		t.Errorf("Expected no fit when every outcome is the same, got %+v", m)
+	}
+	if m := CalcCalibrationMetrics(nil, opts); m.TotalPredictions != 0 || m.CalibrationSlope != nil {
+		t.Errorf("Expected empty metrics, got %+v", m)
+	}
+}
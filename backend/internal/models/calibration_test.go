@@ -0,0 +1,130 @@
+package models
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestCalibrationOptionsValidate(t *testing.T) {
+	opts := CalibrationOptions{Edges: []float64{0, 0.5, 1}}
+	if err := opts.Validate(); err != nil || opts.Binning != CalibrationBinningCustom || opts.Interval != CalibrationIntervalWilson {
+		t.Errorf("Expected edges to choose custom binning, got %+v and %v", opts, err)
+	}
+
+	for name, opts := range map[string]CalibrationOptions{
+		"too many bins":        {Bins: MaxCalibrationBins + 1},
+		"negative bins":        {Bins: -1},
+		"edges of equal bins":  {Binning: CalibrationBinningEqual, Edges: []float64{0, 1}},
+		"bins of custom edges": {Bins: 2, Edges: []float64{0, 1}},
+		"edges not from 0":     {Edges: []float64{0.1, 1}},
+		"decreasing edges":     {Edges: []float64{0, 0.6, 0.4, 1}},
+		"unknown binning":      {Binning: "log"},
+		"unknown points":       {Points: "first"},
+		"unknown interval":     {Interval: "jeffreys"},
+		"confidence of 1":      {Confidence: 1},
+	} {
+		if err := opts.Validate(); err == nil {
+			t.Errorf("Expected %s to be rejected", name)
+		}
+	}
+}
+
+func TestCalibrate(t *testing.T) {
+	opts := defaultCalibrationOptions(t)
+	opts.Bins = 5
+	// 0.7 is floored into the bucket starting at 0.6, as the queries do
+	data := Calibrate([]CalibrationPoint{
+		{ForecastID: 1, PointForecast: 0.7, Outcome: 1},
+		{ForecastID: 2, PointForecast: 1, Outcome: 1},
+		{ForecastID: 2, PointForecast: 0.1, Outcome: 0},
+	}, opts)
+	if len(data.Buckets) != 3 || data.TotalPredictions != 3 || data.TotalForecasts != 2 {
+		t.Fatalf("Expected 3 points on 2 forecasts in 3 buckets, got %+v", data)
+	}
+	for i, start := range []float64{0, 0.6, 0.8} {
+		if b := data.Buckets[i]; math.Abs(b.BucketStart-start) > 1e-9 || math.Abs(b.BucketEnd-start-0.2) > 1e-9 {
+			t.Errorf("Expected bucket %d to run from %v to %v, got %+v", i, start, start+0.2, b)
+		}
+	}
+
+	opts = defaultCalibrationOptions(t)
+	opts.Binning, opts.Bins = CalibrationBinningQuantile, 2
+	data = Calibrate(calibrationPoints(0.2, 0.6), opts)
+	if len(data.Buckets) != 2 || data.Buckets[0].PredictionCount != 4 || data.Buckets[1].BucketStart != 0.6 {
+		t.Errorf("Expected the points split in two at 0.6, got %+v", data.Buckets)
+	}
+
+	opts = CalibrationOptions{Edges: []float64{0, 0.5, 0.9, 1}}
+	if err := opts.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	data = Calibrate(calibrationPoints(0.25, 0.75), opts)
+	if len(data.Buckets) != 2 || data.Buckets[1].BucketStart != 0.5 || data.Buckets[1].BucketEnd != 0.9 || data.Buckets[1].ActualRate != 0.75 {
+		t.Errorf("Expected the custom buckets, got %+v", data.Buckets)
+	}
+}
+
+func TestCalibratePointSelection(t *testing.T) {
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	resolved := created.Add(4 * time.Hour)
+	// held at 0.1 for an hour, then at 0.9 for three
+	points := []CalibrationPoint{
+		{UserID: 1, ForecastID: 1, PointForecast: 0.1, Outcome: 1, CreatedAt: created, ResolvedAt: resolved},
+		{UserID: 1, ForecastID: 1, PointForecast: 0.9, Outcome: 1, CreatedAt: created.Add(time.Hour), ResolvedAt: resolved},
+		{UserID: 2, ForecastID: 1, PointForecast: 0.5, Outcome: 1, CreatedAt: created, ResolvedAt: resolved},
+	}
+
+	opts := defaultCalibrationOptions(t)
+	opts.Points = CalibrationPointsLast
+	data := Calibrate(points, opts)
+	if data.TotalPredictions != 2 || len(data.Buckets) != 2 || data.Buckets[1].BucketStart != 0.9 {
+		t.Errorf("Expected the last point of each user, got %+v", data)
+	}
+
+	opts.Points = CalibrationPointsTimeWeighted
+	m := CalcCalibrationMetrics(points, opts)
+	// the user's points weigh 1/4 and 3/4, the other user's 1
+	want := (0.25*0.81 + 0.75*0.01 + 0.25) / 2
+	if math.Abs(m.BrierScore-want) > 1e-9 || m.TotalPredictions != 3 {
+		t.Errorf("Expected a time-weighted Brier score of %v, got %+v", want, m)
+	}
+
+	byUsers := CalibrateByUsers(points, opts)
+	if len(byUsers) != 2 || byUsers[0].UserID != 1 || byUsers[0].TotalPredictions != 2 || byUsers[1].TotalPredictions != 1 {
+		t.Errorf("Expected each user's points, got %+v", byUsers)
+	}
+}
+
+func TestCalibrationIntervals(t *testing.T) {
+	opts := defaultCalibrationOptions(t)
+	data := Calibrate(calibrationPoints(0.25, 0.75), opts)
+	// the Wilson interval of 1 in 4 at 95%
+	b := data.Buckets[0]
+	if b.ActualRateLow == nil || math.Abs(*b.ActualRateLow-0.0456) > 1e-3 || math.Abs(*b.ActualRateHigh-0.6994) > 1e-3 {
+		t.Errorf("Expected a Wilson interval of about 0.046 to 0.699, got %v to %v", b.ActualRateLow, b.ActualRateHigh)
+	}
+
+	grouped := []CalibrationBucket{{PredictionCount: 4, ActualRate: 0.25}}
+	SetWilsonIntervals(grouped, opts)
+	if *grouped[0].ActualRateLow != *b.ActualRateLow || *grouped[0].ActualRateHigh != *b.ActualRateHigh {
+		t.Errorf("Expected the grouped interval to match, got %+v", grouped[0])
+	}
+
+	opts.Interval = CalibrationIntervalBootstrap
+	first := Calibrate(calibrationPoints(0.25, 0.75), opts)
+	second := Calibrate(calibrationPoints(0.25, 0.75), opts)
+	for i, b := range first.Buckets {
+		if b.ActualRateLow == nil || *b.ActualRateLow > b.ActualRate || *b.ActualRateHigh < b.ActualRate {
+			t.Errorf("Expected the bootstrap interval to hold the rate, got %+v", b)
+		}
+		if *b.ActualRateLow != *second.Buckets[i].ActualRateLow || *b.ActualRateHigh != *second.Buckets[i].ActualRateHigh {
+			t.Errorf("Expected the same bootstrap interval on every call")
+		}
+	}
+
+	opts.Interval = CalibrationIntervalNone
+	if b := Calibrate(calibrationPoints(0.25, 0.75), opts).Buckets[0]; b.ActualRateLow != nil {
+		t.Errorf("Expected no interval, got %+v", b)
+	}
+}
@@ -0,0 +1,87 @@
+package models
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"time"
+)
+
+// PersonalCategory is the category, with its subcategories, of forecasts kept
+// out of the stale forecasts list. Forecasts tagged with it are too.
+const PersonalCategory = "personal"
+
+var (
+	ErrCategoryNotFound     = errors.New("category not found")
+	ErrCategoryNameRequired = errors.New("category name is required")
+	ErrCategoryExists       = errors.New("a category with this name already exists under the same parent")
+	ErrCategoryCycle        = errors.New("a category cannot be moved under itself")
+	ErrCategoryInUse        = errors.New("category has forecasts or subcategories")
+)
+
+// Category is a node in the tree of forecast categories
+type Category struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	ParentID  *int64    `json:"parent_id,omitempty"`
+	CreatedAt time.Time `json:"created"`
+	// Children are only filled in trees, see CategoryTree
+	Children []*Category `json:"children,omitempty"`
+}
+
+// Tag is a forecast tag and how many forecasts have it
+type Tag struct {
+	Name      string `json:"name"`
+	Forecasts int    `json:"forecasts"`
+}
+
+// CategoryTree arranges categories under their parents, returning the roots.
+// Siblings keep the order of the slice.
+func CategoryTree(categories []*Category) []*Category {
+	byID := make(map[int64]*Category, len(categories))
+	for _, c := range categories {
+		node := *c
+		node.Children = nil
+		byID[c.ID] = &node
+	}
+
+	roots := []*Category{}
+	for _, c := range categories {
+		node := byID[c.ID]
+		if c.ParentID != nil {
+			if parent, ok := byID[*c.ParentID]; ok {
+				parent.Children = append(parent.Children, node)
+				continue
+			}
+		}
+		roots = append(roots, node)
+	}
+	return roots
+}
+
+// CategoryDescendants returns the ids of the category and of every category
+// below it
+func CategoryDescendants(categories []*Category, id int64) []int64 {
+	ids := []int64{id}
+	for i := 0; i < len(ids); i++ {
+		for _, c := range categories {
+			if c.ParentID != nil && *c.ParentID == ids[i] && !slices.Contains(ids, c.ID) {
+				ids = append(ids, c.ID)
+			}
+		}
+	}
+	return ids
+}
+
+// NormalizeTags lowercases and trims tags, dropping empty and repeated ones
+func NormalizeTags(tags []string) []string {
+	normalized := []string{}
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.Join(strings.Fields(tag), " "))
+		if tag != "" && !slices.Contains(normalized, tag) {
+			normalized = append(normalized, tag)
+		}
+	}
+	slices.Sort(normalized)
+	return normalized
+}
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestCategoryTree(t *testing.T) {
+	one, two := int64(1), int64(2)
+	categories := []*Category{
+		{ID: 3, Name: "Elections", ParentID: &two},
+		{ID: 1, Name: "Politics"},
+		{ID: 2, Name: "US", ParentID: &one},
+		{ID: 4, Name: "Sports"},
+	}
+
+	roots := CategoryTree(categories)
+	if len(roots) != 2 || roots[0].ID != 1 || roots[1].ID != 4 {
+		t.Fatalf("Expected Politics and Sports at the top, got %+v", roots)
+	}
+	us := roots[0].Children
+	if len(us) != 1 || us[0].ID != 2 || len(us[0].Children) != 1 || us[0].Children[0].ID != 3 {
+		t.Errorf("Expected Elections under US under Politics, got %+v", us)
+	}
+	if categories[1].Children != nil {
+		t.Errorf("Expected the categories to be left alone")
+	}
+
+	if got := CategoryDescendants(categories, 1); !slices.Equal(got, []int64{1, 2, 3}) {
+		t.Errorf("Expected Politics and everything below it, got %v", got)
+	}
+	if got := CategoryDescendants(categories, 4); !slices.Equal(got, []int64{4}) {
+		t.Errorf("Expected Sports alone, got %v", got)
+	}
+}
+
+func TestNormalizeTags(t *testing.T) {
+	got := NormalizeTags([]string{"Senate ", " ", "us  elections", "senate", "US Elections"})
+	if want := []string{"senate", "us elections"}; !slices.Equal(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,85 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// MaxCommentLength is the most characters a comment body may have
+const MaxCommentLength = 10000
+
+// Errors of comment changes
+var (
+	ErrCommentNotFound  = errors.New("comment not found")
+	ErrCommentEmpty     = errors.New("comment body is required")
+	ErrCommentTooLong   = fmt.Errorf("comments are at most %d characters", MaxCommentLength)
+	ErrNotCommentAuthor = errors.New("only its author can change a comment")
+	ErrCommentDeleted   = errors.New("comment was deleted")
+	ErrParentNotFound   = errors.New("parent comment not found on this forecast")
+)
+
+// Comment is a comment on a forecast, or a reply to another comment on it.
+// Bodies are markdown, rendered by clients, and may mention users with
+// @username. Deleted comments keep their place in the thread without a body.
+type Comment struct {
+	ID         int64   `json:"id"`
+	ForecastID int64   `json:"forecast_id"`
+	ParentID   *int64  `json:"parent_id,omitempty"`
+	UserID     int64   `json:"user_id"`
+	UserName   *string `json:"user_name,omitempty"`
+	Body       string  `json:"body"`
+	// Mentions are the ids of the users the body mentions
+	Mentions  []int64    `json:"mentions,omitempty"`
+	CreatedAt time.Time  `json:"created"`
+	EditedAt  *time.Time `json:"edited,omitempty"`
+	DeletedAt *time.Time `json:"deleted,omitempty"`
+}
+
+type CommentFilters struct {
+	ForecastID int64
+	// Page limits the list to one page, sorted by created. Without one
+	// comments are listed oldest first.
+	Page *Page
+}
+
+// ValidateBody trims the body and checks its length
+func (c *Comment) ValidateBody() error {
+	c.Body = strings.TrimSpace(c.Body)
+	if c.Body == "" {
+		return ErrCommentEmpty
+	}
+	if utf8.RuneCountInString(c.Body) > MaxCommentLength {
+		return ErrCommentTooLong
+	}
+	return nil
+}
+
+func (c *Comment) SortValue(sort string) string {
+	return sortTime(c.CreatedAt)
+}
+
+func (c *Comment) RowID() int64 {
+	return c.ID
+}
+
+// mentionPattern matches @username where the @ does not follow a word, so
+// email addresses are no mentions
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w@])@([\w.-]+)`)
+
+// ParseMentions returns the usernames the body mentions, each once, in the
+// order they first appear. Punctuation ending a sentence is not part of them.
+func ParseMentions(body string) []string {
+	var usernames []string
+	for _, match := range mentionPattern.FindAllStringSubmatch(body, -1) {
+		username := strings.TrimRight(match[1], ".-")
+		if username != "" && !slices.Contains(usernames, username) {
+			usernames = append(usernames, username)
+		}
+	}
+	return usernames
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseMentions(t *testing.T) {
+	body := "@alice and @bob.smith, see **this**. Thanks @alice! Mail me at carol@example.com, cc @dave."
+	if got, want := ParseMentions(body), []string{"alice", "bob.smith", "dave"}; !slices.Equal(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+	if got := ParseMentions("no mentions @ all"); got != nil {
+		t.Errorf("Expected no mentions, got %v", got)
+	}
+}
+
+func TestValidateCommentBody(t *testing.T) {
+	c := Comment{Body: "  *looks* right  "}
+	if err := c.ValidateBody(); err != nil || c.Body != "*looks* right" {
+		t.Errorf("Expected a trimmed body, got %q (%v)", c.Body, err)
+	}
+	c.Body = " \n "
+	if err := c.ValidateBody(); err != ErrCommentEmpty {
+		t.Errorf("Expected an empty body to be rejected, got %v", err)
+	}
+	c.Body = strings.Repeat("é", MaxCommentLength)
+	if err := c.ValidateBody(); err != nil {
+		t.Errorf("Expected the length to count characters, got %v", err)
+	}
+	c.Body += "x"
+	if err := c.ValidateBody(); err != ErrCommentTooLong {
+		t.Errorf("Expected a long body to be rejected, got %v", err)
+	}
+}
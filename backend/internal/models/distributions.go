@@ -0,0 +1,185 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// DistributionKind is the way a continuous forecast point describes its distribution
+type DistributionKind string
+
+const (
+	DistributionQuantiles DistributionKind = "quantiles"
+	DistributionNormal    DistributionKind = "normal"
+	DistributionLogNormal DistributionKind = "lognormal"
+)
+
+// minDensityFraction floors the density used for log scoring, relative to a
+// uniform density over the question range, so an outcome a forecaster gave no
+// mass to costs a large but finite penalty
+const minDensityFraction = 1e-4
+
+// Quantile is a single point on a forecaster's CDF: P(X <= Value) = Probability
+type Quantile struct {
+	Probability float64 `json:"probability"`
+	Value       float64 `json:"value"`
+}
+
+// Distribution is the forecast for a numeric or date question. Quantile
+// distributions are linearly interpolated between the question range bounds;
+// normal and lognormal distributions are given by their parameters (for
+// lognormal, mu and sigma of the underlying normal).
+type Distribution struct {
+	Kind      DistributionKind `json:"kind"`
+	Quantiles []Quantile       `json:"quantiles,omitempty"`
+	Mu        float64          `json:"mu,omitempty"`
+	Sigma     float64          `json:"sigma,omitempty"`
+}
+
+func (d *Distribution) Value() (driver.Value, error) {
+	if d == nil {
+		return nil, nil
+	}
+	return json.Marshal(d)
+}
+
+func (d *Distribution) Scan(src any) error {
+	return scanJSON(src, d)
+}
+
+// Validate checks that the distribution is well-formed for a question
+// with the given range
+func (d *Distribution) Validate(rangeMin float64, rangeMax float64) error {
+	switch d.Kind {
+	case DistributionQuantiles:
+		if len(d.Quantiles) == 0 {
+			return errors.New("quantile distributions need at least one quantile")
+		}
+		prev := Quantile{Probability: 0, Value: rangeMin}
+		for _, q := range d.Quantiles {
+			if q.Probability <= prev.Probability || q.Probability >= 1 {
+				return errors.New("quantile probabilities must be strictly increasing and within 0 and 1")
+			}
+			if q.Value <= prev.Value || q.Value >= rangeMax {
+				return fmt.Errorf("quantile values must be strictly increasing and within %v and %v", rangeMin, rangeMax)
+			}
+			prev = q
+		}
+	case DistributionNormal, DistributionLogNormal:
+		if d.Sigma <= 0 || math.IsInf(d.Sigma, 0) || math.IsNaN(d.Sigma) || math.IsInf(d.Mu, 0) || math.IsNaN(d.Mu) {
+			return errors.New("distribution needs a finite mu and a positive sigma")
+		}
+	default:
+		return fmt.Errorf("unknown distribution kind %q", d.Kind)
+	}
+	return nil
+}
+
+// CRPS returns the continuous ranked probability score of the distribution
+// for the observed outcome, in the units of the question. Lower is better.
+func (d *Distribution) CRPS(outcome float64, rangeMin float64, rangeMax float64) float64 {
+	switch d.Kind {
+	case DistributionNormal:
+		z := (outcome - d.Mu) / d.Sigma
+		return d.Sigma * (z*(2*normalCDF(z)-1) + 2*normalPDF(z) - 1/math.Sqrt(math.Pi))
+	case DistributionLogNormal:
+		mean := math.Exp(d.Mu + d.Sigma*d.Sigma/2)
+		halfSpread := mean * (2*normalCDF(d.Sigma/math.Sqrt2) - 1)
+		if outcome <= 0 {
+			return mean - outcome - halfSpread
+		}
+		w := (math.Log(outcome) - d.Mu) / d.Sigma
+		return outcome*(2*normalCDF(w)-1) - 2*mean*(normalCDF(w-d.Sigma)+normalCDF(d.Sigma/math.Sqrt2)-1)
+	default:
+		return d.quantileCRPS(outcome, rangeMin, rangeMax)
+	}
+}
+
+// LogDensity returns the natural log of the forecast density at the outcome,
+// scaled to the question range so it is comparable across questions. A uniform
+// forecast over the range scores 0.
+func (d *Distribution) LogDensity(outcome float64, rangeMin float64, rangeMax float64) float64 {
+	width := rangeMax - rangeMin
+
+	var density float64
+	switch d.Kind {
+	case DistributionNormal:
+		density = normalPDF((outcome-d.Mu)/d.Sigma) / d.Sigma
+	case DistributionLogNormal:
+		if outcome > 0 {
+			density = normalPDF((math.Log(outcome)-d.Mu)/d.Sigma) / (d.Sigma * outcome)
+		}
+	default:
+		density = d.quantileDensity(outcome, rangeMin, rangeMax)
+	}
+
+	return math.Log(math.Max(density*width, minDensityFraction))
+}
+
+// cdfKnots returns the points of the piecewise-linear CDF of a quantile
+// distribution, anchored at 0 on rangeMin and 1 on rangeMax
+func (d *Distribution) cdfKnots(rangeMin float64, rangeMax float64) []Quantile {
+	knots := make([]Quantile, 0, len(d.Quantiles)+2)
+	knots = append(knots, Quantile{Probability: 0, Value: rangeMin})
+	knots = append(knots, d.Quantiles...)
+	knots = append(knots, Quantile{Probability: 1, Value: rangeMax})
+	return knots
+}
+
+func (d *Distribution) quantileDensity(outcome float64, rangeMin float64, rangeMax float64) float64 {
+	knots := d.cdfKnots(rangeMin, rangeMax)
+	for i := 1; i < len(knots); i++ {
+		if outcome >= knots[i-1].Value && outcome <= knots[i].Value {
+			return (knots[i].Probability - knots[i-1].Probability) / (knots[i].Value - knots[i-1].Value)
+		}
+	}
+	return 0
+}
+
+// quantileCRPS integrates (F(x) - 1{x >= outcome})^2 exactly over each linear
+// segment of the CDF, plus the part of the real line between the range and an
+// outcome that fell outside it
+func (d *Distribution) quantileCRPS(outcome float64, rangeMin float64, rangeMax float64) float64 {
+	var crps float64
+	if outcome < rangeMin {
+		crps += rangeMin - outcome
+	}
+	if outcome > rangeMax {
+		crps += outcome - rangeMax
+	}
+
+	knots := d.cdfKnots(rangeMin, rangeMax)
+	for i := 1; i < len(knots); i++ {
+		a, b := knots[i-1], knots[i]
+		if outcome > a.Value && outcome < b.Value {
+			// split the segment at the outcome so the step function is constant on each part
+			fy := a.Probability + (b.Probability-a.Probability)*(outcome-a.Value)/(b.Value-a.Value)
+			crps += squaredSegmentIntegral(a.Value, outcome, a.Probability, fy, 0)
+			crps += squaredSegmentIntegral(outcome, b.Value, fy, b.Probability, 1)
+			continue
+		}
+		step := 0.0
+		if a.Value >= outcome {
+			step = 1
+		}
+		crps += squaredSegmentIntegral(a.Value, b.Value, a.Probability, b.Probability, step)
+	}
+	return crps
+}
+
+// squaredSegmentIntegral integrates (F(x) - c)^2 over [x0, x1] where F is linear from f0 to f1
+func squaredSegmentIntegral(x0 float64, x1 float64, f0 float64, f1 float64, c float64) float64 {
+	u, v := f0-c, f1-c
+	return (x1 - x0) * (u*u + u*v + v*v) / 3
+}
+
+func normalPDF(z float64) float64 {
+	return math.Exp(-z*z/2) / math.Sqrt(2*math.Pi)
+}
+
+func normalCDF(z float64) float64 {
+	return 0.5 * math.Erfc(-z/math.Sqrt2)
+}
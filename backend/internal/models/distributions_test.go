@@ -0,0 +1,125 @@
+package models
+
+import (
+	"math"
+	"testing"
+)
+
+// numericCRPS integrates (F(x) - 1{x >= outcome})^2 with the midpoint rule
+func numericCRPS(cdf func(float64) float64, outcome float64, lo float64, hi float64) float64 {
+	const steps = 200000
+	dx := (hi - lo) / steps
+	var sum float64
+	for i := 0; i < steps; i++ {
+		x := lo + (float64(i)+0.5)*dx
+		step := 0.0
+		if x >= outcome {
+			step = 1
+		}
+		sum += math.Pow(cdf(x)-step, 2) * dx
+	}
+	return sum
+}
+
+func TestDistributionCRPS_UniformQuantiles(t *testing.T) {
+	// A single median at the midpoint gives a uniform distribution over the range
+	d := Distribution{Kind: DistributionQuantiles, Quantiles: []Quantile{{Probability: 0.5, Value: 0.5}}}
+
+	// For a uniform on [0, 1] and outcome 0.5: 1/24 on each side
+	crps := d.CRPS(0.5, 0, 1)
+	if math.Abs(crps-1.0/12) > 1e-9 {
+		t.Errorf("CRPS = %v, want %v", crps, 1.0/12)
+	}
+
+	// A uniform forecast has a log density of 0 everywhere in range
+	if logDensity := d.LogDensity(0.3, 0, 1); math.Abs(logDensity) > 1e-9 {
+		t.Errorf("LogDensity = %v, want 0", logDensity)
+	}
+}
+
+func TestDistributionCRPS_QuantilesMatchNumericIntegration(t *testing.T) {
+	d := Distribution{Kind: DistributionQuantiles, Quantiles: []Quantile{
+		{Probability: 0.1, Value: 20},
+		{Probability: 0.5, Value: 35},
+		{Probability: 0.9, Value: 60},
+	}}
+	cdf := func(x float64) float64 {
+		knots := d.cdfKnots(0, 100)
+		if x <= knots[0].Value {
+			return 0
+		}
+		for i := 1; i < len(knots); i++ {
+			if x <= knots[i].Value {
+				a, b := knots[i-1], knots[i]
+				return a.Probability + (b.Probability-a.Probability)*(x-a.Value)/(b.Value-a.Value)
+			}
+		}
+		return 1
+	}
+
+	for _, outcome := range []float64{-10, 10, 35, 42.5, 99, 120} {
+		want := numericCRPS(cdf, outcome, -20, 130)
+		got := d.CRPS(outcome, 0, 100)
+		if math.Abs(got-want) > 1e-3 {
+			t.Errorf("outcome %v: CRPS = %v, want %v", outcome, got, want)
+		}
+	}
+}
+
+func TestDistributionCRPS_NormalMatchesNumericIntegration(t *testing.T) {
+	d := Distribution{Kind: DistributionNormal, Mu: 2, Sigma: 1.5}
+	cdf := func(x float64) float64 { return normalCDF((x - d.Mu) / d.Sigma) }
+
+	for _, outcome := range []float64{-1, 2, 3.7} {
+		want := numericCRPS(cdf, outcome, -20, 20)
+		got := d.CRPS(outcome, 0, 10)
+		if math.Abs(got-want) > 1e-3 {
+			t.Errorf("outcome %v: CRPS = %v, want %v", outcome, got, want)
+		}
+	}
+}
+
+func TestDistributionCRPS_LogNormalMatchesNumericIntegration(t *testing.T) {
+	d := Distribution{Kind: DistributionLogNormal, Mu: 1, Sigma: 0.5}
+	cdf := func(x float64) float64 {
+		if x <= 0 {
+			return 0
+		}
+		return normalCDF((math.Log(x) - d.Mu) / d.Sigma)
+	}
+
+	for _, outcome := range []float64{-2, 0.5, 2.7, 8} {
+		want := numericCRPS(cdf, outcome, -5, 80)
+		got := d.CRPS(outcome, 0, 20)
+		if math.Abs(got-want) > 1e-3 {
+			t.Errorf("outcome %v: CRPS = %v, want %v", outcome, got, want)
+		}
+	}
+}
+
+func TestDistributionValidate(t *testing.T) {
+	testCases := []struct {
+		name      string
+		d         Distribution
+		shouldErr bool
+	}{
+		{"valid quantiles", Distribution{Kind: DistributionQuantiles, Quantiles: []Quantile{{0.25, 2}, {0.75, 8}}}, false},
+		{"no quantiles", Distribution{Kind: DistributionQuantiles}, true},
+		{"decreasing values", Distribution{Kind: DistributionQuantiles, Quantiles: []Quantile{{0.25, 8}, {0.75, 2}}}, true},
+		{"value outside range", Distribution{Kind: DistributionQuantiles, Quantiles: []Quantile{{0.5, 11}}}, true},
+		{"probability of one", Distribution{Kind: DistributionQuantiles, Quantiles: []Quantile{{1, 5}}}, true},
+		{"valid normal", Distribution{Kind: DistributionNormal, Mu: 5, Sigma: 1}, false},
+		{"zero sigma", Distribution{Kind: DistributionLogNormal, Mu: 1}, true},
+		{"unknown kind", Distribution{Kind: "beta", Mu: 1, Sigma: 1}, true},
+	}
+
+	for _, tc := range testCases {
+		err := tc.d.Validate(0, 10)
+		if tc.shouldErr && err == nil {
+			t.Errorf("%s: expected error, got nil", tc.name)
+		}
+		if !tc.shouldErr && err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+		}
+	}
+}
@@ -1,17 +1,28 @@
 package models
 
 import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
 	"time"
 )
 
+// probabilitySumTolerance is how far a probability vector may drift from 1
+// before it is rejected, to allow for rounding in clients
+const probabilitySumTolerance = 1e-6
+
 type ForecastPoint struct {
-	ID            int64     `json:"id"`
-	ForecastID    int64     `json:"forecast_id"`
-	PointForecast float64   `json:"point_forecast"`
-	Reason        string    `json:"reason"`
-	CreatedAt     time.Time `json:"created"`
-	UserID        int64     `json:"user_id"`
-	UserName      *string   `json:"user_name,omitempty"`
+	ID            int64         `json:"id"`
+	ForecastID    int64         `json:"forecast_id"`
+	PointForecast float64       `json:"point_forecast"`
+	Probabilities Probabilities `json:"probabilities,omitempty"`
+	Distribution  *Distribution `json:"distribution,omitempty"`
+	Reason        string        `json:"reason"`
+	CreatedAt     time.Time     `json:"created"`
+	UserID        int64         `json:"user_id"`
+	UserName      *string       `json:"user_name,omitempty"`
 }
 
 type PointFilters struct {
@@ -21,4 +32,54 @@ type PointFilters struct {
 	DistinctOnForecast *bool
 	OrderByForecastID  *bool
 	CreatedDirection   *string
+	// Viewer keeps the points of forecasts the user, or an anonymous caller
+	// when 0, may see: those listed for them, or any they can view when
+	// ForecastID names one. Nil does not filter.
+	Viewer *int64
+	// Page limits the list to one page, sorted by created
+	Page *Page
+}
+
+// Probabilities is the per-option probability vector of a multiple-choice
+// forecast point, in the same order as the forecast's options
+type Probabilities []float64
+
+func (p Probabilities) Value() (driver.Value, error) {
+	if p == nil {
+		return nil, nil
+	}
+	return json.Marshal(p)
+}
+
+func (p *Probabilities) Scan(src any) error {
+	return scanJSON(src, p)
+}
+
+// ValidateProbability checks that a binary point forecast can be scored
+func ValidateProbability(p float64) error {
+	if p <= 0.0 || p >= 1.0 {
+		return errors.New("point forecasts must be within 0 and 1")
+	}
+	return nil
+}
+
+// ValidateProbabilities checks that a multiple-choice point has one probability
+// per option, that each can be scored and that together they sum to 1
+func ValidateProbabilities(probabilities []float64, optionCount int) error {
+	if len(probabilities) != optionCount {
+		return fmt.Errorf("expected %d probabilities, got %d", optionCount, len(probabilities))
+	}
+
+	var sum float64
+	for _, p := range probabilities {
+		if err := ValidateProbability(p); err != nil {
+			return err
+		}
+		sum += p
+	}
+
+	if math.Abs(sum-1.0) > probabilitySumTolerance {
+		return fmt.Errorf("probabilities must sum to 1, got %v", sum)
+	}
+	return nil
 }
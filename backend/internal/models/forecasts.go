@@ -1,29 +1,176 @@
 package models
 
 import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
 	"time"
 )
 
+// QuestionType determines the shape of a forecast's points and how it resolves
+type QuestionType string
+
+const (
+	QuestionTypeBinary         QuestionType = "binary"
+	QuestionTypeMultipleChoice QuestionType = "multiple_choice"
+	QuestionTypeNumeric        QuestionType = "numeric"
+	// Date questions are numeric questions over unix timestamps in seconds
+	QuestionTypeDate QuestionType = "date"
+)
+
 type Forecast struct {
-	ID                 int64      `json:"id"`
-	Question           string     `json:"question"`
-	Category           string     `json:"category"`
-	CreatedAt          time.Time  `json:"created"`
-	UserID             int64      `json:"user_id"`
-	ResolutionCriteria string     `json:"resolution_criteria"`
-	ClosingDate        *time.Time `json:"closing_date,omitempty"`
-	Resolution         *string    `json:"resolution,omitempty"`
-	ResolvedAt         *time.Time `json:"resolved,omitempty"`
-	ResolutionComment  *string    `json:"comment,omitempty"`
+	ID       int64  `json:"id"`
+	Question string `json:"question"`
+	// Category is the name of the forecast's category
+	Category           string       `json:"category"`
+	CategoryID         *int64       `json:"category_id,omitempty"`
+	CreatedAt          time.Time    `json:"created"`
+	UserID             int64        `json:"user_id"`
+	ResolutionCriteria string       `json:"resolution_criteria"`
+	ClosingDate        *time.Time   `json:"closing_date,omitempty"`
+	Resolution         *Resolution  `json:"resolution,omitempty"`
+	ResolutionValue    *float64     `json:"resolution_value,omitempty"`
+	ResolvedAt         *time.Time   `json:"resolved,omitempty"`
+	ResolutionComment  *string      `json:"comment,omitempty"`
+	QuestionType       QuestionType `json:"question_type"`
+	Options            Options      `json:"options,omitempty"`
+	RangeMin           *float64     `json:"range_min,omitempty"`
+	RangeMax           *float64     `json:"range_max,omitempty"`
+	Tags               []string     `json:"tags,omitempty"`
+	Visibility         Visibility   `json:"visibility"`
+	// SharedWith are the ids of the users a shared forecast is shared with
+	SharedWith []int64 `json:"shared_with,omitempty"`
 }
 
 type ForecastFilters struct {
 	ForecastID *int64
 	Status     *string
-	Category   *string
+	// CategoryIDs keeps forecasts in one of the categories, when not nil
+	CategoryIDs []int64
+	Tag         *string
+	// Viewer keeps the forecasts listed for the user, or for anonymous callers
+	// when 0, see Forecast.IsListedFor. Nil does not filter.
+	Viewer *int64
+	// Page limits the list to one page, sorted by created, closing_date or question
+	Page *Page
+}
+
+// Options holds the labels of a multiple-choice question, stored as a JSON array
+type Options []string
+
+func (o Options) Value() (driver.Value, error) {
+	if o == nil {
+		return nil, nil
+	}
+	return json.Marshal(o)
+}
+
+func (o *Options) Scan(src any) error {
+	return scanJSON(src, o)
 }
 
 // Check if forecast has resolved
 func (f *Forecast) IsResolved() bool {
 	return f.ResolvedAt != nil
 }
+
+func (f *Forecast) IsMultipleChoice() bool {
+	return f.QuestionType == QuestionTypeMultipleChoice
+}
+
+// IsContinuous reports whether points carry a distribution over a numeric range
+func (f *Forecast) IsContinuous() bool {
+	return f.QuestionType == QuestionTypeNumeric || f.QuestionType == QuestionTypeDate
+}
+
+// Validate checks that the question type and its options are consistent.
+// An empty question type defaults to binary.
+func (f *Forecast) Validate() error {
+	switch f.QuestionType {
+	case "":
+		f.QuestionType = QuestionTypeBinary
+		fallthrough
+	case QuestionTypeBinary:
+		if len(f.Options) > 0 || f.RangeMin != nil || f.RangeMax != nil {
+			return errors.New("binary questions do not take options or a range")
+		}
+	case QuestionTypeMultipleChoice:
+		if f.RangeMin != nil || f.RangeMax != nil {
+			return errors.New("multiple choice questions do not take a range")
+		}
+		if len(f.Options) < 2 {
+			return errors.New("multiple choice questions need at least two options")
+		}
+		seen := make(map[string]bool, len(f.Options))
+		for _, option := range f.Options {
+			label := strings.TrimSpace(option)
+			if label == "" {
+				return errors.New("options cannot be empty")
+			}
+			if seen[strings.ToLower(label)] {
+				return fmt.Errorf("duplicate option %q", label)
+			}
+			seen[strings.ToLower(label)] = true
+		}
+	case QuestionTypeNumeric, QuestionTypeDate:
+		if len(f.Options) > 0 {
+			return errors.New("numeric and date questions do not take options")
+		}
+		if f.RangeMin == nil || f.RangeMax == nil || *f.RangeMin >= *f.RangeMax {
+			return errors.New("numeric and date questions need a range_min below range_max")
+		}
+	default:
+		return fmt.Errorf("unknown question type %q", f.QuestionType)
+	}
+	return nil
+}
+
+// ParseOptionResolution returns the index of the option a multiple-choice
+// forecast resolved to. Resolutions are stored as the zero-based option index.
+func (f *Forecast) ParseOptionResolution(resolution string) (int, error) {
+	index, err := strconv.Atoi(resolution)
+	if err != nil {
+		return 0, fmt.Errorf("%w: resolution must be an option index, got %q", ErrInvalidResolution, resolution)
+	}
+	if index < 0 || index >= len(f.Options) {
+		return 0, fmt.Errorf("%w: resolution %d is out of range for %d options", ErrInvalidResolution, index, len(f.Options))
+	}
+	return index, nil
+}
+
+// ParseValueResolution returns the outcome a numeric or date forecast resolved to,
+// which must be within the question's range. Date questions also accept an
+// RFC3339 timestamp.
+func (f *Forecast) ParseValueResolution(resolution string) (float64, error) {
+	value, err := strconv.ParseFloat(resolution, 64)
+	if f.QuestionType == QuestionTypeDate {
+		if t, tErr := time.Parse(time.RFC3339, resolution); tErr == nil {
+			value, err = float64(t.Unix()), nil
+		}
+	}
+	if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+		return 0, fmt.Errorf("%w: resolution must be a number, got %q", ErrInvalidResolution, resolution)
+	}
+	if f.RangeMin != nil && f.RangeMax != nil && (value < *f.RangeMin || value > *f.RangeMax) {
+		return 0, fmt.Errorf("%w: resolution %v is outside the range %v to %v", ErrInvalidResolution, value, *f.RangeMin, *f.RangeMax)
+	}
+	return value, nil
+}
+
+// scanJSON decodes a json/jsonb column into dest, leaving it untouched on NULL
+func scanJSON(src any, dest any) error {
+	switch v := src.(type) {
+	case nil:
+		return nil
+	case []byte:
+		return json.Unmarshal(v, dest)
+	case string:
+		return json.Unmarshal([]byte(v), dest)
+	default:
+		return fmt.Errorf("cannot scan %T into %T", src, dest)
+	}
+}
@@ -0,0 +1,150 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Sort keys of paged lists
+const (
+	SortCreated     = "created"
+	SortClosingDate = "closing_date"
+	SortQuestion    = "question"
+)
+
+const (
+	DefaultPageLimit = 50
+	MaxPageLimit     = 500
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Page selects one page of a list ordered by a sort key and then by id.
+// Repositories fetch one row past the limit, so NewPaged can tell whether
+// there is a next page.
+type Page struct {
+	Limit int
+	Sort  string
+	Desc  bool
+	// After is the position of the last row of the previous page
+	After *Cursor
+}
+
+// Key identifies the page in cache keys
+func (p Page) Key() string {
+	order := "asc"
+	if p.Desc {
+		order = "desc"
+	}
+	after := ""
+	if p.After != nil {
+		after = p.After.Encode()
+	}
+	return fmt.Sprintf("%s:%s:%d:%s", p.Sort, order, p.Limit, after)
+}
+
+// Cursor is the position of a row in a sorted list: its sort value and id. It
+// remembers the ordering, so it cannot be used with another.
+type Cursor struct {
+	Sort  string `json:"s"`
+	Desc  bool   `json:"d,omitempty"`
+	Value string `json:"v"`
+	ID    int64  `json:"i"`
+}
+
+// Encode returns the opaque form of the cursor handed to clients
+func (c Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func DecodeCursor(s string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c Cursor
+	if err := json.Unmarshal(data, &c); err != nil || c.Sort == "" {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// Sortable rows can be paged
+type Sortable interface {
+	// SortValue is the row's value for the sort key, formatted so values
+	// order as strings the way the database orders them
+	SortValue(sort string) string
+	RowID() int64
+}
+
+// Paged is one page of a list and the cursor of the next, if there is one
+type Paged[T any] struct {
+	Items      []T     `json:"items"`
+	NextCursor *string `json:"next_cursor"`
+}
+
+// NewPaged trims rows fetched for the page to its limit and points the next
+// cursor at the last row kept
+func NewPaged[T Sortable](rows []T, page Page) Paged[T] {
+	paged := Paged[T]{Items: rows}
+	if paged.Items == nil {
+		paged.Items = []T{}
+	}
+	if len(rows) <= page.Limit {
+		return paged
+	}
+
+	paged.Items = rows[:page.Limit]
+	last := paged.Items[len(paged.Items)-1]
+	next := Cursor{Sort: page.Sort, Desc: page.Desc, Value: last.SortValue(page.Sort), ID: last.RowID()}.Encode()
+	paged.NextCursor = &next
+	return paged
+}
+
+// sortTimeLayout formats timestamps with the database's microsecond precision,
+// at a fixed width so they order as strings
+const sortTimeLayout = "2006-01-02 15:04:05.000000"
+
+// sortInfinity is how a missing time sorts, after every other time
+const sortInfinity = "infinity"
+
+func sortTime(t time.Time) string {
+	return t.UTC().Format(sortTimeLayout)
+}
+
+func (f *Forecast) SortValue(sort string) string {
+	switch sort {
+	case SortClosingDate:
+		if f.ClosingDate == nil {
+			return sortInfinity
+		}
+		return sortTime(*f.ClosingDate)
+	case SortQuestion:
+		return f.Question
+	}
+	return sortTime(f.CreatedAt)
+}
+
+func (f *Forecast) RowID() int64 {
+	return f.ID
+}
+
+func (p *ForecastPoint) SortValue(sort string) string {
+	return sortTime(p.CreatedAt)
+}
+
+func (p *ForecastPoint) RowID() int64 {
+	return p.ID
+}
+
+func (s Scores) SortValue(sort string) string {
+	return sortTime(s.CreatedAt)
+}
+
+func (s Scores) RowID() int64 {
+	return s.ID
+}
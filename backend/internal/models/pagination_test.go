@@ -0,0 +1,64 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCursor_RoundTrip(t *testing.T) {
+	c := Cursor{Sort: SortQuestion, Desc: true, Value: "Will it rain?", ID: 12}
+	got, err := DecodeCursor(c.Encode())
+	if err != nil {
+		t.Fatalf("Error decoding cursor: %v", err)
+	}
+	if *got != c {
+		t.Errorf("Expected %+v, got %+v", c, *got)
+	}
+
+	for _, s := range []string{"", "not base64!", "e30"} {
+		if _, err := DecodeCursor(s); err != ErrInvalidCursor {
+			t.Errorf("Expected %q to be an invalid cursor, got %v", s, err)
+		}
+	}
+}
+
+func TestNewPaged(t *testing.T) {
+	created := time.Date(2025, 3, 1, 12, 0, 0, 500, time.UTC)
+	rows := []*Forecast{{ID: 3, CreatedAt: created}, {ID: 2, CreatedAt: created}, {ID: 1, CreatedAt: created}}
+	page := Page{Limit: 2, Sort: SortCreated, Desc: true}
+
+	paged := NewPaged(rows, page)
+	if len(paged.Items) != 2 || paged.NextCursor == nil {
+		t.Fatalf("Expected two items and a next cursor, got %+v", paged)
+	}
+	next, err := DecodeCursor(*paged.NextCursor)
+	if err != nil {
+		t.Fatalf("Error decoding next cursor: %v", err)
+	}
+	want := Cursor{Sort: SortCreated, Desc: true, Value: "2025-03-01 12:00:00.000000", ID: 2}
+	if *next != want {
+		t.Errorf("Expected the cursor to point at the last item, %+v, got %+v", want, *next)
+	}
+
+	// the last page has no next cursor
+	if paged := NewPaged(rows[2:], page); len(paged.Items) != 1 || paged.NextCursor != nil {
+		t.Errorf("Expected one item and no next cursor, got %+v", paged)
+	}
+	if paged := NewPaged([]*Forecast(nil), page); paged.Items == nil {
+		t.Error("Expected an empty page to have an empty list of items")
+	}
+}
+
+func TestForecast_SortValue(t *testing.T) {
+	closing := time.Date(2025, 3, 1, 0, 0, 0, 0, time.FixedZone("UTC+2", 2*60*60))
+	f := &Forecast{Question: "q", ClosingDate: &closing}
+	if got := f.SortValue(SortClosingDate); got != "2025-02-28 22:00:00.000000" {
+		t.Errorf("Expected the closing date in UTC, got %q", got)
+	}
+	if got := (&Forecast{}).SortValue(SortClosingDate); got != "infinity" {
+		t.Errorf("Expected a missing closing date to sort last, got %q", got)
+	}
+	if got := f.SortValue(SortQuestion); got != "q" {
+		t.Errorf("Expected the question, got %q", got)
+	}
+}
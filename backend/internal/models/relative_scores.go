@@ -0,0 +1,243 @@
+package models
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"time"
+)
+
+// LogScorePoint is the natural log score a point earned once its forecast
+// resolved, from the time it was made
+type LogScorePoint struct {
+	LogScore  float64
+	CreatedAt time.Time
+}
+
+// UniformLogScore is the log score of a forecast spreading its probability
+// evenly: 50% on a binary forecast, the same on every option of a multiple
+// choice one, and flat over the range of a continuous one, which scores 0
+func (f *Forecast) UniformLogScore() float64 {
+	switch {
+	case f.IsMultipleChoice():
+		return -math.Log(float64(len(f.Options)))
+	case f.IsContinuous():
+		return 0
+	}
+	return math.Log(0.5)
+}
+
+// LogScorePoints returns the log score each point of a resolved forecast
+// earned, grouped by user
+func LogScorePoints(forecast *Forecast, points []*ForecastPoint) (map[int64][]LogScorePoint, error) {
+	score, err := pointLogScore(forecast)
+	if err != nil {
+		return nil, err
+	}
+
+	byUser := make(map[int64][]LogScorePoint)
+	for _, p := range points {
+		logScore, err := score(p.PointForecast, p.Probabilities, p.Distribution)
+		if err != nil {
+			return nil, err
+		}
+		byUser[p.UserID] = append(byUser[p.UserID], LogScorePoint{LogScore: logScore, CreatedAt: p.CreatedAt})
+	}
+	return byUser, nil
+}
+
+// pointLogScore returns how a point of the resolved forecast is log scored
+func pointLogScore(forecast *Forecast) (func(float64, Probabilities, *Distribution) (float64, error), error) {
+	if forecast.Resolution == nil || !forecast.Resolution.IsScored() {
+		return nil, errors.New("forecast is not resolved to a scored outcome")
+	}
+
+	switch {
+	case forecast.IsMultipleChoice():
+		outcome, err := forecast.ParseOptionResolution(string(*forecast.Resolution))
+		if err != nil {
+			return nil, err
+		}
+		return func(_ float64, probabilities Probabilities, _ *Distribution) (float64, error) {
+			if err := ValidateProbabilities(probabilities, len(forecast.Options)); err != nil {
+				return 0, err
+			}
+			return math.Log(probabilities[outcome]), nil
+		}, nil
+	case forecast.IsContinuous():
+		outcome, err := forecast.ParseValueResolution(string(*forecast.Resolution))
+		if err != nil {
+			return nil, err
+		}
+		return func(_ float64, _ Probabilities, distribution *Distribution) (float64, error) {
+			if distribution == nil {
+				return 0, errors.New("forecast point has no distribution")
+			}
+			return distribution.LogDensity(outcome, *forecast.RangeMin, *forecast.RangeMax), nil
+		}, nil
+	}
+
+	outcome, err := forecast.Resolution.BinaryOutcome(forecast.ResolutionValue)
+	if err != nil {
+		return nil, err
+	}
+	return func(p float64, _ Probabilities, _ *Distribution) (float64, error) {
+		if err := ValidateProbability(p); err != nil {
+			return 0, err
+		}
+		return outcome*math.Log(p) + (1-outcome)*math.Log(1-p), nil
+	}, nil
+}
+
+// crowdLogScorePoints returns the log scores of the default community forecast
+// after each point, as it is scored for the crowd
+func crowdLogScorePoints(forecast *Forecast, points []*ForecastPoint) ([]LogScorePoint, error) {
+	score, err := pointLogScore(forecast)
+	if err != nil {
+		return nil, err
+	}
+	series, err := AggregatePoints(points, len(forecast.Options), nil, AggregateOptions{Method: DefaultAggregateMethod})
+	if err != nil {
+		return nil, err
+	}
+
+	logScores := make([]LogScorePoint, 0, len(series))
+	for _, a := range series {
+		var p float64
+		if a.PointForecast != nil {
+			p = *a.PointForecast
+		}
+		logScore, err := score(p, a.Probabilities, nil)
+		if err != nil {
+			return nil, err
+		}
+		logScores = append(logScores, LogScorePoint{LogScore: logScore, CreatedAt: a.CreatedAt})
+	}
+	return logScores, nil
+}
+
+// SetRelativeScores sets the Peer and Baseline scores of the scores of a
+// resolved forecast. The Baseline score is the time-weighted log score less
+// that of a uniform forecast. The Peer score is the user's log score less the
+// average log score of the other users forecasting at the time, averaged over
+// the user's scoring window; it is missing when no one else forecast. The
+// score of crowdID, when not 0, is the community forecast, compared with every
+// user while the users are not compared with it.
+func SetRelativeScores(forecast *Forecast, points []*ForecastPoint, scores []*Scores, crowdID int64) error {
+	if forecast.ResolvedAt == nil {
+		return errors.New("forecast is not resolved")
+	}
+	byUser, err := LogScorePoints(forecast, points)
+	if err != nil {
+		return err
+	}
+	closeDate := scoringCloseDate(forecast.ClosingDate, forecast.ResolvedAt)
+	uniform := forecast.UniformLogScore()
+
+	for _, score := range scores {
+		baseline := score.LogNScoreTimeWeighted - uniform
+		score.BaselineScore = &baseline
+		score.PeerScore = nil
+
+		own, ok := byUser[score.UserID]
+		var others [][]LogScorePoint
+		for userID, userPoints := range byUser {
+			if userID != score.UserID {
+				others = append(others, userPoints)
+			}
+		}
+		if crowdID != 0 && score.UserID == crowdID {
+			if own, err = crowdLogScorePoints(forecast, points); err != nil {
+				return err
+			}
+			ok = len(own) > 0
+		}
+		if ok {
+			score.PeerScore = peerScore(own, others, closeDate)
+		}
+	}
+	return nil
+}
+
+// peerScore averages the gap between the user's log score and the average of
+// the others' over the window from the user's first point to closeDate. While
+// no one else has forecast the gap counts as 0. A window too short to weigh
+// compares the latest points.
+func peerScore(own []LogScorePoint, others [][]LogScorePoint, closeDate time.Time) *float64 {
+	if len(own) == 0 || len(others) == 0 {
+		return nil
+	}
+	own = sortedLogScores(own)
+	for i := range others {
+		others[i] = sortedLogScores(others[i])
+	}
+
+	start := own[0].CreatedAt
+	total := closeDate.Sub(start).Seconds()
+	if total <= 1.0 {
+		at := own[len(own)-1].CreatedAt
+		for _, points := range others {
+			if last := points[len(points)-1].CreatedAt; last.After(at) {
+				at = last
+			}
+		}
+		gap := peerGap(own, others, at)
+		return &gap
+	}
+
+	// the gap changes only when someone forecasts
+	times := []time.Time{start}
+	for _, points := range append([][]LogScorePoint{own}, others...) {
+		for _, p := range points {
+			if p.CreatedAt.After(start) && p.CreatedAt.Before(closeDate) {
+				times = append(times, p.CreatedAt)
+			}
+		}
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+
+	var score float64
+	for i, t := range times {
+		end := closeDate
+		if i+1 < len(times) {
+			end = times[i+1]
+		}
+		score += peerGap(own, others, t) * end.Sub(t).Seconds() / total
+	}
+	return &score
+}
+
+// peerGap is the user's log score less the average of the others' at t
+func peerGap(own []LogScorePoint, others [][]LogScorePoint, t time.Time) float64 {
+	ownScore, _ := logScoreAt(own, t)
+	var sum float64
+	var count int
+	for _, points := range others {
+		if s, ok := logScoreAt(points, t); ok {
+			sum += s
+			count++
+		}
+	}
+	if count == 0 {
+		return 0
+	}
+	return ownScore - sum/float64(count)
+}
+
+// logScoreAt returns the log score of the latest of the sorted points made by t
+func logScoreAt(points []LogScorePoint, t time.Time) (float64, bool) {
+	i := sort.Search(len(points), func(i int) bool { return points[i].CreatedAt.After(t) })
+	if i == 0 {
+		return 0, false
+	}
+	return points[i-1].LogScore, true
+}
+
+func sortedLogScores(points []LogScorePoint) []LogScorePoint {
+	sorted := make([]LogScorePoint, len(points))
+	copy(sorted, points)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].CreatedAt.Before(sorted[j].CreatedAt)
+	})
+	return sorted
+}
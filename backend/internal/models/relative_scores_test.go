@@ -0,0 +1,84 @@
+package models
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestSetRelativeScores(t *testing.T) {
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	closing := created.Add(4 * time.Hour)
+	resolved := created.Add(5 * time.Hour)
+	yes := ResolutionYes
+	forecast := &Forecast{ID: 1, QuestionType: QuestionTypeBinary, CreatedAt: created, ClosingDate: &closing, Resolution: &yes, ResolvedAt: &resolved}
+	// the second user joins halfway through the window
+	points := []*ForecastPoint{
+		{ID: 1, ForecastID: 1, UserID: 1, PointForecast: 0.8, CreatedAt: created},
+		{ID: 2, ForecastID: 1, UserID: 2, PointForecast: 0.5, CreatedAt: created.Add(2 * time.Hour)},
+	}
+
+	var scores []*Scores
+	for _, p := range points {
+		score, err := CalcBinaryScore([]TimePoint{{PointForecast: p.PointForecast, CreatedAt: p.CreatedAt}}, 1, p.UserID, 1, created, &closing, &resolved)
+		if err != nil {
+			t.Fatal(err)
+		}
+		scores = append(scores, &score)
+	}
+	series, err := AggregatePoints(points, 0, nil, AggregateOptions{Method: DefaultAggregateMethod})
+	if err != nil {
+		t.Fatal(err)
+	}
+	crowd, err := CalcBinaryScore(TimePoints(series), 1, 99, 1, created, &closing, &resolved)
+	if err != nil {
+		t.Fatal(err)
+	}
+	scores = append(scores, &crowd)
+
+	if err := SetRelativeScores(forecast, points, scores, 99); err != nil {
+		t.Fatalf("Failed to set relative scores: %v", err)
+	}
+
+	gap := math.Log(0.8) - math.Log(0.5)
+	// the crowd pools 0.8 and 0.5 into 2/3 once both forecast
+	crowdGap := math.Log(2.0/3) - (math.Log(0.8)+math.Log(0.5))/2
+	for i, want := range []struct{ peer, baseline float64 }{
+		// no one to compare with for the first half
+		{gap / 2, gap},
+		{-gap, 0},
+		{crowdGap / 2, (math.Log(0.8)+math.Log(2.0/3))/2 - math.Log(0.5)},
+	} {
+		s := scores[i]
+		if s.PeerScore == nil || math.Abs(*s.PeerScore-want.peer) > 1e-9 {
+			t.Errorf("Expected user %d's peer score %v, got %v", s.UserID, want.peer, s.PeerScore)
+		}
+		if s.BaselineScore == nil || math.Abs(*s.BaselineScore-want.baseline) > 1e-9 {
+			t.Errorf("Expected user %d's baseline score %v, got %v", s.UserID, want.baseline, s.BaselineScore)
+		}
+	}
+
+	// a lone forecaster has no peers
+	lone := []*Scores{{UserID: 1, LogNScoreTimeWeighted: math.Log(0.8)}}
+	if err := SetRelativeScores(forecast, points[:1], lone, 0); err != nil {
+		t.Fatal(err)
+	}
+	if lone[0].PeerScore != nil || lone[0].BaselineScore == nil {
+		t.Errorf("Expected only a baseline score, got %+v", lone[0])
+	}
+
+	open := &Forecast{ID: 2, QuestionType: QuestionTypeBinary, CreatedAt: created}
+	if err := SetRelativeScores(open, points, scores, 0); err == nil {
+		t.Errorf("Expected an unresolved forecast to be rejected")
+	}
+}
+
+func TestUniformLogScore(t *testing.T) {
+	choice := &Forecast{QuestionType: QuestionTypeMultipleChoice, Options: Options{"a", "b", "c", "d"}}
+	if got := choice.UniformLogScore(); math.Abs(got-math.Log(0.25)) > 1e-12 {
+		t.Errorf("Expected ln(1/4), got %v", got)
+	}
+	if got := (&Forecast{QuestionType: QuestionTypeBinary}).UniformLogScore(); math.Abs(got-math.Log(0.5)) > 1e-12 {
+		t.Errorf("Expected ln(1/2), got %v", got)
+	}
+}
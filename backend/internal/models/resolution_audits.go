@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// ResolutionAction is the kind of change a ResolutionAudit records
+type ResolutionAction string
+
+const (
+	ResolutionActionResolve   ResolutionAction = "resolve"
+	ResolutionActionUnresolve ResolutionAction = "unresolve"
+	ResolutionActionReresolve ResolutionAction = "reresolve"
+)
+
+// ResolutionAudit records a change to a forecast's resolution, with the
+// resolution it replaced and how many scores were archived by it
+type ResolutionAudit struct {
+	ID                 int64            `json:"id"`
+	ForecastID         int64            `json:"forecast_id"`
+	UserID             int64            `json:"user_id"`
+	Action             ResolutionAction `json:"action"`
+	PreviousResolution *Resolution      `json:"previous_resolution,omitempty"`
+	// PreviousResolutionValue is the value of a probabilistic previous resolution
+	PreviousResolutionValue *float64    `json:"previous_resolution_value,omitempty"`
+	PreviousResolvedAt      *time.Time  `json:"previous_resolved,omitempty"`
+	Resolution              *Resolution `json:"resolution,omitempty"`
+	ResolutionValue         *float64    `json:"resolution_value,omitempty"`
+	Comment                 *string     `json:"comment,omitempty"`
+	ArchivedScores          int         `json:"archived_scores"`
+	CreatedAt               time.Time   `json:"created"`
+}
@@ -0,0 +1,106 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Resolution is how a forecast resolved. Binary forecasts resolve to one of the
+// constants below. Multiple-choice forecasts store the option index and numeric
+// and date forecasts the outcome value, and both can also be annulled or
+// ambiguous. The codes of YES, NO and ANNULLED predate the enum.
+type Resolution string
+
+const (
+	ResolutionYes Resolution = "1"
+	ResolutionNo  Resolution = "0"
+	// Annulled questions were voided, e.g. their condition never happened
+	ResolutionAnnulled Resolution = "-"
+	// Ambiguous questions happened but cannot be judged against the criteria
+	ResolutionAmbiguous Resolution = "?"
+	// Probabilistic resolutions carry a fractional outcome in ResolutionValue
+	ResolutionProbabilistic Resolution = "p"
+)
+
+// ErrInvalidResolution is wrapped by every error about a resolution the
+// question cannot take
+var ErrInvalidResolution = errors.New("invalid resolution")
+
+var resolutionNames = map[string]Resolution{
+	"yes":           ResolutionYes,
+	"no":            ResolutionNo,
+	"annulled":      ResolutionAnnulled,
+	"ambiguous":     ResolutionAmbiguous,
+	"probabilistic": ResolutionProbabilistic,
+}
+
+// ParseResolution accepts a resolution code or its name in any case. Anything
+// else must look like an option index, outcome value or RFC3339 date, which
+// is checked against the question when the forecast resolves. Use
+// Forecast.ParseResolution to parse a resolution of a known question.
+func ParseResolution(s string) (Resolution, error) {
+	s = strings.TrimSpace(s)
+	if r, ok := resolutionNames[strings.ToLower(s)]; ok {
+		return r, nil
+	}
+	switch Resolution(s) {
+	case ResolutionYes, ResolutionNo, ResolutionAnnulled, ResolutionAmbiguous, ResolutionProbabilistic:
+		return Resolution(s), nil
+	}
+	if value, err := strconv.ParseFloat(s, 64); err == nil && !math.IsNaN(value) && !math.IsInf(value, 0) {
+		return Resolution(s), nil
+	}
+	if _, err := time.Parse(time.RFC3339, s); err == nil {
+		return Resolution(s), nil
+	}
+	return "", fmt.Errorf("%w: unknown resolution %q", ErrInvalidResolution, s)
+}
+
+// ParseResolution parses a resolution of the forecast's question. The names
+// yes, no and probabilistic only apply to binary questions, so "yes" cannot
+// resolve a multiple-choice question to its option 1.
+func (f *Forecast) ParseResolution(s string) (Resolution, error) {
+	r, err := ParseResolution(s)
+	if err != nil || f.QuestionType == QuestionTypeBinary || !r.IsScored() {
+		return r, err
+	}
+	if name := strings.ToLower(strings.TrimSpace(s)); resolutionNames[name] != "" || r == ResolutionProbabilistic {
+		return "", fmt.Errorf("%w: %s questions cannot resolve to %q", ErrInvalidResolution, f.QuestionType, name)
+	}
+	return r, nil
+}
+
+// IsScored reports whether forecasts resolved this way are scored. Annulled
+// and ambiguous forecasts are closed without scores.
+func (r Resolution) IsScored() bool {
+	return r != ResolutionAnnulled && r != ResolutionAmbiguous
+}
+
+// BinaryOutcome returns the outcome a binary forecast is scored against: 1 for
+// YES, 0 for NO and the value of a PROBABILISTIC resolution, which must be
+// between 0 and 1. Only probabilistic resolutions take a value.
+func (r Resolution) BinaryOutcome(value *float64) (float64, error) {
+	if r != ResolutionProbabilistic && value != nil {
+		return 0, fmt.Errorf("%w: resolution %q does not take a value", ErrInvalidResolution, r)
+	}
+	switch r {
+	case ResolutionYes:
+		return 1, nil
+	case ResolutionNo:
+		return 0, nil
+	case ResolutionProbabilistic:
+		if value == nil {
+			return 0, fmt.Errorf("%w: probabilistic resolutions need a resolution_value", ErrInvalidResolution)
+		}
+		if math.IsNaN(*value) || *value < 0 || *value > 1 {
+			return 0, fmt.Errorf("%w: resolution_value must be between 0 and 1, got %v", ErrInvalidResolution, *value)
+		}
+		return *value, nil
+	default:
+		return 0, fmt.Errorf("%w: binary questions cannot resolve to %q", ErrInvalidResolution, r)
+	}
+}
@@ -0,0 +1,94 @@
+package models
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseResolution(t *testing.T) {
+	valid := map[string]Resolution{
+		"1":                    ResolutionYes,
+		"yes":                  ResolutionYes,
+		"No":                   ResolutionNo,
+		"-":                    ResolutionAnnulled,
+		"ANNULLED":             ResolutionAnnulled,
+		"ambiguous":            ResolutionAmbiguous,
+		"?":                    ResolutionAmbiguous,
+		"probabilistic":        ResolutionProbabilistic,
+		"2":                    "2",
+		"12.5":                 "12.5",
+		"2025-03-01T00:00:00Z": "2025-03-01T00:00:00Z",
+	}
+	for input, want := range valid {
+		got, err := ParseResolution(input)
+		if err != nil || got != want {
+			t.Errorf("ParseResolution(%q) = %q, %v; want %q", input, got, err, want)
+		}
+	}
+
+	for _, input := range []string{"", "maybe", "NaN", "Inf", "2025-03-01"} {
+		if _, err := ParseResolution(input); !errors.Is(err, ErrInvalidResolution) {
+			t.Errorf("ParseResolution(%q) = %v; want ErrInvalidResolution", input, err)
+		}
+	}
+}
+
+func TestForecastParseResolution(t *testing.T) {
+	binary := &Forecast{QuestionType: QuestionTypeBinary}
+	choice := &Forecast{QuestionType: QuestionTypeMultipleChoice, Options: Options{"a", "b", "c"}}
+	rangeMin, rangeMax := 0.0, 10.0
+	numeric := &Forecast{QuestionType: QuestionTypeNumeric, RangeMin: &rangeMin, RangeMax: &rangeMax}
+
+	if r, err := binary.ParseResolution("yes"); err != nil || r != ResolutionYes {
+		t.Errorf("binary yes = %q, %v; want %q", r, err, ResolutionYes)
+	}
+	if r, err := choice.ParseResolution("1"); err != nil || r != "1" {
+		t.Errorf("option 1 = %q, %v; want 1", r, err)
+	}
+	if r, err := numeric.ParseResolution("annulled"); err != nil || r != ResolutionAnnulled {
+		t.Errorf("numeric annulled = %q, %v; want %q", r, err, ResolutionAnnulled)
+	}
+
+	for name, check := range map[string]func() error{
+		"yes on multiple choice":           func() error { _, err := choice.ParseResolution("Yes"); return err },
+		"no on numeric":                    func() error { _, err := numeric.ParseResolution("no"); return err },
+		"probabilistic on multiple choice": func() error { _, err := choice.ParseResolution("probabilistic"); return err },
+		"option out of range":              func() error { _, err := choice.ParseOptionResolution("3"); return err },
+		"value out of range":               func() error { _, err := numeric.ParseValueResolution("10.5"); return err },
+		"option index on binary":           func() error { _, err := Resolution("2").BinaryOutcome(nil); return err },
+	} {
+		if err := check(); !errors.Is(err, ErrInvalidResolution) {
+			t.Errorf("%s = %v; want ErrInvalidResolution", name, err)
+		}
+	}
+	if value, err := numeric.ParseValueResolution("10"); err != nil || value != 10 {
+		t.Errorf("value at range_max = %v, %v; want 10", value, err)
+	}
+}
+
+func TestResolutionBinaryOutcome(t *testing.T) {
+	value := 0.7
+	if outcome, err := ResolutionProbabilistic.BinaryOutcome(&value); err != nil || outcome != 0.7 {
+		t.Errorf("probabilistic outcome = %v, %v; want 0.7", outcome, err)
+	}
+	if outcome, err := ResolutionYes.BinaryOutcome(nil); err != nil || outcome != 1 {
+		t.Errorf("yes outcome = %v, %v; want 1", outcome, err)
+	}
+
+	tooBig := 1.5
+	for name, check := range map[string]func() error{
+		"probabilistic without value": func() error { _, err := ResolutionProbabilistic.BinaryOutcome(nil); return err },
+		"probabilistic out of range":  func() error { _, err := ResolutionProbabilistic.BinaryOutcome(&tooBig); return err },
+		"yes with value":              func() error { _, err := ResolutionYes.BinaryOutcome(&value); return err },
+		"option index":                func() error { _, err := Resolution("2").BinaryOutcome(nil); return err },
+		"annulled":                    func() error { _, err := ResolutionAnnulled.BinaryOutcome(nil); return err },
+	} {
+		if check() == nil {
+			t.Errorf("%s should fail", name)
+		}
+	}
+
+	if ResolutionAnnulled.IsScored() || ResolutionAmbiguous.IsScored() || !ResolutionProbabilistic.IsScored() {
+		t.Error("Expected only annulled and ambiguous resolutions to go unscored")
+	}
+}
@@ -0,0 +1,124 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+// The fields of an open forecast its owner may edit
+const (
+	RevisionFieldQuestion           = "question"
+	RevisionFieldResolutionCriteria = "resolution_criteria"
+	RevisionFieldClosingDate        = "closing_date"
+)
+
+// Errors of forecast edits
+var (
+	ErrForecastResolved     = errors.New("resolved forecasts cannot be edited")
+	ErrNoChanges            = errors.New("the edit changes nothing")
+	ErrQuestionRequired     = errors.New("question and resolution criteria cannot be empty")
+	ErrClosingDateEarlier   = errors.New("the closing date can only be extended")
+	ErrClosingDateInThePast = errors.New("the closing date must be in the future")
+)
+
+// ForecastEdit changes the question, resolution criteria or closing date of an
+// open forecast. Fields left nil are kept.
+type ForecastEdit struct {
+	ForecastID         int64      `json:"forecast_id"`
+	Question           *string    `json:"question,omitempty"`
+	ResolutionCriteria *string    `json:"resolution_criteria,omitempty"`
+	ClosingDate        *time.Time `json:"closing_date,omitempty"`
+}
+
+// FieldChange is a field an edit changed, with its text before and after.
+// Closing dates are in RFC 3339, and empty when there was none.
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// FieldChanges is the diff of a revision, stored as a JSON array
+type FieldChanges []FieldChange
+
+func (c FieldChanges) Value() (driver.Value, error) {
+	if c == nil {
+		return nil, nil
+	}
+	return json.Marshal(c)
+}
+
+func (c *FieldChanges) Scan(src any) error {
+	return scanJSON(src, c)
+}
+
+// ForecastRevision records an edit of a forecast. Version is the version of
+// the forecast the edit made, the forecast as created being version 1.
+type ForecastRevision struct {
+	ID         int64        `json:"id"`
+	ForecastID int64        `json:"forecast_id"`
+	Version    int          `json:"version"`
+	UserID     int64        `json:"user_id"`
+	Changes    FieldChanges `json:"changes"`
+	CreatedAt  time.Time    `json:"created"`
+	// AfterLastPoint marks the edits made after the caller's last point on the
+	// forecast, which that point may not account for
+	AfterLastPoint bool `json:"after_last_point,omitempty"`
+}
+
+// ApplyEdit applies the edit to the open forecast as of now, returning what
+// it changed
+func (f *Forecast) ApplyEdit(edit ForecastEdit, now time.Time) (FieldChanges, error) {
+	if f.IsResolved() {
+		return nil, ErrForecastResolved
+	}
+
+	var changes FieldChanges
+	for _, text := range []struct {
+		field string
+		value *string
+		edit  *string
+	}{
+		{RevisionFieldQuestion, &f.Question, edit.Question},
+		{RevisionFieldResolutionCriteria, &f.ResolutionCriteria, edit.ResolutionCriteria},
+	} {
+		if text.edit == nil {
+			continue
+		}
+		value := strings.TrimSpace(*text.edit)
+		if value == "" {
+			return nil, ErrQuestionRequired
+		}
+		if value != *text.value {
+			changes = append(changes, FieldChange{Field: text.field, Old: *text.value, New: value})
+			*text.value = value
+		}
+	}
+
+	if edit.ClosingDate != nil && (f.ClosingDate == nil || !edit.ClosingDate.Equal(*f.ClosingDate)) {
+		if f.ClosingDate != nil && edit.ClosingDate.Before(*f.ClosingDate) {
+			return nil, ErrClosingDateEarlier
+		}
+		if !edit.ClosingDate.After(now) {
+			return nil, ErrClosingDateInThePast
+		}
+		changes = append(changes, FieldChange{Field: RevisionFieldClosingDate, Old: formatClosingDate(f.ClosingDate), New: formatClosingDate(edit.ClosingDate)})
+		closing := *edit.ClosingDate
+		f.ClosingDate = &closing
+	}
+
+	if len(changes) == 0 {
+		return nil, ErrNoChanges
+	}
+	return changes, nil
+}
+
+func formatClosingDate(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
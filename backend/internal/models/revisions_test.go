@@ -0,0 +1,49 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestApplyEdit(t *testing.T) {
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	closing := now.AddDate(0, 1, 0)
+	later := closing.AddDate(0, 1, 0)
+	f := Forecast{Question: "Will it rain?", ResolutionCriteria: "Any rain", ClosingDate: &closing}
+
+	question, criteria := "Will it rain? ", " Any rain at the airport"
+	changes, err := f.ApplyEdit(ForecastEdit{Question: &question, ResolutionCriteria: &criteria, ClosingDate: &later}, now)
+	if err != nil {
+		t.Fatalf("Error applying edit: %v", err)
+	}
+	want := FieldChanges{
+		{Field: RevisionFieldResolutionCriteria, Old: "Any rain", New: "Any rain at the airport"},
+		{Field: RevisionFieldClosingDate, Old: "2025-07-01T00:00:00Z", New: "2025-08-01T00:00:00Z"},
+	}
+	if len(changes) != len(want) || changes[0] != want[0] || changes[1] != want[1] {
+		t.Errorf("Expected %+v, got %+v", want, changes)
+	}
+	if f.ResolutionCriteria != "Any rain at the airport" || !f.ClosingDate.Equal(later) {
+		t.Errorf("Expected the edit to be applied, got %+v", f)
+	}
+
+	empty, past := " ", now.Add(-time.Hour)
+	resolved := Forecast{Question: "q", ResolutionCriteria: "r", ResolvedAt: &now}
+	for _, c := range []struct {
+		name     string
+		forecast Forecast
+		edit     ForecastEdit
+		want     error
+	}{
+		{"resolved", resolved, ForecastEdit{Question: &question}, ErrForecastResolved},
+		{"no changes", f, ForecastEdit{ResolutionCriteria: &criteria, ClosingDate: &later}, ErrNoChanges},
+		{"empty question", f, ForecastEdit{Question: &empty}, ErrQuestionRequired},
+		{"earlier closing date", f, ForecastEdit{ClosingDate: &closing}, ErrClosingDateEarlier},
+		{"past closing date", Forecast{Question: "q", ResolutionCriteria: "r"}, ForecastEdit{ClosingDate: &past}, ErrClosingDateInThePast},
+	} {
+		if _, err := c.forecast.ApplyEdit(c.edit, now); !errors.Is(err, c.want) {
+			t.Errorf("%s: expected %v, got %v", c.name, c.want, err)
+		}
+	}
+}
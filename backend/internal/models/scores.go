@@ -2,22 +2,41 @@ package models
 
 import (
 	"errors"
+	"fmt"
 	"math"
 	"sort"
+	"strconv"
 	"time"
 )
 
 type Scores struct {
-	ID                     int64     `json:"id"`
-	BrierScore             float64   `json:"brier_score"`
-	Log2Score              float64   `json:"log2_score"`
-	LogNScore              float64   `json:"logn_score"`
-	BrierScoreTimeWeighted float64   `json:"brier_score_time_weighted"`
-	Log2ScoreTimeWeighted  float64   `json:"log2_score_time_weighted"`
-	LogNScoreTimeWeighted  float64   `json:"logn_score_time_weighted"`
-	UserID                 int64     `json:"user_id"`
-	ForecastID             int64     `json:"forecast_id"`
-	CreatedAt              time.Time `json:"created"`
+	ID                     int64    `json:"id"`
+	BrierScore             float64  `json:"brier_score"`
+	Log2Score              float64  `json:"log2_score"`
+	LogNScore              float64  `json:"logn_score"`
+	BrierScoreTimeWeighted float64  `json:"brier_score_time_weighted"`
+	Log2ScoreTimeWeighted  float64  `json:"log2_score_time_weighted"`
+	LogNScoreTimeWeighted  float64  `json:"logn_score_time_weighted"`
+	CRPS                   *float64 `json:"crps,omitempty"`
+	CRPSTimeWeighted       *float64 `json:"crps_time_weighted,omitempty"`
+	// PeerScore and BaselineScore compare the log score with the other
+	// forecasters' and with a uniform forecast, see SetRelativeScores. They
+	// are missing on scores from before they were kept until backfilled.
+	PeerScore     *float64 `json:"peer_score,omitempty"`
+	BaselineScore *float64 `json:"baseline_score,omitempty"`
+	// BrierScoreLifetime and LogNScoreLifetime weigh the points over the whole
+	// life of the forecast, a prior standing in for the user before their
+	// first point, so a late forecast is not counted as if held all along.
+	// Continuous forecasts, and scores from before they were kept, have none.
+	BrierScoreLifetime *float64 `json:"brier_score_lifetime,omitempty"`
+	LogNScoreLifetime  *float64 `json:"logn_score_lifetime,omitempty"`
+	// Rules holds the score under every registered ScoringRule, sorted by
+	// name. Continuous forecasts, and scores from before they were kept, have
+	// none.
+	Rules      []RuleScore `json:"rules,omitempty"`
+	UserID     int64       `json:"user_id"`
+	ForecastID int64       `json:"forecast_id"`
+	CreatedAt  time.Time   `json:"created"`
 }
 
 // Base struct for common score fields
@@ -28,15 +47,41 @@ type ScoreMetrics struct {
 	BrierScoreTimeWeighted float64 `json:"brier_score_time_weighted"`
 	Log2ScoreTimeWeighted  float64 `json:"log2_score_time_weighted"`
 	LogNScoreTimeWeighted  float64 `json:"logn_score_time_weighted"`
+	// PeerScore, BaselineScore and the lifetime scores average the scores
+	// that have them. They are missing when none do, rather than 0, which
+	// would read as exactly average or, for the Brier score, perfect.
+	PeerScore          *float64 `json:"peer_score,omitempty"`
+	BaselineScore      *float64 `json:"baseline_score,omitempty"`
+	BrierScoreLifetime *float64 `json:"brier_score_lifetime,omitempty"`
+	LogNScoreLifetime  *float64 `json:"logn_score_lifetime,omitempty"`
+	// Rule is the ScoringRule asked for, if any. RuleScore and
+	// RuleScoreTimeWeighted average the scores under it, skipping scores
+	// without one, and are missing when none are scored under it.
+	Rule                  string   `json:"rule,omitempty"`
+	RuleScore             *float64 `json:"rule_score,omitempty"`
+	RuleScoreTimeWeighted *float64 `json:"rule_score_time_weighted,omitempty"`
 }
 
 type ScoreFilters struct {
-	UserID        *int64
-	ForecastID    *int64
-	Category      *string
+	UserID     *int64
+	ForecastID *int64
+	// CategoryIDs keeps scores of forecasts in one of the categories, when not nil
+	CategoryIDs   []int64
 	GroupByUserID *bool
 	StartDate     *time.Time
 	EndDate       *time.Time
+	// Viewer keeps the scores of forecasts the user, or an anonymous caller
+	// when 0, can view. Nil does not filter.
+	Viewer *int64
+	// Page limits the list to one page, sorted by created
+	Page *Page
+	// Rule names the ScoringRule aggregates average, when not nil
+	Rule *string
+	// QuestionType keeps scores of forecasts of the type, when not nil
+	QuestionType *QuestionType
+	// ExcludeCrowd leaves the crowd pseudo-user out of aggregates, so averages
+	// and user counts are of the users it pools
+	ExcludeCrowd bool
 }
 
 // Overall platform averages
@@ -75,70 +120,141 @@ type TimePoint struct {
 }
 
 func CalcForecastScore(points []TimePoint, outcome bool, userID int64, forecastID int64, forecastCreatedAt time.Time, forecastClosingDate *time.Time, forecastResolvedAt *time.Time) (Scores, error) {
+	var value float64
+	if outcome {
+		value = 1
+	}
+	return CalcBinaryScore(points, value, userID, forecastID, forecastCreatedAt, forecastClosingDate, forecastResolvedAt)
+}
+
+// CalcBinaryScore scores a user's points on a binary forecast against an
+// outcome between 0 and 1. A fractional outcome comes from a probabilistic
+// resolution: the Brier score is the squared distance to it and the log
+// scores weigh the log of each side by the outcome, so 0 and 1 score exactly
+// like a NO and YES resolution.
+func CalcBinaryScore(points []TimePoint, outcome float64, userID int64, forecastID int64, forecastCreatedAt time.Time, forecastClosingDate *time.Time, forecastResolvedAt *time.Time) (Scores, error) {
 	if len(points) == 0 {
 		return Scores{}, errors.New("no probabilities provided")
 	}
+	if math.IsNaN(outcome) || outcome < 0 || outcome > 1 {
+		return Scores{}, fmt.Errorf("outcome must be between 0 and 1, got %v", outcome)
+	}
 
 	// Sort points by CreatedAt to ensure correct time weighting
 	sort.Slice(points, func(i, j int) bool {
 		return points[i].CreatedAt.Before(points[j].CreatedAt)
 	})
 
-	var closeDate time.Time
-	if forecastClosingDate != nil && forecastClosingDate.Before(*forecastResolvedAt) {
-		closeDate = *forecastClosingDate
-	} else {
-		closeDate = *forecastResolvedAt
+	closeDate := scoringCloseDate(forecastClosingDate, forecastResolvedAt)
+
+	createdAts := make([]time.Time, len(points))
+	for i, point := range points {
+		createdAts[i] = point.CreatedAt
 	}
+	weights := timeWeights(createdAts, closeDate)
 
 	var brierSum, log2Sum, logNSum float64
 	var brierSumTimeWeighted, log2SumTimeWeighted, logNSumTimeWeighted float64
 	pointsCount := float64(len(points))
-	firstPointCreatedAt := points[0].CreatedAt
-	totalTimeInForecast := closeDate.Sub(firstPointCreatedAt).Seconds()
 
-	// Edge case: if forecast was created and resolved at the same time (or very close),
-	// fall back to naive (equal-weighted) scoring for time-weighted scores
-	useTimeWeighting := totalTimeInForecast > 1.0 // At least 1 second open
+	for i, point := range points {
+		if err := ValidateProbability(point.PointForecast); err != nil {
+			return Scores{}, err
+		}
+
+		timeWeight := weights[i]
+
+		brier := math.Pow(point.PointForecast-outcome, 2)
+		logN := outcome*math.Log(point.PointForecast) + (1-outcome)*math.Log(1-point.PointForecast)
+		log2 := outcome*math.Log2(point.PointForecast) + (1-outcome)*math.Log2(1-point.PointForecast)
+
+		brierSum += brier
+		logNSum += logN
+		log2Sum += log2
+		brierSumTimeWeighted += brier * timeWeight
+		logNSumTimeWeighted += logN * timeWeight
+		log2SumTimeWeighted += log2 * timeWeight
+	}
+
+	rules := ruleScores(len(points), weights, func(rule ScoringRule, i int) float64 {
+		return rule.Binary(points[i].PointForecast, outcome)
+	})
+
+	return Scores{
+		BrierScore:             brierSum / pointsCount,
+		Log2Score:              log2Sum / pointsCount,
+		LogNScore:              logNSum / pointsCount,
+		BrierScoreTimeWeighted: brierSumTimeWeighted,
+		LogNScoreTimeWeighted:  logNSumTimeWeighted,
+		Log2ScoreTimeWeighted:  log2SumTimeWeighted,
+		Rules:                  rules,
+		UserID:                 userID,
+		ForecastID:             forecastID,
+		CreatedAt:              time.Now(),
+	}, nil
+}
+
+type ChoicePoint struct {
+	Probabilities []float64
+	CreatedAt     time.Time
+}
+
+// CalcMultipleChoiceScore scores a user's points on a multiple-choice forecast that
+// resolved to the option at index outcome. The Brier score is the multi-class
+// variant (sum of squared errors over all options, between 0 and 2) and the log
+// scores use the probability given to the correct option. Time weighting works
+// the same way as in CalcForecastScore.
+func CalcMultipleChoiceScore(points []ChoicePoint, outcome int, optionCount int, userID int64, forecastID int64, forecastCreatedAt time.Time, forecastClosingDate *time.Time, forecastResolvedAt *time.Time) (Scores, error) {
+	if len(points) == 0 {
+		return Scores{}, errors.New("no probabilities provided")
+	}
+	if outcome < 0 || outcome >= optionCount {
+		return Scores{}, errors.New("outcome is not one of the options")
+	}
+
+	sort.Slice(points, func(i, j int) bool {
+		return points[i].CreatedAt.Before(points[j].CreatedAt)
+	})
+
+	closeDate := scoringCloseDate(forecastClosingDate, forecastResolvedAt)
+
+	createdAts := make([]time.Time, len(points))
+	for i, point := range points {
+		createdAts[i] = point.CreatedAt
+	}
+	weights := timeWeights(createdAts, closeDate)
+
+	var brierSum, log2Sum, logNSum float64
+	var brierSumTimeWeighted, log2SumTimeWeighted, logNSumTimeWeighted float64
+	pointsCount := float64(len(points))
 
 	for i, point := range points {
-		if point.PointForecast <= 0.0 || point.PointForecast >= 1.0 {
-			return Scores{}, errors.New("point forecasts must be within 0 and 1")
+		if err := ValidateProbabilities(point.Probabilities, optionCount); err != nil {
+			return Scores{}, err
 		}
 
-		// Calculate time weight
-		var timeWeight float64
-		if useTimeWeighting {
-			// Calculate how long this prediction was held
-			var duration float64
-			if i < len(points)-1 {
-				duration = points[i+1].CreatedAt.Sub(point.CreatedAt).Seconds()
+		var brier float64
+		for option, p := range point.Probabilities {
+			if option == outcome {
+				brier += math.Pow(p-1, 2)
 			} else {
-				duration = closeDate.Sub(point.CreatedAt).Seconds()
+				brier += math.Pow(p, 2)
 			}
-			timeWeight = duration / totalTimeInForecast
-		} else {
-			// Fall back to equal weighting if no time elapsed
-			timeWeight = 1.0 / pointsCount
 		}
+		correct := point.Probabilities[outcome]
 
-		if outcome {
-			brierSum += math.Pow(point.PointForecast-1, 2)
-			logNSum += math.Log(point.PointForecast)
-			log2Sum += math.Log2(point.PointForecast)
-			brierSumTimeWeighted += math.Pow(point.PointForecast-1, 2) * timeWeight
-			logNSumTimeWeighted += math.Log(point.PointForecast) * timeWeight
-			log2SumTimeWeighted += math.Log2(point.PointForecast) * timeWeight
-		} else {
-			brierSum += math.Pow(point.PointForecast, 2)
-			logNSum += math.Log(1 - point.PointForecast)
-			log2Sum += math.Log2(1 - point.PointForecast)
-			brierSumTimeWeighted += math.Pow(point.PointForecast, 2) * timeWeight
-			logNSumTimeWeighted += math.Log(1-point.PointForecast) * timeWeight
-			log2SumTimeWeighted += math.Log2(1-point.PointForecast) * timeWeight
-		}
+		brierSum += brier
+		logNSum += math.Log(correct)
+		log2Sum += math.Log2(correct)
+		brierSumTimeWeighted += brier * weights[i]
+		logNSumTimeWeighted += math.Log(correct) * weights[i]
+		log2SumTimeWeighted += math.Log2(correct) * weights[i]
 	}
 
+	rules := ruleScores(len(points), weights, func(rule ScoringRule, i int) float64 {
+		return rule.MultipleChoice(points[i].Probabilities, outcome)
+	})
+
 	return Scores{
 		BrierScore:             brierSum / pointsCount,
 		Log2Score:              log2Sum / pointsCount,
@@ -146,8 +262,247 @@ func CalcForecastScore(points []TimePoint, outcome bool, userID int64, forecastI
 		BrierScoreTimeWeighted: brierSumTimeWeighted,
 		LogNScoreTimeWeighted:  logNSumTimeWeighted,
 		Log2ScoreTimeWeighted:  log2SumTimeWeighted,
+		Rules:                  rules,
 		UserID:                 userID,
 		ForecastID:             forecastID,
 		CreatedAt:              time.Now(),
 	}, nil
 }
+
+type DistributionPoint struct {
+	Distribution Distribution
+	CreatedAt    time.Time
+}
+
+// CalcContinuousScore scores a user's points on a numeric or date forecast that
+// resolved to outcome. The raw CRPS is kept in the CRPS fields; the Brier fields
+// hold the CRPS divided by the question range and the log fields hold the
+// range-scaled log density, so both stay comparable with binary questions
+// in aggregates. Time weighting works the same way as in CalcForecastScore.
+func CalcContinuousScore(points []DistributionPoint, outcome float64, rangeMin float64, rangeMax float64, userID int64, forecastID int64, forecastCreatedAt time.Time, forecastClosingDate *time.Time, forecastResolvedAt *time.Time) (Scores, error) {
+	if len(points) == 0 {
+		return Scores{}, errors.New("no distributions provided")
+	}
+	if rangeMin >= rangeMax {
+		return Scores{}, errors.New("range_min must be below range_max")
+	}
+
+	sort.Slice(points, func(i, j int) bool {
+		return points[i].CreatedAt.Before(points[j].CreatedAt)
+	})
+
+	closeDate := scoringCloseDate(forecastClosingDate, forecastResolvedAt)
+
+	createdAts := make([]time.Time, len(points))
+	for i, point := range points {
+		createdAts[i] = point.CreatedAt
+	}
+	weights := timeWeights(createdAts, closeDate)
+
+	width := rangeMax - rangeMin
+	var crpsSum, logNSum float64
+	var crpsSumTimeWeighted, logNSumTimeWeighted float64
+	pointsCount := float64(len(points))
+
+	for i, point := range points {
+		if err := point.Distribution.Validate(rangeMin, rangeMax); err != nil {
+			return Scores{}, err
+		}
+
+		crps := point.Distribution.CRPS(outcome, rangeMin, rangeMax)
+		logDensity := point.Distribution.LogDensity(outcome, rangeMin, rangeMax)
+
+		crpsSum += crps
+		logNSum += logDensity
+		crpsSumTimeWeighted += crps * weights[i]
+		logNSumTimeWeighted += logDensity * weights[i]
+	}
+
+	crpsMean := crpsSum / pointsCount
+	return Scores{
+		BrierScore:             crpsMean / width,
+		Log2Score:              logNSum / pointsCount / math.Ln2,
+		LogNScore:              logNSum / pointsCount,
+		BrierScoreTimeWeighted: crpsSumTimeWeighted / width,
+		LogNScoreTimeWeighted:  logNSumTimeWeighted,
+		Log2ScoreTimeWeighted:  logNSumTimeWeighted / math.Ln2,
+		CRPS:                   &crpsMean,
+		CRPSTimeWeighted:       &crpsSumTimeWeighted,
+		UserID:                 userID,
+		ForecastID:             forecastID,
+		CreatedAt:              time.Now(),
+	}, nil
+}
+
+// DefaultLifetimePrior is the probability a user is taken to hold on a binary
+// forecast before their first point
+const DefaultLifetimePrior = 0.5
+
+// LifetimePrior is what lifetime scores count for a user before their first
+// point
+type LifetimePrior struct {
+	// Probability is held on binary forecasts; multiple choice forecasts are
+	// split evenly between the options
+	Probability float64
+	// Crowd holds the community forecast instead, once there is one
+	Crowd bool
+}
+
+// ParseLifetimePrior parses "crowd" or the probability of a fixed prior
+func ParseLifetimePrior(s string) (LifetimePrior, error) {
+	if s == "crowd" {
+		return LifetimePrior{Probability: DefaultLifetimePrior, Crowd: true}, nil
+	}
+	p, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return LifetimePrior{}, fmt.Errorf("lifetime prior must be crowd or a probability, got %q", s)
+	}
+	if err := ValidateProbability(p); err != nil {
+		return LifetimePrior{}, err
+	}
+	return LifetimePrior{Probability: p}, nil
+}
+
+// BinaryPoints returns the points held for a user on a binary forecast from
+// its creation. crowd is the default aggregate series of the forecast.
+func (p LifetimePrior) BinaryPoints(crowd []AggregatePoint, forecastCreatedAt time.Time) []TimePoint {
+	points := []TimePoint{{PointForecast: p.Probability, CreatedAt: forecastCreatedAt}}
+	if p.Crowd {
+		points = append(points, TimePoints(crowd)...)
+	}
+	return points
+}
+
+// ChoicePoints returns the points held for a user on a multiple choice
+// forecast from its creation
+func (p LifetimePrior) ChoicePoints(crowd []AggregatePoint, optionCount int, forecastCreatedAt time.Time) []ChoicePoint {
+	even := make([]float64, optionCount)
+	for i := range even {
+		even[i] = 1 / float64(optionCount)
+	}
+	points := []ChoicePoint{{Probabilities: even, CreatedAt: forecastCreatedAt}}
+	if p.Crowd {
+		points = append(points, ChoicePoints(crowd)...)
+	}
+	return points
+}
+
+// SetBinaryLifetimeScore sets the lifetime scores of a user's score on a
+// binary forecast. prior starts at the forecast's creation and is held until
+// the user's first point, see LifetimePrior.BinaryPoints.
+func SetBinaryLifetimeScore(score *Scores, points []TimePoint, prior []TimePoint, outcome float64, forecastClosingDate *time.Time, forecastResolvedAt *time.Time) error {
+	if len(points) == 0 || len(prior) == 0 {
+		return errors.New("no probabilities provided")
+	}
+	held := lifetimePoints(points, prior, func(p TimePoint) time.Time { return p.CreatedAt })
+
+	createdAts := make([]time.Time, len(held))
+	for i, point := range held {
+		createdAts[i] = point.CreatedAt
+	}
+	weights := timeWeights(createdAts, scoringCloseDate(forecastClosingDate, forecastResolvedAt))
+
+	var brier, logN float64
+	for i, point := range held {
+		if err := ValidateProbability(point.PointForecast); err != nil {
+			return err
+		}
+		brier += weights[i] * math.Pow(point.PointForecast-outcome, 2)
+		logN += weights[i] * (outcome*math.Log(point.PointForecast) + (1-outcome)*math.Log(1-point.PointForecast))
+	}
+	score.BrierScoreLifetime, score.LogNScoreLifetime = &brier, &logN
+	return nil
+}
+
+// SetMultipleChoiceLifetimeScore sets the lifetime scores of a user's score on
+// a multiple choice forecast, see SetBinaryLifetimeScore
+func SetMultipleChoiceLifetimeScore(score *Scores, points []ChoicePoint, prior []ChoicePoint, outcome int, optionCount int, forecastClosingDate *time.Time, forecastResolvedAt *time.Time) error {
+	if len(points) == 0 || len(prior) == 0 {
+		return errors.New("no probabilities provided")
+	}
+	if outcome < 0 || outcome >= optionCount {
+		return errors.New("outcome is not one of the options")
+	}
+	held := lifetimePoints(points, prior, func(p ChoicePoint) time.Time { return p.CreatedAt })
+
+	createdAts := make([]time.Time, len(held))
+	for i, point := range held {
+		createdAts[i] = point.CreatedAt
+	}
+	weights := timeWeights(createdAts, scoringCloseDate(forecastClosingDate, forecastResolvedAt))
+
+	var brier, logN float64
+	for i, point := range held {
+		if err := ValidateProbabilities(point.Probabilities, optionCount); err != nil {
+			return err
+		}
+		for option, p := range point.Probabilities {
+			if option == outcome {
+				brier += weights[i] * math.Pow(p-1, 2)
+			} else {
+				brier += weights[i] * math.Pow(p, 2)
+			}
+		}
+		logN += weights[i] * math.Log(point.Probabilities[outcome])
+	}
+	score.BrierScoreLifetime, score.LogNScoreLifetime = &brier, &logN
+	return nil
+}
+
+// lifetimePoints puts the prior points made before the first of the points
+// ahead of them, sorted
+func lifetimePoints[P any](points []P, prior []P, createdAt func(P) time.Time) []P {
+	sorted := make([]P, len(points))
+	copy(sorted, points)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return createdAt(sorted[i]).Before(createdAt(sorted[j]))
+	})
+
+	var held []P
+	for _, p := range prior {
+		if createdAt(p).Before(createdAt(sorted[0])) {
+			held = append(held, p)
+		}
+	}
+	sort.SliceStable(held, func(i, j int) bool {
+		return createdAt(held[i]).Before(createdAt(held[j]))
+	})
+	return append(held, sorted...)
+}
+
+// scoringCloseDate is the end of the scoring window: the closing date if the
+// forecast closed before it was resolved, otherwise the resolution time
+func scoringCloseDate(forecastClosingDate *time.Time, forecastResolvedAt *time.Time) time.Time {
+	if forecastClosingDate != nil && forecastClosingDate.Before(*forecastResolvedAt) {
+		return *forecastClosingDate
+	}
+	return *forecastResolvedAt
+}
+
+// timeWeights returns the share of the scoring window each point was held for.
+// The window runs from the first point to closeDate, so createdAts must be sorted.
+func timeWeights(createdAts []time.Time, closeDate time.Time) []float64 {
+	weights := make([]float64, len(createdAts))
+	totalTimeInForecast := closeDate.Sub(createdAts[0]).Seconds()
+
+	// Edge case: if forecast was created and resolved at the same time (or very close),
+	// fall back to naive (equal-weighted) scoring for time-weighted scores
+	if totalTimeInForecast <= 1.0 {
+		for i := range weights {
+			weights[i] = 1.0 / float64(len(createdAts))
+		}
+		return weights
+	}
+
+	for i, createdAt := range createdAts {
+		// Calculate how long this prediction was held
+		var duration float64
+		if i < len(createdAts)-1 {
+			duration = createdAts[i+1].Sub(createdAt).Seconds()
+		} else {
+			duration = closeDate.Sub(createdAt).Seconds()
+		}
+		weights[i] = duration / totalTimeInForecast
+	}
+	return weights
+}
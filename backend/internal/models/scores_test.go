@@ -470,3 +470,281 @@ func TestCalcForecastScore_UserStartedLate(t *testing.T) {
 		t.Errorf("BrierScore = %v, want %v", score.BrierScore, expectedNaive)
 	}
 }
+
+func TestCalcMultipleChoiceScore_SinglePoint(t *testing.T) {
+	forecastCreated := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	forecastResolved := time.Date(2024, 1, 8, 0, 0, 0, 0, time.UTC)
+
+	points := []ChoicePoint{
+		{Probabilities: []float64{0.6, 0.3, 0.1}, CreatedAt: forecastCreated},
+	}
+
+	score, err := CalcMultipleChoiceScore(points, 1, 3, 1, 1, forecastCreated, nil, &forecastResolved)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Multi-class Brier: (0.6-0)^2 + (0.3-1)^2 + (0.1-0)^2 = 0.36 + 0.49 + 0.01
+	expectedBrier := 0.86
+	if math.Abs(score.BrierScore-expectedBrier) > 0.0001 {
+		t.Errorf("BrierScore = %v, want %v", score.BrierScore, expectedBrier)
+	}
+	if math.Abs(score.BrierScoreTimeWeighted-expectedBrier) > 0.0001 {
+		t.Errorf("BrierScoreTimeWeighted = %v, want %v", score.BrierScoreTimeWeighted, expectedBrier)
+	}
+
+	// Log scores use the probability of the correct option
+	if math.Abs(score.Log2Score-math.Log2(0.3)) > 0.0001 {
+		t.Errorf("Log2Score = %v, want %v", score.Log2Score, math.Log2(0.3))
+	}
+	if math.Abs(score.LogNScore-math.Log(0.3)) > 0.0001 {
+		t.Errorf("LogNScore = %v, want %v", score.LogNScore, math.Log(0.3))
+	}
+}
+
+func TestCalcMultipleChoiceScore_TwoOptionsMatchesBinary(t *testing.T) {
+	// A two-option question is a binary question with twice the Brier score
+	forecastCreated := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	forecastResolved := time.Date(2024, 1, 11, 0, 0, 0, 0, time.UTC)
+
+	choicePoints := []ChoicePoint{
+		{Probabilities: []float64{0.7, 0.3}, CreatedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{Probabilities: []float64{0.2, 0.8}, CreatedAt: time.Date(2024, 1, 4, 0, 0, 0, 0, time.UTC)},
+	}
+	binaryPoints := []TimePoint{
+		{PointForecast: 0.7, CreatedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{PointForecast: 0.2, CreatedAt: time.Date(2024, 1, 4, 0, 0, 0, 0, time.UTC)},
+	}
+
+	choiceScore, err := CalcMultipleChoiceScore(choicePoints, 0, 2, 1, 1, forecastCreated, nil, &forecastResolved)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	binaryScore, err := CalcForecastScore(binaryPoints, true, 1, 1, forecastCreated, nil, &forecastResolved)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if math.Abs(choiceScore.BrierScoreTimeWeighted-2*binaryScore.BrierScoreTimeWeighted) > 0.0001 {
+		t.Errorf("BrierScoreTimeWeighted = %v, want %v", choiceScore.BrierScoreTimeWeighted, 2*binaryScore.BrierScoreTimeWeighted)
+	}
+	if math.Abs(choiceScore.LogNScoreTimeWeighted-binaryScore.LogNScoreTimeWeighted) > 0.0001 {
+		t.Errorf("LogNScoreTimeWeighted = %v, want %v", choiceScore.LogNScoreTimeWeighted, binaryScore.LogNScoreTimeWeighted)
+	}
+}
+
+func TestCalcMultipleChoiceScore_InvalidProbabilities(t *testing.T) {
+	forecastCreated := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	forecastResolved := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name          string
+		probabilities []float64
+		outcome       int
+	}{
+		{"does not sum to one", []float64{0.5, 0.4, 0.3}, 0},
+		{"wrong length", []float64{0.5, 0.5}, 0},
+		{"zero probability", []float64{0.0, 0.5, 0.5}, 1},
+		{"outcome out of range", []float64{0.2, 0.3, 0.5}, 3},
+	}
+
+	for _, tc := range testCases {
+		points := []ChoicePoint{{Probabilities: tc.probabilities, CreatedAt: forecastCreated}}
+		if _, err := CalcMultipleChoiceScore(points, tc.outcome, 3, 1, 1, forecastCreated, nil, &forecastResolved); err == nil {
+			t.Errorf("%s: expected error, got nil", tc.name)
+		}
+	}
+}
+
+func TestCalcContinuousScore_TimeWeighting(t *testing.T) {
+	forecastCreated := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	forecastResolved := time.Date(2024, 1, 11, 0, 0, 0, 0, time.UTC)
+
+	wide := Distribution{Kind: DistributionNormal, Mu: 50, Sigma: 20}
+	sharp := Distribution{Kind: DistributionNormal, Mu: 70, Sigma: 5}
+	points := []DistributionPoint{
+		{Distribution: wide, CreatedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{Distribution: sharp, CreatedAt: time.Date(2024, 1, 9, 0, 0, 0, 0, time.UTC)},
+	}
+
+	score, err := CalcContinuousScore(points, 72, 0, 100, 1, 1, forecastCreated, nil, &forecastResolved)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Wide forecast held for 8 of 10 days, sharp one for 2
+	expectedCRPS := wide.CRPS(72, 0, 100)*0.8 + sharp.CRPS(72, 0, 100)*0.2
+	if score.CRPSTimeWeighted == nil || math.Abs(*score.CRPSTimeWeighted-expectedCRPS) > 0.0001 {
+		t.Fatalf("CRPSTimeWeighted = %v, want %v", score.CRPSTimeWeighted, expectedCRPS)
+	}
+	if math.Abs(score.BrierScoreTimeWeighted-expectedCRPS/100) > 0.0001 {
+		t.Errorf("BrierScoreTimeWeighted = %v, want %v", score.BrierScoreTimeWeighted, expectedCRPS/100)
+	}
+
+	expectedCRPSNaive := (wide.CRPS(72, 0, 100) + sharp.CRPS(72, 0, 100)) / 2
+	if score.CRPS == nil || math.Abs(*score.CRPS-expectedCRPSNaive) > 0.0001 {
+		t.Errorf("CRPS = %v, want %v", score.CRPS, expectedCRPSNaive)
+	}
+
+	expectedLogN := wide.LogDensity(72, 0, 100)*0.8 + sharp.LogDensity(72, 0, 100)*0.2
+	if math.Abs(score.LogNScoreTimeWeighted-expectedLogN) > 0.0001 {
+		t.Errorf("LogNScoreTimeWeighted = %v, want %v", score.LogNScoreTimeWeighted, expectedLogN)
+	}
+	if math.Abs(score.Log2ScoreTimeWeighted-expectedLogN/math.Ln2) > 0.0001 {
+		t.Errorf("Log2ScoreTimeWeighted = %v, want %v", score.Log2ScoreTimeWeighted, expectedLogN/math.Ln2)
+	}
+}
+
+func TestCalcContinuousScore_SharperCorrectForecastScoresBetter(t *testing.T) {
+	forecastCreated := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	forecastResolved := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	sharp := []DistributionPoint{{Distribution: Distribution{Kind: DistributionQuantiles, Quantiles: []Quantile{{0.1, 3.0}, {0.5, 3.2}, {0.9, 3.4}}}, CreatedAt: forecastCreated}}
+	vague := []DistributionPoint{{Distribution: Distribution{Kind: DistributionQuantiles, Quantiles: []Quantile{{0.1, 1.0}, {0.5, 3.2}, {0.9, 5.5}}}, CreatedAt: forecastCreated}}
+
+	sharpScore, err := CalcContinuousScore(sharp, 3.25, 0, 10, 1, 1, forecastCreated, nil, &forecastResolved)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	vagueScore, err := CalcContinuousScore(vague, 3.25, 0, 10, 2, 1, forecastCreated, nil, &forecastResolved)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if sharpScore.BrierScore >= vagueScore.BrierScore {
+		t.Errorf("sharp CRPS %v should be lower than vague CRPS %v", sharpScore.BrierScore, vagueScore.BrierScore)
+	}
+	if sharpScore.LogNScore <= vagueScore.LogNScore {
+		t.Errorf("sharp log density %v should be higher than vague log density %v", sharpScore.LogNScore, vagueScore.LogNScore)
+	}
+}
+
+func TestCalcBinaryScore_FractionalOutcome(t *testing.T) {
+	forecastCreated := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	forecastResolved := time.Date(2024, 1, 8, 0, 0, 0, 0, time.UTC)
+
+	points := []TimePoint{
+		{PointForecast: 0.6, CreatedAt: forecastCreated},
+	}
+
+	score, err := CalcBinaryScore(points, 0.7, 1, 1, forecastCreated, nil, &forecastResolved)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expectedBrier := math.Pow(0.6-0.7, 2)
+	if math.Abs(score.BrierScore-expectedBrier) > 0.0001 {
+		t.Errorf("BrierScore = %v, want %v", score.BrierScore, expectedBrier)
+	}
+	expectedLog2 := 0.7*math.Log2(0.6) + 0.3*math.Log2(0.4)
+	if math.Abs(score.Log2Score-expectedLog2) > 0.0001 {
+		t.Errorf("Log2Score = %v, want %v", score.Log2Score, expectedLog2)
+	}
+
+	// outcomes of 0 and 1 score exactly like NO and YES
+	yes, _ := CalcForecastScore(points, true, 1, 1, forecastCreated, nil, &forecastResolved)
+	one, _ := CalcBinaryScore(points, 1, 1, 1, forecastCreated, nil, &forecastResolved)
+	if yes.BrierScore != one.BrierScore || yes.LogNScore != one.LogNScore {
+		t.Errorf("Expected outcome 1 to match a YES resolution, got %+v and %+v", one, yes)
+	}
+
+	if _, err := CalcBinaryScore(points, 1.2, 1, 1, forecastCreated, nil, &forecastResolved); err == nil {
+		t.Error("Expected an outcome above 1 to be rejected")
+	}
+}
+
+func TestSetBinaryLifetimeScore_UserStartedLate(t *testing.T) {
+	// The same late start as above, but held at the 0.5 prior for the first 5 days
+	forecastCreated := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	forecastResolved := time.Date(2024, 1, 11, 0, 0, 0, 0, time.UTC)
+	points := []TimePoint{
+		{PointForecast: 0.9, CreatedAt: time.Date(2024, 1, 6, 0, 0, 0, 0, time.UTC)},
+		{PointForecast: 0.1, CreatedAt: time.Date(2024, 1, 8, 0, 0, 0, 0, time.UTC)},
+	}
+
+	score, err := CalcBinaryScore(points, 1, 1, 1, forecastCreated, nil, &forecastResolved)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	prior := LifetimePrior{Probability: DefaultLifetimePrior}
+	if err := SetBinaryLifetimeScore(&score, points, prior.BinaryPoints(nil, forecastCreated), 1, nil, &forecastResolved); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Prior: 5/10, point 1: 2/10, point 2: 3/10
+	expectedBrier := math.Pow(0.5-1, 2)*0.5 + math.Pow(0.9-1, 2)*0.2 + math.Pow(0.1-1, 2)*0.3
+	if score.BrierScoreLifetime == nil || math.Abs(*score.BrierScoreLifetime-expectedBrier) > 0.0001 {
+		t.Errorf("BrierScoreLifetime = %v, want %v", score.BrierScoreLifetime, expectedBrier)
+	}
+	expectedLogN := math.Log(0.5)*0.5 + math.Log(0.9)*0.2 + math.Log(0.1)*0.3
+	if score.LogNScoreLifetime == nil || math.Abs(*score.LogNScoreLifetime-expectedLogN) > 0.0001 {
+		t.Errorf("LogNScoreLifetime = %v, want %v", score.LogNScoreLifetime, expectedLogN)
+	}
+
+	// The participation-window score is unchanged
+	expectedTimeWeighted := math.Pow(0.9-1, 2)*0.4 + math.Pow(0.1-1, 2)*0.6
+	if math.Abs(score.BrierScoreTimeWeighted-expectedTimeWeighted) > 0.0001 {
+		t.Errorf("BrierScoreTimeWeighted = %v, want %v", score.BrierScoreTimeWeighted, expectedTimeWeighted)
+	}
+}
+
+func TestSetBinaryLifetimeScore_CrowdPrior(t *testing.T) {
+	forecastCreated := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	forecastResolved := time.Date(2024, 1, 11, 0, 0, 0, 0, time.UTC)
+	crowdStart := 0.8
+	crowdLater := 0.6
+	// The crowd forms on Jan 3 and moves on Jan 9, after the user starts
+	crowd := []AggregatePoint{
+		{PointForecast: &crowdStart, CreatedAt: time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC)},
+		{PointForecast: &crowdLater, CreatedAt: time.Date(2024, 1, 9, 0, 0, 0, 0, time.UTC)},
+	}
+	points := []TimePoint{{PointForecast: 0.9, CreatedAt: time.Date(2024, 1, 6, 0, 0, 0, 0, time.UTC)}}
+
+	var score Scores
+	prior, err := ParseLifetimePrior("crowd")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := SetBinaryLifetimeScore(&score, points, prior.BinaryPoints(crowd, forecastCreated), 1, nil, &forecastResolved); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// 0.5 for 2 days, the crowd's 0.8 for 3, then the user's 0.9 for 5; the
+	// crowd's later move is ignored
+	expected := math.Pow(0.5-1, 2)*0.2 + math.Pow(0.8-1, 2)*0.3 + math.Pow(0.9-1, 2)*0.5
+	if score.BrierScoreLifetime == nil || math.Abs(*score.BrierScoreLifetime-expected) > 0.0001 {
+		t.Errorf("BrierScoreLifetime = %v, want %v", score.BrierScoreLifetime, expected)
+	}
+}
+
+func TestSetMultipleChoiceLifetimeScore_UserStartedLate(t *testing.T) {
+	forecastCreated := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	forecastResolved := time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC)
+	points := []ChoicePoint{
+		{Probabilities: []float64{0.1, 0.8, 0.1}, CreatedAt: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)},
+	}
+
+	var score Scores
+	prior := LifetimePrior{Probability: DefaultLifetimePrior}
+	if err := SetMultipleChoiceLifetimeScore(&score, points, prior.ChoicePoints(nil, 3, forecastCreated), 1, 3, nil, &forecastResolved); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// An even split for 1 day of 4, then the user's point for 3
+	expected := math.Log(1.0/3)*0.25 + math.Log(0.8)*0.75
+	if score.LogNScoreLifetime == nil || math.Abs(*score.LogNScoreLifetime-expected) > 0.0001 {
+		t.Errorf("LogNScoreLifetime = %v, want %v", score.LogNScoreLifetime, expected)
+	}
+}
+
+func TestParseLifetimePrior(t *testing.T) {
+	prior, err := ParseLifetimePrior("0.3")
+	if err != nil || prior.Probability != 0.3 || prior.Crowd {
+		t.Errorf("ParseLifetimePrior(0.3) = %+v, %v", prior, err)
+	}
+	for _, s := range []string{"", "1", "0", "-0.5", "community"} {
+		if _, err := ParseLifetimePrior(s); err == nil {
+			t.Errorf("ParseLifetimePrior(%q) should fail", s)
+		}
+	}
+}
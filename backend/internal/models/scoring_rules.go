@@ -0,0 +1,219 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ScoringRule scores a probability forecast against its outcome. Every
+// registered rule is kept for each binary and multiple choice score, so adding
+// one takes no new columns, queries or cache keys.
+type ScoringRule interface {
+	// Name is how the rule is stored and asked for, e.g. rule=brier
+	Name() string
+	// LowerIsBetter is true for losses like the Brier score
+	LowerIsBetter() bool
+	// Binary scores the probability p of YES against an outcome between 0
+	// and 1, a fractional outcome coming from a probabilistic resolution
+	Binary(p float64, outcome float64) float64
+	// MultipleChoice scores probabilities over the options against the index
+	// of the option the forecast resolved to
+	MultipleChoice(probabilities []float64, outcome int) float64
+}
+
+// RuleScore is a user's score on a forecast under a ScoringRule, averaged
+// over their points and weighted by how long each was held like the other
+// scores
+type RuleScore struct {
+	Rule              string  `json:"rule"`
+	Score             float64 `json:"score"`
+	ScoreTimeWeighted float64 `json:"score_time_weighted"`
+}
+
+// ScoringRuleInfo describes a registered rule for listing
+type ScoringRuleInfo struct {
+	Name          string `json:"name"`
+	LowerIsBetter bool   `json:"lower_is_better"`
+}
+
+var (
+	scoringRulesMu sync.RWMutex
+	scoringRules   = make(map[string]ScoringRule)
+)
+
+// RegisterScoringRule makes a rule available by its name. It panics if the
+// name is empty or taken, as registering is done once at start up.
+func RegisterScoringRule(rule ScoringRule) {
+	scoringRulesMu.Lock()
+	defer scoringRulesMu.Unlock()
+
+	name := rule.Name()
+	if name == "" {
+		panic("scoring rule has no name")
+	}
+	if _, ok := scoringRules[name]; ok {
+		panic("scoring rule registered twice: " + name)
+	}
+	scoringRules[name] = rule
+}
+
+// GetScoringRule returns the registered rule of the name
+func GetScoringRule(name string) (ScoringRule, error) {
+	scoringRulesMu.RLock()
+	defer scoringRulesMu.RUnlock()
+
+	rule, ok := scoringRules[name]
+	if !ok {
+		names := make([]string, 0, len(scoringRules))
+		for n := range scoringRules {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown scoring rule %q, expected one of %s", name, strings.Join(names, ", "))
+	}
+	return rule, nil
+}
+
+// ScoringRules returns the registered rules sorted by name
+func ScoringRules() []ScoringRule {
+	scoringRulesMu.RLock()
+	defer scoringRulesMu.RUnlock()
+
+	rules := make([]ScoringRule, 0, len(scoringRules))
+	for _, rule := range scoringRules {
+		rules = append(rules, rule)
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].Name() < rules[j].Name() })
+	return rules
+}
+
+// ruleScores scores count points under every registered rule, score giving
+// the score of the i-th point
+func ruleScores(count int, weights []float64, score func(rule ScoringRule, i int) float64) []RuleScore {
+	rules := ScoringRules()
+	scores := make([]RuleScore, len(rules))
+	for r, rule := range rules {
+		var sum, sumTimeWeighted float64
+		for i := 0; i < count; i++ {
+			s := score(rule, i)
+			sum += s
+			sumTimeWeighted += s * weights[i]
+		}
+		scores[r] = RuleScore{Rule: rule.Name(), Score: sum / float64(count), ScoreTimeWeighted: sumTimeWeighted}
+	}
+	return scores
+}
+
+// ClippedLogFloor is the lowest probability the clipped log score counts, so
+// a single confident miss costs at most ln(0.01)
+const ClippedLogFloor = 0.01
+
+func init() {
+	RegisterScoringRule(brierRule{})
+	RegisterScoringRule(logRule{})
+	RegisterScoringRule(sphericalRule{})
+	RegisterScoringRule(quadraticRule{})
+	RegisterScoringRule(clippedLogRule{})
+}
+
+// binaryExpected scores a binary point as the two options NO and YES, weighing
+// each side by the outcome
+func binaryExpected(rule ScoringRule, p float64, outcome float64) float64 {
+	probabilities := []float64{1 - p, p}
+	return outcome*rule.MultipleChoice(probabilities, 1) + (1-outcome)*rule.MultipleChoice(probabilities, 0)
+}
+
+// brierRule is the squared error; on binary forecasts only the YES side is
+// counted, as in the brier_score column
+type brierRule struct{}
+
+func (brierRule) Name() string        { return "brier" }
+func (brierRule) LowerIsBetter() bool { return true }
+
+func (brierRule) Binary(p float64, outcome float64) float64 {
+	return math.Pow(p-outcome, 2)
+}
+
+func (brierRule) MultipleChoice(probabilities []float64, outcome int) float64 {
+	var score float64
+	for option, p := range probabilities {
+		if option == outcome {
+			score += math.Pow(p-1, 2)
+		} else {
+			score += math.Pow(p, 2)
+		}
+	}
+	return score
+}
+
+// logRule is the natural log of the probability given to the outcome, as in
+// the logn_score column
+type logRule struct{}
+
+func (logRule) Name() string        { return "log" }
+func (logRule) LowerIsBetter() bool { return false }
+
+func (r logRule) Binary(p float64, outcome float64) float64 {
+	return binaryExpected(r, p, outcome)
+}
+
+func (logRule) MultipleChoice(probabilities []float64, outcome int) float64 {
+	return math.Log(probabilities[outcome])
+}
+
+// sphericalRule is the probability given to the outcome over the length of
+// the probability vector, between 0 and 1
+type sphericalRule struct{}
+
+func (sphericalRule) Name() string        { return "spherical" }
+func (sphericalRule) LowerIsBetter() bool { return false }
+
+func (r sphericalRule) Binary(p float64, outcome float64) float64 {
+	return binaryExpected(r, p, outcome)
+}
+
+func (sphericalRule) MultipleChoice(probabilities []float64, outcome int) float64 {
+	var norm float64
+	for _, p := range probabilities {
+		norm += p * p
+	}
+	return probabilities[outcome] / math.Sqrt(norm)
+}
+
+// quadraticRule is twice the probability given to the outcome less the sum of
+// the squared probabilities, between -1 and 1: the Brier score of every
+// option turned into a reward
+type quadraticRule struct{}
+
+func (quadraticRule) Name() string        { return "quadratic" }
+func (quadraticRule) LowerIsBetter() bool { return false }
+
+func (r quadraticRule) Binary(p float64, outcome float64) float64 {
+	return binaryExpected(r, p, outcome)
+}
+
+func (quadraticRule) MultipleChoice(probabilities []float64, outcome int) float64 {
+	var sumSquares float64
+	for _, p := range probabilities {
+		sumSquares += p * p
+	}
+	return 2*probabilities[outcome] - sumSquares
+}
+
+// clippedLogRule is the log score with the probability given to the outcome
+// raised to ClippedLogFloor, bounding the cost of a miss
+type clippedLogRule struct{}
+
+func (clippedLogRule) Name() string        { return "clipped_log" }
+func (clippedLogRule) LowerIsBetter() bool { return false }
+
+func (r clippedLogRule) Binary(p float64, outcome float64) float64 {
+	return binaryExpected(r, p, outcome)
+}
+
+func (clippedLogRule) MultipleChoice(probabilities []float64, outcome int) float64 {
+	return math.Log(math.Max(probabilities[outcome], ClippedLogFloor))
+}
@@ -0,0 +1,101 @@
+package models
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestScoringRules(t *testing.T) {
+	var names []string
+	for _, rule := range ScoringRules() {
+		names = append(names, rule.Name())
+	}
+	want := []string{"brier", "clipped_log", "log", "quadratic", "spherical"}
+	if len(names) != len(want) {
+		t.Fatalf("Expected the rules %v, got %v", want, names)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Errorf("Expected the rules %v sorted by name, got %v", want, names)
+		}
+	}
+
+	if _, err := GetScoringRule("ranked_probability"); err == nil {
+		t.Error("Expected an unknown rule to be rejected")
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected registering a taken name to panic")
+		}
+	}()
+	RegisterScoringRule(brierRule{})
+}
+
+func TestScoringRuleValues(t *testing.T) {
+	probabilities := []float64{0.6, 0.3, 0.1}
+	norm := math.Sqrt(0.36 + 0.09 + 0.01)
+	for name, want := range map[string]float64{
+		"brier":       0.36 + 0.49 + 0.01,
+		"log":         math.Log(0.3),
+		"spherical":   0.3 / norm,
+		"quadratic":   0.6 - 0.46,
+		"clipped_log": math.Log(0.3),
+	} {
+		rule, err := GetScoringRule(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := rule.MultipleChoice(probabilities, 1); math.Abs(got-want) > 1e-9 {
+			t.Errorf("Expected the %s score %v, got %v", name, want, got)
+		}
+	}
+
+	// a confident miss costs at most the floor
+	clipped, _ := GetScoringRule("clipped_log")
+	if got := clipped.Binary(0.001, 1); math.Abs(got-math.Log(ClippedLogFloor)) > 1e-9 {
+		t.Errorf("Expected the clipped log score to stop at ln(%v), got %v", ClippedLogFloor, got)
+	}
+	// binary quadratic is 1 - 2 * brier
+	quadratic, _ := GetScoringRule("quadratic")
+	if got := quadratic.Binary(0.7, 1); math.Abs(got-(1-2*0.09)) > 1e-9 {
+		t.Errorf("Expected the binary quadratic score %v, got %v", 1-2*0.09, got)
+	}
+}
+
+func TestCalcBinaryScore_RuleScores(t *testing.T) {
+	forecastCreated := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	forecastResolved := time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC)
+	points := []TimePoint{
+		{PointForecast: 0.2, CreatedAt: forecastCreated},
+		{PointForecast: 0.8, CreatedAt: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)},
+	}
+
+	score, err := CalcBinaryScore(points, 1, 1, 1, forecastCreated, nil, &forecastResolved)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(score.Rules) != len(ScoringRules()) {
+		t.Fatalf("Expected a score for every rule, got %+v", score.Rules)
+	}
+
+	// the brier and log rules match the fixed columns
+	for _, rs := range score.Rules {
+		switch rs.Rule {
+		case "brier":
+			if math.Abs(rs.Score-score.BrierScore) > 1e-9 || math.Abs(rs.ScoreTimeWeighted-score.BrierScoreTimeWeighted) > 1e-9 {
+				t.Errorf("Expected the brier rule to match brier_score, got %+v and %+v", rs, score)
+			}
+		case "log":
+			if math.Abs(rs.Score-score.LogNScore) > 1e-9 || math.Abs(rs.ScoreTimeWeighted-score.LogNScoreTimeWeighted) > 1e-9 {
+				t.Errorf("Expected the log rule to match logn_score, got %+v and %+v", rs, score)
+			}
+		case "spherical":
+			// 0.2 held for 1 day of 4, 0.8 for 3
+			low, high := 0.2/math.Sqrt(0.68), 0.8/math.Sqrt(0.68)
+			if math.Abs(rs.ScoreTimeWeighted-(low*0.25+high*0.75)) > 1e-9 {
+				t.Errorf("Expected a time-weighted spherical score of %v, got %+v", low*0.25+high*0.75, rs)
+			}
+		}
+	}
+}
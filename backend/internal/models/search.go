@@ -0,0 +1,49 @@
+package models
+
+// Fields search matches are found in
+const (
+	SearchFieldQuestion           = "question"
+	SearchFieldResolutionCriteria = "resolution_criteria"
+	SearchFieldComment            = "comment"
+	SearchFieldReason             = "reason"
+)
+
+const (
+	DefaultSearchLimit = 20
+	MaxSearchLimit     = 100
+	// MaxSearchReasons bounds the point reasons returned with each forecast
+	MaxSearchReasons = 3
+)
+
+// Snippets mark matching words with these
+const (
+	HighlightStart = "<mark>"
+	HighlightStop  = "</mark>"
+)
+
+type SearchFilters struct {
+	// Query is in web search syntax: words, "quoted phrases" and -excluded words
+	Query  string
+	Status *string
+	// CategoryIDs keeps forecasts in one of the categories, when not nil
+	CategoryIDs []int64
+	Limit       int
+	// Viewer keeps the forecasts listed for the user, or for anonymous callers
+	// when 0. Nil does not filter.
+	Viewer *int64
+}
+
+// SearchResult is a forecast matching a search, with where it matched
+type SearchResult struct {
+	Forecast *Forecast     `json:"forecast"`
+	Rank     float64       `json:"rank"`
+	Matches  []SearchMatch `json:"matches"`
+}
+
+// SearchMatch is a highlighted snippet of matching text
+type SearchMatch struct {
+	Field string `json:"field"`
+	// PointID is the point whose reason matched
+	PointID *int64 `json:"point_id,omitempty"`
+	Snippet string `json:"snippet"`
+}
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// RefreshToken is a stored refresh token. Each refresh replaces the token
+// with a new one in the same family, so a replaced token being presented
+// again means it leaked and the whole family is revoked.
+type RefreshToken struct {
+	ID        int64
+	UserID    int64
+	TokenHash string
+	FamilyID  string
+	// TokenVersion is the user's token version when the family was started;
+	// changing the password bumps it and so ends every family
+	TokenVersion int
+	CreatedAt    time.Time
+	ExpiresAt    time.Time
+	RevokedAt    *time.Time
+}
+
+// Session is what logging in or refreshing returns
+type Session struct {
+	AccessToken  string    `json:"token"`
+	RefreshToken string    `json:"refresh_token"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
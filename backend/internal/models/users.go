@@ -1,12 +1,35 @@
 package models
 
 import (
+	"fmt"
 	"time"
 )
 
+// Role decides which protected routes a user can call
+type Role string
+
+const (
+	RoleAdmin      Role = "admin"
+	RoleForecaster Role = "forecaster"
+	// Bots can read everything but cannot create forecasts, points or scores
+	RoleBot Role = "bot"
+)
+
+// ParseRole validates a role name
+func ParseRole(s string) (Role, error) {
+	switch r := Role(s); r {
+	case RoleAdmin, RoleForecaster, RoleBot:
+		return r, nil
+	}
+	return "", fmt.Errorf("unknown role %q", s)
+}
+
 type User struct {
 	ID        int64     `json:"id" db:"id"`
 	Username  string    `json:"username" db:"username"`
 	Password  string    `json:"-" db:"password"`
+	Role      Role      `json:"role" db:"role"`
 	CreatedAt time.Time `json:"created" db:"created"`
+	// TokenVersion is bumped to invalidate every token issued to the user
+	TokenVersion int `json:"-" db:"token_version"`
 }
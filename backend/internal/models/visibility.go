@@ -0,0 +1,61 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// Visibility determines who may see a forecast, with its points and scores
+type Visibility string
+
+const (
+	// Public forecasts are listed for everyone
+	VisibilityPublic Visibility = "public"
+	// Unlisted forecasts are open to anyone with their id, but only listed for
+	// their owner
+	VisibilityUnlisted Visibility = "unlisted"
+	// Private forecasts are only seen by their owner
+	VisibilityPrivate Visibility = "private"
+	// Shared forecasts are seen by their owner and the users in SharedWith
+	VisibilityShared Visibility = "shared"
+)
+
+// ErrForecastNotFound is returned for forecasts that do not exist or that the
+// caller may not see, so hidden forecasts are not revealed
+var ErrForecastNotFound = errors.New("forecast not found")
+
+// ValidateVisibility checks the visibility and who the forecast is shared with.
+// An empty visibility defaults to public.
+func (f *Forecast) ValidateVisibility() error {
+	switch f.Visibility {
+	case "":
+		f.Visibility = VisibilityPublic
+	case VisibilityPublic, VisibilityUnlisted, VisibilityPrivate, VisibilityShared:
+	default:
+		return fmt.Errorf("unknown visibility %q", f.Visibility)
+	}
+	if f.Visibility != VisibilityShared && len(f.SharedWith) > 0 {
+		return errors.New("only shared forecasts take shared_with")
+	}
+	return nil
+}
+
+// CanView reports whether the user, 0 for an anonymous caller, may see the
+// forecast
+func (f *Forecast) CanView(userID int64) bool {
+	return f.Visibility == VisibilityUnlisted || f.IsListedFor(userID)
+}
+
+// IsListedFor reports whether the forecast shows in the user's lists
+func (f *Forecast) IsListedFor(userID int64) bool {
+	switch {
+	case f.Visibility == VisibilityPublic || f.Visibility == "":
+		return true
+	case userID != 0 && f.UserID == userID:
+		return true
+	case f.Visibility == VisibilityShared:
+		return userID != 0 && slices.Contains(f.SharedWith, userID)
+	}
+	return false
+}
@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"backend/internal/database"
+	"backend/internal/models"
+	"context"
+	"database/sql"
+	"time"
+)
+
+// APIKeyRepository defines the interface for API key data operations
+type APIKeyRepository interface {
+	CreateAPIKey(ctx context.Context, key *models.APIKey) error
+	// GetAPIKeyByHash returns the key with the hash unless it was revoked
+	GetAPIKeyByHash(ctx context.Context, keyHash string) (*models.APIKey, error)
+	ListAPIKeys(ctx context.Context, userID int64) ([]models.APIKey, error)
+	RevokeAPIKey(ctx context.Context, id int64, userID int64) error
+	UpdateLastUsed(ctx context.Context, id int64, lastUsed time.Time) error
+}
+
+// PostgresAPIKeyRepository implements the APIKeyRepository interface
+type PostgresAPIKeyRepository struct {
+	db *database.DB
+}
+
+// NewAPIKeyRepository creates a new PostgresAPIKeyRepository instance
+func NewAPIKeyRepository(db *database.DB) APIKeyRepository {
+	return &PostgresAPIKeyRepository{db: db}
+}
+
+func (r *PostgresAPIKeyRepository) CreateAPIKey(ctx context.Context, key *models.APIKey) error {
+	key.CreatedAt = time.Now()
+
+	query := `INSERT INTO api_keys (user_id, name, prefix, key_hash, scopes, created)
+              VALUES ($1, $2, $3, $4, $5, $6)
+              RETURNING id`
+
+	return r.db.Querier(ctx).QueryRowContext(ctx, query,
+		key.UserID,
+		key.Name,
+		key.Prefix,
+		key.KeyHash,
+		key.Scopes,
+		key.CreatedAt).Scan(&key.ID)
+}
+
+func (r *PostgresAPIKeyRepository) GetAPIKeyByHash(ctx context.Context, keyHash string) (*models.APIKey, error) {
+	query := `SELECT id, user_id, name, prefix, key_hash, scopes, created, last_used, revoked
+              FROM api_keys
+              WHERE key_hash = $1 AND revoked IS NULL`
+
+	var key models.APIKey
+	err := r.db.Querier(ctx).QueryRowContext(ctx, query, keyHash).Scan(
+		&key.ID,
+		&key.UserID,
+		&key.Name,
+		&key.Prefix,
+		&key.KeyHash,
+		&key.Scopes,
+		&key.CreatedAt,
+		&key.LastUsedAt,
+		&key.RevokedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &key, nil
+}
+
+func (r *PostgresAPIKeyRepository) ListAPIKeys(ctx context.Context, userID int64) ([]models.APIKey, error) {
+	query := `SELECT id, user_id, name, prefix, scopes, created, last_used, revoked
+              FROM api_keys
+              WHERE user_id = $1
+              ORDER BY created, id`
+
+	rows, err := r.db.Querier(ctx).QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []models.APIKey
+	for rows.Next() {
+		var key models.APIKey
+		if err := rows.Scan(
+			&key.ID,
+			&key.UserID,
+			&key.Name,
+			&key.Prefix,
+			&key.Scopes,
+			&key.CreatedAt,
+			&key.LastUsedAt,
+			&key.RevokedAt,
+		); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
+
+// RevokeAPIKey revokes one of the user's keys. Revoking a key twice keeps the
+// first revocation time.
+func (r *PostgresAPIKeyRepository) RevokeAPIKey(ctx context.Context, id int64, userID int64) error {
+	query := `UPDATE api_keys SET revoked = COALESCE(revoked, $3) WHERE id = $1 AND user_id = $2`
+
+	result, err := r.db.Querier(ctx).ExecContext(ctx, query, id, userID, time.Now())
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+func (r *PostgresAPIKeyRepository) UpdateLastUsed(ctx context.Context, id int64, lastUsed time.Time) error {
+	query := `UPDATE api_keys SET last_used = $2 WHERE id = $1`
+
+	_, err := r.db.Querier(ctx).ExecContext(ctx, query, id, lastUsed)
+	return err
+}
@@ -15,6 +15,9 @@ import (
 type CalibrationRepository interface {
 	GetCalibrationData(ctx context.Context, filters models.CalibrationFilters) (*models.CalibrationData, error)
 	GetCalibrationDataByUsers(ctx context.Context, filters models.CalibrationFilters) ([]models.UserCalibrationData, error)
+	// GetCalibrationPoints returns the points the calibration is built from,
+	// oldest first
+	GetCalibrationPoints(ctx context.Context, filters models.CalibrationFilters) ([]models.CalibrationPoint, error)
 }
 
 // PostgresCalibrationRepository implements the CalibrationRepository interface
@@ -27,20 +30,22 @@ func NewCalibrationRepository(db *database.DB) CalibrationRepository {
 	return &PostgresCalibrationRepository{db: db}
 }
 
-func buildCalibrationBaseQuery(filters models.CalibrationFilters, groupByUser bool) (string, []any) {
+// calibrationConditions selects the points of resolved binary forecasts
+// matching the filters
+func calibrationConditions(filters models.CalibrationFilters) ([]string, []any) {
 	args := []any{}
 	argsCounter := 1
 
-	whereConditions := []string{"f.resolution IN ('0', '1')"}
+	whereConditions := []string{"f.resolution IN ('0', '1')", "f.question_type = 'binary'"}
 
 	if filters.UserID != nil {
 		whereConditions = append(whereConditions, fmt.Sprintf("p.user_id = $%d", argsCounter))
 		args = append(args, *filters.UserID)
 		argsCounter++
 	}
-	if filters.Category != nil {
-		whereConditions = append(whereConditions, fmt.Sprintf("lower(f.category) LIKE $%d", argsCounter))
-		args = append(args, "%"+*filters.Category+"%")
+	if filters.CategoryIDs != nil {
+		whereConditions = append(whereConditions, fmt.Sprintf("f.category_id = any($%d)", argsCounter))
+		args = append(args, filters.CategoryIDs)
 		argsCounter++
 	}
 	if filters.StartDate != nil {
@@ -53,6 +58,26 @@ func buildCalibrationBaseQuery(filters models.CalibrationFilters, groupByUser bo
 		args = append(args, *filters.EndDate)
 		argsCounter++
 	}
+	if filters.Viewer != nil {
+		whereConditions = append(whereConditions, visibilityCondition("f.", argsCounter, false))
+		args = append(args, *filters.Viewer)
+		argsCounter++
+	}
+	return whereConditions, args
+}
+
+// calibrationBins returns the number of equal-width buckets of the filters
+func calibrationBins(filters models.CalibrationFilters) int {
+	if filters.Bins > 0 {
+		return filters.Bins
+	}
+	return models.DefaultCalibrationBins
+}
+
+func buildCalibrationBaseQuery(filters models.CalibrationFilters, groupByUser bool) (string, []any) {
+	whereConditions, args := calibrationConditions(filters)
+	// forecasts of 1 fall in the last bucket
+	bucketStart := fmt.Sprintf("LEAST(FLOOR(point_forecast * %[1]d), %[1]d - 1) / %[1]d", calibrationBins(filters))
 
 	userIDSelect := ""
 	userIDGroupBy := ""
@@ -74,15 +99,36 @@ WITH point_outcomes AS (
 )
 SELECT
     %s
-    FLOOR(point_forecast * 10) / 10 as bucket_start,
+    %s as bucket_start,
     COUNT(*) as prediction_count,
     AVG(point_forecast) as avg_prediction,
     AVG(outcome::float) as actual_rate,
     COUNT(DISTINCT forecast_id) as forecast_count
 FROM point_outcomes
-GROUP BY %s FLOOR(point_forecast * 10) / 10
+GROUP BY %s %s
 ORDER BY %s bucket_start
-`, strings.Join(whereConditions, " AND "), userIDSelect, userIDGroupBy, userIDSelect)
+`, strings.Join(whereConditions, " AND "), userIDSelect, bucketStart, userIDGroupBy, bucketStart, userIDSelect)
+
+	return query, args
+}
+
+func buildCalibrationPointsQuery(filters models.CalibrationFilters) (string, []any) {
+	whereConditions, args := calibrationConditions(filters)
+
+	query := fmt.Sprintf(`
+SELECT
+    p.user_id,
+    p.forecast_id,
+    p.point_forecast,
+    CASE WHEN f.resolution = '1' THEN 1 ELSE 0 END as outcome,
+    p.created,
+    f.closing_date,
+    COALESCE(f.resolved, p.created)
+FROM points p
+INNER JOIN forecasts f ON p.forecast_id = f.id
+WHERE %s
+ORDER BY p.created, p.id
+`, strings.Join(whereConditions, " AND "))
 
 	return query, args
 }
@@ -93,7 +139,7 @@ func (r *PostgresCalibrationRepository) GetCalibrationData(ctx context.Context,
 	query, args := buildCalibrationBaseQuery(filters, false)
 
 	start := time.Now()
-	rows, err := r.db.QueryContext(ctx, query, args...)
+	rows, err := r.db.Querier(ctx).QueryContext(ctx, query, args...)
 	if err != nil {
 		log.Error("failed to execute calibration query", slog.String("error", err.Error()))
 		return nil, err
@@ -117,7 +163,7 @@ func (r *PostgresCalibrationRepository) GetCalibrationData(ctx context.Context,
 			log.Error("failed to scan calibration row", slog.String("error", err.Error()))
 			return nil, err
 		}
-		bucket.BucketEnd = bucket.BucketStart + 0.1
+		bucket.BucketEnd = bucket.BucketStart + 1/float64(calibrationBins(filters))
 		buckets = append(buckets, bucket)
 		totalPredictions += bucket.PredictionCount
 		totalForecasts += forecastCount
@@ -141,7 +187,7 @@ func (r *PostgresCalibrationRepository) GetCalibrationDataByUsers(ctx context.Co
 	query, args := buildCalibrationBaseQuery(filters, true)
 
 	start := time.Now()
-	rows, err := r.db.QueryContext(ctx, query, args...)
+	rows, err := r.db.Querier(ctx).QueryContext(ctx, query, args...)
 	if err != nil {
 		log.Error("failed to execute calibration by users query", slog.String("error", err.Error()))
 		return nil, err
@@ -167,7 +213,7 @@ func (r *PostgresCalibrationRepository) GetCalibrationDataByUsers(ctx context.Co
 			log.Error("failed to scan calibration by users row", slog.String("error", err.Error()))
 			return nil, err
 		}
-		bucket.BucketEnd = bucket.BucketStart + 0.1
+		bucket.BucketEnd = bucket.BucketStart + 1/float64(calibrationBins(filters))
 
 		if _, exists := userDataMap[userID]; !exists {
 			userDataMap[userID] = &models.UserCalibrationData{
@@ -194,3 +240,29 @@ func (r *PostgresCalibrationRepository) GetCalibrationDataByUsers(ctx context.Co
 	log.Info("calibration by users query results", slog.Int("user_count", len(result)))
 	return result, nil
 }
+
+func (r *PostgresCalibrationRepository) GetCalibrationPoints(ctx context.Context, filters models.CalibrationFilters) ([]models.CalibrationPoint, error) {
+	log := logger.FromContext(ctx)
+
+	query, args := buildCalibrationPointsQuery(filters)
+
+	start := time.Now()
+	rows, err := r.db.Querier(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		log.Error("failed to execute calibration points query", slog.String("error", err.Error()))
+		return nil, err
+	}
+	log.Info("executed calibration points query", slog.Duration("duration", time.Since(start)))
+	defer rows.Close()
+
+	points := []models.CalibrationPoint{}
+	for rows.Next() {
+		var p models.CalibrationPoint
+		if err := rows.Scan(&p.UserID, &p.ForecastID, &p.PointForecast, &p.Outcome, &p.CreatedAt, &p.ClosingDate, &p.ResolvedAt); err != nil {
+			log.Error("failed to scan calibration point", slog.String("error", err.Error()))
+			return nil, err
+		}
+		points = append(points, p)
+	}
+	return points, rows.Err()
+}
@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"backend/internal/database"
+	"backend/internal/models"
+	"context"
+	"database/sql"
+	"time"
+)
+
+// CategoryRepository defines the interface for the category and tag taxonomy
+type CategoryRepository interface {
+	// GetCategories returns every category, ordered by name
+	GetCategories(ctx context.Context) ([]*models.Category, error)
+	GetCategoryByID(ctx context.Context, id int64) (*models.Category, error)
+	CreateCategory(ctx context.Context, c *models.Category) error
+	// UpdateCategory renames or moves a category, renaming its forecasts' category
+	UpdateCategory(ctx context.Context, c *models.Category) error
+	DeleteCategory(ctx context.Context, id int64) error
+	// CountForecasts counts the forecasts directly in the category
+	CountForecasts(ctx context.Context, id int64) (int, error)
+	// GetTags returns the tags in use, ordered by name
+	GetTags(ctx context.Context) ([]models.Tag, error)
+}
+
+// PostgresCategoryRepository implements the CategoryRepository interface
+type PostgresCategoryRepository struct {
+	db *database.DB
+}
+
+// NewCategoryRepository creates a new PostgresCategoryRepository instance
+func NewCategoryRepository(db *database.DB) CategoryRepository {
+	return &PostgresCategoryRepository{db: db}
+}
+
+func (r *PostgresCategoryRepository) GetCategories(ctx context.Context) ([]*models.Category, error) {
+	query := `SELECT id, name, parent_id, created
+              FROM categories
+              ORDER BY lower(name), id`
+
+	rows, err := r.db.Querier(ctx).QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	categories := []*models.Category{}
+	for rows.Next() {
+		var c models.Category
+		if err := rows.Scan(&c.ID, &c.Name, &c.ParentID, &c.CreatedAt); err != nil {
+			return nil, err
+		}
+		categories = append(categories, &c)
+	}
+
+	return categories, rows.Err()
+}
+
+func (r *PostgresCategoryRepository) GetCategoryByID(ctx context.Context, id int64) (*models.Category, error) {
+	query := `SELECT id, name, parent_id, created FROM categories WHERE id = $1`
+
+	var c models.Category
+	err := r.db.Querier(ctx).QueryRowContext(ctx, query, id).Scan(&c.ID, &c.Name, &c.ParentID, &c.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (r *PostgresCategoryRepository) CreateCategory(ctx context.Context, c *models.Category) error {
+	c.CreatedAt = time.Now()
+
+	query := `INSERT INTO categories (name, parent_id, created)
+              VALUES ($1, $2, $3)
+              RETURNING id`
+
+	return r.db.Querier(ctx).QueryRowContext(ctx, query, c.Name, c.ParentID, c.CreatedAt).Scan(&c.ID)
+}
+
+func (r *PostgresCategoryRepository) UpdateCategory(ctx context.Context, c *models.Category) error {
+	return r.db.WithinTx(ctx, func(ctx context.Context) error {
+		query := `UPDATE categories SET name = $1, parent_id = $2 WHERE id = $3`
+		result, err := r.db.Querier(ctx).ExecContext(ctx, query, c.Name, c.ParentID, c.ID)
+		if err != nil {
+			return err
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rowsAffected == 0 {
+			return sql.ErrNoRows
+		}
+
+		query = `UPDATE forecasts SET category = $1 WHERE category_id = $2`
+		_, err = r.db.Querier(ctx).ExecContext(ctx, query, c.Name, c.ID)
+		return err
+	})
+}
+
+func (r *PostgresCategoryRepository) DeleteCategory(ctx context.Context, id int64) error {
+	result, err := r.db.Querier(ctx).ExecContext(ctx, `DELETE FROM categories WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *PostgresCategoryRepository) CountForecasts(ctx context.Context, id int64) (int, error) {
+	var count int
+	err := r.db.Querier(ctx).QueryRowContext(ctx, `SELECT count(*) FROM forecasts WHERE category_id = $1`, id).Scan(&count)
+	return count, err
+}
+
+func (r *PostgresCategoryRepository) GetTags(ctx context.Context) ([]models.Tag, error) {
+	query := `SELECT t.name, count(*)
+              FROM tags t
+              JOIN forecast_tags ft ON ft.tag_id = t.id
+              GROUP BY t.name
+              ORDER BY t.name`
+
+	rows, err := r.db.Querier(ctx).QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []models.Tag{}
+	for rows.Next() {
+		var tag models.Tag
+		if err := rows.Scan(&tag.Name, &tag.Forecasts); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+
+	return tags, rows.Err()
+}
@@ -0,0 +1,186 @@
+package repository
+
+import (
+	"backend/internal/database"
+	"backend/internal/logger"
+	"backend/internal/models"
+	"context"
+	"log/slog"
+	"time"
+)
+
+// CommentRepository defines the interface for comment data operations
+type CommentRepository interface {
+	GetComments(ctx context.Context, filters models.CommentFilters) ([]*models.Comment, error)
+	GetCommentByID(ctx context.Context, id int64) (*models.Comment, error)
+	CreateComment(ctx context.Context, c *models.Comment) error
+	// UpdateComment saves the body, edit and deletion times of a comment
+	UpdateComment(ctx context.Context, c *models.Comment) error
+	// SetCommentMentions replaces the users a comment mentions, skipping
+	// usernames no user has
+	SetCommentMentions(ctx context.Context, id int64, usernames []string) error
+}
+
+// PostgresCommentRepository implements the CommentRepository interface
+type PostgresCommentRepository struct {
+	db *database.DB
+}
+
+// NewCommentRepository creates a new PostgresCommentRepository instance
+func NewCommentRepository(db *database.DB) CommentRepository {
+	return &PostgresCommentRepository{db: db}
+}
+
+const commentSelect = `select
+		c.id, c.forecast_id, c.parent_id, c.user_id, u.username, c.body, c.created, c.edited, c.deleted
+		from comments c
+		left join users u on u.id = c.user_id`
+
+func buildCommentQuery(filters models.CommentFilters) (string, error) {
+	query := commentSelect + `
+		where c.forecast_id = $1`
+	if filters.Page != nil {
+		return paginate(query, *filters.Page, commentSorts, 2)
+	}
+	return query + `
+		order by c.created, c.id`, nil
+}
+
+func (r *PostgresCommentRepository) GetComments(ctx context.Context, filters models.CommentFilters) ([]*models.Comment, error) {
+	log := logger.FromContext(ctx)
+
+	query, err := buildCommentQuery(filters)
+	if err != nil {
+		return nil, err
+	}
+	args := append([]any{filters.ForecastID}, pageArgs(filters.Page)...)
+
+	start := time.Now()
+	rows, err := r.db.Querier(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	log.Info("executed query", slog.Duration("duration", time.Since(start)), slog.Bool("success", err == nil))
+	defer rows.Close()
+
+	comments := []*models.Comment{}
+	for rows.Next() {
+		c, err := scanComment(rows)
+		if err != nil {
+			return nil, err
+		}
+		comments = append(comments, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	log.Info("query results", slog.Int("count", len(comments)))
+	return comments, r.loadMentions(ctx, comments)
+}
+
+func (r *PostgresCommentRepository) GetCommentByID(ctx context.Context, id int64) (*models.Comment, error) {
+	row := r.db.Querier(ctx).QueryRowContext(ctx, commentSelect+` where c.id = $1`, id)
+	c, err := scanComment(row)
+	if err != nil {
+		return nil, err
+	}
+	return c, r.loadMentions(ctx, []*models.Comment{c})
+}
+
+func (r *PostgresCommentRepository) CreateComment(ctx context.Context, c *models.Comment) error {
+	c.CreatedAt = time.Now()
+
+	query := `INSERT INTO comments (forecast_id
+					, parent_id
+					, user_id
+					, body
+					, created)
+              VALUES ($1, $2, $3, $4, $5)
+              RETURNING id`
+
+	return r.db.Querier(ctx).QueryRowContext(ctx, query,
+		c.ForecastID,
+		c.ParentID,
+		c.UserID,
+		c.Body,
+		c.CreatedAt).Scan(&c.ID)
+}
+
+func (r *PostgresCommentRepository) UpdateComment(ctx context.Context, c *models.Comment) error {
+	query := `UPDATE comments SET
+				body = $1
+				, edited = $2
+				, deleted = $3
+			 WHERE id = $4`
+
+	_, err := r.db.Querier(ctx).ExecContext(ctx, query, c.Body, c.EditedAt, c.DeletedAt, c.ID)
+	return err
+}
+
+func (r *PostgresCommentRepository) SetCommentMentions(ctx context.Context, id int64, usernames []string) error {
+	return r.db.WithinTx(ctx, func(ctx context.Context) error {
+		if _, err := r.db.Querier(ctx).ExecContext(ctx, `DELETE FROM comment_mentions WHERE comment_id = $1`, id); err != nil {
+			return err
+		}
+		if len(usernames) == 0 {
+			return nil
+		}
+		query := `INSERT INTO comment_mentions (comment_id, user_id) SELECT $1, id FROM users WHERE username = any($2)`
+		_, err := r.db.Querier(ctx).ExecContext(ctx, query, id, usernames)
+		return err
+	})
+}
+
+// scanComment reads a row selected by commentSelect
+func scanComment(row interface{ Scan(...any) error }) (*models.Comment, error) {
+	var c models.Comment
+	err := row.Scan(
+		&c.ID,
+		&c.ForecastID,
+		&c.ParentID,
+		&c.UserID,
+		&c.UserName,
+		&c.Body,
+		&c.CreatedAt,
+		&c.EditedAt,
+		&c.DeletedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// loadMentions fills in the users the comments mention
+func (r *PostgresCommentRepository) loadMentions(ctx context.Context, comments []*models.Comment) error {
+	if len(comments) == 0 {
+		return nil
+	}
+	byID := make(map[int64]*models.Comment, len(comments))
+	ids := make([]int64, 0, len(comments))
+	for _, c := range comments {
+		byID[c.ID] = c
+		ids = append(ids, c.ID)
+	}
+
+	query := `SELECT comment_id, user_id
+			  FROM comment_mentions
+			  WHERE comment_id = any($1)
+			  ORDER BY comment_id, user_id`
+	rows, err := r.db.Querier(ctx).QueryContext(ctx, query, ids)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id, userID int64
+		if err := rows.Scan(&id, &userID); err != nil {
+			return err
+		}
+		if c, ok := byID[id]; ok {
+			c.Mentions = append(c.Mentions, userID)
+		}
+	}
+	return rows.Err()
+}
@@ -43,6 +43,8 @@ func buildForecastPointQuery(filters models.PointFilters) (string, error) {
 		"p.id",
 		"p.forecast_id",
 		"p.point_forecast",
+		"p.probabilities",
+		"p.distribution",
 		"p.reason",
 		"p.created",
 		"p.user_id",
@@ -73,6 +75,12 @@ func buildForecastPointQuery(filters models.PointFilters) (string, error) {
 		whereConditions = append(whereConditions, "p.created < "+fmt.Sprintf("$%d", argsCounter))
 		argsCounter++
 	}
+	if filters.Viewer != nil {
+		// a forecast named by its id needs not be listed for the viewer
+		visible := visibilityCondition("f.", argsCounter, filters.ForecastID == nil)
+		whereConditions = append(whereConditions, "p.forecast_id in (select f.id from forecasts f where "+visible+")")
+		argsCounter++
+	}
 
 	// finally order by statements
 	orderBy := []string{}
@@ -101,6 +109,9 @@ func buildForecastPointQuery(filters models.PointFilters) (string, error) {
 		strings.Join(orderBy, ", "),
 	)
 
+	if filters.Page != nil {
+		return paginate(query, *filters.Page, pointSorts, argsCounter)
+	}
 	return query, nil
 }
 
@@ -123,9 +134,13 @@ func (r *PostgresForecastPointRepository) GetForecastPoints(ctx context.Context,
 		args = append(args, *filters.Date)
 		args = append(args, filters.Date.AddDate(0, 0, 1))
 	}
+	if filters.Viewer != nil {
+		args = append(args, *filters.Viewer)
+	}
+	args = append(args, pageArgs(filters.Page)...)
 
 	start := time.Now()
-	rows, err := r.db.QueryContext(ctx, query, args...)
+	rows, err := r.db.Querier(ctx).QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -139,6 +154,8 @@ func (r *PostgresForecastPointRepository) GetForecastPoints(ctx context.Context,
 		if err := rows.Scan(&fp.ID,
 			&fp.ForecastID,
 			&fp.PointForecast,
+			&fp.Probabilities,
+			&fp.Distribution,
 			&fp.Reason,
 			&fp.CreatedAt,
 			&fp.UserID,
@@ -156,12 +173,14 @@ func (r *PostgresForecastPointRepository) CreateForecastPoint(ctx context.Contex
 
 	query := `INSERT INTO points (forecast_id
 											, point_forecast
+											, probabilities
+											, distribution
 											, created
 											, reason
 											, user_id)
-				VALUES ($1, $2, $3, $4, $5)
+				VALUES ($1, $2, $3, $4, $5, $6, $7)
 				RETURNING id`
 
-	err := r.db.QueryRowContext(ctx, query, fp.ForecastID, fp.PointForecast, fp.CreatedAt, fp.Reason, fp.UserID).Scan(&fp.ID)
+	err := r.db.Querier(ctx).QueryRowContext(ctx, query, fp.ForecastID, fp.PointForecast, fp.Probabilities, fp.Distribution, fp.CreatedAt, fp.Reason, fp.UserID).Scan(&fp.ID)
 	return err
 }
@@ -5,6 +5,7 @@ import (
 	"backend/internal/logger"
 	"backend/internal/models"
 	"context"
+	"database/sql"
 	"fmt"
 	"log/slog"
 	"strings"
@@ -18,11 +19,23 @@ type ForecastRepository interface {
 	GetForecasts(ctx context.Context, filters models.ForecastFilters) ([]*models.Forecast, error)
 	GetForecastByID(ctx context.Context, id int64) (*models.Forecast, error)
 	CheckForecastOwnership(ctx context.Context, id int64, userID int64) (bool, error)
+	// CheckForecastStatus reports whether the forecast is open. Within a
+	// transaction it locks the forecast until the transaction ends, so
+	// concurrent resolutions and new points see each other's changes.
 	CheckForecastStatus(ctx context.Context, id int64) (bool, error)
 	CreateForecast(ctx context.Context, f *models.Forecast) error
 	UpdateForecast(ctx context.Context, f *models.Forecast) error
+	// SetForecastTags replaces the tags of a forecast, creating missing ones
+	SetForecastTags(ctx context.Context, id int64, tags []string) error
+	// SetForecastShares replaces the users a forecast is shared with, skipping
+	// users that do not exist
+	SetForecastShares(ctx context.Context, id int64, userIDs []int64) error
 	DeleteForecast(ctx context.Context, id int64, userID int64) error
-	GetStaleAndNewForecasts(ctx context.Context, userID int64) ([]*models.Forecast, error)
+	// GetStaleAndNewForecasts returns the open forecasts listed for the viewer
+	// that the user has not forecast on lately
+	GetStaleAndNewForecasts(ctx context.Context, userID int64, viewer int64) ([]*models.Forecast, error)
+	// SearchForecasts ranks the forecasts whose text or point reasons match
+	SearchForecasts(ctx context.Context, filters models.SearchFilters) ([]models.SearchResult, error)
 }
 
 // PostgresForecastRepository implements the ForecastRepository interface
@@ -40,13 +53,20 @@ func buildForecastQuery(filters models.ForecastFilters) (string, error) {
 		"id",
 		"question",
 		"category",
+		"category_id",
 		"created",
 		"user_id",
 		"resolution_criteria",
 		"closing_date",
 		"resolution",
+		"resolution_value",
 		"resolved",
 		"comment",
+		"question_type",
+		"options",
+		"range_min",
+		"range_max",
+		"visibility",
 	}
 
 	fromClause := "forecasts"
@@ -59,22 +79,25 @@ func buildForecastQuery(filters models.ForecastFilters) (string, error) {
 	}
 
 	//status filtering
-	switch {
-	case filters.Status != nil && *filters.Status == "open":
-		whereConditions = append(whereConditions, "resolved is null")
-	case filters.Status != nil && *filters.Status == "resolved":
-		whereConditions = append(whereConditions, "resolved is not null")
-	case filters.Status != nil && *filters.Status == "open":
-		whereConditions = append(whereConditions, "current_date < closing_date")
-	case filters.Status != nil && *filters.Status == "closed":
-		whereConditions = append(whereConditions, "current_date > closing_date")
-	default:
-		// Do nothing if there is no status
+	if condition := statusCondition(filters.Status, ""); condition != "" {
+		whereConditions = append(whereConditions, condition)
 	}
 
 	//category filtering
-	if filters.Category != nil {
-		whereConditions = append(whereConditions, "lower(category) like "+fmt.Sprintf("$%d", argsCounter))
+	if filters.CategoryIDs != nil {
+		whereConditions = append(whereConditions, "category_id = any("+fmt.Sprintf("$%d", argsCounter)+")")
+		argsCounter++
+	}
+
+	//tag filtering
+	if filters.Tag != nil {
+		whereConditions = append(whereConditions, "id in (select ft.forecast_id from forecast_tags ft join tags t on t.id = ft.tag_id where t.name = "+fmt.Sprintf("$%d", argsCounter)+")")
+		argsCounter++
+	}
+
+	//visibility filtering
+	if filters.Viewer != nil {
+		whereConditions = append(whereConditions, visibilityCondition("", argsCounter, true))
 		argsCounter++
 	}
 
@@ -88,24 +111,61 @@ func buildForecastQuery(filters models.ForecastFilters) (string, error) {
 		strings.Join(whereConditions, " and "),
 	)
 
+	if filters.Page != nil {
+		return paginate(query, *filters.Page, forecastSorts, argsCounter)
+	}
 	return query, nil
 }
 
-func (r *PostgresForecastRepository) GetForecasts(ctx context.Context, filters models.ForecastFilters) ([]*models.Forecast, error) {
-	if filters.Category != nil && *filters.Category != "" {
-		categoryPattern := "%" + *filters.Category + "%"
-		filters.Category = &categoryPattern
+// statusCondition is the where condition of a status filter on forecasts,
+// whose columns are qualified with the prefix
+func statusCondition(status *string, prefix string) string {
+	if status == nil {
+		return ""
+	}
+	switch *status {
+	case "open":
+		return prefix + "resolved is null"
+	case "resolved":
+		return prefix + "resolved is not null"
+	case "closed":
+		return "current_date > " + prefix + "closing_date"
 	}
+	return ""
+}
 
+// visibilityCondition is the where condition keeping the forecasts the viewer,
+// the argument at index arg, may see, or only those listed for them, see
+// models.Forecast.CanView and IsListedFor. Forecast columns are qualified
+// with the prefix.
+func visibilityCondition(prefix string, arg int, listed bool) string {
+	open := prefix + "visibility = 'public'"
+	if !listed {
+		open = prefix + "visibility in ('public', 'unlisted')"
+	}
+	return fmt.Sprintf(
+		"(%[1]s or %[2]suser_id = $%[3]d or (%[2]svisibility = 'shared' and %[2]sid in (select fs.forecast_id from forecast_shares fs where fs.user_id = $%[3]d)))",
+		open, prefix, arg,
+	)
+}
+
+func (r *PostgresForecastRepository) GetForecasts(ctx context.Context, filters models.ForecastFilters) ([]*models.Forecast, error) {
 	query, err := buildForecastQuery(filters)
 	if err != nil {
 		return nil, err
 	}
 
 	args := []any{}
-	if filters.Category != nil {
-		args = append(args, *filters.Category)
+	if filters.CategoryIDs != nil {
+		args = append(args, filters.CategoryIDs)
+	}
+	if filters.Tag != nil {
+		args = append(args, *filters.Tag)
 	}
+	if filters.Viewer != nil {
+		args = append(args, *filters.Viewer)
+	}
+	args = append(args, pageArgs(filters.Page)...)
 
 	return r.queryForecasts(ctx, query, args...)
 }
@@ -120,27 +180,37 @@ func (r *PostgresForecastRepository) GetForecastByID(ctx context.Context, id int
 
 	start := time.Now()
 	var forecast models.Forecast
-	err = r.db.QueryRowContext(ctx, query, id).Scan(&forecast.ID,
+	err = r.db.Querier(ctx).QueryRowContext(ctx, query, id).Scan(&forecast.ID,
 		&forecast.Question,
 		&forecast.Category,
+		&forecast.CategoryID,
 		&forecast.CreatedAt,
 		&forecast.UserID,
 		&forecast.ResolutionCriteria,
 		&forecast.ClosingDate,
 		&forecast.Resolution,
+		&forecast.ResolutionValue,
 		&forecast.ResolvedAt,
-		&forecast.ResolutionComment)
+		&forecast.ResolutionComment,
+		&forecast.QuestionType,
+		&forecast.Options,
+		&forecast.RangeMin,
+		&forecast.RangeMax,
+		&forecast.Visibility)
 	if err != nil {
 		return nil, err
 	}
 	log.Info("executed query", slog.Duration("duration", time.Since(start)), slog.Bool("success", err == nil))
+	if err := r.loadRelations(ctx, []*models.Forecast{&forecast}); err != nil {
+		return nil, err
+	}
 	return &forecast, nil
 }
 
 func (r *PostgresForecastRepository) CheckForecastOwnership(ctx context.Context, id int64, user_id int64) (bool, error) {
 	query := `SELECT user_id FROM forecasts WHERE id = $1`
 	var forecastUserID int64
-	err := r.db.QueryRowContext(ctx, query, id).Scan(&forecastUserID)
+	err := r.db.Querier(ctx).QueryRowContext(ctx, query, id).Scan(&forecastUserID)
 	if err != nil {
 		return false, err
 	}
@@ -149,9 +219,9 @@ func (r *PostgresForecastRepository) CheckForecastOwnership(ctx context.Context,
 }
 
 func (r *PostgresForecastRepository) CheckForecastStatus(ctx context.Context, id int64) (bool, error) {
-	query := `SELECT (resolved is null) FROM forecasts WHERE id = $1`
+	query := `SELECT (resolved is null) FROM forecasts WHERE id = $1 FOR UPDATE`
 	var resolved bool
-	err := r.db.QueryRowContext(ctx, query, id).Scan(&resolved)
+	err := r.db.Querier(ctx).QueryRowContext(ctx, query, id).Scan(&resolved)
 
 	return resolved, err
 }
@@ -162,90 +232,119 @@ func (r *PostgresForecastRepository) CreateForecast(ctx context.Context, f *mode
 	query := `INSERT INTO forecasts (
 				question
 				, category
+				, category_id
 				, created
 				, user_id
 				, resolution_criteria
 				, closing_date
+				, question_type
+				, options
+				, range_min
+				, range_max
+				, visibility
 				)
-				VALUES ($1, $2, $3, $4, $5, $6) 
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12) 
 				RETURNING id`
 
-	err := r.db.QueryRowContext(ctx, query, f.Question, f.Category, f.CreatedAt, f.UserID, f.ResolutionCriteria, f.ClosingDate).Scan(&f.ID)
-	return err
-}
-
-func (r *PostgresForecastRepository) UpdateForecast(ctx context.Context, f *models.Forecast) error {
-	tx, err := r.db.BeginTx(ctx, nil)
-	if err != nil {
-		return err
-	}
-
-	defer func() {
+	return r.db.WithinTx(ctx, func(ctx context.Context) error {
+		err := r.db.Querier(ctx).QueryRowContext(ctx, query, f.Question, f.Category, f.CategoryID, f.CreatedAt, f.UserID, f.ResolutionCriteria, f.ClosingDate, f.QuestionType, f.Options, f.RangeMin, f.RangeMax, f.Visibility).Scan(&f.ID)
 		if err != nil {
-			tx.Rollback()
+			return err
 		}
-	}()
+		if err := r.SetForecastTags(ctx, f.ID, f.Tags); err != nil {
+			return err
+		}
+		return r.SetForecastShares(ctx, f.ID, f.SharedWith)
+	})
+}
 
+func (r *PostgresForecastRepository) UpdateForecast(ctx context.Context, f *models.Forecast) error {
 	query := `UPDATE forecasts SET
 				question = $1
 				, category = $2
-				, resolution_criteria = $3
-				, closing_date = $4
-				, resolution = $5
-				, resolved = $6
-				, comment = $7
-			 WHERE id = $8`
-
-	_, err = tx.ExecContext(ctx, query,
+				, category_id = $3
+				, resolution_criteria = $4
+				, closing_date = $5
+				, resolution = $6
+				, resolution_value = $7
+				, resolved = $8
+				, comment = $9
+				, visibility = $10
+			 WHERE id = $11`
+
+	_, err := r.db.Querier(ctx).ExecContext(ctx, query,
 		f.Question,
 		f.Category,
+		f.CategoryID,
 		f.ResolutionCriteria,
 		f.ClosingDate,
 		f.Resolution,
+		f.ResolutionValue,
 		f.ResolvedAt,
 		f.ResolutionComment,
+		f.Visibility,
 		f.ID,
 	)
-	if err != nil {
-		return err
-	}
-
-	err = tx.Commit()
 	return err
 }
 
-// user_id filtered methods
-func (r *PostgresForecastRepository) DeleteForecast(ctx context.Context, id int64, user_id int64) error {
-	tx, err := r.db.BeginTx(ctx, nil)
-	if err != nil {
-		return err
-	}
+func (r *PostgresForecastRepository) SetForecastTags(ctx context.Context, id int64, tags []string) error {
+	return r.db.WithinTx(ctx, func(ctx context.Context) error {
+		if _, err := r.db.Querier(ctx).ExecContext(ctx, `DELETE FROM forecast_tags WHERE forecast_id = $1`, id); err != nil {
+			return err
+		}
+		if len(tags) == 0 {
+			return nil
+		}
 
-	defer func() {
-		if err != nil {
-			tx.Rollback()
+		query := `INSERT INTO tags (name) SELECT unnest($1::text[]) ON CONFLICT (name) DO NOTHING`
+		if _, err := r.db.Querier(ctx).ExecContext(ctx, query, tags); err != nil {
+			return err
 		}
-	}()
-	//if this delete fails, due to user_id not owning forecast, return and do not delete forecast points.
-	//also checking this in service, but this adds redundancy since I delete all user forecast points.
-	queryForecasts := `DELETE FROM forecasts WHERE id = $1 and user_id = $2`
-	_, err = tx.ExecContext(ctx, queryForecasts, id, user_id)
-	if err != nil {
+		query = `INSERT INTO forecast_tags (forecast_id, tag_id) SELECT $1, id FROM tags WHERE name = any($2)`
+		_, err := r.db.Querier(ctx).ExecContext(ctx, query, id, tags)
 		return err
-	}
+	})
+}
 
-	queryForecastPoints := `DELETE FROM points WHERE forecast_id = $1`
-	_, err = tx.ExecContext(ctx, queryForecastPoints, id)
-	if err != nil {
+func (r *PostgresForecastRepository) SetForecastShares(ctx context.Context, id int64, userIDs []int64) error {
+	return r.db.WithinTx(ctx, func(ctx context.Context) error {
+		if _, err := r.db.Querier(ctx).ExecContext(ctx, `DELETE FROM forecast_shares WHERE forecast_id = $1`, id); err != nil {
+			return err
+		}
+		if len(userIDs) == 0 {
+			return nil
+		}
+
+		query := `INSERT INTO forecast_shares (forecast_id, user_id) SELECT $1, id FROM users WHERE id = any($2)`
+		_, err := r.db.Querier(ctx).ExecContext(ctx, query, id, userIDs)
 		return err
-	}
+	})
+}
 
-	err = tx.Commit()
-	return err
+// user_id filtered methods
+func (r *PostgresForecastRepository) DeleteForecast(ctx context.Context, id int64, user_id int64) error {
+	return r.db.WithinTx(ctx, func(ctx context.Context) error {
+		//if this delete fails, due to user_id not owning forecast, return and do not delete forecast points.
+		//also checking this in service, but this adds redundancy since I delete all user forecast points.
+		queryForecasts := `DELETE FROM forecasts WHERE id = $1 and user_id = $2`
+		if _, err := r.db.Querier(ctx).ExecContext(ctx, queryForecasts, id, user_id); err != nil {
+			return err
+		}
+
+		queryForecastPoints := `DELETE FROM points WHERE forecast_id = $1`
+		_, err := r.db.Querier(ctx).ExecContext(ctx, queryForecastPoints, id)
+		return err
+	})
 }
 
-func (r *PostgresForecastRepository) GetStaleAndNewForecasts(ctx context.Context, userID int64) ([]*models.Forecast, error) {
-	query := `WITH latest_forecast_points as (
+func (r *PostgresForecastRepository) GetStaleAndNewForecasts(ctx context.Context, userID int64, viewer int64) ([]*models.Forecast, error) {
+	query := `WITH RECURSIVE personal_categories as (
+							 select id from categories where lower(name) = '` + models.PersonalCategory + `'
+							 union all
+							 select c.id from categories c join personal_categories pc on c.parent_id = pc.id
+							),
+							latest_forecast_points as (
 							 select forecast_id
 							 , max(created) latest_created
 							 , count(id) count_forecast_points
@@ -258,23 +357,35 @@ func (r *PostgresForecastRepository) GetStaleAndNewForecasts(ctx context.Context
 							f.id
 							, f.question
 							, f.category
+							, f.category_id
 							, f.created
 							, f.user_id
 							, f.resolution_criteria
 							, f.closing_date
 							, f.resolution
+							, f.resolution_value
 							, f.resolved
 							, f.comment
+							, f.question_type
+							, f.options
+							, f.range_min
+							, f.range_max
+							, f.visibility
 							FROM forecasts f
 							LEFT JOIN latest_forecast_points lfp
 							ON f.id = lfp.forecast_id
 							WHERE f.resolved is null
 							AND (lfp.forecast_id is null or lfp.latest_created < current_date - 7)
-							AND lower(f.category) not like '%personal%'
+							AND (f.category_id is null or f.category_id not in (select id from personal_categories))
+							AND f.id not in (
+								select ft.forecast_id from forecast_tags ft join tags t on t.id = ft.tag_id
+								where t.name = '` + models.PersonalCategory + `'
+							)
+							AND ` + visibilityCondition("f.", 2, true) + `
 							order by f.created desc
 							limit 40`
 
-	return r.queryForecasts(ctx, query, userID)
+	return r.queryForecasts(ctx, query, userID, viewer)
 }
 
 // Helper function to query forecasts
@@ -282,7 +393,7 @@ func (r *PostgresForecastRepository) queryForecasts(ctx context.Context, query s
 	log := logger.FromContext(ctx)
 
 	start := time.Now()
-	rows, err := r.db.QueryContext(ctx, query, args...)
+	rows, err := r.db.Querier(ctx).QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -297,18 +408,309 @@ func (r *PostgresForecastRepository) queryForecasts(ctx context.Context, query s
 			&f.ID,
 			&f.Question,
 			&f.Category,
+			&f.CategoryID,
 			&f.CreatedAt,
 			&f.UserID,
 			&f.ResolutionCriteria,
 			&f.ClosingDate,
 			&f.Resolution,
+			&f.ResolutionValue,
 			&f.ResolvedAt,
-			&f.ResolutionComment)
+			&f.ResolutionComment,
+			&f.QuestionType,
+			&f.Options,
+			&f.RangeMin,
+			&f.RangeMax,
+			&f.Visibility)
 		if err != nil {
 			return nil, err
 		}
 		forecasts = append(forecasts, &f)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 	log.Info("query results", slog.Int("count", len(forecasts)))
-	return forecasts, rows.Err()
+	return forecasts, r.loadRelations(ctx, forecasts)
+}
+
+// loadRelations fills in the tags of the forecasts and who they are shared with
+func (r *PostgresForecastRepository) loadRelations(ctx context.Context, forecasts []*models.Forecast) error {
+	if err := r.loadTags(ctx, forecasts); err != nil {
+		return err
+	}
+	return r.loadShares(ctx, forecasts)
+}
+
+// loadTags fills in the tags of the forecasts
+func (r *PostgresForecastRepository) loadTags(ctx context.Context, forecasts []*models.Forecast) error {
+	if len(forecasts) == 0 {
+		return nil
+	}
+	byID := make(map[int64]*models.Forecast, len(forecasts))
+	ids := make([]int64, 0, len(forecasts))
+	for _, f := range forecasts {
+		byID[f.ID] = f
+		ids = append(ids, f.ID)
+	}
+
+	query := `SELECT ft.forecast_id, t.name
+			  FROM forecast_tags ft
+			  JOIN tags t ON t.id = ft.tag_id
+			  WHERE ft.forecast_id = any($1)
+			  ORDER BY ft.forecast_id, t.name`
+	rows, err := r.db.Querier(ctx).QueryContext(ctx, query, ids)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		var tag string
+		if err := rows.Scan(&id, &tag); err != nil {
+			return err
+		}
+		if f, ok := byID[id]; ok {
+			f.Tags = append(f.Tags, tag)
+		}
+	}
+	return rows.Err()
+}
+
+// loadShares fills in the users the forecasts are shared with
+func (r *PostgresForecastRepository) loadShares(ctx context.Context, forecasts []*models.Forecast) error {
+	byID := make(map[int64]*models.Forecast)
+	ids := []int64{}
+	for _, f := range forecasts {
+		if f.Visibility == models.VisibilityShared {
+			byID[f.ID] = f
+			ids = append(ids, f.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	query := `SELECT forecast_id, user_id
+			  FROM forecast_shares
+			  WHERE forecast_id = any($1)
+			  ORDER BY forecast_id, user_id`
+	rows, err := r.db.Querier(ctx).QueryContext(ctx, query, ids)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id, userID int64
+		if err := rows.Scan(&id, &userID); err != nil {
+			return err
+		}
+		if f, ok := byID[id]; ok {
+			f.SharedWith = append(f.SharedWith, userID)
+		}
+	}
+	return rows.Err()
+}
+
+// searchHeadline are the ts_headline options of search snippets
+const searchHeadline = "StartSel=" + models.HighlightStart + ", StopSel=" + models.HighlightStop + ", MinWords=15, MaxWords=35, MaxFragments=2"
+
+// htmlEscaped escapes the characters of the SQL text expression HTML gives
+// meaning to, as html.EscapeString does, so its snippets only mark up matches
+func htmlEscaped(expr string) string {
+	for _, r := range [][2]string{{"&", "&amp;"}, {"<", "&lt;"}, {">", "&gt;"}, {`"`, "&#34;"}, {"''", "&#39;"}} {
+		expr = fmt.Sprintf("replace(%s, '%s', '%s')", expr, r[0], r[1])
+	}
+	return expr
+}
+
+// searchedFields are the forecast columns search snippets are taken from
+var searchedFields = []string{
+	models.SearchFieldQuestion,
+	models.SearchFieldResolutionCriteria,
+	models.SearchFieldComment,
+}
+
+// buildSearchQuery ranks forecasts by how well their text matches $1, adding
+// the ranks of their matching point reasons, with a snippet of each matching
+// field
+func buildSearchQuery(filters models.SearchFilters) (string, error) {
+	selectFields := []string{
+		"f.id",
+		"f.question",
+		"f.category",
+		"f.category_id",
+		"f.created",
+		"f.user_id",
+		"f.resolution_criteria",
+		"f.closing_date",
+		"f.resolution",
+		"f.resolution_value",
+		"f.resolved",
+		"f.comment",
+		"f.question_type",
+		"f.options",
+		"f.range_min",
+		"f.range_max",
+		"f.visibility",
+		"ts_rank(f.search_vector, q.query) + coalesce(r.rank, 0) as rank",
+	}
+	for _, field := range searchedFields {
+		selectFields = append(selectFields, fmt.Sprintf(
+			"case when to_tsvector('english', coalesce(f.%[1]s, '')) @@ q.query then ts_headline('english', %[2]s, q.query, '%[3]s') end",
+			field, htmlEscaped("f."+field), searchHeadline,
+		))
+	}
+
+	whereConditions := []string{"(f.search_vector @@ q.query or r.forecast_id is not null)"}
+	argsCounter := 2
+	if condition := statusCondition(filters.Status, "f."); condition != "" {
+		whereConditions = append(whereConditions, condition)
+	}
+	if filters.CategoryIDs != nil {
+		whereConditions = append(whereConditions, "f.category_id = any("+fmt.Sprintf("$%d", argsCounter)+")")
+		argsCounter++
+	}
+	if filters.Viewer != nil {
+		whereConditions = append(whereConditions, visibilityCondition("f.", argsCounter, true))
+		argsCounter++
+	}
+
+	query := fmt.Sprintf(
+		`with q as (select websearch_to_tsquery('english', $1) as query),
+		reasons as (
+			select p.forecast_id, sum(ts_rank(p.reason_vector, q.query)) as rank
+			from points p, q
+			where p.reason_vector @@ q.query
+			group by p.forecast_id
+		)
+		select
+		%s
+		from forecasts f
+		cross join q
+		left join reasons r on r.forecast_id = f.id
+		where %s
+		order by rank desc, f.id desc
+		limit $%d`,
+		strings.Join(selectFields, ",\n\t\t"),
+		strings.Join(whereConditions, " and "),
+		argsCounter,
+	)
+	return query, nil
+}
+
+func (r *PostgresForecastRepository) SearchForecasts(ctx context.Context, filters models.SearchFilters) ([]models.SearchResult, error) {
+	log := logger.FromContext(ctx)
+
+	query, err := buildSearchQuery(filters)
+	if err != nil {
+		return nil, err
+	}
+
+	args := []any{filters.Query}
+	if filters.CategoryIDs != nil {
+		args = append(args, filters.CategoryIDs)
+	}
+	if filters.Viewer != nil {
+		args = append(args, *filters.Viewer)
+	}
+	args = append(args, filters.Limit)
+
+	start := time.Now()
+	rows, err := r.db.Querier(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	log.Info("executed query", slog.Duration("duration", time.Since(start)), slog.Bool("success", err == nil))
+	defer rows.Close()
+
+	results := []models.SearchResult{}
+	byForecast := make(map[int64]int)
+	var ids []int64
+	for rows.Next() {
+		var f models.Forecast
+		var result models.SearchResult
+		snippets := make([]sql.NullString, len(searchedFields))
+		if err := rows.Scan(
+			&f.ID,
+			&f.Question,
+			&f.Category,
+			&f.CategoryID,
+			&f.CreatedAt,
+			&f.UserID,
+			&f.ResolutionCriteria,
+			&f.ClosingDate,
+			&f.Resolution,
+			&f.ResolutionValue,
+			&f.ResolvedAt,
+			&f.ResolutionComment,
+			&f.QuestionType,
+			&f.Options,
+			&f.RangeMin,
+			&f.RangeMax,
+			&f.Visibility,
+			&result.Rank,
+			&snippets[0],
+			&snippets[1],
+			&snippets[2],
+		); err != nil {
+			return nil, err
+		}
+
+		result.Forecast = &f
+		result.Matches = []models.SearchMatch{}
+		for i, snippet := range snippets {
+			if snippet.Valid {
+				result.Matches = append(result.Matches, models.SearchMatch{Field: searchedFields[i], Snippet: snippet.String})
+			}
+		}
+		byForecast[f.ID] = len(results)
+		ids = append(ids, f.ID)
+		results = append(results, result)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return results, nil
+	}
+
+	forecasts := make([]*models.Forecast, 0, len(results))
+	for _, result := range results {
+		forecasts = append(forecasts, result.Forecast)
+	}
+	if err := r.loadRelations(ctx, forecasts); err != nil {
+		return nil, err
+	}
+
+	// the best matching reasons of each forecast found
+	reasonRows, err := r.db.Querier(ctx).QueryContext(ctx, `
+		select id, forecast_id, snippet from (
+			select p.id, p.forecast_id,
+				ts_headline('english', `+htmlEscaped("p.reason")+`, q.query, '`+searchHeadline+`') as snippet,
+				row_number() over (partition by p.forecast_id order by ts_rank(p.reason_vector, q.query) desc, p.id desc) as n
+			from points p, websearch_to_tsquery('english', $1) q(query)
+			where p.forecast_id = any($2) and p.reason_vector @@ q.query
+		) reasons
+		where n <= $3
+		order by forecast_id, n`,
+		filters.Query, ids, models.MaxSearchReasons)
+	if err != nil {
+		return nil, err
+	}
+	defer reasonRows.Close()
+
+	for reasonRows.Next() {
+		var pointID, forecastID int64
+		var snippet string
+		if err := reasonRows.Scan(&pointID, &forecastID, &snippet); err != nil {
+			return nil, err
+		}
+		result := &results[byForecast[forecastID]]
+		result.Matches = append(result.Matches, models.SearchMatch{Field: models.SearchFieldReason, PointID: &pointID, Snippet: snippet})
+	}
+	log.Info("query results", slog.Int("count", len(results)))
+	return results, reasonRows.Err()
 }
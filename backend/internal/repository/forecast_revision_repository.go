@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"backend/internal/database"
+	"backend/internal/logger"
+	"backend/internal/models"
+	"context"
+	"log/slog"
+	"time"
+)
+
+// ForecastRevisionRepository defines the interface for the edit history of forecasts
+type ForecastRevisionRepository interface {
+	// CreateRevision stores the revision as the next version of its forecast
+	CreateRevision(ctx context.Context, revision *models.ForecastRevision) error
+	GetRevisions(ctx context.Context, forecastID int64) ([]models.ForecastRevision, error)
+}
+
+// PostgresForecastRevisionRepository implements the ForecastRevisionRepository interface
+type PostgresForecastRevisionRepository struct {
+	db *database.DB
+}
+
+// NewForecastRevisionRepository creates a new PostgresForecastRevisionRepository instance
+func NewForecastRevisionRepository(db *database.DB) ForecastRevisionRepository {
+	return &PostgresForecastRevisionRepository{db: db}
+}
+
+func (r *PostgresForecastRevisionRepository) CreateRevision(ctx context.Context, revision *models.ForecastRevision) error {
+	revision.CreatedAt = time.Now()
+
+	// the forecast as created is version 1
+	query := `INSERT INTO forecast_revisions (forecast_id
+					, version
+					, user_id
+					, changes
+					, created)
+              SELECT $1, coalesce(max(version), 1) + 1, $2, $3, $4
+              FROM forecast_revisions
+              WHERE forecast_id = $1
+              RETURNING id, version`
+
+	return r.db.Querier(ctx).QueryRowContext(ctx, query,
+		revision.ForecastID,
+		revision.UserID,
+		revision.Changes,
+		revision.CreatedAt).Scan(&revision.ID, &revision.Version)
+}
+
+func (r *PostgresForecastRevisionRepository) GetRevisions(ctx context.Context, forecastID int64) ([]models.ForecastRevision, error) {
+	log := logger.FromContext(ctx)
+
+	query := `SELECT id
+				, forecast_id
+				, version
+				, user_id
+				, changes
+				, created
+			  FROM forecast_revisions
+			  WHERE forecast_id = $1
+			  ORDER BY version`
+
+	start := time.Now()
+	rows, err := r.db.Querier(ctx).QueryContext(ctx, query, forecastID)
+	if err != nil {
+		return nil, err
+	}
+	log.Info("executed query", slog.Duration("duration", time.Since(start)), slog.Bool("success", err == nil))
+	defer rows.Close()
+
+	revisions := []models.ForecastRevision{}
+	for rows.Next() {
+		var rev models.ForecastRevision
+		if err := rows.Scan(
+			&rev.ID,
+			&rev.ForecastID,
+			&rev.Version,
+			&rev.UserID,
+			&rev.Changes,
+			&rev.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, rev)
+	}
+	log.Info("query results", slog.Int("count", len(revisions)))
+	return revisions, rows.Err()
+}
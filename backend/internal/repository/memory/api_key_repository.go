@@ -0,0 +1,111 @@
+package memory
+
+import (
+	"backend/internal/models"
+	"backend/internal/repository"
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// APIKeyRepository implements repository.APIKeyRepository in memory
+type APIKeyRepository struct {
+	store *Store
+}
+
+// NewAPIKeyRepository creates a new in-memory APIKeyRepository on the store
+func NewAPIKeyRepository(store *Store) repository.APIKeyRepository {
+	return &APIKeyRepository{store: store}
+}
+
+func (r *APIKeyRepository) CreateAPIKey(ctx context.Context, key *models.APIKey) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.users[key.UserID]; !ok {
+		return foreignKeyError("api_keys", "user_id", key.UserID)
+	}
+	for _, k := range r.store.apiKeys {
+		if k.KeyHash == key.KeyHash {
+			return fmt.Errorf("duplicate key value violates unique constraint: key_hash already exists")
+		}
+	}
+
+	key.CreatedAt = time.Now()
+	r.store.lastAPIKeyID++
+	key.ID = r.store.lastAPIKeyID
+
+	stored := cloneAPIKey(key)
+	stored.LastUsedAt, stored.RevokedAt = nil, nil
+	r.store.apiKeys[key.ID] = stored
+	return nil
+}
+
+func (r *APIKeyRepository) GetAPIKeyByHash(ctx context.Context, keyHash string) (*models.APIKey, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	for _, k := range r.store.apiKeys {
+		if k.KeyHash == keyHash && k.RevokedAt == nil {
+			return cloneAPIKey(k), nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+// ListAPIKeys returns the user's keys without their hash, as the SQL does not select it
+func (r *APIKeyRepository) ListAPIKeys(ctx context.Context, userID int64) ([]models.APIKey, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var keys []models.APIKey
+	for _, k := range r.store.apiKeys {
+		if k.UserID != userID {
+			continue
+		}
+		key := cloneAPIKey(k)
+		key.KeyHash = ""
+		keys = append(keys, *key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].ID < keys[j].ID
+	})
+	return keys, nil
+}
+
+func (r *APIKeyRepository) RevokeAPIKey(ctx context.Context, id int64, userID int64) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	k, ok := r.store.apiKeys[id]
+	if !ok || k.UserID != userID {
+		return sql.ErrNoRows
+	}
+	if k.RevokedAt == nil {
+		now := time.Now()
+		k.RevokedAt = &now
+	}
+	return nil
+}
+
+func (r *APIKeyRepository) UpdateLastUsed(ctx context.Context, id int64, lastUsed time.Time) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if k, ok := r.store.apiKeys[id]; ok {
+		k.LastUsedAt = &lastUsed
+	}
+	return nil
+}
+
+func cloneAPIKey(k *models.APIKey) *models.APIKey {
+	c := *k
+	c.LastUsedAt = cloneTime(k.LastUsedAt)
+	c.RevokedAt = cloneTime(k.RevokedAt)
+	if k.Scopes != nil {
+		c.Scopes = append(models.Scopes{}, k.Scopes...)
+	}
+	return &c
+}
@@ -0,0 +1,180 @@
+package memory
+
+import (
+	"backend/internal/models"
+	"backend/internal/repository"
+	"context"
+	"math"
+	"sort"
+)
+
+// CalibrationRepository implements repository.CalibrationRepository in memory
+type CalibrationRepository struct {
+	store *Store
+}
+
+// NewCalibrationRepository creates a new in-memory CalibrationRepository on the store
+func NewCalibrationRepository(store *Store) repository.CalibrationRepository {
+	return &CalibrationRepository{store: store}
+}
+
+// calibrationGroup accumulates one row of the calibration query
+type calibrationGroup struct {
+	userID      int64
+	bucketStart float64
+	bucketWidth float64
+	count       int
+	sumForecast float64
+	sumOutcome  float64
+	forecasts   map[int64]bool
+}
+
+func (g *calibrationGroup) bucket() models.CalibrationBucket {
+	return models.CalibrationBucket{
+		BucketStart:     g.bucketStart,
+		BucketEnd:       g.bucketStart + g.bucketWidth,
+		PredictionCount: g.count,
+		AvgPrediction:   g.sumForecast / float64(g.count),
+		ActualRate:      g.sumOutcome / float64(g.count),
+	}
+}
+
+func (r *CalibrationRepository) GetCalibrationData(ctx context.Context, filters models.CalibrationFilters) (*models.CalibrationData, error) {
+	data := &models.CalibrationData{}
+	for _, g := range r.calibrationGroups(filters, false) {
+		data.Buckets = append(data.Buckets, g.bucket())
+		data.TotalPredictions += g.count
+		data.TotalForecasts += len(g.forecasts)
+	}
+	return data, nil
+}
+
+func (r *CalibrationRepository) GetCalibrationDataByUsers(ctx context.Context, filters models.CalibrationFilters) ([]models.UserCalibrationData, error) {
+	result := []models.UserCalibrationData{}
+	for _, g := range r.calibrationGroups(filters, true) {
+		if len(result) == 0 || result[len(result)-1].UserID != g.userID {
+			result = append(result, models.UserCalibrationData{
+				UserID:  g.userID,
+				Buckets: []models.CalibrationBucket{},
+			})
+		}
+		userData := &result[len(result)-1]
+		userData.Buckets = append(userData.Buckets, g.bucket())
+		userData.TotalPredictions += g.count
+		userData.TotalForecasts += len(g.forecasts)
+	}
+	return result, nil
+}
+
+func (r *CalibrationRepository) GetCalibrationPoints(ctx context.Context, filters models.CalibrationFilters) ([]models.CalibrationPoint, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	return r.store.calibrationPoints(filters), nil
+}
+
+// calibrationGroups buckets the points of resolved binary forecasts by
+// floor(point_forecast * bins) / bins, forecasts of 1 falling in the last
+// bucket, optionally per user, ordered by user and bucket
+func (r *CalibrationRepository) calibrationGroups(filters models.CalibrationFilters, groupByUser bool) []*calibrationGroup {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	type groupKey struct {
+		userID      int64
+		bucketStart float64
+	}
+	groups := make(map[groupKey]*calibrationGroup)
+	bins := float64(filters.Bins)
+	if filters.Bins <= 0 {
+		bins = models.DefaultCalibrationBins
+	}
+
+	for _, p := range r.store.calibrationPoints(filters) {
+		key := groupKey{bucketStart: math.Min(math.Floor(p.PointForecast*bins), bins-1) / bins}
+		if groupByUser {
+			key.userID = p.UserID
+		}
+		g, ok := groups[key]
+		if !ok {
+			g = &calibrationGroup{userID: key.userID, bucketStart: key.bucketStart, bucketWidth: 1 / bins, forecasts: make(map[int64]bool)}
+			groups[key] = g
+		}
+		g.count++
+		g.sumForecast += p.PointForecast
+		g.sumOutcome += p.Outcome
+		g.forecasts[p.ForecastID] = true
+	}
+
+	sorted := make([]*calibrationGroup, 0, len(groups))
+	for _, g := range groups {
+		sorted = append(sorted, g)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].userID != sorted[j].userID {
+			return sorted[i].userID < sorted[j].userID
+		}
+		return sorted[i].bucketStart < sorted[j].bucketStart
+	})
+	return sorted
+}
+
+// calibrationPoints returns the points of resolved binary forecasts matching
+// the filters, oldest first. Callers must hold the store lock.
+func (s *Store) calibrationPoints(filters models.CalibrationFilters) []models.CalibrationPoint {
+	var matching []*models.ForecastPoint
+	for _, p := range s.points {
+		f, ok := s.forecasts[p.ForecastID]
+		if !ok || f.Resolution == nil || (*f.Resolution != models.ResolutionNo && *f.Resolution != models.ResolutionYes) {
+			continue
+		}
+		if f.QuestionType != models.QuestionTypeBinary {
+			continue
+		}
+		if filters.UserID != nil && p.UserID != *filters.UserID {
+			continue
+		}
+		if !inCategories(f.CategoryID, filters.CategoryIDs) {
+			continue
+		}
+		if filters.StartDate != nil && p.CreatedAt.Before(*filters.StartDate) {
+			continue
+		}
+		if filters.EndDate != nil && p.CreatedAt.After(*filters.EndDate) {
+			continue
+		}
+		if !s.visibleTo(f.ID, filters.Viewer, false) {
+			continue
+		}
+		matching = append(matching, p)
+	}
+	sort.Slice(matching, func(i, j int) bool {
+		if !matching[i].CreatedAt.Equal(matching[j].CreatedAt) {
+			return matching[i].CreatedAt.Before(matching[j].CreatedAt)
+		}
+		return matching[i].ID < matching[j].ID
+	})
+
+	points := make([]models.CalibrationPoint, 0, len(matching))
+	for _, p := range matching {
+		f := s.forecasts[p.ForecastID]
+		outcome := 0.0
+		if *f.Resolution == models.ResolutionYes {
+			outcome = 1
+		}
+		resolvedAt := p.CreatedAt
+		if f.ResolvedAt != nil {
+			resolvedAt = *f.ResolvedAt
+		}
+		points = append(points, models.CalibrationPoint{
+			UserID:        p.UserID,
+			ForecastID:    p.ForecastID,
+			PointForecast: p.PointForecast,
+			Outcome:       outcome,
+			CreatedAt:     p.CreatedAt,
+			ClosingDate:   f.ClosingDate,
+			ResolvedAt:    resolvedAt,
+		})
+	}
+	return points
+}
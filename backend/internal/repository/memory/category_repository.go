@@ -0,0 +1,165 @@
+package memory
+
+import (
+	"backend/internal/models"
+	"backend/internal/repository"
+	"context"
+	"database/sql"
+	"sort"
+	"strings"
+	"time"
+)
+
+// CategoryRepository implements repository.CategoryRepository in memory
+type CategoryRepository struct {
+	store *Store
+}
+
+// NewCategoryRepository creates a new in-memory CategoryRepository on the store
+func NewCategoryRepository(store *Store) repository.CategoryRepository {
+	return &CategoryRepository{store: store}
+}
+
+func (r *CategoryRepository) GetCategories(ctx context.Context) ([]*models.Category, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	categories := []*models.Category{}
+	for _, c := range r.store.sortedCategories() {
+		categories = append(categories, cloneCategory(c))
+	}
+	return categories, nil
+}
+
+func (r *CategoryRepository) GetCategoryByID(ctx context.Context, id int64) (*models.Category, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	c, ok := r.store.categories[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return cloneCategory(c), nil
+}
+
+func (r *CategoryRepository) CreateCategory(ctx context.Context, c *models.Category) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if c.ParentID != nil {
+		if _, ok := r.store.categories[*c.ParentID]; !ok {
+			return foreignKeyError("categories", "parent_id", *c.ParentID)
+		}
+	}
+
+	c.CreatedAt = time.Now()
+	r.store.lastCategoryID++
+	c.ID = r.store.lastCategoryID
+	r.store.categories[c.ID] = cloneCategory(c)
+	return nil
+}
+
+func (r *CategoryRepository) UpdateCategory(ctx context.Context, c *models.Category) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	stored, ok := r.store.categories[c.ID]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	if c.ParentID != nil {
+		if _, ok := r.store.categories[*c.ParentID]; !ok {
+			return foreignKeyError("categories", "parent_id", *c.ParentID)
+		}
+	}
+
+	stored.Name = c.Name
+	stored.ParentID = cloneInt64(c.ParentID)
+	for _, f := range r.store.forecasts {
+		if f.CategoryID != nil && *f.CategoryID == c.ID {
+			f.Category = c.Name
+		}
+	}
+	return nil
+}
+
+func (r *CategoryRepository) DeleteCategory(ctx context.Context, id int64) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.categories[id]; !ok {
+		return sql.ErrNoRows
+	}
+	// forecasts and subcategories reference categories without cascading
+	for _, f := range r.store.forecasts {
+		if f.CategoryID != nil && *f.CategoryID == id {
+			return foreignKeyError("forecasts", "category_id", id)
+		}
+	}
+	for _, c := range r.store.categories {
+		if c.ParentID != nil && *c.ParentID == id {
+			return foreignKeyError("categories", "parent_id", id)
+		}
+	}
+
+	delete(r.store.categories, id)
+	return nil
+}
+
+func (r *CategoryRepository) CountForecasts(ctx context.Context, id int64) (int, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	count := 0
+	for _, f := range r.store.forecasts {
+		if f.CategoryID != nil && *f.CategoryID == id {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *CategoryRepository) GetTags(ctx context.Context) ([]models.Tag, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	counts := make(map[string]int)
+	for _, f := range r.store.forecasts {
+		for _, tag := range f.Tags {
+			counts[tag]++
+		}
+	}
+
+	tags := []models.Tag{}
+	for name, count := range counts {
+		tags = append(tags, models.Tag{Name: name, Forecasts: count})
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		return tags[i].Name < tags[j].Name
+	})
+	return tags, nil
+}
+
+// sortedCategories returns the stored categories ordered as GetCategories
+// orders them. Callers must hold the store lock.
+func (s *Store) sortedCategories() []*models.Category {
+	categories := make([]*models.Category, 0, len(s.categories))
+	for _, c := range s.categories {
+		categories = append(categories, c)
+	}
+	sort.Slice(categories, func(i, j int) bool {
+		a, b := strings.ToLower(categories[i].Name), strings.ToLower(categories[j].Name)
+		if a != b {
+			return a < b
+		}
+		return categories[i].ID < categories[j].ID
+	})
+	return categories
+}
+
+func cloneCategory(c *models.Category) *models.Category {
+	clone := *c
+	clone.ParentID = cloneInt64(c.ParentID)
+	clone.Children = nil
+	return &clone
+}
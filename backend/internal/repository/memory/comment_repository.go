@@ -0,0 +1,130 @@
+package memory
+
+import (
+	"backend/internal/models"
+	"backend/internal/repository"
+	"context"
+	"database/sql"
+	"slices"
+	"sort"
+	"time"
+)
+
+// CommentRepository implements repository.CommentRepository in memory
+type CommentRepository struct {
+	store *Store
+}
+
+// NewCommentRepository creates a new in-memory CommentRepository on the store
+func NewCommentRepository(store *Store) repository.CommentRepository {
+	return &CommentRepository{store: store}
+}
+
+func (r *CommentRepository) GetComments(ctx context.Context, filters models.CommentFilters) ([]*models.Comment, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	comments := []*models.Comment{}
+	for _, c := range r.store.comments {
+		if c.ForecastID == filters.ForecastID {
+			comments = append(comments, r.store.commentRow(c))
+		}
+	}
+	sort.Slice(comments, func(i, j int) bool {
+		if !comments[i].CreatedAt.Equal(comments[j].CreatedAt) {
+			return comments[i].CreatedAt.Before(comments[j].CreatedAt)
+		}
+		return comments[i].ID < comments[j].ID
+	})
+	return paginate(comments, filters.Page, models.SortCreated)
+}
+
+func (r *CommentRepository) GetCommentByID(ctx context.Context, id int64) (*models.Comment, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	c, ok := r.store.comments[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return r.store.commentRow(c), nil
+}
+
+func (r *CommentRepository) CreateComment(ctx context.Context, c *models.Comment) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.forecasts[c.ForecastID]; !ok {
+		return foreignKeyError("comments", "forecast_id", c.ForecastID)
+	}
+	if c.ParentID != nil {
+		if _, ok := r.store.comments[*c.ParentID]; !ok {
+			return foreignKeyError("comments", "parent_id", *c.ParentID)
+		}
+	}
+	if _, ok := r.store.users[c.UserID]; !ok {
+		return foreignKeyError("comments", "user_id", c.UserID)
+	}
+
+	c.CreatedAt = time.Now()
+	r.store.lastCommentID++
+	c.ID = r.store.lastCommentID
+	stored := cloneComment(c)
+	stored.Mentions = nil
+	r.store.comments[c.ID] = stored
+	return nil
+}
+
+func (r *CommentRepository) UpdateComment(ctx context.Context, c *models.Comment) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if stored, ok := r.store.comments[c.ID]; ok {
+		stored.Body = c.Body
+		stored.EditedAt = cloneTime(c.EditedAt)
+		stored.DeletedAt = cloneTime(c.DeletedAt)
+	}
+	return nil
+}
+
+func (r *CommentRepository) SetCommentMentions(ctx context.Context, id int64, usernames []string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	c, ok := r.store.comments[id]
+	if !ok {
+		return foreignKeyError("comment_mentions", "comment_id", id)
+	}
+	var mentions []int64
+	for _, u := range r.store.users {
+		if slices.Contains(usernames, u.Username) {
+			mentions = append(mentions, u.ID)
+		}
+	}
+	slices.Sort(mentions)
+	c.Mentions = mentions
+	return nil
+}
+
+// commentRow returns a copy of the comment as the queries select it, with its
+// author's name. Callers must hold the store lock.
+func (s *Store) commentRow(c *models.Comment) *models.Comment {
+	comment := cloneComment(c)
+	// left join users
+	comment.UserName = nil
+	if u, ok := s.users[c.UserID]; ok {
+		username := u.Username
+		comment.UserName = &username
+	}
+	return comment
+}
+
+func cloneComment(c *models.Comment) *models.Comment {
+	clone := *c
+	clone.ParentID = cloneInt64(c.ParentID)
+	clone.UserName = cloneString(c.UserName)
+	clone.Mentions = slices.Clone(c.Mentions)
+	clone.EditedAt = cloneTime(c.EditedAt)
+	clone.DeletedAt = cloneTime(c.DeletedAt)
+	return &clone
+}
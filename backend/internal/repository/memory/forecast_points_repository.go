@@ -0,0 +1,109 @@
+package memory
+
+import (
+	"backend/internal/models"
+	"backend/internal/repository"
+	"context"
+	"errors"
+	"sort"
+	"time"
+)
+
+// ForecastPointRepository implements repository.ForecastPointRepository in memory
+type ForecastPointRepository struct {
+	store *Store
+}
+
+// NewForecastPointRepository creates a new in-memory ForecastPointRepository on the store
+func NewForecastPointRepository(store *Store) repository.ForecastPointRepository {
+	return &ForecastPointRepository{store: store}
+}
+
+func (r *ForecastPointRepository) GetForecastPoints(ctx context.Context, filters models.PointFilters) ([]*models.ForecastPoint, error) {
+	distinct := filters.DistinctOnForecast != nil && *filters.DistinctOnForecast
+	orderByForecast := filters.OrderByForecastID != nil && *filters.OrderByForecastID
+	if distinct && !orderByForecast {
+		// postgres rejects distinct on (forecast_id) unless the ordering starts with it
+		return nil, errors.New("SELECT DISTINCT ON expressions must match initial ORDER BY expressions")
+	}
+	ascending := filters.CreatedDirection != nil && *filters.CreatedDirection == "ASC"
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var points []*models.ForecastPoint
+	for _, p := range r.store.points {
+		if filters.UserID != nil && p.UserID != *filters.UserID {
+			continue
+		}
+		if filters.ForecastID != nil && p.ForecastID != *filters.ForecastID {
+			continue
+		}
+		if filters.Date != nil && (p.CreatedAt.Before(*filters.Date) || !p.CreatedAt.Before(filters.Date.AddDate(0, 0, 1))) {
+			continue
+		}
+		if !r.store.visibleTo(p.ForecastID, filters.Viewer, filters.ForecastID == nil) {
+			continue
+		}
+
+		point := clonePoint(p)
+		// left join users
+		point.UserName = nil
+		if u, ok := r.store.users[p.UserID]; ok {
+			username := u.Username
+			point.UserName = &username
+		}
+		points = append(points, point)
+	}
+
+	sort.Slice(points, func(i, j int) bool {
+		a, b := points[i], points[j]
+		if orderByForecast && a.ForecastID != b.ForecastID {
+			return a.ForecastID < b.ForecastID
+		}
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			if ascending {
+				return a.CreatedAt.Before(b.CreatedAt)
+			}
+			return a.CreatedAt.After(b.CreatedAt)
+		}
+		// break ties the way rows were inserted, so results are deterministic
+		if ascending {
+			return a.ID < b.ID
+		}
+		return a.ID > b.ID
+	})
+
+	if distinct {
+		var firstPerForecast []*models.ForecastPoint
+		for i, p := range points {
+			if i == 0 || p.ForecastID != points[i-1].ForecastID {
+				firstPerForecast = append(firstPerForecast, p)
+			}
+		}
+		points = firstPerForecast
+	}
+
+	return paginate(points, filters.Page, models.SortCreated)
+}
+
+func (r *ForecastPointRepository) CreateForecastPoint(ctx context.Context, fp *models.ForecastPoint) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.forecasts[fp.ForecastID]; !ok {
+		return foreignKeyError("points", "forecast_id", fp.ForecastID)
+	}
+	if _, ok := r.store.users[fp.UserID]; !ok {
+		return foreignKeyError("points", "user_id", fp.UserID)
+	}
+
+	fp.CreatedAt = time.Now()
+	r.store.lastPointID++
+	fp.ID = r.store.lastPointID
+
+	stored := clonePoint(fp)
+	stored.UserName = nil
+	r.store.points[fp.ID] = stored
+	return nil
+}
@@ -0,0 +1,270 @@
+package memory
+
+import (
+	"backend/internal/models"
+	"backend/internal/repository"
+	"context"
+	"database/sql"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ForecastRepository implements repository.ForecastRepository in memory
+type ForecastRepository struct {
+	store *Store
+}
+
+// NewForecastRepository creates a new in-memory ForecastRepository on the store
+func NewForecastRepository(store *Store) repository.ForecastRepository {
+	return &ForecastRepository{store: store}
+}
+
+func (r *ForecastRepository) GetForecasts(ctx context.Context, filters models.ForecastFilters) ([]*models.Forecast, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	today := currentDate()
+
+	var forecasts []*models.Forecast
+	for _, f := range r.store.sortedForecasts() {
+		if filters.ForecastID != nil && f.ID != *filters.ForecastID {
+			continue
+		}
+		if filters.Status != nil {
+			switch *filters.Status {
+			case "open":
+				if f.ResolvedAt != nil {
+					continue
+				}
+			case "resolved":
+				if f.ResolvedAt == nil {
+					continue
+				}
+			case "closed":
+				if f.ClosingDate == nil || !today.After(*f.ClosingDate) {
+					continue
+				}
+			}
+		}
+		if !inCategories(f.CategoryID, filters.CategoryIDs) {
+			continue
+		}
+		if filters.Tag != nil && !slices.Contains(f.Tags, *filters.Tag) {
+			continue
+		}
+		if !r.store.visibleTo(f.ID, filters.Viewer, true) {
+			continue
+		}
+		forecasts = append(forecasts, cloneForecast(f))
+	}
+	return paginate(forecasts, filters.Page, models.SortCreated, models.SortClosingDate, models.SortQuestion)
+}
+
+func (r *ForecastRepository) GetForecastByID(ctx context.Context, id int64) (*models.Forecast, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	f, ok := r.store.forecasts[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return cloneForecast(f), nil
+}
+
+func (r *ForecastRepository) CheckForecastOwnership(ctx context.Context, id int64, userID int64) (bool, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	f, ok := r.store.forecasts[id]
+	if !ok {
+		return false, sql.ErrNoRows
+	}
+	return f.UserID == userID, nil
+}
+
+func (r *ForecastRepository) CheckForecastStatus(ctx context.Context, id int64) (bool, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	f, ok := r.store.forecasts[id]
+	if !ok {
+		return false, sql.ErrNoRows
+	}
+	return f.ResolvedAt == nil, nil
+}
+
+func (r *ForecastRepository) CreateForecast(ctx context.Context, f *models.Forecast) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.users[f.UserID]; !ok {
+		return foreignKeyError("forecasts", "user_id", f.UserID)
+	}
+
+	f.CreatedAt = time.Now()
+	r.store.lastForecastID++
+	f.ID = r.store.lastForecastID
+
+	if f.Visibility == "" {
+		f.Visibility = models.VisibilityPublic
+	}
+	stored := cloneForecast(f)
+	stored.SharedWith = r.store.existingUsers(f.SharedWith)
+	// resolution fields are only ever written by UpdateForecast
+	stored.Resolution, stored.ResolutionValue, stored.ResolvedAt, stored.ResolutionComment = nil, nil, nil, nil
+	r.store.forecasts[f.ID] = stored
+	return nil
+}
+
+func (r *ForecastRepository) UpdateForecast(ctx context.Context, f *models.Forecast) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	stored, ok := r.store.forecasts[f.ID]
+	if !ok {
+		return nil
+	}
+	stored.Question = f.Question
+	stored.Category = f.Category
+	stored.CategoryID = cloneInt64(f.CategoryID)
+	stored.ResolutionCriteria = f.ResolutionCriteria
+	stored.ClosingDate = cloneTime(f.ClosingDate)
+	stored.Resolution = cloneResolution(f.Resolution)
+	stored.ResolutionValue = cloneFloat(f.ResolutionValue)
+	stored.ResolvedAt = cloneTime(f.ResolvedAt)
+	stored.ResolutionComment = cloneString(f.ResolutionComment)
+	stored.Visibility = f.Visibility
+	return nil
+}
+
+func (r *ForecastRepository) SetForecastTags(ctx context.Context, id int64, tags []string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if f, ok := r.store.forecasts[id]; ok {
+		f.Tags = append([]string{}, tags...)
+	}
+	return nil
+}
+
+func (r *ForecastRepository) SetForecastShares(ctx context.Context, id int64, userIDs []int64) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if f, ok := r.store.forecasts[id]; ok {
+		f.SharedWith = r.store.existingUsers(userIDs)
+	}
+	return nil
+}
+
+// existingUsers returns the ids of the users that exist, sorted as the shares
+// are loaded. Callers must hold the store lock.
+func (s *Store) existingUsers(userIDs []int64) []int64 {
+	var existing []int64
+	for _, id := range userIDs {
+		if _, ok := s.users[id]; ok && !slices.Contains(existing, id) {
+			existing = append(existing, id)
+		}
+	}
+	slices.Sort(existing)
+	return existing
+}
+
+func (r *ForecastRepository) DeleteForecast(ctx context.Context, id int64, userID int64) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if f, ok := r.store.forecasts[id]; ok && f.UserID == userID {
+		delete(r.store.forecasts, id)
+
+		// resolution audits and their archived scores, revisions and comments
+		// cascade in Postgres
+		for auditID, a := range r.store.audits {
+			if a.ForecastID == id {
+				delete(r.store.audits, auditID)
+			}
+		}
+		for revisionID, rev := range r.store.revisions {
+			if rev.ForecastID == id {
+				delete(r.store.revisions, revisionID)
+			}
+		}
+		for commentID, c := range r.store.comments {
+			if c.ForecastID == id {
+				delete(r.store.comments, commentID)
+			}
+		}
+		for scoreID, s := range r.store.archivedScores {
+			if s.score.ForecastID == id {
+				delete(r.store.archivedScores, scoreID)
+			}
+		}
+	}
+	for pointID, p := range r.store.points {
+		if p.ForecastID == id {
+			delete(r.store.points, pointID)
+		}
+	}
+	return nil
+}
+
+func (r *ForecastRepository) GetStaleAndNewForecasts(ctx context.Context, userID int64, viewer int64) ([]*models.Forecast, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	latestCreated := make(map[int64]time.Time)
+	for _, p := range r.store.points {
+		if p.UserID != userID {
+			continue
+		}
+		if latest, ok := latestCreated[p.ForecastID]; !ok || p.CreatedAt.After(latest) {
+			latestCreated[p.ForecastID] = p.CreatedAt
+		}
+	}
+
+	staleBefore := currentDate().AddDate(0, 0, -7)
+
+	var personal []int64
+	for _, c := range r.store.sortedCategories() {
+		if strings.EqualFold(c.Name, models.PersonalCategory) {
+			personal = append(personal, models.CategoryDescendants(r.store.sortedCategories(), c.ID)...)
+		}
+	}
+
+	var forecasts []*models.Forecast
+	for _, f := range r.store.sortedForecasts() {
+		if f.ResolvedAt != nil || slices.Contains(f.Tags, models.PersonalCategory) || !f.IsListedFor(viewer) {
+			continue
+		}
+		if f.CategoryID != nil && slices.Contains(personal, *f.CategoryID) {
+			continue
+		}
+		if latest, ok := latestCreated[f.ID]; ok && !latest.Before(staleBefore) {
+			continue
+		}
+		forecasts = append(forecasts, cloneForecast(f))
+	}
+
+	sort.SliceStable(forecasts, func(i, j int) bool {
+		return forecasts[i].CreatedAt.After(forecasts[j].CreatedAt)
+	})
+	if len(forecasts) > 40 {
+		forecasts = forecasts[:40]
+	}
+	return forecasts, nil
+}
+
+// sortedForecasts returns the stored forecasts in insertion order.
+// Callers must hold the store lock.
+func (s *Store) sortedForecasts() []*models.Forecast {
+	forecasts := make([]*models.Forecast, 0, len(s.forecasts))
+	for _, f := range s.forecasts {
+		forecasts = append(forecasts, f)
+	}
+	sort.Slice(forecasts, func(i, j int) bool {
+		return forecasts[i].ID < forecasts[j].ID
+	})
+	return forecasts
+}
@@ -0,0 +1,67 @@
+package memory
+
+import (
+	"backend/internal/models"
+	"backend/internal/repository"
+	"context"
+	"slices"
+	"sort"
+	"time"
+)
+
+// ForecastRevisionRepository implements repository.ForecastRevisionRepository in memory
+type ForecastRevisionRepository struct {
+	store *Store
+}
+
+// NewForecastRevisionRepository creates a new in-memory ForecastRevisionRepository on the store
+func NewForecastRevisionRepository(store *Store) repository.ForecastRevisionRepository {
+	return &ForecastRevisionRepository{store: store}
+}
+
+func (r *ForecastRevisionRepository) CreateRevision(ctx context.Context, revision *models.ForecastRevision) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.forecasts[revision.ForecastID]; !ok {
+		return foreignKeyError("forecast_revisions", "forecast_id", revision.ForecastID)
+	}
+	if _, ok := r.store.users[revision.UserID]; !ok {
+		return foreignKeyError("forecast_revisions", "user_id", revision.UserID)
+	}
+
+	revision.Version = 2
+	for _, rev := range r.store.revisions {
+		if rev.ForecastID == revision.ForecastID {
+			revision.Version = max(revision.Version, rev.Version+1)
+		}
+	}
+	revision.CreatedAt = time.Now()
+	r.store.lastRevisionID++
+	revision.ID = r.store.lastRevisionID
+	r.store.revisions[revision.ID] = cloneRevision(revision)
+	return nil
+}
+
+func (r *ForecastRevisionRepository) GetRevisions(ctx context.Context, forecastID int64) ([]models.ForecastRevision, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	revisions := []models.ForecastRevision{}
+	for _, rev := range r.store.revisions {
+		if rev.ForecastID == forecastID {
+			revisions = append(revisions, *cloneRevision(rev))
+		}
+	}
+	sort.Slice(revisions, func(i, j int) bool {
+		return revisions[i].Version < revisions[j].Version
+	})
+	return revisions, nil
+}
+
+func cloneRevision(rev *models.ForecastRevision) *models.ForecastRevision {
+	c := *rev
+	c.Changes = slices.Clone(rev.Changes)
+	c.AfterLastPoint = false
+	return &c
+}
@@ -0,0 +1,280 @@
+package memory
+
+import (
+	"backend/internal/models"
+	"context"
+	"database/sql"
+	"math"
+	"testing"
+)
+
+func seed(t *testing.T) (*Store, int64, int64) {
+	t.Helper()
+	ctx := context.Background()
+	store := NewStore()
+
+	users := NewUserRepository(store)
+	alice := &models.User{Username: "alice", Password: "hash"}
+	if err := users.CreateUser(ctx, alice); err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+	bob := &models.User{Username: "bob", Password: "hash"}
+	if err := users.CreateUser(ctx, bob); err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+	return store, alice.ID, bob.ID
+}
+
+// createForecast creates a forecast in the top-level category with the name,
+// creating the category when it is missing
+func createForecast(t *testing.T, store *Store, userID int64, category string) *models.Forecast {
+	t.Helper()
+	f := &models.Forecast{Question: "q", Category: category, CategoryID: categoryID(t, store, category), UserID: userID, QuestionType: models.QuestionTypeBinary}
+	if err := NewForecastRepository(store).CreateForecast(context.Background(), f); err != nil {
+		t.Fatalf("CreateForecast failed: %v", err)
+	}
+	return f
+}
+
+func categoryID(t *testing.T, store *Store, name string) *int64 {
+	t.Helper()
+	repo := NewCategoryRepository(store)
+	categories, _ := repo.GetCategories(context.Background())
+	for _, c := range categories {
+		if c.ParentID == nil && c.Name == name {
+			return &c.ID
+		}
+	}
+	c := &models.Category{Name: name}
+	if err := repo.CreateCategory(context.Background(), c); err != nil {
+		t.Fatalf("CreateCategory failed: %v", err)
+	}
+	return &c.ID
+}
+
+func createPoint(t *testing.T, store *Store, forecastID int64, userID int64, p float64) {
+	t.Helper()
+	fp := &models.ForecastPoint{ForecastID: forecastID, UserID: userID, PointForecast: p}
+	if err := NewForecastPointRepository(store).CreateForecastPoint(context.Background(), fp); err != nil {
+		t.Fatalf("CreateForecastPoint failed: %v", err)
+	}
+}
+
+func resolve(t *testing.T, store *Store, f *models.Forecast, resolution models.Resolution) {
+	t.Helper()
+	f.Resolution = &resolution
+	f.ResolvedAt = &f.CreatedAt
+	if err := NewForecastRepository(store).UpdateForecast(context.Background(), f); err != nil {
+		t.Fatalf("UpdateForecast failed: %v", err)
+	}
+}
+
+func TestUserRepositoryUniqueUsername(t *testing.T) {
+	store, _, _ := seed(t)
+	users := NewUserRepository(store)
+
+	if _, err := users.CreateUserWithPassword(context.Background(), "alice", "hash"); err == nil {
+		t.Error("Expected duplicate username to be rejected")
+	}
+	if _, err := users.GetUserByUsername(context.Background(), "carol"); err != sql.ErrNoRows {
+		t.Errorf("Expected sql.ErrNoRows for a missing user, got %v", err)
+	}
+}
+
+func TestForecastRepositoryFilters(t *testing.T) {
+	store, alice, _ := seed(t)
+	ctx := context.Background()
+	repo := NewForecastRepository(store)
+
+	weather := createForecast(t, store, alice, "Weather")
+	createForecast(t, store, alice, "politics")
+	resolve(t, store, weather, "1")
+
+	status := "open"
+	open, _ := repo.GetForecasts(ctx, models.ForecastFilters{Status: &status})
+	if len(open) != 1 || open[0].Category != "politics" {
+		t.Errorf("Expected only the politics forecast to be open, got %+v", open)
+	}
+
+	matched, _ := repo.GetForecasts(ctx, models.ForecastFilters{CategoryIDs: []int64{*weather.CategoryID}})
+	if len(matched) != 1 || matched[0].ID != weather.ID {
+		t.Errorf("Expected the weather forecast alone, got %+v", matched)
+	}
+	// an empty set of categories matches nothing, as category_id = any('{}')
+	matched, _ = repo.GetForecasts(ctx, models.ForecastFilters{CategoryIDs: []int64{}})
+	if len(matched) != 0 {
+		t.Errorf("Expected no category to match nothing, got %d forecasts", len(matched))
+	}
+
+	if err := repo.CreateForecast(ctx, &models.Forecast{UserID: 99}); err == nil {
+		t.Error("Expected a forecast for a missing user to be rejected")
+	}
+}
+
+func TestForecastPointRepositoryDistinctOnForecast(t *testing.T) {
+	store, alice, bob := seed(t)
+	ctx := context.Background()
+	repo := NewForecastPointRepository(store)
+
+	first := createForecast(t, store, alice, "a")
+	second := createForecast(t, store, alice, "b")
+	createPoint(t, store, first.ID, alice, 0.2)
+	createPoint(t, store, second.ID, alice, 0.4)
+	createPoint(t, store, first.ID, alice, 0.6)
+	createPoint(t, store, first.ID, bob, 0.9)
+
+	distinct, orderByForecast := true, true
+	latest, err := repo.GetForecastPoints(ctx, models.PointFilters{UserID: &alice, DistinctOnForecast: &distinct, OrderByForecastID: &orderByForecast})
+	if err != nil {
+		t.Fatalf("GetForecastPoints failed: %v", err)
+	}
+	if len(latest) != 2 || latest[0].PointForecast != 0.6 || latest[1].PointForecast != 0.4 {
+		t.Errorf("Expected alice's latest point per forecast, got %+v", latest)
+	}
+	if latest[0].UserName == nil || *latest[0].UserName != "alice" {
+		t.Errorf("Expected username to be joined in, got %v", latest[0].UserName)
+	}
+
+	if _, err := repo.GetForecastPoints(ctx, models.PointFilters{DistinctOnForecast: &distinct}); err == nil {
+		t.Error("Expected distinct on forecast without ordering by forecast to fail")
+	}
+
+	asc := "ASC"
+	ordered, _ := repo.GetForecastPoints(ctx, models.PointFilters{ForecastID: &first.ID, CreatedDirection: &asc})
+	if len(ordered) != 3 || ordered[0].PointForecast != 0.2 || ordered[2].PointForecast != 0.9 {
+		t.Errorf("Expected points in ascending order, got %+v", ordered)
+	}
+}
+
+func TestCalibrationRepositoryBuckets(t *testing.T) {
+	store, alice, bob := seed(t)
+	ctx := context.Background()
+
+	yes := createForecast(t, store, alice, "a")
+	no := createForecast(t, store, alice, "b")
+	annulled := createForecast(t, store, alice, "c")
+	createPoint(t, store, yes.ID, alice, 0.72)
+	createPoint(t, store, no.ID, alice, 0.78)
+	createPoint(t, store, yes.ID, bob, 0.15)
+	createPoint(t, store, annulled.ID, bob, 0.5)
+	resolve(t, store, yes, "1")
+	resolve(t, store, no, "0")
+	resolve(t, store, annulled, "-")
+
+	repo := NewCalibrationRepository(store)
+	data, err := repo.GetCalibrationData(ctx, models.CalibrationFilters{})
+	if err != nil {
+		t.Fatalf("GetCalibrationData failed: %v", err)
+	}
+	if len(data.Buckets) != 2 || data.TotalPredictions != 3 {
+		t.Fatalf("Expected 3 predictions in 2 buckets, got %+v", data)
+	}
+	high := data.Buckets[1]
+	if math.Abs(high.BucketStart-0.7) > 1e-9 || high.PredictionCount != 2 || high.ActualRate != 0.5 || math.Abs(high.AvgPrediction-0.75) > 1e-9 {
+		t.Errorf("Unexpected 0.7 bucket %+v", high)
+	}
+
+	byUser, _ := repo.GetCalibrationDataByUsers(ctx, models.CalibrationFilters{})
+	if len(byUser) != 2 || byUser[0].UserID != alice || byUser[0].TotalPredictions != 2 || byUser[1].TotalPredictions != 1 {
+		t.Errorf("Unexpected calibration by users %+v", byUser)
+	}
+}
+
+func TestScoreRepositoryAggregates(t *testing.T) {
+	store, alice, bob := seed(t)
+	ctx := context.Background()
+	repo := NewScoreRepository(store)
+
+	weather := createForecast(t, store, alice, "weather")
+	sports := createForecast(t, store, alice, "sports")
+	peer := 0.4
+	for _, s := range []models.Scores{
+		{UserID: alice, ForecastID: weather.ID, BrierScore: 0.1, PeerScore: &peer, Rules: []models.RuleScore{{Rule: "spherical", Score: 0.9, ScoreTimeWeighted: 0.8}}},
+		{UserID: alice, ForecastID: sports.ID, BrierScore: 0.3, Rules: []models.RuleScore{{Rule: "spherical", Score: 0.5, ScoreTimeWeighted: 0.6}}},
+		{UserID: bob, ForecastID: weather.ID, BrierScore: 0.5},
+	} {
+		if err := repo.CreateScore(ctx, &s); err != nil {
+			t.Fatalf("CreateScore failed: %v", err)
+		}
+	}
+
+	overall, _ := repo.GetAggregateScores(ctx, models.ScoreFilters{})
+	if math.Abs(overall.BrierScore-0.3) > 1e-9 || overall.TotalUsers != 2 || overall.TotalForecasts != 2 {
+		t.Errorf("Unexpected overall scores %+v", overall)
+	}
+	// like AVG, scores without a peer score are left out of its average
+	if overall.PeerScore == nil || *overall.PeerScore != peer || overall.BaselineScore != nil || overall.BrierScoreLifetime != nil {
+		t.Errorf("Expected the peer score averaged over the scores having one, got %+v", overall)
+	}
+
+	rule := "spherical"
+	spherical, _ := repo.GetAggregateScores(ctx, models.ScoreFilters{Rule: &rule})
+	if spherical.RuleScore == nil || math.Abs(*spherical.RuleScore-0.7) > 1e-9 || math.Abs(*spherical.RuleScoreTimeWeighted-0.7) > 1e-9 || spherical.Rule != rule {
+		t.Errorf("Expected the spherical score averaged over the scores having one, got %+v", spherical)
+	}
+	if math.Abs(spherical.BrierScore-0.3) > 1e-9 || overall.RuleScore != nil {
+		t.Errorf("Expected the rule to leave the other averages alone, got %+v", spherical)
+	}
+
+	unscored := "quadratic"
+	if quadratic, _ := repo.GetAggregateScores(ctx, models.ScoreFilters{Rule: &unscored}); quadratic.RuleScore != nil || quadratic.Rule != unscored {
+		t.Errorf("Expected no quadratic average without quadratic scores, got %+v", quadratic)
+	}
+
+	byUser, _ := repo.GetAggregateScoresByUsers(ctx, models.ScoreFilters{CategoryIDs: []int64{*weather.CategoryID}})
+	if len(byUser) != 2 || byUser[0].BrierScore != 0.1 || byUser[1].BrierScore != 0.5 {
+		t.Errorf("Unexpected weather scores by user %+v", byUser)
+	}
+
+	groupByUser := true
+	if _, err := repo.GetAggregateScores(ctx, models.ScoreFilters{GroupByUserID: &groupByUser}); err == nil {
+		t.Error("Expected group by user to be rejected")
+	}
+	if err := repo.DeleteScore(ctx, 42); err != sql.ErrNoRows {
+		t.Errorf("Expected sql.ErrNoRows deleting a missing score, got %v", err)
+	}
+}
+
+func TestTransactorRollsBack(t *testing.T) {
+	store, alice, _ := seed(t)
+	ctx := context.Background()
+	forecasts := NewForecastRepository(store)
+	scores := NewScoreRepository(store)
+	f := createForecast(t, store, alice, "a")
+
+	err := NewTransactor(store).WithinTx(ctx, func(ctx context.Context) error {
+		resolve(t, store, f, "1")
+		if err := scores.CreateScore(ctx, &models.Scores{UserID: alice, ForecastID: f.ID}); err != nil {
+			return err
+		}
+		return sql.ErrConnDone
+	})
+	if err != sql.ErrConnDone {
+		t.Fatalf("Expected the error from fn, got %v", err)
+	}
+
+	stored, _ := forecasts.GetForecastByID(ctx, f.ID)
+	if stored.IsResolved() {
+		t.Error("Expected the resolution to be rolled back")
+	}
+	if rows, _ := scores.GetScores(ctx, models.ScoreFilters{}); len(rows) != 0 {
+		t.Errorf("Expected the score to be rolled back, got %d", len(rows))
+	}
+}
+
+func TestScoreRepositoryCreateScoreUpserts(t *testing.T) {
+	store, alice, _ := seed(t)
+	ctx := context.Background()
+	repo := NewScoreRepository(store)
+	f := createForecast(t, store, alice, "a")
+
+	first := models.Scores{UserID: alice, ForecastID: f.ID, BrierScore: 0.1}
+	second := models.Scores{UserID: alice, ForecastID: f.ID, BrierScore: 0.2}
+	repo.CreateScore(ctx, &first)
+	repo.CreateScore(ctx, &second)
+
+	rows, _ := repo.GetScores(ctx, models.ScoreFilters{})
+	if len(rows) != 1 || rows[0].BrierScore != 0.2 || second.ID != first.ID {
+		t.Errorf("Expected a single replaced score, got %+v", rows)
+	}
+}
@@ -0,0 +1,43 @@
+package memory
+
+import (
+	"backend/internal/models"
+	"fmt"
+	"slices"
+	"sort"
+)
+
+// paginate returns the page of rows after the cursor, ordered by the sort key
+// and then id, with one row past the limit like the Postgres queries
+func paginate[T models.Sortable](rows []T, page *models.Page, sorts ...string) ([]T, error) {
+	if page == nil {
+		return rows, nil
+	}
+	if !slices.Contains(sorts, page.Sort) {
+		return nil, fmt.Errorf("cannot sort by %q", page.Sort)
+	}
+
+	// before reports whether a row at the position comes first in the ordering
+	before := func(value string, id int64, otherValue string, otherID int64) bool {
+		if value != otherValue {
+			return (value < otherValue) != page.Desc
+		}
+		return id != otherID && (id < otherID) != page.Desc
+	}
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		return before(rows[i].SortValue(page.Sort), rows[i].RowID(), rows[j].SortValue(page.Sort), rows[j].RowID())
+	})
+
+	var paged []T
+	for _, row := range rows {
+		if len(paged) > page.Limit {
+			break
+		}
+		if page.After != nil && !before(page.After.Value, page.After.ID, row.SortValue(page.Sort), row.RowID()) {
+			continue
+		}
+		paged = append(paged, row)
+	}
+	return paged, nil
+}
@@ -0,0 +1,78 @@
+package memory
+
+import (
+	"backend/internal/models"
+	"backend/internal/repository"
+	"context"
+	"sort"
+	"time"
+)
+
+// ResolutionAuditRepository implements repository.ResolutionAuditRepository in memory
+type ResolutionAuditRepository struct {
+	store *Store
+}
+
+// NewResolutionAuditRepository creates a new in-memory ResolutionAuditRepository on the store
+func NewResolutionAuditRepository(store *Store) repository.ResolutionAuditRepository {
+	return &ResolutionAuditRepository{store: store}
+}
+
+func (r *ResolutionAuditRepository) CreateResolutionAudit(ctx context.Context, audit *models.ResolutionAudit) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.forecasts[audit.ForecastID]; !ok {
+		return foreignKeyError("resolution_audits", "forecast_id", audit.ForecastID)
+	}
+	if _, ok := r.store.users[audit.UserID]; !ok {
+		return foreignKeyError("resolution_audits", "user_id", audit.UserID)
+	}
+
+	audit.CreatedAt = time.Now()
+	r.store.lastAuditID++
+	audit.ID = r.store.lastAuditID
+
+	stored := cloneAudit(audit)
+	stored.ArchivedScores = 0
+	r.store.audits[audit.ID] = stored
+	return nil
+}
+
+func (r *ResolutionAuditRepository) GetResolutionAudits(ctx context.Context, forecastID int64) ([]models.ResolutionAudit, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	archivedByAudit := make(map[int64]int)
+	for _, a := range r.store.archivedScores {
+		archivedByAudit[a.auditID]++
+	}
+
+	var audits []models.ResolutionAudit
+	for _, a := range r.store.audits {
+		if a.ForecastID != forecastID {
+			continue
+		}
+		audit := *cloneAudit(a)
+		audit.ArchivedScores = archivedByAudit[a.ID]
+		audits = append(audits, audit)
+	}
+	sort.Slice(audits, func(i, j int) bool {
+		if !audits[i].CreatedAt.Equal(audits[j].CreatedAt) {
+			return audits[i].CreatedAt.Before(audits[j].CreatedAt)
+		}
+		return audits[i].ID < audits[j].ID
+	})
+	return audits, nil
+}
+
+func cloneAudit(a *models.ResolutionAudit) *models.ResolutionAudit {
+	c := *a
+	c.PreviousResolution = cloneResolution(a.PreviousResolution)
+	c.PreviousResolutionValue = cloneFloat(a.PreviousResolutionValue)
+	c.PreviousResolvedAt = cloneTime(a.PreviousResolvedAt)
+	c.Resolution = cloneResolution(a.Resolution)
+	c.ResolutionValue = cloneFloat(a.ResolutionValue)
+	c.Comment = cloneString(a.Comment)
+	return &c
+}
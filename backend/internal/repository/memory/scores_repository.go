@@ -0,0 +1,357 @@
+package memory
+
+import (
+	"backend/internal/models"
+	"backend/internal/repository"
+	"context"
+	"database/sql"
+	"errors"
+	"sort"
+	"time"
+)
+
+// ScoreRepository implements repository.ScoreRepository in memory
+type ScoreRepository struct {
+	store *Store
+}
+
+// NewScoreRepository creates a new in-memory ScoreRepository on the store
+func NewScoreRepository(store *Store) repository.ScoreRepository {
+	return &ScoreRepository{store: store}
+}
+
+func (r *ScoreRepository) GetScores(ctx context.Context, filters models.ScoreFilters) ([]models.Scores, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var scores []models.Scores
+	for _, s := range r.store.sortedScores() {
+		if filters.UserID != nil && s.UserID != *filters.UserID {
+			continue
+		}
+		if filters.ForecastID != nil && s.ForecastID != *filters.ForecastID {
+			continue
+		}
+		if !r.store.visibleTo(s.ForecastID, filters.Viewer, false) {
+			continue
+		}
+		scores = append(scores, cloneScore(s))
+	}
+
+	sort.SliceStable(scores, func(i, j int) bool {
+		return scores[i].CreatedAt.After(scores[j].CreatedAt)
+	})
+	return paginate(scores, filters.Page, models.SortCreated)
+}
+
+// GetAverageScores mirrors the SQL, which groups by id and so returns every
+// score with its id and user_id zeroed
+func (r *ScoreRepository) GetAverageScores(ctx context.Context, viewer *int64) ([]models.Scores, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var scores []models.Scores
+	for _, s := range r.store.sortedScores() {
+		if !r.store.visibleTo(s.ForecastID, viewer, false) {
+			continue
+		}
+		scores = append(scores, models.Scores{
+			BrierScore:             s.BrierScore,
+			Log2Score:              s.Log2Score,
+			LogNScore:              s.LogNScore,
+			BrierScoreTimeWeighted: s.BrierScoreTimeWeighted,
+			Log2ScoreTimeWeighted:  s.Log2ScoreTimeWeighted,
+			LogNScoreTimeWeighted:  s.LogNScoreTimeWeighted,
+			ForecastID:             s.ForecastID,
+			CreatedAt:              s.CreatedAt,
+		})
+	}
+	return scores, nil
+}
+
+// CreateScore inserts the score, or replaces the existing score of the same
+// user and forecast
+func (r *ScoreRepository) CreateScore(ctx context.Context, score *models.Scores) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.users[score.UserID]; !ok {
+		return foreignKeyError("scores", "user_id", score.UserID)
+	}
+	if _, ok := r.store.forecasts[score.ForecastID]; !ok {
+		return foreignKeyError("scores", "forecast_id", score.ForecastID)
+	}
+
+	score.CreatedAt = time.Now()
+
+	// upsert on (forecast_id, user_id) like the unique index in Postgres
+	for _, existing := range r.store.scores {
+		if existing.ForecastID == score.ForecastID && existing.UserID == score.UserID {
+			score.ID = existing.ID
+			stored := cloneScore(score)
+			r.store.scores[score.ID] = &stored
+			return nil
+		}
+	}
+
+	r.store.lastScoreID++
+	score.ID = r.store.lastScoreID
+
+	stored := cloneScore(score)
+	r.store.scores[score.ID] = &stored
+	return nil
+}
+
+func (r *ScoreRepository) UpdateScore(ctx context.Context, score *models.Scores) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	stored, ok := r.store.scores[score.ID]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	stored.BrierScore = score.BrierScore
+	stored.Log2Score = score.Log2Score
+	stored.LogNScore = score.LogNScore
+	stored.BrierScoreTimeWeighted = score.BrierScoreTimeWeighted
+	stored.Log2ScoreTimeWeighted = score.Log2ScoreTimeWeighted
+	stored.LogNScoreTimeWeighted = score.LogNScoreTimeWeighted
+	stored.CRPS = cloneFloat(score.CRPS)
+	stored.CRPSTimeWeighted = cloneFloat(score.CRPSTimeWeighted)
+	stored.PeerScore = cloneFloat(score.PeerScore)
+	stored.BaselineScore = cloneFloat(score.BaselineScore)
+	stored.BrierScoreLifetime = cloneFloat(score.BrierScoreLifetime)
+	stored.LogNScoreLifetime = cloneFloat(score.LogNScoreLifetime)
+	if score.Rules != nil {
+		stored.Rules = append([]models.RuleScore(nil), score.Rules...)
+	}
+	return nil
+}
+
+func (r *ScoreRepository) DeleteScore(ctx context.Context, scoreID int64) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.scores[scoreID]; !ok {
+		return sql.ErrNoRows
+	}
+	delete(r.store.scores, scoreID)
+	return nil
+}
+
+// ArchiveScores moves every score of the forecast into the archive under the
+// given audit record and returns how many were moved
+func (r *ScoreRepository) ArchiveScores(ctx context.Context, forecastID int64, auditID int64) (int64, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.audits[auditID]; !ok {
+		return 0, foreignKeyError("archived_scores", "audit_id", auditID)
+	}
+
+	var archived int64
+	for id, s := range r.store.scores {
+		if s.ForecastID != forecastID {
+			continue
+		}
+		r.store.archivedScores[id] = &archivedScore{score: cloneScore(s), auditID: auditID}
+		delete(r.store.scores, id)
+		archived++
+	}
+	return archived, nil
+}
+
+func (r *ScoreRepository) GetAggregateScores(ctx context.Context, filters models.ScoreFilters) (*models.OverallScores, error) {
+	if filters.GroupByUserID != nil && *filters.GroupByUserID {
+		return nil, errors.New("group by user id is not supported")
+	}
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var agg scoreAggregate
+	agg.rule = filters.Rule
+	for _, s := range r.store.filteredScores(filters) {
+		agg.add(s)
+	}
+
+	return &models.OverallScores{
+		ScoreMetrics:   agg.metrics(),
+		TotalUsers:     len(agg.users),
+		TotalForecasts: len(agg.forecasts),
+	}, nil
+}
+
+func (r *ScoreRepository) GetAggregateScoresByUsers(ctx context.Context, filters models.ScoreFilters) ([]models.UserScores, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	byUser := make(map[int64]*scoreAggregate)
+	var userIDs []int64
+	for _, s := range r.store.filteredScores(filters) {
+		agg, ok := byUser[s.UserID]
+		if !ok {
+			agg = &scoreAggregate{rule: filters.Rule}
+			byUser[s.UserID] = agg
+			userIDs = append(userIDs, s.UserID)
+		}
+		agg.add(s)
+	}
+	sort.Slice(userIDs, func(i, j int) bool { return userIDs[i] < userIDs[j] })
+
+	var userScores []models.UserScores
+	for _, userID := range userIDs {
+		agg := byUser[userID]
+		userScores = append(userScores, models.UserScores{
+			ScoreMetrics:   agg.metrics(),
+			UserID:         userID,
+			TotalForecasts: len(agg.forecasts),
+		})
+	}
+	return userScores, nil
+}
+
+// filteredScores applies the where clause of buildAggregateScoreQuery.
+// Callers must hold the store lock.
+func (s *Store) filteredScores(filters models.ScoreFilters) []*models.Scores {
+	var scores []*models.Scores
+	for _, score := range s.sortedScores() {
+		if filters.UserID != nil && score.UserID != *filters.UserID {
+			continue
+		}
+		if filters.ForecastID != nil && score.ForecastID != *filters.ForecastID {
+			continue
+		}
+		if filters.CategoryIDs != nil {
+			f, ok := s.forecasts[score.ForecastID]
+			if !ok || !inCategories(f.CategoryID, filters.CategoryIDs) {
+				continue
+			}
+		}
+		if filters.StartDate != nil && score.CreatedAt.Before(*filters.StartDate) {
+			continue
+		}
+		if filters.EndDate != nil && score.CreatedAt.After(*filters.EndDate) {
+			continue
+		}
+		if !s.visibleTo(score.ForecastID, filters.Viewer, false) {
+			continue
+		}
+		if f, ok := s.forecasts[score.ForecastID]; filters.QuestionType != nil && (!ok || f.QuestionType != *filters.QuestionType) {
+			continue
+		}
+		if u, ok := s.users[score.UserID]; filters.ExcludeCrowd && ok && u.Username == models.CrowdUsername && u.Role == models.RoleBot {
+			continue
+		}
+		scores = append(scores, score)
+	}
+	return scores
+}
+
+// sortedScores returns the stored scores in insertion order.
+// Callers must hold the store lock.
+func (s *Store) sortedScores() []*models.Scores {
+	scores := make([]*models.Scores, 0, len(s.scores))
+	for _, score := range s.scores {
+		scores = append(scores, score)
+	}
+	sort.Slice(scores, func(i, j int) bool {
+		return scores[i].ID < scores[j].ID
+	})
+	return scores
+}
+
+// scoreAggregate accumulates the averages and distinct counts of the aggregate
+// queries
+type scoreAggregate struct {
+	sum       models.ScoreMetrics
+	count     int
+	peer      nullableAverage
+	baseline  nullableAverage
+	brierLife nullableAverage
+	lognLife  nullableAverage
+	// rule, when not nil, is the scoring rule also averaged, as by the left
+	// join on rule_scores
+	rule                  *string
+	ruleScore             nullableAverage
+	ruleScoreTimeWeighted nullableAverage
+	users                 map[int64]bool
+	forecasts             map[int64]bool
+}
+
+func (a *scoreAggregate) add(s *models.Scores) {
+	if a.users == nil {
+		a.users = make(map[int64]bool)
+		a.forecasts = make(map[int64]bool)
+	}
+	a.sum.BrierScore += s.BrierScore
+	a.sum.Log2Score += s.Log2Score
+	a.sum.LogNScore += s.LogNScore
+	a.sum.BrierScoreTimeWeighted += s.BrierScoreTimeWeighted
+	a.sum.Log2ScoreTimeWeighted += s.Log2ScoreTimeWeighted
+	a.sum.LogNScoreTimeWeighted += s.LogNScoreTimeWeighted
+	a.peer.add(s.PeerScore)
+	a.baseline.add(s.BaselineScore)
+	a.brierLife.add(s.BrierScoreLifetime)
+	a.lognLife.add(s.LogNScoreLifetime)
+	if a.rule != nil {
+		for _, rs := range s.Rules {
+			if rs.Rule == *a.rule {
+				a.ruleScore.add(&rs.Score)
+				a.ruleScoreTimeWeighted.add(&rs.ScoreTimeWeighted)
+			}
+		}
+	}
+	a.count++
+	a.users[s.UserID] = true
+	a.forecasts[s.ForecastID] = true
+}
+
+// metrics returns the averages, or zeros when nothing matched as coalesce does
+func (a *scoreAggregate) metrics() models.ScoreMetrics {
+	var m models.ScoreMetrics
+	if a.rule != nil {
+		m.Rule, m.RuleScore, m.RuleScoreTimeWeighted = *a.rule, a.ruleScore.average(), a.ruleScoreTimeWeighted.average()
+	}
+	if a.count == 0 {
+		return m
+	}
+	n := float64(a.count)
+	return models.ScoreMetrics{
+		BrierScore:             a.sum.BrierScore / n,
+		Log2Score:              a.sum.Log2Score / n,
+		LogNScore:              a.sum.LogNScore / n,
+		BrierScoreTimeWeighted: a.sum.BrierScoreTimeWeighted / n,
+		Log2ScoreTimeWeighted:  a.sum.Log2ScoreTimeWeighted / n,
+		LogNScoreTimeWeighted:  a.sum.LogNScoreTimeWeighted / n,
+		PeerScore:              a.peer.average(),
+		BaselineScore:          a.baseline.average(),
+		BrierScoreLifetime:     a.brierLife.average(),
+		LogNScoreLifetime:      a.lognLife.average(),
+		Rule:                   m.Rule,
+		RuleScore:              m.RuleScore,
+		RuleScoreTimeWeighted:  m.RuleScoreTimeWeighted,
+	}
+}
+
+// nullableAverage averages a nullable column, skipping nulls as AVG does
+type nullableAverage struct {
+	sum   float64
+	count int
+}
+
+func (n *nullableAverage) add(v *float64) {
+	if v != nil {
+		n.sum += *v
+		n.count++
+	}
+}
+
+// average returns the average, or nil when every value was null as AVG does
+func (n nullableAverage) average() *float64 {
+	if n.count == 0 {
+		return nil
+	}
+	avg := n.sum / float64(n.count)
+	return &avg
+}
@@ -0,0 +1,286 @@
+package memory
+
+import (
+	"backend/internal/models"
+	"context"
+	"html"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// The weights Postgres' ts_rank gives to the A, B, C and D labels the
+// migration sets on the question, resolution criteria, comment and reason
+const (
+	weightQuestion           = 1.0
+	weightResolutionCriteria = 0.4
+	weightComment            = 0.2
+	weightReason             = 0.1
+)
+
+// snippetWords is how many words a snippet keeps, as MaxWords in ts_headline
+const snippetWords = 35
+
+var wordPattern = regexp.MustCompile(`[\p{L}\p{N}]+`)
+
+// stopWords are the common words Postgres' english configuration ignores that
+// are likely in questions
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true,
+	"by": true, "did": true, "do": true, "does": true, "for": true, "from": true, "has": true,
+	"have": true, "if": true, "in": true, "into": true, "is": true, "it": true, "its": true,
+	"of": true, "on": true, "or": true, "than": true, "that": true, "the": true, "their": true,
+	"then": true, "there": true, "this": true, "to": true, "was": true, "were": true,
+	"what": true, "when": true, "which": true, "who": true, "will": true, "with": true,
+}
+
+// stem is a crude stand-in for Postgres' english stemmer, good enough for
+// plurals and the common verb forms
+func stem(word string) string {
+	for _, suffix := range []string{"ing", "ies", "es", "ed", "s"} {
+		if strings.HasSuffix(word, suffix) && len(word)-len(suffix) >= 3 {
+			word = strings.TrimSuffix(word, suffix)
+			if suffix == "ies" {
+				word += "y"
+			}
+			return word
+		}
+	}
+	return word
+}
+
+// token is a word of a text and where it is
+type token struct {
+	stem       string
+	start, end int
+}
+
+func tokenize(text string) []token {
+	var tokens []token
+	for _, loc := range wordPattern.FindAllStringIndex(text, -1) {
+		word := strings.ToLower(text[loc[0]:loc[1]])
+		if stopWords[word] {
+			continue
+		}
+		tokens = append(tokens, token{stem: stem(word), start: loc[0], end: loc[1]})
+	}
+	return tokens
+}
+
+// searchQuery is a parsed web search query: every term must match and no
+// excluded word may. A term of several words is a phrase.
+type searchQuery struct {
+	terms    [][]string
+	excluded []string
+}
+
+func parseSearchQuery(query string) searchQuery {
+	var q searchQuery
+	for i, part := range strings.Split(query, `"`) {
+		// odd parts are between quotes
+		if i%2 == 1 {
+			var phrase []string
+			for _, t := range tokenize(part) {
+				phrase = append(phrase, t.stem)
+			}
+			if phrase != nil {
+				q.terms = append(q.terms, phrase)
+			}
+			continue
+		}
+		for _, field := range strings.Fields(part) {
+			excluded := strings.HasPrefix(field, "-")
+			for _, t := range tokenize(field) {
+				if excluded {
+					q.excluded = append(q.excluded, t.stem)
+				} else {
+					q.terms = append(q.terms, []string{t.stem})
+				}
+			}
+		}
+	}
+	return q
+}
+
+// hits returns the tokens of the text matching a term
+func (q searchQuery) hits(tokens []token) []token {
+	var hits []token
+	for _, term := range q.terms {
+		for i := 0; i+len(term) <= len(tokens); i++ {
+			matches := true
+			for j, word := range term {
+				if tokens[i+j].stem != word {
+					matches = false
+					break
+				}
+			}
+			if matches {
+				hits = append(hits, tokens[i:i+len(term)]...)
+			}
+		}
+	}
+	return hits
+}
+
+// matches reports whether texts, taken together, contain every term and no
+// excluded word
+func (q searchQuery) matches(texts ...[]token) bool {
+	if len(q.terms) == 0 {
+		return false
+	}
+	for _, term := range q.terms {
+		found := false
+		for _, tokens := range texts {
+			if len((searchQuery{terms: [][]string{term}}).hits(tokens)) > 0 {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	for _, tokens := range texts {
+		for _, t := range tokens {
+			if slices.Contains(q.excluded, t.stem) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// headline marks the matching words of the text, keeping the words around the
+// first match when it is long. The text is HTML escaped, so only the marks are
+// markup.
+func headline(text string, tokens []token, hits []token) string {
+	start, end := 0, len(text)
+	if len(tokens) > snippetWords {
+		first := 0
+		if len(hits) > 0 {
+			first = slices.IndexFunc(tokens, func(t token) bool { return t.start == hits[0].start })
+		}
+		from := max(0, min(first-snippetWords/4, len(tokens)-snippetWords))
+		start, end = tokens[from].start, tokens[from+snippetWords-1].end
+	}
+
+	marked := make(map[int]int, len(hits))
+	for _, h := range hits {
+		marked[h.start] = h.end
+	}
+	var b strings.Builder
+	pos := start
+	for _, t := range tokens {
+		stop, ok := marked[t.start]
+		if !ok || t.start < start || t.end > end {
+			continue
+		}
+		b.WriteString(html.EscapeString(text[pos:t.start]))
+		b.WriteString(models.HighlightStart + html.EscapeString(text[t.start:stop]) + models.HighlightStop)
+		pos = stop
+	}
+	b.WriteString(html.EscapeString(text[pos:end]))
+	return b.String()
+}
+
+// searchField is a searched text of a forecast and its weight in the rank
+type searchField struct {
+	name   string
+	text   string
+	weight float64
+}
+
+func (r *ForecastRepository) SearchForecasts(ctx context.Context, filters models.SearchFilters) ([]models.SearchResult, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	q := parseSearchQuery(filters.Query)
+	today := currentDate()
+
+	type reasonMatch struct {
+		point *models.ForecastPoint
+		rank  float64
+		match models.SearchMatch
+	}
+	reasons := make(map[int64][]reasonMatch)
+	for _, p := range r.store.points {
+		tokens := tokenize(p.Reason)
+		if !q.matches(tokens) {
+			continue
+		}
+		hits := q.hits(tokens)
+		pointID := p.ID
+		reasons[p.ForecastID] = append(reasons[p.ForecastID], reasonMatch{
+			point: p,
+			rank:  weightReason * float64(len(hits)),
+			match: models.SearchMatch{Field: models.SearchFieldReason, PointID: &pointID, Snippet: headline(p.Reason, tokens, hits)},
+		})
+	}
+
+	results := []models.SearchResult{}
+	for _, f := range r.store.forecasts {
+		switch {
+		case filters.Status != nil && *filters.Status == "open" && f.ResolvedAt != nil,
+			filters.Status != nil && *filters.Status == "resolved" && f.ResolvedAt == nil,
+			filters.Status != nil && *filters.Status == "closed" && (f.ClosingDate == nil || !today.After(*f.ClosingDate)):
+			continue
+		}
+		if !inCategories(f.CategoryID, filters.CategoryIDs) || !r.store.visibleTo(f.ID, filters.Viewer, true) {
+			continue
+		}
+
+		fields := []searchField{
+			{models.SearchFieldQuestion, f.Question, weightQuestion},
+			{models.SearchFieldResolutionCriteria, f.ResolutionCriteria, weightResolutionCriteria},
+		}
+		if f.ResolutionComment != nil {
+			fields = append(fields, searchField{models.SearchFieldComment, *f.ResolutionComment, weightComment})
+		}
+
+		result := models.SearchResult{Forecast: cloneForecast(f), Matches: []models.SearchMatch{}}
+		all := make([][]token, len(fields))
+		for i, field := range fields {
+			all[i] = tokenize(field.text)
+		}
+		matched := q.matches(all...)
+		for i, field := range fields {
+			hits := q.hits(all[i])
+			if matched {
+				result.Rank += field.weight * float64(len(hits))
+			}
+			if q.matches(all[i]) {
+				result.Matches = append(result.Matches, models.SearchMatch{Field: field.name, Snippet: headline(field.text, all[i], hits)})
+			}
+		}
+
+		found := reasons[f.ID]
+		if !matched && len(found) == 0 {
+			continue
+		}
+		sort.Slice(found, func(i, j int) bool {
+			if found[i].rank != found[j].rank {
+				return found[i].rank > found[j].rank
+			}
+			return found[i].point.ID > found[j].point.ID
+		})
+		for i, reason := range found {
+			result.Rank += reason.rank
+			if i < models.MaxSearchReasons {
+				result.Matches = append(result.Matches, reason.match)
+			}
+		}
+		results = append(results, result)
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Rank != results[j].Rank {
+			return results[i].Rank > results[j].Rank
+		}
+		return results[i].Forecast.ID > results[j].Forecast.ID
+	})
+	if filters.Limit > 0 && len(results) > filters.Limit {
+		results = results[:filters.Limit]
+	}
+	return results, nil
+}
@@ -0,0 +1,112 @@
+package memory
+
+import (
+	"backend/internal/models"
+	"backend/internal/repository"
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// SessionRepository implements repository.SessionRepository in memory
+type SessionRepository struct {
+	store *Store
+}
+
+// NewSessionRepository creates a new in-memory SessionRepository on the store
+func NewSessionRepository(store *Store) repository.SessionRepository {
+	return &SessionRepository{store: store}
+}
+
+func (r *SessionRepository) CreateRefreshToken(ctx context.Context, token *models.RefreshToken) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.users[token.UserID]; !ok {
+		return foreignKeyError("refresh_tokens", "user_id", token.UserID)
+	}
+	for _, t := range r.store.refreshTokens {
+		if t.TokenHash == token.TokenHash {
+			return fmt.Errorf("duplicate key value violates unique constraint: token_hash already exists")
+		}
+	}
+
+	token.CreatedAt = time.Now()
+	r.store.lastRefreshTokenID++
+	token.ID = r.store.lastRefreshTokenID
+
+	stored := cloneRefreshToken(token)
+	stored.RevokedAt = nil
+	r.store.refreshTokens[token.ID] = stored
+	return nil
+}
+
+func (r *SessionRepository) GetRefreshTokenByHash(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	for _, t := range r.store.refreshTokens {
+		if t.TokenHash == tokenHash {
+			return cloneRefreshToken(t), nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (r *SessionRepository) RevokeRefreshToken(ctx context.Context, id int64) (bool, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	t, ok := r.store.refreshTokens[id]
+	if !ok || t.RevokedAt != nil {
+		return false, nil
+	}
+	now := time.Now()
+	t.RevokedAt = &now
+	return true, nil
+}
+
+func (r *SessionRepository) RevokeRefreshTokenFamily(ctx context.Context, familyID string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	now := time.Now()
+	for _, t := range r.store.refreshTokens {
+		if t.FamilyID == familyID && t.RevokedAt == nil {
+			revokedAt := now
+			t.RevokedAt = &revokedAt
+		}
+	}
+	return nil
+}
+
+func (r *SessionRepository) RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	now := time.Now()
+	for id, expires := range r.store.revokedTokens {
+		if expires.Before(now) {
+			delete(r.store.revokedTokens, id)
+		}
+	}
+	if _, ok := r.store.revokedTokens[jti]; !ok {
+		r.store.revokedTokens[jti] = expiresAt
+	}
+	return nil
+}
+
+func (r *SessionRepository) IsTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	_, ok := r.store.revokedTokens[jti]
+	return ok, nil
+}
+
+func cloneRefreshToken(t *models.RefreshToken) *models.RefreshToken {
+	c := *t
+	c.RevokedAt = cloneTime(t.RevokedAt)
+	return &c
+}
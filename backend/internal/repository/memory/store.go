@@ -0,0 +1,205 @@
+// Package memory implements the repository interfaces on top of in-process
+// maps, so services and handlers can be exercised without a database. The
+// repositories mirror the semantics of the Postgres queries, including their
+// filtering, ordering and error values.
+package memory
+
+import (
+	"backend/internal/models"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Store holds the tables shared by the in-memory repositories. Repositories
+// built on the same store see each other's writes, as they would in Postgres.
+type Store struct {
+	mu sync.RWMutex
+	// txMu serializes transactions, see Transactor
+	txMu sync.Mutex
+
+	users          map[int64]*models.User
+	forecasts      map[int64]*models.Forecast
+	categories     map[int64]*models.Category
+	points         map[int64]*models.ForecastPoint
+	scores         map[int64]*models.Scores
+	archivedScores map[int64]*archivedScore
+	audits         map[int64]*models.ResolutionAudit
+	revisions      map[int64]*models.ForecastRevision
+	comments       map[int64]*models.Comment
+	apiKeys        map[int64]*models.APIKey
+	refreshTokens  map[int64]*models.RefreshToken
+	// revokedTokens maps the jti of a revoked access token to its expiry
+	revokedTokens map[string]time.Time
+
+	lastUserID     int64
+	lastForecastID int64
+	lastCategoryID int64
+	lastPointID    int64
+	lastScoreID    int64
+	lastAuditID    int64
+	lastRevisionID int64
+	lastCommentID  int64
+	lastAPIKeyID   int64
+
+	lastRefreshTokenID int64
+}
+
+// archivedScore is a row of archived_scores
+type archivedScore struct {
+	score   models.Scores
+	auditID int64
+}
+
+// NewStore creates an empty Store
+func NewStore() *Store {
+	return &Store{
+		users:          make(map[int64]*models.User),
+		forecasts:      make(map[int64]*models.Forecast),
+		categories:     make(map[int64]*models.Category),
+		points:         make(map[int64]*models.ForecastPoint),
+		scores:         make(map[int64]*models.Scores),
+		archivedScores: make(map[int64]*archivedScore),
+		audits:         make(map[int64]*models.ResolutionAudit),
+		revisions:      make(map[int64]*models.ForecastRevision),
+		comments:       make(map[int64]*models.Comment),
+		apiKeys:        make(map[int64]*models.APIKey),
+		refreshTokens:  make(map[int64]*models.RefreshToken),
+		revokedTokens:  make(map[string]time.Time),
+	}
+}
+
+// foreignKeyError mimics the error Postgres returns when a row references a
+// missing parent
+func foreignKeyError(table string, column string, id int64) error {
+	return fmt.Errorf("insert on table %q violates foreign key constraint: %s %d does not exist", table, column, id)
+}
+
+// currentDate is the in-memory equivalent of current_date
+func currentDate() time.Time {
+	now := time.Now()
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+}
+
+// inCategories is the in-memory equivalent of category_id = any(ids), for a
+// filter that is skipped when ids is nil
+func inCategories(categoryID *int64, ids []int64) bool {
+	return ids == nil || (categoryID != nil && slices.Contains(ids, *categoryID))
+}
+
+// visibleTo is the in-memory equivalent of visibilityCondition on the
+// forecast, for a filter that is skipped when viewer is nil. Callers must hold
+// the store lock.
+func (s *Store) visibleTo(forecastID int64, viewer *int64, listed bool) bool {
+	if viewer == nil {
+		return true
+	}
+	f, ok := s.forecasts[forecastID]
+	if !ok {
+		return false
+	}
+	if listed {
+		return f.IsListedFor(*viewer)
+	}
+	return f.CanView(*viewer)
+}
+
+// likeContains is the in-memory equivalent of lower(column) like '%pattern%'.
+// As in the SQL only the column is lowercased, not the pattern.
+func likeContains(value string, pattern string) bool {
+	return strings.Contains(strings.ToLower(value), pattern)
+}
+
+func cloneTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	c := *t
+	return &c
+}
+
+func cloneString(s *string) *string {
+	if s == nil {
+		return nil
+	}
+	c := *s
+	return &c
+}
+
+func cloneResolution(r *models.Resolution) *models.Resolution {
+	if r == nil {
+		return nil
+	}
+	c := *r
+	return &c
+}
+
+func cloneFloat(f *float64) *float64 {
+	if f == nil {
+		return nil
+	}
+	c := *f
+	return &c
+}
+
+func cloneInt64(i *int64) *int64 {
+	if i == nil {
+		return nil
+	}
+	c := *i
+	return &c
+}
+
+func cloneForecast(f *models.Forecast) *models.Forecast {
+	c := *f
+	c.ClosingDate = cloneTime(f.ClosingDate)
+	c.Resolution = cloneResolution(f.Resolution)
+	c.ResolutionValue = cloneFloat(f.ResolutionValue)
+	c.ResolvedAt = cloneTime(f.ResolvedAt)
+	c.ResolutionComment = cloneString(f.ResolutionComment)
+	c.RangeMin = cloneFloat(f.RangeMin)
+	c.RangeMax = cloneFloat(f.RangeMax)
+	c.CategoryID = cloneInt64(f.CategoryID)
+	if f.Options != nil {
+		c.Options = append(models.Options{}, f.Options...)
+	}
+	if f.Tags != nil {
+		c.Tags = append([]string{}, f.Tags...)
+	}
+	if f.SharedWith != nil {
+		c.SharedWith = append([]int64{}, f.SharedWith...)
+	}
+	return &c
+}
+
+func clonePoint(p *models.ForecastPoint) *models.ForecastPoint {
+	c := *p
+	c.UserName = cloneString(p.UserName)
+	if p.Probabilities != nil {
+		c.Probabilities = append(models.Probabilities{}, p.Probabilities...)
+	}
+	if p.Distribution != nil {
+		d := *p.Distribution
+		if d.Quantiles != nil {
+			d.Quantiles = append([]models.Quantile{}, d.Quantiles...)
+		}
+		c.Distribution = &d
+	}
+	return &c
+}
+
+func cloneScore(s *models.Scores) models.Scores {
+	c := *s
+	c.CRPS = cloneFloat(s.CRPS)
+	c.CRPSTimeWeighted = cloneFloat(s.CRPSTimeWeighted)
+	c.PeerScore = cloneFloat(s.PeerScore)
+	c.BaselineScore = cloneFloat(s.BaselineScore)
+	c.BrierScoreLifetime = cloneFloat(s.BrierScoreLifetime)
+	c.LogNScoreLifetime = cloneFloat(s.LogNScoreLifetime)
+	if s.Rules != nil {
+		c.Rules = append([]models.RuleScore(nil), s.Rules...)
+	}
+	return c
+}
@@ -0,0 +1,139 @@
+package memory
+
+import (
+	"backend/internal/models"
+	"backend/internal/repository"
+	"context"
+	"time"
+)
+
+type txKey struct{}
+
+// Transactor implements repository.Transactor in memory. Transactions are
+// serialized, and a failed one restores the tables to their state when it
+// began, so writes made outside a transaction while one is running may be
+// lost on rollback. IDs are not reused after a rollback, as with sequences.
+type Transactor struct {
+	store *Store
+}
+
+// NewTransactor creates a new in-memory Transactor on the store
+func NewTransactor(store *Store) repository.Transactor {
+	return &Transactor{store: store}
+}
+
+func (t *Transactor) WithinTx(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	if ctx.Value(txKey{}) != nil {
+		return fn(ctx)
+	}
+
+	t.store.txMu.Lock()
+	defer t.store.txMu.Unlock()
+
+	snapshot := t.store.snapshot()
+	defer func() {
+		if p := recover(); p != nil {
+			t.store.restore(snapshot)
+			panic(p)
+		}
+		if err != nil {
+			t.store.restore(snapshot)
+		}
+	}()
+
+	return fn(context.WithValue(ctx, txKey{}, true))
+}
+
+// tables is a copy of the store's rows
+type tables struct {
+	users          map[int64]*models.User
+	forecasts      map[int64]*models.Forecast
+	categories     map[int64]*models.Category
+	points         map[int64]*models.ForecastPoint
+	scores         map[int64]*models.Scores
+	archivedScores map[int64]*archivedScore
+	audits         map[int64]*models.ResolutionAudit
+	revisions      map[int64]*models.ForecastRevision
+	comments       map[int64]*models.Comment
+	apiKeys        map[int64]*models.APIKey
+	refreshTokens  map[int64]*models.RefreshToken
+	revokedTokens  map[string]time.Time
+}
+
+func (s *Store) snapshot() tables {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	t := tables{
+		users:     make(map[int64]*models.User, len(s.users)),
+		forecasts: make(map[int64]*models.Forecast, len(s.forecasts)),
+		points:    make(map[int64]*models.ForecastPoint, len(s.points)),
+		scores:    make(map[int64]*models.Scores, len(s.scores)),
+
+		categories:     make(map[int64]*models.Category, len(s.categories)),
+		archivedScores: make(map[int64]*archivedScore, len(s.archivedScores)),
+		audits:         make(map[int64]*models.ResolutionAudit, len(s.audits)),
+		revisions:      make(map[int64]*models.ForecastRevision, len(s.revisions)),
+		comments:       make(map[int64]*models.Comment, len(s.comments)),
+		apiKeys:        make(map[int64]*models.APIKey, len(s.apiKeys)),
+		refreshTokens:  make(map[int64]*models.RefreshToken, len(s.refreshTokens)),
+		revokedTokens:  make(map[string]time.Time, len(s.revokedTokens)),
+	}
+	for id, u := range s.users {
+		user := *u
+		t.users[id] = &user
+	}
+	for id, f := range s.forecasts {
+		t.forecasts[id] = cloneForecast(f)
+	}
+	for id, c := range s.categories {
+		t.categories[id] = cloneCategory(c)
+	}
+	for id, p := range s.points {
+		t.points[id] = clonePoint(p)
+	}
+	for id, score := range s.scores {
+		c := cloneScore(score)
+		t.scores[id] = &c
+	}
+	for id, a := range s.archivedScores {
+		t.archivedScores[id] = &archivedScore{score: cloneScore(&a.score), auditID: a.auditID}
+	}
+	for id, a := range s.audits {
+		t.audits[id] = cloneAudit(a)
+	}
+	for id, rev := range s.revisions {
+		t.revisions[id] = cloneRevision(rev)
+	}
+	for id, c := range s.comments {
+		t.comments[id] = cloneComment(c)
+	}
+	for id, k := range s.apiKeys {
+		t.apiKeys[id] = cloneAPIKey(k)
+	}
+	for id, rt := range s.refreshTokens {
+		t.refreshTokens[id] = cloneRefreshToken(rt)
+	}
+	for jti, expires := range s.revokedTokens {
+		t.revokedTokens[jti] = expires
+	}
+	return t
+}
+
+func (s *Store) restore(t tables) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.users = t.users
+	s.forecasts = t.forecasts
+	s.categories = t.categories
+	s.points = t.points
+	s.scores = t.scores
+	s.archivedScores = t.archivedScores
+	s.audits = t.audits
+	s.revisions = t.revisions
+	s.comments = t.comments
+	s.apiKeys = t.apiKeys
+	s.refreshTokens = t.refreshTokens
+	s.revokedTokens = t.revokedTokens
+}
@@ -0,0 +1,174 @@
+package memory
+
+import (
+	"backend/internal/models"
+	"backend/internal/repository"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+)
+
+// UserRepository implements repository.UserRepository in memory
+type UserRepository struct {
+	store *Store
+}
+
+// NewUserRepository creates a new in-memory UserRepository on the store
+func NewUserRepository(store *Store) repository.UserRepository {
+	return &UserRepository{store: store}
+}
+
+func (r *UserRepository) CreateUser(ctx context.Context, user *models.User) error {
+	user.CreatedAt = time.Now()
+
+	if user.Role == "" {
+		user.Role = models.RoleForecaster
+	}
+
+	id, err := r.insert(user.Username, user.Password, user.Role, user.CreatedAt)
+	if err != nil {
+		return err
+	}
+	user.ID = id
+	return nil
+}
+
+func (r *UserRepository) GetUserByID(ctx context.Context, id int64) (*models.User, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	u, ok := r.store.users[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	user := *u
+	return &user, nil
+}
+
+func (r *UserRepository) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	for _, u := range r.store.users {
+		if u.Username == username {
+			user := *u
+			return &user, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (r *UserRepository) DeleteUser(ctx context.Context, id int64) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.users[id]; !ok {
+		return errors.New("user not found")
+	}
+	delete(r.store.users, id)
+
+	// api keys, refresh tokens, forecast shares and mentions cascade in Postgres
+	for _, f := range r.store.forecasts {
+		f.SharedWith = slices.DeleteFunc(f.SharedWith, func(userID int64) bool { return userID == id })
+	}
+	for _, c := range r.store.comments {
+		c.Mentions = slices.DeleteFunc(c.Mentions, func(userID int64) bool { return userID == id })
+	}
+	for keyID, k := range r.store.apiKeys {
+		if k.UserID == id {
+			delete(r.store.apiKeys, keyID)
+		}
+	}
+	for tokenID, t := range r.store.refreshTokens {
+		if t.UserID == id {
+			delete(r.store.refreshTokens, tokenID)
+		}
+	}
+	return nil
+}
+
+func (r *UserRepository) ValidateUser(ctx context.Context, id int64) (bool, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	_, ok := r.store.users[id]
+	return ok, nil
+}
+
+func (r *UserRepository) UpdatePassword(ctx context.Context, id int64, password string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if u, ok := r.store.users[id]; ok {
+		u.Password = password
+		u.TokenVersion++
+	}
+	return nil
+}
+
+func (r *UserRepository) BumpTokenVersion(ctx context.Context, id int64) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if u, ok := r.store.users[id]; ok {
+		u.TokenVersion++
+	}
+	return nil
+}
+
+func (r *UserRepository) UpdateRole(ctx context.Context, id int64, role models.Role) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	u, ok := r.store.users[id]
+	if !ok {
+		return errors.New("user not found")
+	}
+	u.Role = role
+	u.TokenVersion++
+	return nil
+}
+
+// ListUsers returns users without their password hash, as the SQL does not select it
+func (r *UserRepository) ListUsers(ctx context.Context) ([]*models.User, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var users []*models.User
+	for _, u := range r.store.users {
+		users = append(users, &models.User{ID: u.ID, Username: u.Username, Role: u.Role, CreatedAt: u.CreatedAt})
+	}
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].ID < users[j].ID
+	})
+	return users, nil
+}
+
+func (r *UserRepository) CreateUserWithPassword(ctx context.Context, username string, passwordHash string) (int64, error) {
+	return r.insert(username, passwordHash, models.RoleForecaster, time.Now())
+}
+
+func (r *UserRepository) insert(username string, password string, role models.Role, createdAt time.Time) (int64, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, u := range r.store.users {
+		if u.Username == username {
+			return 0, fmt.Errorf("duplicate key value violates unique constraint: username %q already exists", username)
+		}
+	}
+
+	r.store.lastUserID++
+	r.store.users[r.store.lastUserID] = &models.User{
+		ID:        r.store.lastUserID,
+		Username:  username,
+		Password:  password,
+		Role:      role,
+		CreatedAt: createdAt,
+	}
+	return r.store.lastUserID, nil
+}
@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"backend/internal/models"
+	"fmt"
+)
+
+// sortColumn is what a sort key orders a list's rows by, and the type cursor
+// values are cast to when comparing with it
+type sortColumn struct {
+	expr string
+	cast string
+}
+
+var (
+	forecastSorts = map[string]sortColumn{
+		models.SortCreated:     {"created", "timestamp"},
+		models.SortClosingDate: {"coalesce(closing_date, 'infinity')", "timestamp"},
+		models.SortQuestion:    {"coalesce(question, '')", "text"},
+	}
+	pointSorts = map[string]sortColumn{
+		models.SortCreated: {"created", "timestamp"},
+	}
+	scoreSorts = map[string]sortColumn{
+		models.SortCreated: {"created", "timestamp"},
+	}
+	commentSorts = map[string]sortColumn{
+		models.SortCreated: {"created", "timestamp"},
+	}
+)
+
+// paginate wraps a list query to return the page of it after the cursor,
+// ordered by the sort key and then id, with one row past the limit. Its
+// arguments, from pageArgs, are numbered from argsCounter.
+func paginate(query string, page models.Page, sorts map[string]sortColumn, argsCounter int) (string, error) {
+	column, ok := sorts[page.Sort]
+	if !ok {
+		return "", fmt.Errorf("cannot sort by %q", page.Sort)
+	}
+
+	direction, comparison := "asc", ">"
+	if page.Desc {
+		direction, comparison = "desc", "<"
+	}
+
+	where := "1=1"
+	if page.After != nil {
+		where = fmt.Sprintf("(%s, id) %s ($%d::%s, $%d)", column.expr, comparison, argsCounter, column.cast, argsCounter+1)
+		argsCounter += 2
+	}
+
+	return fmt.Sprintf(
+		`select * from (%s) page
+		where %s
+		order by %s %s, id %s
+		limit $%d`,
+		query,
+		where,
+		column.expr, direction, direction,
+		argsCounter,
+	), nil
+}
+
+// pageArgs returns the arguments of the query paginate wraps around
+func pageArgs(page *models.Page) []any {
+	if page == nil {
+		return nil
+	}
+
+	var args []any
+	if page.After != nil {
+		args = append(args, page.After.Value, page.After.ID)
+	}
+	return append(args, page.Limit+1)
+}
@@ -22,6 +22,8 @@ func TestBuildForecastPointQueryAllFilters(t *testing.T) {
 			p.id,
 			p.forecast_id,
 			p.point_forecast,
+			p.probabilities,
+			p.distribution,
 			p.reason,
 			p.created,
 			p.user_id,
@@ -69,6 +71,8 @@ func TestBuildForecastPointQueryNoFilters(t *testing.T) {
 		p.id,
 		p.forecast_id,
 		p.point_forecast,
+		p.probabilities,
+		p.distribution,
 		p.reason,
 		p.created,
 		p.user_id,
@@ -98,6 +102,8 @@ func TestBuildForecastPointQueryAscDirection(t *testing.T) {
 		p.id,
 		p.forecast_id,
 		p.point_forecast,
+		p.probabilities,
+		p.distribution,
 		p.reason,
 		p.created,
 		p.user_id,
@@ -134,6 +140,8 @@ func TestBuildForecastPointForecastID(t *testing.T) {
 		p.id,
 		p.forecast_id,
 		p.point_forecast,
+		p.probabilities,
+		p.distribution,
 		p.reason,
 		p.created,
 		p.user_id,
@@ -164,6 +172,8 @@ func TestBuildForecastPointQuery_GetForecastPointsByForecastID(t *testing.T) {
 		p.id,
 		p.forecast_id,
 		p.point_forecast,
+		p.probabilities,
+		p.distribution,
 		p.reason,
 		p.created,
 		p.user_id,
@@ -200,6 +210,8 @@ func TestBuildForecastPointQuery_GetForecastPointsByForecastIDAndUser(t *testing
 		p.id,
 		p.forecast_id,
 		p.point_forecast,
+		p.probabilities,
+		p.distribution,
 		p.reason,
 		p.created,
 		p.user_id,
@@ -238,6 +250,8 @@ func TestBuildForecastPointQuery_GetLatestForecastPoints(t *testing.T) {
 		p.id,
 		p.forecast_id,
 		p.point_forecast,
+		p.probabilities,
+		p.distribution,
 		p.reason,
 		p.created,
 		p.user_id,
@@ -276,6 +290,8 @@ func TestBuildForecastPointQuery_GetLatestForecastPointsByUser(t *testing.T) {
 		p.id,
 		p.forecast_id,
 		p.point_forecast,
+		p.probabilities,
+		p.distribution,
 		p.reason,
 		p.created,
 		p.user_id,
@@ -316,6 +332,8 @@ func TestBuildForecastPointQuery_GetForecastPointsByDate(t *testing.T) {
 		p.id,
 		p.forecast_id,
 		p.point_forecast,
+		p.probabilities,
+		p.distribution,
 		p.reason,
 		p.created,
 		p.user_id,
@@ -352,6 +370,8 @@ func TestBuildForecastPointQuery_WithUserIDOnly(t *testing.T) {
 		p.id,
 		p.forecast_id,
 		p.point_forecast,
+		p.probabilities,
+		p.distribution,
 		p.reason,
 		p.created,
 		p.user_id,
@@ -385,6 +405,8 @@ func TestBuildForecastPointQuery_WithDateOnly(t *testing.T) {
 		p.id,
 		p.forecast_id,
 		p.point_forecast,
+		p.probabilities,
+		p.distribution,
 		p.reason,
 		p.created,
 		p.user_id,
@@ -464,12 +486,11 @@ func TestBuildForecastPointQuery_OrderingVariations(t *testing.T) {
 // Forecast queries tests
 func TestBuildForecastQueryAllFilters(t *testing.T) {
 	forecastID := int64(1)
-	category := "finance"
 	status := "open"
 	filters := models.ForecastFilters{
-		ForecastID: &forecastID,
-		Status:     &status,
-		Category:   &category,
+		ForecastID:  &forecastID,
+		Status:      &status,
+		CategoryIDs: []int64{3, 4},
 	}
 
 	query, err := buildForecastQuery(filters)
@@ -481,17 +502,24 @@ func TestBuildForecastQueryAllFilters(t *testing.T) {
 		id,
 		question,
 		category,
+		category_id,
 		created,
 		user_id,
 		resolution_criteria,
 		closing_date,
 		resolution,
+		resolution_value,
 		resolved, 
-		comment 
+		comment,
+		question_type,
+		options,
+		range_min,
+		range_max,
+		visibility
 		from forecasts
 		where 1=1 and id = $1 
 		and resolved is null
-		and lower(category) like $2`
+		and category_id = any($2)`
 
 	normalizedExpected := normalizeSQL(expectedQuery)
 	normalizedActual := normalizeSQL(query)
@@ -514,13 +542,20 @@ func TestBuildForecastQuery_WithClosedStatus(t *testing.T) {
 		id,
 		question,
 		category,
+		category_id,
 		created,
 		user_id,
 		resolution_criteria,
 		closing_date,
 		resolution,
+		resolution_value,
 		resolved, 
-		comment 
+		comment,
+		question_type,
+		options,
+		range_min,
+		range_max,
+		visibility
 		from forecasts
 		where 1=1
 		and current_date > closing_date`
@@ -533,11 +568,10 @@ func TestBuildForecastQuery_WithClosedStatus(t *testing.T) {
 }
 
 func TestBuildForecastQuery_WithCategoryAndResolvedStatus(t *testing.T) {
-	category := "finance"
 	status := "resolved"
 	filters := models.ForecastFilters{
-		Category: &category,
-		Status:   &status,
+		CategoryIDs: []int64{3, 4},
+		Status:      &status,
 	}
 	query, err := buildForecastQuery(filters)
 	if err != nil {
@@ -547,17 +581,24 @@ func TestBuildForecastQuery_WithCategoryAndResolvedStatus(t *testing.T) {
 		id,
 		question,
 		category,
+		category_id,
 		created,
 		user_id,
 		resolution_criteria,
 		closing_date,
 		resolution,
+		resolution_value,
 		resolved, 
-		comment 
+		comment,
+		question_type,
+		options,
+		range_min,
+		range_max,
+		visibility
 		from forecasts
 		where 1=1
 		and resolved is not null
-		and lower(category) like $1`
+		and category_id = any($1)`
 	normalizedExpected := normalizeSQL(expectedQuery)
 	normalizedActual := normalizeSQL(query)
 
@@ -576,13 +617,20 @@ func TestBuildForecastQuery_NoFilters(t *testing.T) {
 		id,
 		question,
 		category,
+		category_id,
 		created,
 		user_id,
 		resolution_criteria,
 		closing_date,
 		resolution,
+		resolution_value,
 		resolved, 
-		comment 
+		comment,
+		question_type,
+		options,
+		range_min,
+		range_max,
+		visibility
 		from forecasts
 		where 1=1`
 	normalizedExpected := normalizeSQL(expectedQuery)
@@ -593,7 +641,117 @@ func TestBuildForecastQuery_NoFilters(t *testing.T) {
 	}
 }
 
+func TestBuildForecastQuery_WithPage(t *testing.T) {
+	filters := models.ForecastFilters{
+		CategoryIDs: []int64{3, 4},
+		Page: &models.Page{
+			Limit: 20,
+			Sort:  models.SortClosingDate,
+			After: &models.Cursor{Sort: models.SortClosingDate, Value: "2025-01-01 00:00:00.000000", ID: 7},
+		},
+	}
+	query, err := buildForecastQuery(filters)
+	if err != nil {
+		t.Fatalf("Error building forecast query: %v", err)
+	}
+	expectedQuery := `select * from (select
+		id, question, category, category_id, created, user_id, resolution_criteria, closing_date,
+		resolution, resolution_value, resolved, comment, question_type, options,
+		range_min, range_max, visibility
+		from forecasts
+		where 1=1 and category_id = any($1)) page
+		where (coalesce(closing_date, 'infinity'), id) > ($2::timestamp, $3)
+		order by coalesce(closing_date, 'infinity') asc, id asc
+		limit $4`
+	normalizedExpected := normalizeSQL(expectedQuery)
+	normalizedActual := normalizeSQL(query)
+
+	if normalizedActual != normalizedExpected {
+		t.Errorf("Query mismatch:\nExpected: %s\nGot: %s", normalizedExpected, normalizedActual)
+	}
+
+	filters.Page.Sort = "resolution"
+	if _, err := buildForecastQuery(filters); err == nil {
+		t.Error("Expected an unknown sort key to be rejected")
+	}
+}
+
+func TestBuildForecastQuery_WithViewer(t *testing.T) {
+	viewer := int64(5)
+	filters := models.ForecastFilters{CategoryIDs: []int64{3}, Viewer: &viewer}
+	query, err := buildForecastQuery(filters)
+	if err != nil {
+		t.Fatalf("Error building forecast query: %v", err)
+	}
+
+	normalized := normalizeSQL(query)
+	expected := normalizeSQL(`where 1=1 and category_id = any($1)
+		and (visibility = 'public' or user_id = $2 or (visibility = 'shared' and id in
+		(select fs.forecast_id from forecast_shares fs where fs.user_id = $2)))`)
+	if !strings.HasSuffix(normalized, expected) {
+		t.Errorf("Expected the listed forecasts of the viewer, got: %s", normalized)
+	}
+}
+
 // scores queries tests
+func TestBuildSearchQuery_WithStatusAndCategory(t *testing.T) {
+	status := "closed"
+	filters := models.SearchFilters{
+		Query:       "election",
+		Status:      &status,
+		CategoryIDs: []int64{3, 4},
+		Limit:       20,
+	}
+	query, err := buildSearchQuery(filters)
+	if err != nil {
+		t.Fatalf("Error building search query: %v", err)
+	}
+
+	expectedQuery := `with q as (select websearch_to_tsquery('english', $1) as query),
+		reasons as (
+			select p.forecast_id, sum(ts_rank(p.reason_vector, q.query)) as rank
+			from points p, q
+			where p.reason_vector @@ q.query
+			group by p.forecast_id
+		)
+		select
+		f.id, f.question, f.category, f.category_id, f.created, f.user_id, f.resolution_criteria,
+		f.closing_date, f.resolution, f.resolution_value, f.resolved, f.comment,
+		f.question_type, f.options, f.range_min, f.range_max, f.visibility,
+		ts_rank(f.search_vector, q.query) + coalesce(r.rank, 0) as rank,
+		case when to_tsvector('english', coalesce(f.question, '')) @@ q.query then ts_headline('english', replace(replace(replace(replace(replace(f.question, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&#34;'), '''', '&#39;'), q.query, 'StartSel=<mark>, StopSel=</mark>, MinWords=15, MaxWords=35, MaxFragments=2') end,
+		case when to_tsvector('english', coalesce(f.resolution_criteria, '')) @@ q.query then ts_headline('english', replace(replace(replace(replace(replace(f.resolution_criteria, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&#34;'), '''', '&#39;'), q.query, 'StartSel=<mark>, StopSel=</mark>, MinWords=15, MaxWords=35, MaxFragments=2') end,
+		case when to_tsvector('english', coalesce(f.comment, '')) @@ q.query then ts_headline('english', replace(replace(replace(replace(replace(f.comment, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&#34;'), '''', '&#39;'), q.query, 'StartSel=<mark>, StopSel=</mark>, MinWords=15, MaxWords=35, MaxFragments=2') end
+		from forecasts f
+		cross join q
+		left join reasons r on r.forecast_id = f.id
+		where (f.search_vector @@ q.query or r.forecast_id is not null) and current_date > f.closing_date and f.category_id = any($2)
+		order by rank desc, f.id desc
+		limit $3`
+
+	normalizedExpected := normalizeSQL(expectedQuery)
+	normalizedActual := normalizeSQL(query)
+
+	if normalizedActual != normalizedExpected {
+		t.Errorf("Query mismatch:\nExpected: %s\nGot: %s", normalizedExpected, normalizedActual)
+	}
+}
+
+func TestBuildSearchQuery_NoFilters(t *testing.T) {
+	query, err := buildSearchQuery(models.SearchFilters{Query: "election", Limit: 20})
+	if err != nil {
+		t.Fatalf("Error building search query: %v", err)
+	}
+
+	normalized := normalizeSQL(query)
+	if !strings.Contains(normalized, "where (f.search_vector @@ q.query or r.forecast_id is not null) order by") {
+		t.Errorf("Expected only the match condition, got: %s", normalized)
+	}
+	if !strings.HasSuffix(normalized, "limit $2") {
+		t.Errorf("Expected the limit to be the second argument, got: %s", normalized)
+	}
+}
+
 func TestBuildScoreQueryNoFilters(t *testing.T) {
 	filters := models.ScoreFilters{}
 	query, err := buildScoreQuery(filters)
@@ -604,7 +762,7 @@ func TestBuildScoreQueryNoFilters(t *testing.T) {
 	expectedQuery := `select 
 		id, brier_score, log2_score, logn_score, 
 		brier_score_time_weighted, log2_score_time_weighted, 
-		logn_score_time_weighted, user_id, forecast_id, created 
+		logn_score_time_weighted, crps, crps_time_weighted, peer_score, baseline_score, brier_score_lifetime, logn_score_lifetime, user_id, forecast_id, created 
 		from scores 
 		where 1=1 
 		order by created DESC`
@@ -631,7 +789,7 @@ func TestBuildScoreQueryWithUserID(t *testing.T) {
 	expectedQuery := `select 
 		id, brier_score, log2_score, logn_score, 
 		brier_score_time_weighted, log2_score_time_weighted, 
-		logn_score_time_weighted, user_id, forecast_id, created 
+		logn_score_time_weighted, crps, crps_time_weighted, peer_score, baseline_score, brier_score_lifetime, logn_score_lifetime, user_id, forecast_id, created 
 		from scores 
 		where 1=1 and user_id = $1 
 		order by created DESC`
@@ -658,7 +816,7 @@ func TestBuildScoreQueryWithForecastID(t *testing.T) {
 	expectedQuery := `select 
 		id, brier_score, log2_score, logn_score, 
 		brier_score_time_weighted, log2_score_time_weighted, 
-		logn_score_time_weighted, user_id, forecast_id, created 
+		logn_score_time_weighted, crps, crps_time_weighted, peer_score, baseline_score, brier_score_lifetime, logn_score_lifetime, user_id, forecast_id, created 
 		from scores 
 		where 1=1 and forecast_id = $1 
 		order by created DESC`
@@ -687,7 +845,7 @@ func TestBuildScoreQueryWithAllFilters(t *testing.T) {
 	expectedQuery := `select 
 		id, brier_score, log2_score, logn_score, 
 		brier_score_time_weighted, log2_score_time_weighted, 
-		logn_score_time_weighted, user_id, forecast_id, created 
+		logn_score_time_weighted, crps, crps_time_weighted, peer_score, baseline_score, brier_score_lifetime, logn_score_lifetime, user_id, forecast_id, created 
 		from scores 
 		where 1=1 and user_id = $1 and forecast_id = $2 
 		order by created DESC`
@@ -714,7 +872,7 @@ func TestBuildScoreQuery_GetScoresByUserID(t *testing.T) {
 	expectedQuery := `SELECT 
 		id, brier_score, log2_score, logn_score, 
 		brier_score_time_weighted, log2_score_time_weighted, 
-		logn_score_time_weighted, user_id, forecast_id, created 
+		logn_score_time_weighted, crps, crps_time_weighted, peer_score, baseline_score, brier_score_lifetime, logn_score_lifetime, user_id, forecast_id, created 
 		FROM scores 
 		WHERE 1=1 AND user_id = $1 
 		ORDER BY created DESC`
@@ -743,7 +901,7 @@ func TestBuildScoreQuery_GetScoreByForecastAndUser(t *testing.T) {
 	expectedQuery := `SELECT 
 		id, brier_score, log2_score, logn_score, 
 		brier_score_time_weighted, log2_score_time_weighted, 
-		logn_score_time_weighted, user_id, forecast_id, created 
+		logn_score_time_weighted, crps, crps_time_weighted, peer_score, baseline_score, brier_score_lifetime, logn_score_lifetime, user_id, forecast_id, created 
 		FROM scores 
 		WHERE 1=1 AND user_id = $1 AND forecast_id = $2 
 		ORDER BY created DESC`
@@ -756,6 +914,54 @@ func TestBuildScoreQuery_GetScoreByForecastAndUser(t *testing.T) {
 	}
 }
 
+func TestBuildScoreQuery_WithViewer(t *testing.T) {
+	userID := int64(2)
+	viewer := int64(5)
+	query, err := buildScoreQuery(models.ScoreFilters{UserID: &userID, Viewer: &viewer})
+	if err != nil {
+		t.Fatalf("Error building score query: %v", err)
+	}
+
+	normalized := normalizeSQL(query)
+	expected := normalizeSQL(`where 1=1 and user_id = $1
+		and forecast_id in (select f.id from forecasts f where (f.visibility in ('public', 'unlisted')
+		or f.user_id = $2 or (f.visibility = 'shared' and f.id in
+		(select fs.forecast_id from forecast_shares fs where fs.user_id = $2))))`)
+	if !strings.Contains(normalized, expected) {
+		t.Errorf("Expected the scores of forecasts the viewer may see, got: %s", normalized)
+	}
+}
+
+func TestBuildScoreQuery_WithPage(t *testing.T) {
+	userID := int64(5)
+	filters := models.ScoreFilters{
+		UserID: &userID,
+		Page:   &models.Page{Limit: 10, Sort: models.SortCreated, Desc: true},
+	}
+	query, err := buildScoreQuery(filters)
+	if err != nil {
+		t.Fatalf("Error building score query: %v", err)
+	}
+
+	expectedQuery := `select * from (select
+		id, brier_score, log2_score, logn_score,
+		brier_score_time_weighted, log2_score_time_weighted,
+		logn_score_time_weighted, crps, crps_time_weighted, peer_score, baseline_score, brier_score_lifetime, logn_score_lifetime, user_id, forecast_id, created
+		from scores
+		where 1=1 and user_id = $1
+		order by created DESC) page
+		where 1=1
+		order by created desc, id desc
+		limit $2`
+
+	normalizedExpected := normalizeSQL(expectedQuery)
+	normalizedActual := normalizeSQL(query)
+
+	if normalizedActual != normalizedExpected {
+		t.Errorf("Query mismatch:\nExpected: %s\nGot: %s", normalizedExpected, normalizedActual)
+	}
+}
+
 // Aggregate score queries tests
 func TestBuildAggregateScoreQuery_GetOverallScores(t *testing.T) {
 	// Test for GetOverallScores - no filters, no groupBy
@@ -773,6 +979,10 @@ func TestBuildAggregateScoreQuery_GetOverallScores(t *testing.T) {
 		coalesce(AVG(s.brier_score_time_weighted), 0) as avg_brier_time_weighted,
 		coalesce(AVG(s.log2_score_time_weighted), 0) as avg_log2_time_weighted,
 		coalesce(AVG(s.logn_score_time_weighted), 0) as avg_logn_time_weighted,
+		AVG(s.peer_score) as avg_peer,
+		AVG(s.baseline_score) as avg_baseline,
+		AVG(s.brier_score_lifetime) as avg_brier_lifetime,
+		AVG(s.logn_score_lifetime) as avg_logn_lifetime,
 		COUNT(DISTINCT s.user_id) as total_users,
 		COUNT(DISTINCT s.forecast_id) as total_forecasts
 		FROM scores s
@@ -786,11 +996,49 @@ func TestBuildAggregateScoreQuery_GetOverallScores(t *testing.T) {
 	}
 }
 
+func TestBuildAggregateScoreQuery_QuestionTypeWithoutCrowd(t *testing.T) {
+	// Test for the Brier weights of a question type without the crowd, under a rule
+	rule := "spherical"
+	questionType := models.QuestionTypeMultipleChoice
+	filters := models.ScoreFilters{QuestionType: &questionType, ExcludeCrowd: true, Rule: &rule}
+
+	query, err := buildAggregateScoreQuery(filters)
+	if err != nil {
+		t.Fatalf("Error building aggregate score query: %v", err)
+	}
+
+	expectedQuery := `SELECT 
+		coalesce(AVG(s.brier_score), 0) as avg_brier,
+		coalesce(AVG(s.log2_score), 0) as avg_log2,
+		coalesce(AVG(s.logn_score), 0) as avg_logn,
+		coalesce(AVG(s.brier_score_time_weighted), 0) as avg_brier_time_weighted,
+		coalesce(AVG(s.log2_score_time_weighted), 0) as avg_log2_time_weighted,
+		coalesce(AVG(s.logn_score_time_weighted), 0) as avg_logn_time_weighted,
+		AVG(s.peer_score) as avg_peer,
+		AVG(s.baseline_score) as avg_baseline,
+		AVG(s.brier_score_lifetime) as avg_brier_lifetime,
+		AVG(s.logn_score_lifetime) as avg_logn_lifetime,
+		AVG(r.score) as avg_rule,
+		AVG(r.score_time_weighted) as avg_rule_time_weighted,
+		COUNT(DISTINCT s.user_id) as total_users,
+		COUNT(DISTINCT s.forecast_id) as total_forecasts
+		FROM scores s
+		left join forecasts f on s.forecast_id = f.id
+		left join rule_scores r on r.score_id = s.id and r.rule = $4
+		WHERE 1=1 and f.question_type = $1 and s.user_id not in (select id from users where username = $2 and role = $3)`
+
+	normalizedExpected := normalizeSQL(expectedQuery)
+	normalizedActual := normalizeSQL(query)
+
+	if normalizedActual != normalizedExpected {
+		t.Errorf("Query mismatch:\nExpected: %s\nGot: %s", normalizedExpected, normalizedActual)
+	}
+}
+
 func TestBuildAggregateScoreQuery_GetCategoryScores(t *testing.T) {
 	// Test for GetCategoryScores - category filter, no groupBy
-	category := "technology"
 	filters := models.ScoreFilters{
-		Category: &category,
+		CategoryIDs: []int64{3, 4},
 	}
 
 	query, err := buildAggregateScoreQuery(filters)
@@ -805,11 +1053,15 @@ func TestBuildAggregateScoreQuery_GetCategoryScores(t *testing.T) {
 		coalesce(AVG(s.brier_score_time_weighted), 0) as avg_brier_time_weighted,
 		coalesce(AVG(s.log2_score_time_weighted), 0) as avg_log2_time_weighted,
 		coalesce(AVG(s.logn_score_time_weighted), 0) as avg_logn_time_weighted,
+		AVG(s.peer_score) as avg_peer,
+		AVG(s.baseline_score) as avg_baseline,
+		AVG(s.brier_score_lifetime) as avg_brier_lifetime,
+		AVG(s.logn_score_lifetime) as avg_logn_lifetime,
 		COUNT(DISTINCT s.user_id) as total_users,
 		COUNT(DISTINCT s.forecast_id) as total_forecasts
 		FROM scores s
 		left join forecasts f on s.forecast_id = f.id
-		WHERE 1=1 AND lower(f.category) like $1`
+		WHERE 1=1 AND f.category_id = any($1)`
 
 	normalizedExpected := normalizeSQL(expectedQuery)
 	normalizedActual := normalizeSQL(query)
@@ -838,6 +1090,10 @@ func TestBuildAggregateScoreQuery_GetOverallScoresByUsers(t *testing.T) {
 		coalesce(AVG(s.brier_score_time_weighted), 0) as avg_brier_time_weighted,
 		coalesce(AVG(s.log2_score_time_weighted), 0) as avg_log2_time_weighted,
 		coalesce(AVG(s.logn_score_time_weighted), 0) as avg_logn_time_weighted,
+		AVG(s.peer_score) as avg_peer,
+		AVG(s.baseline_score) as avg_baseline,
+		AVG(s.brier_score_lifetime) as avg_brier_lifetime,
+		AVG(s.logn_score_lifetime) as avg_logn_lifetime,
 		s.user_id,
 		COUNT(DISTINCT s.forecast_id) as total_forecasts
 		FROM scores s
@@ -854,11 +1110,51 @@ func TestBuildAggregateScoreQuery_GetOverallScoresByUsers(t *testing.T) {
 
 func TestBuildAggregateScoreQuery_GetCategoryScoresByUsers(t *testing.T) {
 	// Test for GetCategoryScoresByUsers - category filter with groupBy user_id
-	category := "finance"
 	groupByUserID := true
 	filters := models.ScoreFilters{
-		Category:      &category,
+		CategoryIDs:   []int64{3, 4},
+		GroupByUserID: &groupByUserID,
+	}
+
+	query, err := buildAggregateScoreQuery(filters)
+	if err != nil {
+		t.Fatalf("Error building aggregate score query: %v", err)
+	}
+
+	expectedQuery := `SELECT 
+		coalesce(AVG(s.brier_score), 0) as avg_brier,
+		coalesce(AVG(s.log2_score), 0) as avg_log2,
+		coalesce(AVG(s.logn_score), 0) as avg_logn,
+		coalesce(AVG(s.brier_score_time_weighted), 0) as avg_brier_time_weighted,
+		coalesce(AVG(s.log2_score_time_weighted), 0) as avg_log2_time_weighted,
+		coalesce(AVG(s.logn_score_time_weighted), 0) as avg_logn_time_weighted,
+		AVG(s.peer_score) as avg_peer,
+		AVG(s.baseline_score) as avg_baseline,
+		AVG(s.brier_score_lifetime) as avg_brier_lifetime,
+		AVG(s.logn_score_lifetime) as avg_logn_lifetime,
+		s.user_id,
+		COUNT(DISTINCT s.forecast_id) as total_forecasts
+		FROM scores s
+		left join forecasts f on s.forecast_id = f.id
+		WHERE 1=1 AND f.category_id = any($1)
+		group by s.user_id`
+
+	normalizedExpected := normalizeSQL(expectedQuery)
+	normalizedActual := normalizeSQL(query)
+
+	if normalizedActual != normalizedExpected {
+		t.Errorf("Query mismatch:\nExpected: %s\nGot: %s", normalizedExpected, normalizedActual)
+	}
+}
+
+func TestBuildAggregateScoreQuery_RuleScoresByUsers(t *testing.T) {
+	// Test for a scoring rule with groupBy user_id - the rule is the last argument
+	groupByUserID := true
+	rule := "spherical"
+	filters := models.ScoreFilters{
+		CategoryIDs:   []int64{3, 4},
 		GroupByUserID: &groupByUserID,
+		Rule:          &rule,
 	}
 
 	query, err := buildAggregateScoreQuery(filters)
@@ -873,11 +1169,18 @@ func TestBuildAggregateScoreQuery_GetCategoryScoresByUsers(t *testing.T) {
 		coalesce(AVG(s.brier_score_time_weighted), 0) as avg_brier_time_weighted,
 		coalesce(AVG(s.log2_score_time_weighted), 0) as avg_log2_time_weighted,
 		coalesce(AVG(s.logn_score_time_weighted), 0) as avg_logn_time_weighted,
+		AVG(s.peer_score) as avg_peer,
+		AVG(s.baseline_score) as avg_baseline,
+		AVG(s.brier_score_lifetime) as avg_brier_lifetime,
+		AVG(s.logn_score_lifetime) as avg_logn_lifetime,
+		AVG(r.score) as avg_rule,
+		AVG(r.score_time_weighted) as avg_rule_time_weighted,
 		s.user_id,
 		COUNT(DISTINCT s.forecast_id) as total_forecasts
 		FROM scores s
 		left join forecasts f on s.forecast_id = f.id
-		WHERE 1=1 AND lower(f.category) like $1
+		left join rule_scores r on r.score_id = s.id and r.rule = $2
+		WHERE 1=1 AND f.category_id = any($1)
 		group by s.user_id`
 
 	normalizedExpected := normalizeSQL(expectedQuery)
@@ -907,6 +1210,10 @@ func TestBuildAggregateScoreQuery_GetUserOverallScores(t *testing.T) {
 		coalesce(AVG(s.brier_score_time_weighted), 0) as avg_brier_time_weighted,
 		coalesce(AVG(s.log2_score_time_weighted), 0) as avg_log2_time_weighted,
 		coalesce(AVG(s.logn_score_time_weighted), 0) as avg_logn_time_weighted,
+		AVG(s.peer_score) as avg_peer,
+		AVG(s.baseline_score) as avg_baseline,
+		AVG(s.brier_score_lifetime) as avg_brier_lifetime,
+		AVG(s.logn_score_lifetime) as avg_logn_lifetime,
 		COUNT(DISTINCT s.user_id) as total_users,
 		COUNT(DISTINCT s.forecast_id) as total_forecasts
 		FROM scores s
@@ -923,10 +1230,9 @@ func TestBuildAggregateScoreQuery_GetUserOverallScores(t *testing.T) {
 func TestBuildAggregateScoreQuery_GetUserCategoryScores(t *testing.T) {
 	// Test for GetUserCategoryScores - userID + category filters, no groupBy
 	userID := int64(5)
-	category := "politics"
 	filters := models.ScoreFilters{
-		UserID:   &userID,
-		Category: &category,
+		UserID:      &userID,
+		CategoryIDs: []int64{3, 4},
 	}
 
 	query, err := buildAggregateScoreQuery(filters)
@@ -941,11 +1247,15 @@ func TestBuildAggregateScoreQuery_GetUserCategoryScores(t *testing.T) {
 		coalesce(AVG(s.brier_score_time_weighted), 0) as avg_brier_time_weighted,
 		coalesce(AVG(s.log2_score_time_weighted), 0) as avg_log2_time_weighted,
 		coalesce(AVG(s.logn_score_time_weighted), 0) as avg_logn_time_weighted,
+		AVG(s.peer_score) as avg_peer,
+		AVG(s.baseline_score) as avg_baseline,
+		AVG(s.brier_score_lifetime) as avg_brier_lifetime,
+		AVG(s.logn_score_lifetime) as avg_logn_lifetime,
 		COUNT(DISTINCT s.user_id) as total_users,
 		COUNT(DISTINCT s.forecast_id) as total_forecasts
 		FROM scores s
 		left join forecasts f on s.forecast_id = f.id
-		WHERE 1=1 AND s.user_id = $1 AND lower(f.category) like $2`
+		WHERE 1=1 AND s.user_id = $1 AND f.category_id = any($2)`
 
 	normalizedExpected := normalizeSQL(expectedQuery)
 	normalizedActual := normalizeSQL(query)
@@ -974,6 +1284,10 @@ func TestBuildAggregateScoreQuery_WithForecastID(t *testing.T) {
 		coalesce(AVG(s.brier_score_time_weighted), 0) as avg_brier_time_weighted,
 		coalesce(AVG(s.log2_score_time_weighted), 0) as avg_log2_time_weighted,
 		coalesce(AVG(s.logn_score_time_weighted), 0) as avg_logn_time_weighted,
+		AVG(s.peer_score) as avg_peer,
+		AVG(s.baseline_score) as avg_baseline,
+		AVG(s.brier_score_lifetime) as avg_brier_lifetime,
+		AVG(s.logn_score_lifetime) as avg_logn_lifetime,
 		COUNT(DISTINCT s.user_id) as total_users,
 		COUNT(DISTINCT s.forecast_id) as total_forecasts
 		FROM scores s
@@ -991,12 +1305,11 @@ func TestBuildAggregateScoreQuery_AllFilters(t *testing.T) {
 	// Test with all filters combined
 	userID := int64(5)
 	forecastID := int64(10)
-	category := "sports"
 	groupByUserID := true
 	filters := models.ScoreFilters{
 		UserID:        &userID,
 		ForecastID:    &forecastID,
-		Category:      &category,
+		CategoryIDs:   []int64{3, 4},
 		GroupByUserID: &groupByUserID,
 	}
 
@@ -1012,11 +1325,15 @@ func TestBuildAggregateScoreQuery_AllFilters(t *testing.T) {
 		coalesce(AVG(s.brier_score_time_weighted), 0) as avg_brier_time_weighted,
 		coalesce(AVG(s.log2_score_time_weighted), 0) as avg_log2_time_weighted,
 		coalesce(AVG(s.logn_score_time_weighted), 0) as avg_logn_time_weighted,
+		AVG(s.peer_score) as avg_peer,
+		AVG(s.baseline_score) as avg_baseline,
+		AVG(s.brier_score_lifetime) as avg_brier_lifetime,
+		AVG(s.logn_score_lifetime) as avg_logn_lifetime,
 		s.user_id,
 		COUNT(DISTINCT s.forecast_id) as total_forecasts
 		FROM scores s
 		left join forecasts f on s.forecast_id = f.id
-		WHERE 1=1 AND s.user_id = $1 AND s.forecast_id = $2 AND lower(f.category) like $3
+		WHERE 1=1 AND s.user_id = $1 AND s.forecast_id = $2 AND f.category_id = any($3)
 		group by s.user_id`
 
 	normalizedExpected := normalizeSQL(expectedQuery)
@@ -1044,3 +1361,29 @@ func normalizeSQL(sql string) string {
 	// Convert to lowercase for case-insensitive comparison
 	return strings.ToLower(sql)
 }
+
+func TestBuildCommentQuery(t *testing.T) {
+	query, err := buildCommentQuery(models.CommentFilters{ForecastID: 1})
+	if err != nil {
+		t.Fatalf("Error building comment query: %v", err)
+	}
+	expectedQuery := `select
+		c.id, c.forecast_id, c.parent_id, c.user_id, u.username, c.body, c.created, c.edited, c.deleted
+		from comments c
+		left join users u on u.id = c.user_id
+		where c.forecast_id = $1
+		order by c.created, c.id`
+	if normalizeSQL(query) != normalizeSQL(expectedQuery) {
+		t.Errorf("Query mismatch:\nExpected: %s\nGot: %s", normalizeSQL(expectedQuery), normalizeSQL(query))
+	}
+
+	page := &models.Page{Limit: 10, Sort: models.SortCreated, Desc: true, After: &models.Cursor{Sort: models.SortCreated, Desc: true, Value: "2025-01-01 00:00:00.000000", ID: 7}}
+	query, err = buildCommentQuery(models.CommentFilters{ForecastID: 1, Page: page})
+	if err != nil {
+		t.Fatalf("Error building comment query: %v", err)
+	}
+	normalized := normalizeSQL(query)
+	if !strings.HasSuffix(normalized, "where (created, id) < ($2::timestamp, $3) order by created desc, id desc limit $4") {
+		t.Errorf("Expected the page to follow the forecast id, got: %s", normalized)
+	}
+}
@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"backend/internal/database"
+	"backend/internal/logger"
+	"backend/internal/models"
+	"context"
+	"log/slog"
+	"time"
+)
+
+// ResolutionAuditRepository defines the interface for the resolution audit trail
+type ResolutionAuditRepository interface {
+	CreateResolutionAudit(ctx context.Context, audit *models.ResolutionAudit) error
+	GetResolutionAudits(ctx context.Context, forecastID int64) ([]models.ResolutionAudit, error)
+}
+
+// PostgresResolutionAuditRepository implements the ResolutionAuditRepository interface
+type PostgresResolutionAuditRepository struct {
+	db *database.DB
+}
+
+// NewResolutionAuditRepository creates a new PostgresResolutionAuditRepository instance
+func NewResolutionAuditRepository(db *database.DB) ResolutionAuditRepository {
+	return &PostgresResolutionAuditRepository{db: db}
+}
+
+func (r *PostgresResolutionAuditRepository) CreateResolutionAudit(ctx context.Context, audit *models.ResolutionAudit) error {
+	audit.CreatedAt = time.Now()
+
+	query := `INSERT INTO resolution_audits (forecast_id
+					, user_id
+					, action
+					, previous_resolution
+					, previous_resolution_value
+					, previous_resolved
+					, resolution
+					, resolution_value
+					, comment
+					, created)
+              VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+              RETURNING id`
+
+	return r.db.Querier(ctx).QueryRowContext(ctx, query,
+		audit.ForecastID,
+		audit.UserID,
+		audit.Action,
+		audit.PreviousResolution,
+		audit.PreviousResolutionValue,
+		audit.PreviousResolvedAt,
+		audit.Resolution,
+		audit.ResolutionValue,
+		audit.Comment,
+		audit.CreatedAt).Scan(&audit.ID)
+}
+
+func (r *PostgresResolutionAuditRepository) GetResolutionAudits(ctx context.Context, forecastID int64) ([]models.ResolutionAudit, error) {
+	log := logger.FromContext(ctx)
+
+	query := `SELECT a.id
+				, a.forecast_id
+				, a.user_id
+				, a.action
+				, a.previous_resolution
+				, a.previous_resolution_value
+				, a.previous_resolved
+				, a.resolution
+				, a.resolution_value
+				, a.comment
+				, (SELECT COUNT(*) FROM archived_scores s WHERE s.audit_id = a.id) as archived_scores
+				, a.created
+			  FROM resolution_audits a
+			  WHERE a.forecast_id = $1
+			  ORDER BY a.created, a.id`
+
+	start := time.Now()
+	rows, err := r.db.Querier(ctx).QueryContext(ctx, query, forecastID)
+	if err != nil {
+		return nil, err
+	}
+	log.Info("executed query", slog.Duration("duration", time.Since(start)), slog.Bool("success", err == nil))
+	defer rows.Close()
+
+	var audits []models.ResolutionAudit
+	for rows.Next() {
+		var a models.ResolutionAudit
+		if err := rows.Scan(
+			&a.ID,
+			&a.ForecastID,
+			&a.UserID,
+			&a.Action,
+			&a.PreviousResolution,
+			&a.PreviousResolutionValue,
+			&a.PreviousResolvedAt,
+			&a.Resolution,
+			&a.ResolutionValue,
+			&a.Comment,
+			&a.ArchivedScores,
+			&a.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		audits = append(audits, a)
+	}
+	log.Info("query results", slog.Int("count", len(audits)))
+	return audits, rows.Err()
+}
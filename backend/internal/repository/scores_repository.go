@@ -19,12 +19,15 @@ import (
 type ScoreRepository interface {
 	// get scores
 	GetScores(ctx context.Context, filters models.ScoreFilters) ([]models.Scores, error)
-	GetAverageScores(ctx context.Context) ([]models.Scores, error)
+	// GetAverageScores averages the scores of the forecasts the viewer, 0 when
+	// anonymous, can view, or of every forecast when viewer is nil
+	GetAverageScores(ctx context.Context, viewer *int64) ([]models.Scores, error)
 
 	// create, update, delete scores
 	CreateScore(ctx context.Context, score *models.Scores) error
 	UpdateScore(ctx context.Context, score *models.Scores) error
 	DeleteScore(ctx context.Context, scoreID int64) error
+	ArchiveScores(ctx context.Context, forecastID int64, auditID int64) (int64, error)
 
 	// aggregate scores
 	GetAggregateScores(ctx context.Context, filters models.ScoreFilters) (*models.OverallScores, error)
@@ -50,6 +53,12 @@ func buildScoreQuery(filters models.ScoreFilters) (string, error) {
 		"brier_score_time_weighted",
 		"log2_score_time_weighted",
 		"logn_score_time_weighted",
+		"crps",
+		"crps_time_weighted",
+		"peer_score",
+		"baseline_score",
+		"brier_score_lifetime",
+		"logn_score_lifetime",
 		"user_id",
 		"forecast_id",
 		"created",
@@ -67,6 +76,10 @@ func buildScoreQuery(filters models.ScoreFilters) (string, error) {
 		whereConditions = append(whereConditions, "forecast_id = "+fmt.Sprintf("$%d", argsCounter))
 		argsCounter++
 	}
+	if filters.Viewer != nil {
+		whereConditions = append(whereConditions, "forecast_id in (select f.id from forecasts f where "+visibilityCondition("f.", argsCounter, false)+")")
+		argsCounter++
+	}
 
 	orderBy := "created DESC"
 
@@ -77,6 +90,10 @@ func buildScoreQuery(filters models.ScoreFilters) (string, error) {
 		strings.Join(whereConditions, " and "),
 		orderBy,
 	)
+
+	if filters.Page != nil {
+		return paginate(query, *filters.Page, scoreSorts, argsCounter)
+	}
 	return query, nil
 }
 
@@ -96,8 +113,13 @@ func (r *PostgresScoreRepository) GetScores(ctx context.Context, filters models.
 	if filters.ForecastID != nil {
 		args = append(args, *filters.ForecastID)
 	}
+	if filters.Viewer != nil {
+		args = append(args, *filters.Viewer)
+	}
+	args = append(args, pageArgs(filters.Page)...)
+
 	start := time.Now()
-	rows, err := r.db.QueryContext(ctx, query, args...)
+	rows, err := r.db.Querier(ctx).QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -115,6 +137,12 @@ func (r *PostgresScoreRepository) GetScores(ctx context.Context, filters models.
 			&s.BrierScoreTimeWeighted,
 			&s.Log2ScoreTimeWeighted,
 			&s.LogNScoreTimeWeighted,
+			&s.CRPS,
+			&s.CRPSTimeWeighted,
+			&s.PeerScore,
+			&s.BaselineScore,
+			&s.BrierScoreLifetime,
+			&s.LogNScoreLifetime,
 			&s.UserID,
 			&s.ForecastID,
 			&s.CreatedAt,
@@ -123,38 +151,142 @@ func (r *PostgresScoreRepository) GetScores(ctx context.Context, filters models.
 		}
 		scores = append(scores, s)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 	log.Info("query results", slog.Int("count", len(scores)))
-	return scores, rows.Err()
+	return scores, r.loadRuleScores(ctx, scores)
+}
+
+// loadRuleScores fills in the scores under each scoring rule
+func (r *PostgresScoreRepository) loadRuleScores(ctx context.Context, scores []models.Scores) error {
+	if len(scores) == 0 {
+		return nil
+	}
+	byID := make(map[int64]*models.Scores, len(scores))
+	ids := make([]int64, 0, len(scores))
+	for i := range scores {
+		byID[scores[i].ID] = &scores[i]
+		ids = append(ids, scores[i].ID)
+	}
+
+	query := `SELECT score_id, rule, score, score_time_weighted
+			  FROM rule_scores
+			  WHERE score_id = any($1)
+			  ORDER BY score_id, rule`
+	rows, err := r.db.Querier(ctx).QueryContext(ctx, query, ids)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		var rs models.RuleScore
+		if err := rows.Scan(&id, &rs.Rule, &rs.Score, &rs.ScoreTimeWeighted); err != nil {
+			return err
+		}
+		if s, ok := byID[id]; ok {
+			s.Rules = append(s.Rules, rs)
+		}
+	}
+	return rows.Err()
 }
 
+// setRuleScores replaces the scores of a score under each scoring rule
+func (r *PostgresScoreRepository) setRuleScores(ctx context.Context, scoreID int64, rules []models.RuleScore) error {
+	if _, err := r.db.Querier(ctx).ExecContext(ctx, `DELETE FROM rule_scores WHERE score_id = $1`, scoreID); err != nil {
+		return err
+	}
+	if len(rules) == 0 {
+		return nil
+	}
+
+	names := make([]string, len(rules))
+	values := make([]float64, len(rules))
+	timeWeighted := make([]float64, len(rules))
+	for i, rs := range rules {
+		names[i], values[i], timeWeighted[i] = rs.Rule, rs.Score, rs.ScoreTimeWeighted
+	}
+	query := `INSERT INTO rule_scores (score_id, rule, score, score_time_weighted)
+			  SELECT $1, unnest($2::text[]), unnest($3::double precision[]), unnest($4::double precision[])`
+	_, err := r.db.Querier(ctx).ExecContext(ctx, query, scoreID, names, values, timeWeighted)
+	return err
+}
+
+// CreateScore inserts the score, or replaces the existing score of the same
+// user and forecast along with its rule scores, so retrying a resolution never
+// duplicates rows
 func (r *PostgresScoreRepository) CreateScore(ctx context.Context, score *models.Scores) error {
 	score.CreatedAt = time.Now()
 
+	return r.db.WithinTx(ctx, func(ctx context.Context) error {
+		if err := r.upsertScore(ctx, score); err != nil {
+			return err
+		}
+		return r.setRuleScores(ctx, score.ID, score.Rules)
+	})
+}
+
+func (r *PostgresScoreRepository) upsertScore(ctx context.Context, score *models.Scores) error {
 	query := `INSERT INTO scores (brier_score
 					, log2_score
 					, logn_score
 					, brier_score_time_weighted
 					, log2_score_time_weighted
 					, logn_score_time_weighted
+					, crps
+					, crps_time_weighted
+					, peer_score
+					, baseline_score
+					, brier_score_lifetime
+					, logn_score_lifetime
 					, user_id
 					, forecast_id
 					, created)
-              VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+              VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+              ON CONFLICT (forecast_id, user_id) DO UPDATE SET
+                brier_score = EXCLUDED.brier_score
+                , log2_score = EXCLUDED.log2_score
+                , logn_score = EXCLUDED.logn_score
+                , brier_score_time_weighted = EXCLUDED.brier_score_time_weighted
+                , log2_score_time_weighted = EXCLUDED.log2_score_time_weighted
+                , logn_score_time_weighted = EXCLUDED.logn_score_time_weighted
+                , crps = EXCLUDED.crps
+                , crps_time_weighted = EXCLUDED.crps_time_weighted
+                , peer_score = EXCLUDED.peer_score
+                , baseline_score = EXCLUDED.baseline_score
+                , brier_score_lifetime = EXCLUDED.brier_score_lifetime
+                , logn_score_lifetime = EXCLUDED.logn_score_lifetime
+                , created = EXCLUDED.created
               RETURNING id`
 
-	return r.db.QueryRowContext(ctx, query,
+	return r.db.Querier(ctx).QueryRowContext(ctx, query,
 		score.BrierScore,
 		score.Log2Score,
 		score.LogNScore,
 		score.BrierScoreTimeWeighted,
 		score.Log2ScoreTimeWeighted,
 		score.LogNScoreTimeWeighted,
+		score.CRPS,
+		score.CRPSTimeWeighted,
+		score.PeerScore,
+		score.BaselineScore,
+		score.BrierScoreLifetime,
+		score.LogNScoreLifetime,
 		score.UserID,
 		score.ForecastID,
 		score.CreatedAt).Scan(&score.ID)
 }
 
-func (r *PostgresScoreRepository) GetAverageScores(ctx context.Context) ([]models.Scores, error) {
+func (r *PostgresScoreRepository) GetAverageScores(ctx context.Context, viewer *int64) ([]models.Scores, error) {
+	where := "1=1"
+	args := []any{}
+	if viewer != nil {
+		where = "forecast_id in (select f.id from forecasts f where " + visibilityCondition("f.", 1, false) + ")"
+		args = append(args, *viewer)
+	}
+
 	query := `SELECT 0 as id
 				, coalesce(AVG(brier_score), 0) as brier_score
 				, coalesce(AVG(log2_score), 0) as log2_score
@@ -166,9 +298,10 @@ func (r *PostgresScoreRepository) GetAverageScores(ctx context.Context) ([]model
 				, forecast_id
 				, max(created) as created
 			  FROM scores
+			  WHERE ` + where + `
 			  GROUP BY forecast_id, user_id, id`
 
-	rows, err := r.db.QueryContext(ctx, query)
+	rows, err := r.db.Querier(ctx).QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -196,7 +329,21 @@ func (r *PostgresScoreRepository) GetAverageScores(ctx context.Context) ([]model
 	return scores, rows.Err()
 }
 
+// UpdateScore updates the score, and replaces its rule scores unless Rules is
+// nil
 func (r *PostgresScoreRepository) UpdateScore(ctx context.Context, score *models.Scores) error {
+	return r.db.WithinTx(ctx, func(ctx context.Context) error {
+		if err := r.updateScore(ctx, score); err != nil {
+			return err
+		}
+		if score.Rules == nil {
+			return nil
+		}
+		return r.setRuleScores(ctx, score.ID, score.Rules)
+	})
+}
+
+func (r *PostgresScoreRepository) updateScore(ctx context.Context, score *models.Scores) error {
 	query := `UPDATE scores
 			  SET brier_score = $1
 			  , log2_score = $2
@@ -204,15 +351,27 @@ func (r *PostgresScoreRepository) UpdateScore(ctx context.Context, score *models
 			  , brier_score_time_weighted = $4
 			  , log2_score_time_weighted = $5
 			  , logn_score_time_weighted = $6
-			  WHERE id = $7`
-
-	result, err := r.db.ExecContext(ctx, query,
+			  , crps = $7
+			  , crps_time_weighted = $8
+			  , peer_score = $9
+			  , baseline_score = $10
+			  , brier_score_lifetime = $11
+			  , logn_score_lifetime = $12
+			  WHERE id = $13`
+
+	result, err := r.db.Querier(ctx).ExecContext(ctx, query,
 		score.BrierScore,
 		score.Log2Score,
 		score.LogNScore,
 		score.BrierScoreTimeWeighted,
 		score.Log2ScoreTimeWeighted,
 		score.LogNScoreTimeWeighted,
+		score.CRPS,
+		score.CRPSTimeWeighted,
+		score.PeerScore,
+		score.BaselineScore,
+		score.BrierScoreLifetime,
+		score.LogNScoreLifetime,
 		score.ID)
 	if err != nil {
 		return err
@@ -231,7 +390,7 @@ func (r *PostgresScoreRepository) UpdateScore(ctx context.Context, score *models
 func (r *PostgresScoreRepository) DeleteScore(ctx context.Context, score_id int64) error {
 	query := `DELETE FROM scores WHERE id = $1`
 
-	result, err := r.db.ExecContext(ctx, query, score_id)
+	result, err := r.db.Querier(ctx).ExecContext(ctx, query, score_id)
 	if err != nil {
 		return err
 	}
@@ -246,6 +405,61 @@ func (r *PostgresScoreRepository) DeleteScore(ctx context.Context, score_id int6
 	return nil
 }
 
+// ArchiveScores moves every score of the forecast into archived_scores under
+// the given audit record, with their rule scores, and returns how many were
+// moved
+func (r *PostgresScoreRepository) ArchiveScores(ctx context.Context, forecastID int64, auditID int64) (int64, error) {
+	query := `WITH archived AS (
+				DELETE FROM scores WHERE forecast_id = $1
+				RETURNING id
+				, brier_score
+				, log2_score
+				, logn_score
+				, brier_score_time_weighted
+				, log2_score_time_weighted
+				, logn_score_time_weighted
+				, crps
+				, crps_time_weighted
+				, peer_score
+				, baseline_score
+				, brier_score_lifetime
+				, logn_score_lifetime
+				, user_id
+				, forecast_id
+				, created
+			  ), moved AS (
+			  INSERT INTO archived_scores (id
+				, brier_score
+				, log2_score
+				, logn_score
+				, brier_score_time_weighted
+				, log2_score_time_weighted
+				, logn_score_time_weighted
+				, crps
+				, crps_time_weighted
+				, peer_score
+				, baseline_score
+				, brier_score_lifetime
+				, logn_score_lifetime
+				, user_id
+				, forecast_id
+				, created
+				, audit_id)
+			  SELECT archived.*, $2 FROM archived
+			  RETURNING id
+			  ), archived_rules AS (
+			  INSERT INTO archived_rule_scores (score_id, rule, score, score_time_weighted)
+			  SELECT r.score_id, r.rule, r.score, r.score_time_weighted
+			  FROM rule_scores r JOIN moved ON r.score_id = moved.id
+			  )
+			  SELECT COUNT(*) FROM moved`
+
+	// the statement sees the rule scores before the deleted scores cascade
+	var archived int64
+	err := r.db.Querier(ctx).QueryRowContext(ctx, query, forecastID, auditID).Scan(&archived)
+	return archived, err
+}
+
 func buildAggregateScoreQuery(filters models.ScoreFilters) (string, error) {
 	// build select fields, start with the common ones
 	selectFields := []string{
@@ -255,6 +469,16 @@ func buildAggregateScoreQuery(filters models.ScoreFilters) (string, error) {
 		"coalesce(AVG(s.brier_score_time_weighted), 0) as avg_brier_time_weighted",
 		"coalesce(AVG(s.log2_score_time_weighted), 0) as avg_log2_time_weighted",
 		"coalesce(AVG(s.logn_score_time_weighted), 0) as avg_logn_time_weighted",
+		"AVG(s.peer_score) as avg_peer",
+		"AVG(s.baseline_score) as avg_baseline",
+		"AVG(s.brier_score_lifetime) as avg_brier_lifetime",
+		"AVG(s.logn_score_lifetime) as avg_logn_lifetime",
+	}
+	if filters.Rule != nil {
+		selectFields = append(selectFields,
+			"AVG(r.score) as avg_rule",
+			"AVG(r.score_time_weighted) as avg_rule_time_weighted",
+		)
 	}
 
 	groupByClauses := []string{}
@@ -280,9 +504,11 @@ func buildAggregateScoreQuery(filters models.ScoreFilters) (string, error) {
 		whereConditions = append(whereConditions, "s.forecast_id = "+fmt.Sprintf("$%d", argsCounter))
 		argsCounter++
 	}
-	if filters.Category != nil {
+	if filters.CategoryIDs != nil || filters.Viewer != nil || filters.QuestionType != nil {
 		joinClauses = append(joinClauses, "left join forecasts f on s.forecast_id = f.id")
-		whereConditions = append(whereConditions, "lower(f.category) like "+fmt.Sprintf("$%d", argsCounter))
+	}
+	if filters.CategoryIDs != nil {
+		whereConditions = append(whereConditions, "f.category_id = any("+fmt.Sprintf("$%d", argsCounter)+")")
 		argsCounter++
 	}
 	if filters.StartDate != nil {
@@ -293,6 +519,24 @@ func buildAggregateScoreQuery(filters models.ScoreFilters) (string, error) {
 		whereConditions = append(whereConditions, "s.created <= "+fmt.Sprintf("$%d", argsCounter))
 		argsCounter++
 	}
+	if filters.Viewer != nil {
+		whereConditions = append(whereConditions, visibilityCondition("f.", argsCounter, false))
+		argsCounter++
+	}
+	if filters.QuestionType != nil {
+		whereConditions = append(whereConditions, "f.question_type = "+fmt.Sprintf("$%d", argsCounter))
+		argsCounter++
+	}
+	if filters.ExcludeCrowd {
+		// a user who took the name before the crowd existed is not the crowd
+		whereConditions = append(whereConditions, fmt.Sprintf("s.user_id not in (select id from users where username = $%d and role = $%d)", argsCounter, argsCounter+1))
+		argsCounter += 2
+	}
+	if filters.Rule != nil {
+		// a left join keeps the scores without one in the other averages
+		joinClauses = append(joinClauses, "left join rule_scores r on r.score_id = s.id and r.rule = "+fmt.Sprintf("$%d", argsCounter))
+		argsCounter++
+	}
 
 	query := fmt.Sprintf(
 		`select %s from %s %s where %s %s`,
@@ -321,9 +565,8 @@ func (r *PostgresScoreRepository) GetAggregateScores(ctx context.Context, filter
 	if filters.ForecastID != nil {
 		args = append(args, *filters.ForecastID)
 	}
-	if filters.Category != nil {
-		categoryPattern := "%" + *filters.Category + "%"
-		args = append(args, categoryPattern)
+	if filters.CategoryIDs != nil {
+		args = append(args, filters.CategoryIDs)
 	}
 	if filters.StartDate != nil {
 		args = append(args, *filters.StartDate)
@@ -331,22 +574,27 @@ func (r *PostgresScoreRepository) GetAggregateScores(ctx context.Context, filter
 	if filters.EndDate != nil {
 		args = append(args, *filters.EndDate)
 	}
+	if filters.Viewer != nil {
+		args = append(args, *filters.Viewer)
+	}
+	if filters.QuestionType != nil {
+		args = append(args, *filters.QuestionType)
+	}
+	if filters.ExcludeCrowd {
+		args = append(args, models.CrowdUsername, models.RoleBot)
+	}
+	if filters.Rule != nil {
+		args = append(args, *filters.Rule)
+	}
 	if filters.GroupByUserID != nil && *filters.GroupByUserID {
 		return nil, errors.New("group by user id is not supported")
 	}
 
 	start := time.Now()
 	var aggregateScores models.OverallScores
-	err = r.db.QueryRowContext(ctx, query, args...).Scan(
-		&aggregateScores.BrierScore,
-		&aggregateScores.Log2Score,
-		&aggregateScores.LogNScore,
-		&aggregateScores.BrierScoreTimeWeighted,
-		&aggregateScores.Log2ScoreTimeWeighted,
-		&aggregateScores.LogNScoreTimeWeighted,
-		&aggregateScores.TotalUsers,
-		&aggregateScores.TotalForecasts,
-	)
+	dest := scoreMetricsDest(&aggregateScores.ScoreMetrics, filters.Rule)
+	dest = append(dest, &aggregateScores.TotalUsers, &aggregateScores.TotalForecasts)
+	err = r.db.Querier(ctx).QueryRowContext(ctx, query, args...).Scan(dest...)
 	if err != nil {
 		return nil, err
 	}
@@ -366,9 +614,8 @@ func (r *PostgresScoreRepository) GetAggregateScoresByUsers(ctx context.Context,
 	if filters.UserID != nil {
 		args = append(args, *filters.UserID)
 	}
-	if filters.Category != nil {
-		categoryPattern := "%" + *filters.Category + "%"
-		args = append(args, categoryPattern)
+	if filters.CategoryIDs != nil {
+		args = append(args, filters.CategoryIDs)
 	}
 	if filters.StartDate != nil {
 		args = append(args, *filters.StartDate)
@@ -376,9 +623,21 @@ func (r *PostgresScoreRepository) GetAggregateScoresByUsers(ctx context.Context,
 	if filters.EndDate != nil {
 		args = append(args, *filters.EndDate)
 	}
+	if filters.Viewer != nil {
+		args = append(args, *filters.Viewer)
+	}
+	if filters.QuestionType != nil {
+		args = append(args, *filters.QuestionType)
+	}
+	if filters.ExcludeCrowd {
+		args = append(args, models.CrowdUsername, models.RoleBot)
+	}
+	if filters.Rule != nil {
+		args = append(args, *filters.Rule)
+	}
 
 	start := time.Now()
-	rows, err := r.db.QueryContext(ctx, query, args...)
+	rows, err := r.db.Querier(ctx).QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -388,16 +647,9 @@ func (r *PostgresScoreRepository) GetAggregateScoresByUsers(ctx context.Context,
 	var userScores []models.UserScores
 	for rows.Next() {
 		var u models.UserScores
-		if err := rows.Scan(
-			&u.BrierScore,
-			&u.Log2Score,
-			&u.LogNScore,
-			&u.BrierScoreTimeWeighted,
-			&u.Log2ScoreTimeWeighted,
-			&u.LogNScoreTimeWeighted,
-			&u.UserID,
-			&u.TotalForecasts,
-		); err != nil {
+		dest := scoreMetricsDest(&u.ScoreMetrics, filters.Rule)
+		dest = append(dest, &u.UserID, &u.TotalForecasts)
+		if err := rows.Scan(dest...); err != nil {
 			return nil, err
 		}
 		userScores = append(userScores, u)
@@ -405,3 +657,25 @@ func (r *PostgresScoreRepository) GetAggregateScoresByUsers(ctx context.Context,
 	log.Info("query results", slog.Int("count", len(userScores)))
 	return userScores, rows.Err()
 }
+
+// scoreMetricsDest returns where to scan the averages of
+// buildAggregateScoreQuery, including those of the rule when not nil
+func scoreMetricsDest(m *models.ScoreMetrics, rule *string) []any {
+	dest := []any{
+		&m.BrierScore,
+		&m.Log2Score,
+		&m.LogNScore,
+		&m.BrierScoreTimeWeighted,
+		&m.Log2ScoreTimeWeighted,
+		&m.LogNScoreTimeWeighted,
+		&m.PeerScore,
+		&m.BaselineScore,
+		&m.BrierScoreLifetime,
+		&m.LogNScoreLifetime,
+	}
+	if rule != nil {
+		m.Rule = *rule
+		dest = append(dest, &m.RuleScore, &m.RuleScoreTimeWeighted)
+	}
+	return dest
+}
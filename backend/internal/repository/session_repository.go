@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"backend/internal/database"
+	"backend/internal/models"
+	"context"
+	"time"
+)
+
+// SessionRepository defines the interface for refresh and revoked token data operations
+type SessionRepository interface {
+	CreateRefreshToken(ctx context.Context, token *models.RefreshToken) error
+	// GetRefreshTokenByHash returns the token with the hash, even if it was revoked
+	GetRefreshTokenByHash(ctx context.Context, tokenHash string) (*models.RefreshToken, error)
+	// RevokeRefreshToken revokes the token and reports whether this call did,
+	// so that two refreshes racing on one token cannot both succeed
+	RevokeRefreshToken(ctx context.Context, id int64) (bool, error)
+	RevokeRefreshTokenFamily(ctx context.Context, familyID string) error
+	// RevokeToken revokes the access token with the jti until it expires
+	RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error
+	IsTokenRevoked(ctx context.Context, jti string) (bool, error)
+}
+
+// PostgresSessionRepository implements the SessionRepository interface
+type PostgresSessionRepository struct {
+	db *database.DB
+}
+
+// NewSessionRepository creates a new PostgresSessionRepository instance
+func NewSessionRepository(db *database.DB) SessionRepository {
+	return &PostgresSessionRepository{db: db}
+}
+
+func (r *PostgresSessionRepository) CreateRefreshToken(ctx context.Context, token *models.RefreshToken) error {
+	token.CreatedAt = time.Now()
+
+	query := `INSERT INTO refresh_tokens (user_id, token_hash, family_id, token_version, created, expires)
+              VALUES ($1, $2, $3, $4, $5, $6)
+              RETURNING id`
+
+	return r.db.Querier(ctx).QueryRowContext(ctx, query,
+		token.UserID,
+		token.TokenHash,
+		token.FamilyID,
+		token.TokenVersion,
+		token.CreatedAt,
+		token.ExpiresAt).Scan(&token.ID)
+}
+
+func (r *PostgresSessionRepository) GetRefreshTokenByHash(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
+	query := `SELECT id, user_id, token_hash, family_id, token_version, created, expires, revoked
+              FROM refresh_tokens
+              WHERE token_hash = $1`
+
+	var token models.RefreshToken
+	err := r.db.Querier(ctx).QueryRowContext(ctx, query, tokenHash).Scan(
+		&token.ID,
+		&token.UserID,
+		&token.TokenHash,
+		&token.FamilyID,
+		&token.TokenVersion,
+		&token.CreatedAt,
+		&token.ExpiresAt,
+		&token.RevokedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+func (r *PostgresSessionRepository) RevokeRefreshToken(ctx context.Context, id int64) (bool, error) {
+	query := `UPDATE refresh_tokens SET revoked = $2 WHERE id = $1 AND revoked IS NULL`
+
+	result, err := r.db.Querier(ctx).ExecContext(ctx, query, id, time.Now())
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rowsAffected == 1, nil
+}
+
+func (r *PostgresSessionRepository) RevokeRefreshTokenFamily(ctx context.Context, familyID string) error {
+	query := `UPDATE refresh_tokens SET revoked = $2 WHERE family_id = $1 AND revoked IS NULL`
+
+	_, err := r.db.Querier(ctx).ExecContext(ctx, query, familyID, time.Now())
+	return err
+}
+
+// RevokeToken also prunes revocations of tokens that have since expired, as
+// expired tokens are rejected anyway
+func (r *PostgresSessionRepository) RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	prune := `DELETE FROM revoked_tokens WHERE expires < $1`
+	if _, err := r.db.Querier(ctx).ExecContext(ctx, prune, time.Now()); err != nil {
+		return err
+	}
+
+	query := `INSERT INTO revoked_tokens (jti, expires) VALUES ($1, $2)
+              ON CONFLICT (jti) DO NOTHING`
+
+	_, err := r.db.Querier(ctx).ExecContext(ctx, query, jti, expiresAt)
+	return err
+}
+
+func (r *PostgresSessionRepository) IsTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	query := `SELECT EXISTS(SELECT 1 FROM revoked_tokens WHERE jti = $1)`
+
+	var revoked bool
+	err := r.db.Querier(ctx).QueryRowContext(ctx, query, jti).Scan(&revoked)
+	return revoked, err
+}
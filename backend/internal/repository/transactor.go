@@ -0,0 +1,17 @@
+package repository
+
+import (
+	"backend/internal/database"
+	"context"
+)
+
+// Transactor runs a unit of work so that every repository write made with the
+// context passed to fn commits or rolls back together
+type Transactor interface {
+	WithinTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// NewTransactor creates a Transactor backed by database transactions
+func NewTransactor(db *database.DB) Transactor {
+	return db
+}
@@ -17,7 +17,10 @@ type UserRepository interface {
 	GetUserByUsername(ctx context.Context, username string) (*models.User, error)
 	DeleteUser(ctx context.Context, id int64) error
 	ValidateUser(ctx context.Context, id int64) (bool, error)
+	// UpdatePassword also bumps the token version, logging the user out everywhere
 	UpdatePassword(ctx context.Context, id int64, password string) error
+	BumpTokenVersion(ctx context.Context, id int64) error
+	UpdateRole(ctx context.Context, id int64, role models.Role) error
 	ListUsers(ctx context.Context) ([]*models.User, error)
 	CreateUserWithPassword(ctx context.Context, username string, passwordHash string) (int64, error)
 }
@@ -34,28 +37,34 @@ func NewUserRepository(db *database.DB) UserRepository {
 
 func (r *PostgresUserRepository) CreateUser(ctx context.Context, user *models.User) error {
 	user.CreatedAt = time.Now()
+	if user.Role == "" {
+		user.Role = models.RoleForecaster
+	}
 
-	query := `INSERT INTO users (username, password, created)
-              VALUES ($1, $2, $3)	
+	query := `INSERT INTO users (username, password, role, created)
+              VALUES ($1, $2, $3, $4)	
               RETURNING id`
 
-	return r.db.QueryRowContext(ctx, query,
+	return r.db.Querier(ctx).QueryRowContext(ctx, query,
 		user.Username,
 		user.Password,
+		user.Role,
 		user.CreatedAt).Scan(&user.ID)
 }
 
 func (r *PostgresUserRepository) GetUserByID(ctx context.Context, id int64) (*models.User, error) {
-	query := `SELECT id, username, password, created
+	query := `SELECT id, username, password, role, created, token_version
               FROM users
               WHERE id = $1`
 
 	var user models.User
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
+	err := r.db.Querier(ctx).QueryRowContext(ctx, query, id).Scan(
 		&user.ID,
 		&user.Username,
 		&user.Password,
-		&user.CreatedAt)
+		&user.Role,
+		&user.CreatedAt,
+		&user.TokenVersion)
 
 	if err != nil {
 		return nil, err
@@ -65,16 +74,18 @@ func (r *PostgresUserRepository) GetUserByID(ctx context.Context, id int64) (*mo
 }
 
 func (r *PostgresUserRepository) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
-	query := `SELECT id, username, password, created
+	query := `SELECT id, username, password, role, created, token_version
               FROM users
               WHERE username = $1`
 
 	var user models.User
-	err := r.db.QueryRowContext(ctx, query, username).Scan(
+	err := r.db.Querier(ctx).QueryRowContext(ctx, query, username).Scan(
 		&user.ID,
 		&user.Username,
 		&user.Password,
-		&user.CreatedAt)
+		&user.Role,
+		&user.CreatedAt,
+		&user.TokenVersion)
 
 	if err != nil {
 		return nil, err
@@ -86,7 +97,7 @@ func (r *PostgresUserRepository) GetUserByUsername(ctx context.Context, username
 func (r *PostgresUserRepository) DeleteUser(ctx context.Context, id int64) error {
 	query := `DELETE FROM users WHERE id = $1`
 
-	result, err := r.db.ExecContext(ctx, query, id)
+	result, err := r.db.Querier(ctx).ExecContext(ctx, query, id)
 	if err != nil {
 		return err
 	}
@@ -107,7 +118,7 @@ func (r *PostgresUserRepository) ValidateUser(ctx context.Context, id int64) (bo
 	query := `SELECT EXISTS(SELECT 1 FROM users WHERE id = $1)`
 
 	var exists bool
-	err := r.db.QueryRowContext(ctx, query, id).Scan(&exists)
+	err := r.db.Querier(ctx).QueryRowContext(ctx, query, id).Scan(&exists)
 	if err != nil {
 		return false, err
 	}
@@ -116,16 +127,45 @@ func (r *PostgresUserRepository) ValidateUser(ctx context.Context, id int64) (bo
 }
 
 func (r *PostgresUserRepository) UpdatePassword(ctx context.Context, id int64, password string) error {
-	query := `UPDATE users SET password = $2 WHERE id = $1`
+	query := `UPDATE users SET password = $2, token_version = token_version + 1 WHERE id = $1`
+
+	_, err := r.db.Querier(ctx).ExecContext(ctx, query, id, password)
+	return err
+}
+
+func (r *PostgresUserRepository) BumpTokenVersion(ctx context.Context, id int64) error {
+	query := `UPDATE users SET token_version = token_version + 1 WHERE id = $1`
 
-	_, err := r.db.ExecContext(ctx, query, id, password)
+	_, err := r.db.Querier(ctx).ExecContext(ctx, query, id)
 	return err
 }
 
+// UpdateRole changes the user's role and revokes their tokens, which carry the
+// old role
+func (r *PostgresUserRepository) UpdateRole(ctx context.Context, id int64, role models.Role) error {
+	query := `UPDATE users SET role = $2, token_version = token_version + 1 WHERE id = $1`
+
+	result, err := r.db.Querier(ctx).ExecContext(ctx, query, id, role)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return errors.New("user not found")
+	}
+
+	return nil
+}
+
 func (r *PostgresUserRepository) ListUsers(ctx context.Context) ([]*models.User, error) {
-	query := `SELECT id, username, created FROM users`
+	query := `SELECT id, username, role, created FROM users`
 
-	rows, err := r.db.QueryContext(ctx, query)
+	rows, err := r.db.Querier(ctx).QueryContext(ctx, query)
 	if err != nil {
 		return nil, err
 	}
@@ -136,7 +176,7 @@ func (r *PostgresUserRepository) ListUsers(ctx context.Context) ([]*models.User,
 	for rows.Next() {
 		var user models.User
 
-		if err := rows.Scan(&user.ID, &user.Username, &user.CreatedAt); err != nil {
+		if err := rows.Scan(&user.ID, &user.Username, &user.Role, &user.CreatedAt); err != nil {
 			return nil, err
 		}
 		users = append(users, &user)
@@ -154,7 +194,7 @@ func (r *PostgresUserRepository) CreateUserWithPassword(ctx context.Context, use
               VALUES ($1, $2, $3)  
               RETURNING id`
 
-	err := r.db.QueryRowContext(ctx, query,
+	err := r.db.Querier(ctx).QueryRowContext(ctx, query,
 		username,
 		passwordHash,
 		createdAt).Scan(&userID)
@@ -0,0 +1,1510 @@
+package routes
+
+import (
+	"backend/internal/cache"
+	"backend/internal/cache/redistest"
+	"backend/internal/models"
+	"backend/internal/repository"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// End-to-end tests against the in-memory repositories; unlike the
+// integration tests these need no running server or database
+
+func TestForecastLifecycle(t *testing.T) {
+	s := newTestServer(t)
+	alice := s.register("alice")
+	bob := s.register("bob")
+
+	forecast := map[string]any{
+		"question":            "Will it rain tomorrow?",
+		"category":            "weather",
+		"resolution_criteria": "Rain is recorded at the airport",
+	}
+	s.mustDo("POST", "/api/forecasts/create", alice, forecast, http.StatusCreated, nil)
+
+	var open []models.Forecast
+	s.mustDo("GET", "/forecasts?status=open", "", nil, http.StatusOK, &open)
+	if len(open) != 1 || open[0].QuestionType != models.QuestionTypeBinary {
+		t.Fatalf("Expected one open binary forecast, got %+v", open)
+	}
+	forecastID := open[0].ID
+
+	s.mustDo("POST", "/api/forecast-points", alice, map[string]any{"forecast_id": forecastID, "point_forecast": 0.8, "reason": "clouds"}, http.StatusCreated, nil)
+	s.mustDo("POST", "/api/forecast-points", bob, map[string]any{"forecast_id": forecastID, "point_forecast": 0.3, "reason": "dry season"}, http.StatusCreated, nil)
+
+	var points []models.ForecastPoint
+	s.mustDo("GET", fmt.Sprintf("/forecast-points?forecast_id=%d", forecastID), "", nil, http.StatusOK, &points)
+	if len(points) != 2 {
+		t.Fatalf("Expected 2 forecast points, got %d", len(points))
+	}
+	if points[0].UserName == nil || *points[0].UserName != "bob" {
+		t.Errorf("Expected the newest point first with its username, got %+v", points[0])
+	}
+
+	// only the owner can resolve
+	if status := s.do("PUT", "/api/resolve", bob, map[string]any{"id": forecastID, "resolution": "1"}, nil); status == http.StatusOK {
+		t.Fatalf("Expected resolving someone else's forecast to fail")
+	}
+	s.mustDo("PUT", "/api/resolve", alice, map[string]any{"id": forecastID, "resolution": "1", "comment": "it rained"}, http.StatusOK, nil)
+
+	var resolved models.Forecast
+	s.mustDo("GET", fmt.Sprintf("/forecasts/%d", forecastID), "", nil, http.StatusOK, &resolved)
+	if !resolved.IsResolved() || resolved.Resolution == nil || *resolved.Resolution != "1" {
+		t.Fatalf("Expected forecast to resolve to 1, got %+v", resolved)
+	}
+
+	// the crowd, created as user 3, is scored on alice's point and then on the
+	// geometric mean of both users' odds
+	var scores []models.Scores
+	s.mustDo("GET", fmt.Sprintf("/scores?forecast_id=%d", forecastID), "", nil, http.StatusOK, &scores)
+	if len(scores) != 3 {
+		t.Fatalf("Expected 2 scores and the crowd's, got %d", len(scores))
+	}
+	brierByUser := make(map[int64]float64)
+	for _, score := range scores {
+		brierByUser[score.UserID] = score.BrierScore
+	}
+	odds := math.Sqrt(0.8 / 0.2 * 0.3 / 0.7)
+	for userID, want := range map[int64]float64{1: 0.04, 2: 0.49, 3: (0.04 + math.Pow(1/(1+odds), 2)) / 2} {
+		if math.Abs(brierByUser[userID]-want) > 1e-9 {
+			t.Errorf("Expected user %d to have brier score %v, got %v", userID, want, brierByUser[userID])
+		}
+	}
+
+	var calibration models.CalibrationData
+	s.mustDo("GET", "/calibration", "", nil, http.StatusOK, &calibration)
+	if calibration.TotalPredictions != 2 || len(calibration.Buckets) != 2 {
+		t.Errorf("Expected 2 predictions in 2 buckets, got %+v", calibration)
+	}
+
+	// a resolved forecast takes no more points
+	if status := s.do("POST", "/api/forecast-points", bob, map[string]any{"forecast_id": forecastID, "point_forecast": 0.9}, nil); status == http.StatusCreated {
+		t.Errorf("Expected forecasting on a resolved forecast to fail")
+	}
+}
+
+func TestMultipleChoiceLifecycle(t *testing.T) {
+	s := newTestServer(t)
+	alice := s.register("alice")
+
+	forecast := map[string]any{
+		"question":            "Which team wins the league?",
+		"category":            "c",
+		"resolution_criteria": "Final standings",
+		"question_type":       "multiple_choice",
+		"options":             []string{"Red", "Blue", "Green"},
+	}
+	s.mustDo("POST", "/api/forecasts/create", alice, forecast, http.StatusCreated, nil)
+
+	var forecasts []models.Forecast
+	s.mustDo("GET", "/forecasts", "", nil, http.StatusOK, &forecasts)
+	forecastID := forecasts[0].ID
+
+	if status := s.do("POST", "/api/forecast-points", alice, map[string]any{"forecast_id": forecastID, "probabilities": []float64{0.5, 0.5}}, nil); status == http.StatusCreated {
+		t.Fatalf("Expected probabilities for the wrong number of options to be rejected")
+	}
+	s.mustDo("POST", "/api/forecast-points", alice, map[string]any{"forecast_id": forecastID, "probabilities": []float64{0.2, 0.7, 0.1}}, http.StatusCreated, nil)
+	s.mustDo("PUT", "/api/resolve", alice, map[string]any{"id": forecastID, "resolution": "1"}, http.StatusOK, nil)
+
+	var scores []models.Scores
+	s.mustDo("GET", fmt.Sprintf("/scores?forecast_id=%d", forecastID), "", nil, http.StatusOK, &scores)
+	if len(scores) != 1 {
+		t.Fatalf("Expected 1 score, got %d", len(scores))
+	}
+	if want := 0.04 + 0.09 + 0.01; math.Abs(scores[0].BrierScore-want) > 1e-9 {
+		t.Errorf("Expected brier score %v, got %v", want, scores[0].BrierScore)
+	}
+}
+
+func TestProtectedRoutesRequireToken(t *testing.T) {
+	s := newTestServer(t)
+
+	if status := s.do("POST", "/api/forecasts/create", "", map[string]any{"question": "q"}, nil); status != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without a token, got %d", status)
+	}
+	if status := s.do("POST", "/api/forecasts/create", "not-a-token", map[string]any{"question": "q"}, nil); status != http.StatusUnauthorized {
+		t.Errorf("Expected 401 with an invalid token, got %d", status)
+	}
+}
+
+// failingScoreRepository fails every CreateScore after the first failAfter
+type failingScoreRepository struct {
+	repository.ScoreRepository
+	failAfter int
+	created   int
+}
+
+func (r *failingScoreRepository) CreateScore(ctx context.Context, score *models.Scores) error {
+	if r.failAfter >= 0 && r.created >= r.failAfter {
+		return errors.New("score insert failed")
+	}
+	r.created++
+	return r.ScoreRepository.CreateScore(ctx, score)
+}
+
+func TestResolveRollsBackOnScoreFailure(t *testing.T) {
+	var scoreRepo *failingScoreRepository
+	s := newTestServer(t, func(r *Repositories) {
+		scoreRepo = &failingScoreRepository{ScoreRepository: r.Score, failAfter: 1}
+		r.Score = scoreRepo
+	})
+	alice := s.register("alice")
+	bob := s.register("bob")
+
+	s.mustDo("POST", "/api/forecasts/create", alice, map[string]any{"question": "q", "category": "c", "resolution_criteria": "r"}, http.StatusCreated, nil)
+	s.mustDo("POST", "/api/forecast-points", alice, map[string]any{"forecast_id": 1, "point_forecast": 0.6}, http.StatusCreated, nil)
+	s.mustDo("POST", "/api/forecast-points", bob, map[string]any{"forecast_id": 1, "point_forecast": 0.4}, http.StatusCreated, nil)
+
+	// the second score fails, so neither the resolution nor the first score stick
+	if status := s.do("PUT", "/api/resolve", alice, map[string]any{"id": 1, "resolution": "0"}, nil); status == http.StatusOK {
+		t.Fatal("Expected resolution to fail")
+	}
+
+	var forecast models.Forecast
+	s.mustDo("GET", "/forecasts/1", "", nil, http.StatusOK, &forecast)
+	if forecast.IsResolved() {
+		t.Fatalf("Expected forecast to stay open after a failed resolution, got %+v", forecast)
+	}
+	var scores []models.Scores
+	s.mustDo("GET", "/scores?user_id=1", "", nil, http.StatusOK, &scores)
+	if len(scores) != 0 {
+		t.Fatalf("Expected no scores after a failed resolution, got %d", len(scores))
+	}
+
+	// retrying once the failure clears resolves with exactly one score per user
+	scoreRepo.failAfter = -1
+	s.mustDo("PUT", "/api/resolve", alice, map[string]any{"id": 1, "resolution": "0"}, http.StatusOK, nil)
+	s.mustDo("GET", "/scores?forecast_id=1", "", nil, http.StatusOK, &scores)
+	if len(scores) != 3 {
+		t.Fatalf("Expected 2 scores and the crowd's after retrying, got %d", len(scores))
+	}
+}
+
+// barrierPointRepository holds the next calls to GetForecastPoints until as
+// many as were armed have arrived, lining concurrent requests up past the read
+type barrierPointRepository struct {
+	repository.ForecastPointRepository
+	mu      sync.Mutex
+	pending int
+	release chan struct{}
+}
+
+func (r *barrierPointRepository) arm(calls int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pending, r.release = calls, make(chan struct{})
+}
+
+func (r *barrierPointRepository) GetForecastPoints(ctx context.Context, filters models.PointFilters) ([]*models.ForecastPoint, error) {
+	r.mu.Lock()
+	release := r.release
+	if r.pending > 0 {
+		r.pending--
+		if r.pending == 0 {
+			close(r.release)
+		}
+	} else {
+		release = nil
+	}
+	r.mu.Unlock()
+
+	if release != nil {
+		select {
+		case <-release:
+		case <-time.After(5 * time.Second):
+		}
+	}
+	return r.ForecastPointRepository.GetForecastPoints(ctx, filters)
+}
+
+func TestConcurrentResolutions(t *testing.T) {
+	var pointRepo *barrierPointRepository
+	s := newTestServer(t, func(r *Repositories) {
+		pointRepo = &barrierPointRepository{ForecastPointRepository: r.ForecastPoint}
+		r.ForecastPoint = pointRepo
+	})
+	alice := s.register("alice")
+	bob := s.register("bob")
+
+	s.mustDo("POST", "/api/forecasts/create", alice, map[string]any{"question": "q", "category": "c", "resolution_criteria": "r"}, http.StatusCreated, nil)
+	s.mustDo("POST", "/api/forecast-points", alice, map[string]any{"forecast_id": 1, "point_forecast": 0.6}, http.StatusCreated, nil)
+	s.mustDo("POST", "/api/forecast-points", bob, map[string]any{"forecast_id": 1, "point_forecast": 0.4}, http.StatusCreated, nil)
+
+	// both requests find the forecast open before either resolves it
+	pointRepo.arm(2)
+	statuses := make([]int, 2)
+	var wg sync.WaitGroup
+	for i, resolution := range []string{"yes", "annulled"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			statuses[i] = s.do("PUT", "/api/resolve", alice, map[string]any{"id": 1, "resolution": resolution}, nil)
+		}()
+	}
+	wg.Wait()
+
+	if ok := slices.Index(statuses, http.StatusOK); ok < 0 || statuses[1-ok] == http.StatusOK {
+		t.Fatalf("Expected exactly one resolution to succeed, got %v", statuses)
+	}
+	var audits []models.ResolutionAudit
+	s.mustDo("GET", "/resolution-audits?forecast_id=1", "", nil, http.StatusOK, &audits)
+	if len(audits) != 1 {
+		t.Errorf("Expected one resolution in the audit trail, got %+v", audits)
+	}
+	var forecast models.Forecast
+	s.mustDo("GET", "/forecasts/1", "", nil, http.StatusOK, &forecast)
+	var scores []models.Scores
+	s.mustDo("GET", "/scores?forecast_id=1", "", nil, http.StatusOK, &scores)
+	if want := map[models.Resolution]int{models.ResolutionYes: 3, models.ResolutionAnnulled: 0}[*forecast.Resolution]; len(scores) != want {
+		t.Errorf("Expected %d scores for a forecast resolved as %q, got %d", want, *forecast.Resolution, len(scores))
+	}
+}
+
+// afterPointRepository runs after once the next call to GetForecastPoints has
+// read the points, interleaving a request with the one reading them
+type afterPointRepository struct {
+	repository.ForecastPointRepository
+	after func()
+}
+
+func (r *afterPointRepository) GetForecastPoints(ctx context.Context, filters models.PointFilters) ([]*models.ForecastPoint, error) {
+	points, err := r.ForecastPointRepository.GetForecastPoints(ctx, filters)
+	if after := r.after; after != nil {
+		r.after = nil
+		after()
+	}
+	return points, err
+}
+
+func TestPointDuringResolution(t *testing.T) {
+	var pointRepo *afterPointRepository
+	s := newTestServer(t, func(r *Repositories) {
+		pointRepo = &afterPointRepository{ForecastPointRepository: r.ForecastPoint}
+		r.ForecastPoint = pointRepo
+	})
+	alice := s.register("alice")
+	bob := s.register("bob")
+	carol := s.register("carol")
+
+	s.mustDo("POST", "/api/forecasts/create", alice, map[string]any{"question": "q", "category": "c", "resolution_criteria": "r"}, http.StatusCreated, nil)
+	s.mustDo("POST", "/api/forecast-points", alice, map[string]any{"forecast_id": 1, "point_forecast": 0.6}, http.StatusCreated, nil)
+	s.mustDo("POST", "/api/forecast-points", bob, map[string]any{"forecast_id": 1, "point_forecast": 0.4}, http.StatusCreated, nil)
+
+	// carol forecasts once the resolution has first read the points, before
+	// it locks the forecast
+	pointRepo.after = func() {
+		s.mustDo("POST", "/api/forecast-points", carol, map[string]any{"forecast_id": 1, "point_forecast": 0.9}, http.StatusCreated, nil)
+	}
+	s.mustDo("PUT", "/api/resolve", alice, map[string]any{"id": 1, "resolution": "yes"}, http.StatusOK, nil)
+
+	var scores []models.Scores
+	s.mustDo("GET", "/scores?forecast_id=1", "", nil, http.StatusOK, &scores)
+	if len(scores) != 4 {
+		t.Errorf("Expected carol's point to be scored with the others and the crowd, got %d scores", len(scores))
+	}
+	if status := s.do("POST", "/api/forecast-points", carol, map[string]any{"forecast_id": 1, "point_forecast": 0.5}, nil); status == http.StatusCreated {
+		t.Error("Expected a point on a resolved forecast to be rejected")
+	}
+}
+
+func TestUnresolveAndReresolve(t *testing.T) {
+	s := newTestServer(t)
+	alice := s.register("alice")
+	bob := s.register("bob")
+
+	s.mustDo("POST", "/api/forecasts/create", alice, map[string]any{"question": "q", "category": "c", "resolution_criteria": "r"}, http.StatusCreated, nil)
+	s.mustDo("POST", "/api/forecast-points", alice, map[string]any{"forecast_id": 1, "point_forecast": 0.8}, http.StatusCreated, nil)
+	s.mustDo("POST", "/api/forecast-points", bob, map[string]any{"forecast_id": 1, "point_forecast": 0.3}, http.StatusCreated, nil)
+
+	if status := s.do("PUT", "/api/unresolve", alice, map[string]any{"id": 1}, nil); status == http.StatusOK {
+		t.Fatal("Expected unresolving an open forecast to fail")
+	}
+	s.mustDo("PUT", "/api/resolve", alice, map[string]any{"id": 1, "resolution": "1"}, http.StatusOK, nil)
+
+	// only the owner can unresolve
+	if status := s.do("PUT", "/api/unresolve", bob, map[string]any{"id": 1}, nil); status == http.StatusOK {
+		t.Fatal("Expected unresolving someone else's forecast to fail")
+	}
+	s.mustDo("PUT", "/api/unresolve", alice, map[string]any{"id": 1, "comment": "resolved too early"}, http.StatusOK, nil)
+
+	var forecast models.Forecast
+	s.mustDo("GET", "/forecasts/1", "", nil, http.StatusOK, &forecast)
+	if forecast.IsResolved() || forecast.Resolution != nil {
+		t.Fatalf("Expected forecast to be open again, got %+v", forecast)
+	}
+	var scores []models.Scores
+	s.mustDo("GET", "/scores?forecast_id=1", "", nil, http.StatusOK, &scores)
+	if len(scores) != 0 {
+		t.Fatalf("Expected scores to be archived, got %d", len(scores))
+	}
+
+	s.mustDo("PUT", "/api/resolve", alice, map[string]any{"id": 1, "resolution": "1"}, http.StatusOK, nil)
+	s.mustDo("GET", "/forecasts/1", "", nil, http.StatusOK, &forecast)
+	resolvedAt := *forecast.ResolvedAt
+
+	// re-resolving to the other outcome recomputes the scores in place
+	s.mustDo("PUT", "/api/unresolve", alice, map[string]any{"id": 1, "resolution": "0", "comment": "misread the criteria"}, http.StatusOK, nil)
+	s.mustDo("GET", "/forecasts/1", "", nil, http.StatusOK, &forecast)
+	if forecast.Resolution == nil || *forecast.Resolution != "0" || !forecast.ResolvedAt.Equal(resolvedAt) {
+		t.Fatalf("Expected forecast to re-resolve to 0 at the original time, got %+v", forecast)
+	}
+	s.mustDo("GET", "/scores?forecast_id=1", "", nil, http.StatusOK, &scores)
+	if len(scores) != 3 {
+		t.Fatalf("Expected 2 scores and the crowd's, got %d", len(scores))
+	}
+	odds := math.Sqrt(0.8 / 0.2 * 0.3 / 0.7)
+	for _, score := range scores {
+		want := map[int64]float64{1: 0.64, 2: 0.09, 3: (0.64 + math.Pow(odds/(1+odds), 2)) / 2}[score.UserID]
+		if math.Abs(score.BrierScore-want) > 1e-9 {
+			t.Errorf("Expected user %d to have brier score %v, got %v", score.UserID, want, score.BrierScore)
+		}
+	}
+
+	var audits []models.ResolutionAudit
+	s.mustDo("GET", "/resolution-audits?forecast_id=1", "", nil, http.StatusOK, &audits)
+	wantActions := []models.ResolutionAction{
+		models.ResolutionActionResolve,
+		models.ResolutionActionUnresolve,
+		models.ResolutionActionResolve,
+		models.ResolutionActionReresolve,
+	}
+	if len(audits) != len(wantActions) {
+		t.Fatalf("Expected %d audits, got %+v", len(wantActions), audits)
+	}
+	for i, audit := range audits {
+		if audit.Action != wantActions[i] {
+			t.Errorf("Expected audit %d to be %s, got %s", i, wantActions[i], audit.Action)
+		}
+	}
+	if audits[1].ArchivedScores != 3 || audits[3].ArchivedScores != 3 || audits[0].ArchivedScores != 0 {
+		t.Errorf("Expected unresolutions to archive 3 scores each, got %+v", audits)
+	}
+	if audits[3].PreviousResolution == nil || *audits[3].PreviousResolution != "1" {
+		t.Errorf("Expected the re-resolution to record the previous resolution, got %+v", audits[3])
+	}
+}
+
+func TestResolutionKinds(t *testing.T) {
+	s := newTestServer(t)
+	alice := s.register("alice")
+
+	for i := 0; i < 2; i++ {
+		s.mustDo("POST", "/api/forecasts/create", alice, map[string]any{"question": "q", "category": "c", "resolution_criteria": "r"}, http.StatusCreated, nil)
+		s.mustDo("POST", "/api/forecast-points", alice, map[string]any{"forecast_id": i + 1, "point_forecast": 0.6}, http.StatusCreated, nil)
+	}
+
+	s.mustDo("POST", "/api/forecasts/create", alice, map[string]any{"question": "q", "category": "c", "resolution_criteria": "r", "question_type": "multiple_choice", "options": []string{"a", "b", "c"}}, http.StatusCreated, nil)
+	s.mustDo("POST", "/api/forecast-points", alice, map[string]any{"forecast_id": 3, "probabilities": []float64{0.2, 0.7, 0.1}}, http.StatusCreated, nil)
+	s.mustDo("POST", "/api/forecasts/create", alice, map[string]any{"question": "q", "category": "c", "resolution_criteria": "r", "question_type": "numeric", "range_min": 0, "range_max": 10}, http.StatusCreated, nil)
+
+	// resolutions the question cannot take are bad requests
+	for _, body := range []map[string]any{
+		{"id": 1, "resolution": "maybe"},
+		{"id": 1, "resolution": "probabilistic"},
+		{"id": 1, "resolution": "probabilistic", "resolution_value": 1.5},
+		{"id": 1, "resolution": "yes", "resolution_value": 0.5},
+		{"id": 1, "resolution": "2"},
+		{"id": 3, "resolution": "yes"},
+		{"id": 3, "resolution": "3"},
+		{"id": 4, "resolution": "11"},
+	} {
+		if status := s.do("PUT", "/api/resolve", alice, body, nil); status != http.StatusBadRequest {
+			t.Errorf("Expected resolution %v to be a bad request, got %d", body, status)
+		}
+	}
+
+	s.mustDo("PUT", "/api/resolve", alice, map[string]any{"id": 3, "resolution": "1"}, http.StatusOK, nil)
+	s.mustDo("PUT", "/api/unresolve", alice, map[string]any{"id": 3, "resolution": "no"}, http.StatusBadRequest, nil)
+	s.mustDo("PUT", "/api/unresolve", alice, map[string]any{"id": 3, "resolution": "-1"}, http.StatusBadRequest, nil)
+
+	s.mustDo("PUT", "/api/resolve", alice, map[string]any{"id": 1, "resolution": "probabilistic", "resolution_value": 0.7}, http.StatusOK, nil)
+	var forecast models.Forecast
+	s.mustDo("GET", "/forecasts/1", "", nil, http.StatusOK, &forecast)
+	if forecast.Resolution == nil || *forecast.Resolution != models.ResolutionProbabilistic || forecast.ResolutionValue == nil || *forecast.ResolutionValue != 0.7 {
+		t.Fatalf("Expected a probabilistic resolution of 0.7, got %+v", forecast)
+	}
+	var scores []models.Scores
+	s.mustDo("GET", "/scores?forecast_id=1", "", nil, http.StatusOK, &scores)
+	if len(scores) != 1 || math.Abs(scores[0].BrierScore-0.01) > 1e-9 {
+		t.Fatalf("Expected a brier score of 0.01 against 0.7, got %+v", scores)
+	}
+
+	// ambiguous forecasts close without scores
+	s.mustDo("PUT", "/api/resolve", alice, map[string]any{"id": 2, "resolution": "ambiguous"}, http.StatusOK, nil)
+	s.mustDo("GET", "/forecasts/2", "", nil, http.StatusOK, &forecast)
+	if !forecast.IsResolved() || *forecast.Resolution != models.ResolutionAmbiguous {
+		t.Fatalf("Expected forecast to resolve as ambiguous, got %+v", forecast)
+	}
+	s.mustDo("GET", "/scores?forecast_id=2", "", nil, http.StatusOK, &scores)
+	if len(scores) != 0 {
+		t.Errorf("Expected no scores for an ambiguous forecast, got %d", len(scores))
+	}
+}
+
+func TestRoles(t *testing.T) {
+	s := newTestServer(t)
+	alice := s.register("alice")
+	bob := s.register("bob")
+	bot := s.registerAs("bot", models.RoleBot)
+	admin := s.registerAs("admin", models.RoleAdmin)
+
+	// bots can read but not write
+	if status := s.do("POST", "/api/forecasts/create", bot, map[string]any{"question": "q", "category": "c"}, nil); status != http.StatusForbidden {
+		t.Errorf("Expected a bot creating a forecast to be forbidden, got %d", status)
+	}
+	s.mustDo("GET", "/forecasts", bot, nil, http.StatusOK, nil)
+
+	// admin routes are for admins only
+	if status := s.do("PUT", "/api/admin/users/password", alice, map[string]any{"user_id": 2, "new_password": "hijacked"}, nil); status != http.StatusForbidden {
+		t.Errorf("Expected a forecaster resetting a password to be forbidden, got %d", status)
+	}
+	s.mustDo("PUT", "/api/admin/users/password", admin, map[string]any{"user_id": 2, "new_password": "reset-by-admin"}, http.StatusOK, nil)
+	// resetting the password logs bob out
+	if status := s.do("GET", "/api/forecasts/llm", bob, nil, nil); status != http.StatusUnauthorized {
+		t.Errorf("Expected bob's token to be revoked by the reset, got %d", status)
+	}
+	var login struct {
+		Token string `json:"token"`
+	}
+	s.mustDo("POST", "/users/login", "", map[string]string{"username": "bob", "password": "reset-by-admin"}, http.StatusOK, &login)
+	bob = login.Token
+
+	// admins can resolve and delete anyone's forecast
+	s.mustDo("POST", "/api/forecasts/create", alice, map[string]any{"question": "q", "category": "c", "resolution_criteria": "r"}, http.StatusCreated, nil)
+	s.mustDo("POST", "/api/forecast-points", bob, map[string]any{"forecast_id": 1, "point_forecast": 0.4}, http.StatusCreated, nil)
+	if status := s.do("PUT", "/api/admin/resolve", alice, map[string]any{"id": 1, "resolution": "no"}, nil); status != http.StatusForbidden {
+		t.Errorf("Expected a forecaster using the admin resolve to be forbidden, got %d", status)
+	}
+	s.mustDo("PUT", "/api/admin/resolve", admin, map[string]any{"id": 1, "resolution": "no"}, http.StatusOK, nil)
+
+	var audits []models.ResolutionAudit
+	s.mustDo("GET", "/resolution-audits?forecast_id=1", "", nil, http.StatusOK, &audits)
+	if len(audits) != 1 || audits[0].UserID != 4 {
+		t.Errorf("Expected the admin to be recorded as resolving, got %+v", audits)
+	}
+
+	s.mustDo("DELETE", "/api/admin/forecasts", admin, map[string]any{"forecast_id": 1}, http.StatusOK, nil)
+	var forecasts []models.Forecast
+	s.mustDo("GET", "/forecasts", "", nil, http.StatusOK, &forecasts)
+	if len(forecasts) != 0 {
+		t.Errorf("Expected the admin to delete alice's forecast, got %+v", forecasts)
+	}
+
+	// roles can be changed by admins and apply from the next login, revoking
+	// the tokens carrying the old role
+	s.mustDo("PUT", "/api/admin/users/role", admin, map[string]any{"user_id": 1, "role": "bot"}, http.StatusOK, nil)
+	if status := s.do("POST", "/api/forecasts/create", alice, map[string]any{"question": "q", "category": "c"}, nil); status != http.StatusUnauthorized {
+		t.Errorf("Expected alice's token to be revoked by the role change, got %d", status)
+	}
+	if status := s.do("PUT", "/api/admin/users/role", admin, map[string]any{"user_id": 1, "role": "superuser"}, nil); status != http.StatusBadRequest {
+		t.Errorf("Expected an unknown role to be a bad request, got %d", status)
+	}
+	demoted := s.login("alice")
+	if status := s.do("POST", "/api/forecasts/create", demoted, map[string]any{"question": "q", "category": "c"}, nil); status != http.StatusForbidden {
+		t.Errorf("Expected a demoted user to be forbidden, got %d", status)
+	}
+	if status := s.do("DELETE", "/api/users?id=2", demoted, nil, nil); status != http.StatusForbidden {
+		t.Errorf("Expected deleting another user to be forbidden, got %d", status)
+	}
+	s.mustDo("DELETE", "/api/users?id=2", admin, nil, http.StatusOK, nil)
+
+	// a demoted admin loses the admin routes at once
+	other := s.registerAs("other", models.RoleAdmin)
+	s.mustDo("PUT", "/api/admin/users/role", admin, map[string]any{"user_id": 5, "role": "forecaster"}, http.StatusOK, nil)
+	if status := s.do("PUT", "/api/admin/users/role", other, map[string]any{"user_id": 4, "role": "forecaster"}, nil); status != http.StatusUnauthorized {
+		t.Errorf("Expected the demoted admin's token to be revoked, got %d", status)
+	}
+	if status := s.do("PUT", "/api/admin/users/role", s.login("other"), map[string]any{"user_id": 4, "role": "forecaster"}, nil); status != http.StatusForbidden {
+		t.Errorf("Expected the demoted admin to be forbidden after logging in again, got %d", status)
+	}
+}
+
+func TestAPIKeys(t *testing.T) {
+	s := newTestServer(t)
+	alice := s.register("alice")
+	bob := s.register("bob")
+	s.mustDo("POST", "/api/forecasts/create", alice, map[string]any{"question": "q", "category": "c", "resolution_criteria": "r"}, http.StatusCreated, nil)
+
+	var created struct {
+		Key    string        `json:"key"`
+		APIKey models.APIKey `json:"api_key"`
+	}
+	if status := s.do("POST", "/api/keys", bob, map[string]any{"name": "agent", "scopes": []string{"points:fly"}}, nil); status != http.StatusBadRequest {
+		t.Errorf("Expected an unknown scope to be rejected, got %d", status)
+	}
+	s.mustDo("POST", "/api/keys", bob, map[string]any{"name": "agent", "scopes": []string{"points:write", "forecasts:read"}}, http.StatusCreated, &created)
+	key := created.Key
+
+	// the key forecasts as bob, and its scopes stop anything else
+	s.mustDo("POST", "/api/forecast-points", key, map[string]any{"forecast_id": 1, "point_forecast": 0.4}, http.StatusCreated, nil)
+	var stale []models.Forecast
+	s.mustDo("GET", "/api/forecasts/llm", key, nil, http.StatusOK, &stale)
+	if status := s.do("POST", "/api/forecasts/create", key, map[string]any{"question": "q", "category": "c"}, nil); status != http.StatusForbidden {
+		t.Errorf("Expected a key without forecasts:write to be forbidden, got %d", status)
+	}
+	if status := s.do("POST", "/api/keys", key, map[string]any{"name": "escalated"}, nil); status != http.StatusForbidden {
+		t.Errorf("Expected a key creating keys to be forbidden, got %d", status)
+	}
+	var points []models.ForecastPoint
+	s.mustDo("GET", "/forecast-points?forecast_id=1", "", nil, http.StatusOK, &points)
+	if len(points) != 1 || points[0].UserID != 2 {
+		t.Fatalf("Expected the key's point to belong to bob, got %+v", points)
+	}
+
+	var keys []models.APIKey
+	s.mustDo("GET", "/api/keys", bob, nil, http.StatusOK, &keys)
+	if len(keys) != 1 || keys[0].LastUsedAt == nil || keys[0].Prefix != key[:11] || keys[0].KeyHash != "" {
+		t.Fatalf("Expected one used key listed by prefix, got %+v", keys)
+	}
+
+	// only the owner can revoke, and revoked keys stop working
+	if status := s.do("DELETE", fmt.Sprintf("/api/keys?id=%d", keys[0].ID), alice, nil, nil); status != http.StatusNotFound {
+		t.Errorf("Expected revoking someone else's key to be not found, got %d", status)
+	}
+	s.mustDo("DELETE", fmt.Sprintf("/api/keys?id=%d", keys[0].ID), bob, nil, http.StatusOK, nil)
+	if status := s.do("GET", "/api/forecasts/llm", key, nil, nil); status != http.StatusUnauthorized {
+		t.Errorf("Expected a revoked key to be unauthorized, got %d", status)
+	}
+	if status := s.do("GET", "/api/forecasts/llm", "fk_not-a-key", nil, nil); status != http.StatusUnauthorized {
+		t.Errorf("Expected an unknown key to be unauthorized, got %d", status)
+	}
+}
+
+func TestSessions(t *testing.T) {
+	s := newTestServer(t)
+	s.register("alice")
+
+	var session models.Session
+	credentials := map[string]string{"username": "alice", "password": "password123"}
+	s.mustDo("POST", "/users/login", "", credentials, http.StatusOK, &session)
+	if session.RefreshToken == "" || session.ExpiresAt.IsZero() {
+		t.Fatalf("Expected login to return a refresh token and expiry, got %+v", session)
+	}
+
+	// refreshing rotates the refresh token
+	var refreshed models.Session
+	s.mustDo("POST", "/users/refresh", "", map[string]string{"refresh_token": session.RefreshToken}, http.StatusOK, &refreshed)
+	if refreshed.RefreshToken == session.RefreshToken || refreshed.AccessToken == session.AccessToken {
+		t.Fatalf("Expected refreshing to issue new tokens, got %+v", refreshed)
+	}
+	s.mustDo("GET", "/api/forecasts/llm", refreshed.AccessToken, nil, http.StatusOK, nil)
+
+	// reusing a rotated refresh token revokes the whole family
+	if status := s.do("POST", "/users/refresh", "", map[string]string{"refresh_token": session.RefreshToken}, nil); status != http.StatusUnauthorized {
+		t.Errorf("Expected a reused refresh token to be unauthorized, got %d", status)
+	}
+	if status := s.do("POST", "/users/refresh", "", map[string]string{"refresh_token": refreshed.RefreshToken}, nil); status != http.StatusUnauthorized {
+		t.Errorf("Expected the family of a reused refresh token to be revoked, got %d", status)
+	}
+
+	// logging out revokes the access and refresh token
+	s.mustDo("POST", "/users/login", "", credentials, http.StatusOK, &session)
+	other := s.login("alice")
+	s.mustDo("POST", "/users/logout", session.AccessToken, map[string]string{"refresh_token": session.RefreshToken}, http.StatusOK, nil)
+	if status := s.do("GET", "/api/forecasts/llm", session.AccessToken, nil, nil); status != http.StatusUnauthorized {
+		t.Errorf("Expected a logged out token to be unauthorized, got %d", status)
+	}
+	if status := s.do("POST", "/users/refresh", "", map[string]string{"refresh_token": session.RefreshToken}, nil); status != http.StatusUnauthorized {
+		t.Errorf("Expected a logged out refresh token to be unauthorized, got %d", status)
+	}
+	s.mustDo("GET", "/api/forecasts/llm", other, nil, http.StatusOK, nil)
+
+	// changing the password ends every session
+	s.mustDo("POST", "/users/login", "", credentials, http.StatusOK, &session)
+	s.mustDo("PUT", "/api/users/password", other, map[string]string{"old_password": "password123", "new_password": "new-password"}, http.StatusOK, nil)
+	for _, token := range []string{other, session.AccessToken} {
+		if status := s.do("GET", "/api/forecasts/llm", token, nil, nil); status != http.StatusUnauthorized {
+			t.Errorf("Expected tokens issued before a password change to be unauthorized, got %d", status)
+		}
+	}
+	if status := s.do("POST", "/users/refresh", "", map[string]string{"refresh_token": session.RefreshToken}, nil); status != http.StatusUnauthorized {
+		t.Errorf("Expected refresh tokens issued before a password change to be unauthorized, got %d", status)
+	}
+
+	// logging out everywhere does too
+	var first, second models.Session
+	credentials["password"] = "new-password"
+	s.mustDo("POST", "/users/login", "", credentials, http.StatusOK, &first)
+	s.mustDo("POST", "/users/login", "", credentials, http.StatusOK, &second)
+	s.mustDo("POST", "/users/logout", first.AccessToken, map[string]bool{"everywhere": true}, http.StatusOK, nil)
+	if status := s.do("GET", "/api/forecasts/llm", second.AccessToken, nil, nil); status != http.StatusUnauthorized {
+		t.Errorf("Expected logging out everywhere to revoke other tokens, got %d", status)
+	}
+	if status := s.do("POST", "/users/logout", "", nil, nil); status != http.StatusUnauthorized {
+		t.Errorf("Expected logging out without a token to be unauthorized, got %d", status)
+	}
+}
+
+func TestCacheAcrossNodes(t *testing.T) {
+	backends := map[string]func(t *testing.T, redis cache.RedisOptions) cache.Cache{
+		"redis": func(t *testing.T, redis cache.RedisOptions) cache.Cache {
+			return cache.NewRedisCache(redis, "", time.Minute)
+		},
+		"synced": func(t *testing.T, redis cache.RedisOptions) cache.Cache {
+			c, err := cache.NewSyncedCache(cache.NewCache(), redis, "")
+			if err != nil {
+				t.Fatalf("Error creating synced cache: %v", err)
+			}
+			t.Cleanup(func() { c.Close() })
+			return c
+		},
+	}
+
+	for name, newCache := range backends {
+		t.Run(name, func(t *testing.T) {
+			redis := cache.RedisOptions{Addr: redistest.NewServer(t).Addr()}
+			store := newTestStore(t)
+			cacheA, cacheB := newCache(t, redis), newCache(t, redis)
+			a, b := newTestNode(t, store, cacheA), newTestNode(t, store, cacheB)
+
+			alice := a.register("alice")
+			bob := a.register("bob")
+			a.mustDo("POST", "/api/forecasts/create", alice, map[string]any{"question": "q", "category": "c", "resolution_criteria": "r"}, http.StatusCreated, nil)
+			a.mustDo("POST", "/api/forecast-points", bob, map[string]any{"forecast_id": 1, "point_forecast": 0.4}, http.StatusCreated, nil)
+
+			// b caches the forecast, the list of open forecasts and its scores
+			var forecast models.Forecast
+			var open []models.Forecast
+			var scores []models.Scores
+			b.mustDo("GET", "/forecasts/1", "", nil, http.StatusOK, &forecast)
+			b.mustDo("GET", "/forecasts?status=open", "", nil, http.StatusOK, &open)
+			b.mustDo("GET", "/scores?forecast_id=1", "", nil, http.StatusOK, &scores)
+			if forecast.IsResolved() || len(open) != 1 || len(scores) != 0 {
+				t.Fatalf("Expected one open forecast, got %+v, %+v and %+v", forecast, open, scores)
+			}
+
+			// resolving on a invalidates b's entries
+			a.mustDo("PUT", "/api/resolve", alice, map[string]any{"id": 1, "resolution": "yes"}, http.StatusOK, nil)
+			deadline := time.Now().Add(time.Second)
+			for {
+				b.mustDo("GET", "/forecasts/1", "", nil, http.StatusOK, &forecast)
+				b.mustDo("GET", "/forecasts?status=open", "", nil, http.StatusOK, &open)
+				b.mustDo("GET", "/scores?forecast_id=1", "", nil, http.StatusOK, &scores)
+				if forecast.IsResolved() && len(open) == 0 && len(scores) == 1 {
+					break
+				}
+				if time.Now().After(deadline) {
+					t.Fatalf("Expected the other node to see the resolution, got %+v, %+v and %+v", forecast, open, scores)
+				}
+				time.Sleep(5 * time.Millisecond)
+			}
+
+			for _, c := range []cache.Cache{cacheA, cacheB} {
+				if stats := c.Stats(); stats.Errors != 0 {
+					t.Errorf("Expected every cached value to round trip, got %+v", stats)
+				}
+			}
+		})
+	}
+}
+
+func TestCacheInvalidation(t *testing.T) {
+	s := newTestServer(t)
+	alice := s.register("alice")
+	bob := s.register("bob")
+	s.mustDo("POST", "/api/forecasts/create", alice, map[string]any{"question": "q", "category": "c", "resolution_criteria": "r"}, http.StatusCreated, nil)
+	s.mustDo("POST", "/api/forecasts/create", alice, map[string]any{"question": "q2", "category": "c", "resolution_criteria": "r"}, http.StatusCreated, nil)
+	s.mustDo("POST", "/api/forecast-points", bob, map[string]any{"forecast_id": 1, "point_forecast": 0.4}, http.StatusCreated, nil)
+
+	// cache bob's latest points, scores and the calibration
+	var points []models.ForecastPoint
+	var scores []models.Scores
+	var calibration models.CalibrationData
+	s.mustDo("GET", "/forecast-points?user_id=2", "", nil, http.StatusOK, &points)
+	s.mustDo("GET", "/scores?user_id=2", "", nil, http.StatusOK, &scores)
+	s.mustDo("GET", "/calibration", "", nil, http.StatusOK, &calibration)
+	if len(points) != 1 || len(scores) != 0 || calibration.TotalForecasts != 0 {
+		t.Fatalf("Expected one point and nothing resolved, got %+v, %+v and %+v", points, scores, calibration)
+	}
+
+	s.mustDo("POST", "/api/forecast-points", bob, map[string]any{"forecast_id": 2, "point_forecast": 0.7}, http.StatusCreated, nil)
+	s.mustDo("GET", "/forecast-points?user_id=2", "", nil, http.StatusOK, &points)
+	if len(points) != 2 {
+		t.Errorf("Expected a new point to refresh the user's latest points, got %+v", points)
+	}
+
+	s.mustDo("PUT", "/api/resolve", alice, map[string]any{"id": 1, "resolution": "yes"}, http.StatusOK, nil)
+	s.mustDo("GET", "/scores?user_id=2", "", nil, http.StatusOK, &scores)
+	if len(scores) != 1 {
+		t.Errorf("Expected a resolution to refresh the scores, got %+v", scores)
+	}
+	s.mustDo("GET", "/calibration", "", nil, http.StatusOK, &calibration)
+	if calibration.TotalForecasts != 1 {
+		t.Errorf("Expected a resolution to refresh the calibration, got %+v", calibration)
+	}
+
+	s.mustDo("PUT", "/api/unresolve", alice, map[string]any{"id": 1}, http.StatusOK, nil)
+	s.mustDo("GET", "/scores?user_id=2", "", nil, http.StatusOK, &scores)
+	s.mustDo("GET", "/calibration", "", nil, http.StatusOK, &calibration)
+	if len(scores) != 0 || calibration.TotalForecasts != 0 {
+		t.Errorf("Expected unresolving to refresh the scores and calibration, got %+v and %+v", scores, calibration)
+	}
+
+	var users []models.User
+	s.mustDo("GET", "/users", "", nil, http.StatusOK, &users)
+	carol := s.register("carol")
+	s.mustDo("GET", "/users", "", nil, http.StatusOK, &users)
+	if len(users) != 3 {
+		t.Errorf("Expected a new user to refresh the users, got %+v", users)
+	}
+	s.mustDo("DELETE", "/api/users?id=3", carol, nil, http.StatusOK, nil)
+	s.mustDo("GET", "/users", "", nil, http.StatusOK, &users)
+	if len(users) != 2 {
+		t.Errorf("Expected a deleted user to refresh the users, got %+v", users)
+	}
+}
+
+func TestPagination(t *testing.T) {
+	s := newTestServer(t)
+	alice := s.register("alice")
+	bob := s.register("bob")
+	for _, question := range []string{"e", "c", "a", "d", "b"} {
+		s.mustDo("POST", "/api/forecasts/create", alice, map[string]any{"question": question, "category": "c", "resolution_criteria": "r"}, http.StatusCreated, nil)
+	}
+
+	// walk the forecasts by question, keeping two fields
+	var questions []string
+	path := "/forecasts?sort=question&order=asc&limit=2&fields=id,question"
+	for pages := 0; path != ""; pages++ {
+		if pages == 3 {
+			t.Fatal("Expected three pages")
+		}
+		var page struct {
+			Items      []map[string]any `json:"items"`
+			NextCursor *string          `json:"next_cursor"`
+		}
+		s.mustDo("GET", path, "", nil, http.StatusOK, &page)
+		for _, item := range page.Items {
+			if len(item) != 2 {
+				t.Errorf("Expected only the selected fields, got %v", item)
+			}
+			questions = append(questions, item["question"].(string))
+		}
+		path = ""
+		if page.NextCursor != nil {
+			path = "/forecasts?limit=2&fields=id,question&cursor=" + *page.NextCursor
+		}
+	}
+	if strings.Join(questions, "") != "abcde" {
+		t.Errorf("Expected the questions in order, got %v", questions)
+	}
+
+	// newest first by default
+	var newest models.Paged[models.Forecast]
+	s.mustDo("GET", "/forecasts?limit=3", "", nil, http.StatusOK, &newest)
+	if len(newest.Items) != 3 || newest.Items[0].ID != 5 || newest.Items[2].ID != 3 || newest.NextCursor == nil {
+		t.Errorf("Expected forecasts 5 to 3 and a next cursor, got %+v", newest)
+	}
+
+	// unpaged lists are still plain arrays
+	var all []models.Forecast
+	s.mustDo("GET", "/forecasts", "", nil, http.StatusOK, &all)
+	if len(all) != 5 {
+		t.Errorf("Expected every forecast, got %d", len(all))
+	}
+
+	for _, value := range []float64{0.1, 0.2, 0.3} {
+		s.mustDo("POST", "/api/forecast-points", bob, map[string]any{"forecast_id": 1, "point_forecast": value}, http.StatusCreated, nil)
+	}
+	var first, second models.Paged[models.ForecastPoint]
+	s.mustDo("GET", "/forecast-points?forecast_id=1&limit=2", "", nil, http.StatusOK, &first)
+	if len(first.Items) != 2 || first.Items[0].PointForecast != 0.3 || first.NextCursor == nil {
+		t.Fatalf("Expected the two newest points and a next cursor, got %+v", first)
+	}
+	s.mustDo("GET", "/forecast-points?forecast_id=1&limit=2&cursor="+*first.NextCursor, "", nil, http.StatusOK, &second)
+	if len(second.Items) != 1 || second.Items[0].PointForecast != 0.1 || second.NextCursor != nil {
+		t.Errorf("Expected the oldest point and no next cursor, got %+v", second)
+	}
+
+	s.mustDo("PUT", "/api/resolve", alice, map[string]any{"id": 1, "resolution": "yes"}, http.StatusOK, nil)
+	var scores models.Paged[models.Scores]
+	s.mustDo("GET", "/scores?forecast_id=1&limit=1", "", nil, http.StatusOK, &scores)
+	if len(scores.Items) != 1 || scores.NextCursor != nil {
+		t.Errorf("Expected bob's score alone on the page, got %+v", scores)
+	}
+
+	for _, path := range []string{
+		"/forecasts?limit=0",
+		"/forecasts?limit=501",
+		"/forecasts?sort=resolution",
+		"/forecasts?order=up",
+		"/forecasts?cursor=garbage",
+		"/forecasts?sort=question&cursor=" + *newest.NextCursor,
+		"/forecasts?fields=id,nope",
+		"/forecast-points?sort=question",
+		"/scores?cursor=" + *newest.NextCursor + "&sort=closing_date",
+	} {
+		s.mustDo("GET", path, "", nil, http.StatusBadRequest, nil)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	s := newTestServer(t)
+	alice := s.register("alice")
+	bob := s.register("bob")
+	s.mustDo("POST", "/api/forecasts/create", alice, map[string]any{"question": "Will the election be held in May?", "category": "politics", "resolution_criteria": "Official results"}, http.StatusCreated, nil)
+	s.mustDo("POST", "/api/forecasts/create", alice, map[string]any{"question": "Will it rain tomorrow?", "category": "weather", "resolution_criteria": "Resolves yes if any station reports rain"}, http.StatusCreated, nil)
+	s.mustDo("POST", "/api/forecasts/create", alice, map[string]any{"question": "Will the senate pass the bill?", "category": "politics", "resolution_criteria": "A recorded vote"}, http.StatusCreated, nil)
+	s.mustDo("POST", "/api/forecast-points", bob, map[string]any{"forecast_id": 3, "point_forecast": 0.6, "reason": "Polls before the elections favour the majority"}, http.StatusCreated, nil)
+	s.mustDo("POST", "/api/forecast-points", bob, map[string]any{"forecast_id": 3, "point_forecast": 0.7, "reason": "The election is close"}, http.StatusCreated, nil)
+
+	var results []models.SearchResult
+	s.mustDo("GET", "/search?q=election", "", nil, http.StatusOK, &results)
+	if len(results) != 2 {
+		t.Fatalf("Expected two forecasts, got %+v", results)
+	}
+	// a question match outranks reason matches
+	if results[0].Forecast.ID != 1 || results[1].Forecast.ID != 3 {
+		t.Errorf("Expected forecast 1 then forecast 3, got %d and %d", results[0].Forecast.ID, results[1].Forecast.ID)
+	}
+	if m := results[0].Matches; len(m) != 1 || m[0].Field != models.SearchFieldQuestion || !strings.Contains(m[0].Snippet, "<mark>election</mark>") {
+		t.Errorf("Expected a highlighted question, got %+v", m)
+	}
+	reasons := results[1].Matches
+	if len(reasons) != 2 {
+		t.Fatalf("Expected both reasons grouped under their forecast, got %+v", reasons)
+	}
+	for _, m := range reasons {
+		if m.Field != models.SearchFieldReason || m.PointID == nil || !strings.Contains(m.Snippet, "<mark>") {
+			t.Errorf("Expected a highlighted reason of a point, got %+v", m)
+		}
+	}
+
+	s.mustDo("GET", "/search?q=rain", "", nil, http.StatusOK, &results)
+	if len(results) != 1 || len(results[0].Matches) != 2 {
+		t.Errorf("Expected the question and criteria of forecast 2 to match, got %+v", results)
+	}
+	s.mustDo("GET", "/search?q=election+-senate", "", nil, http.StatusOK, &results)
+	if len(results) != 2 {
+		t.Errorf("Expected reasons to match even when the question is excluded, got %+v", results)
+	}
+	s.mustDo("GET", `/search?q="election+is+close"`, "", nil, http.StatusOK, &results)
+	if len(results) != 1 || results[0].Forecast.ID != 3 {
+		t.Errorf("Expected the phrase to match forecast 3 alone, got %+v", results)
+	}
+
+	// user text is escaped, so the marks are the only markup in a snippet
+	s.mustDo("POST", "/api/forecast-points", bob, map[string]any{"forecast_id": 2, "point_forecast": 0.5, "reason": "<script>alert(1)</script> forecasts say drizzle & wind"}, http.StatusCreated, nil)
+	s.mustDo("GET", "/search?q=drizzle", "", nil, http.StatusOK, &results)
+	if len(results) != 1 || len(results[0].Matches) != 1 {
+		t.Fatalf("Expected the reason of forecast 2 to match, got %+v", results)
+	}
+	if snippet := results[0].Matches[0].Snippet; strings.Contains(snippet, "<script>") || !strings.Contains(snippet, "&lt;script&gt;") || !strings.Contains(snippet, "<mark>drizzle</mark> &amp; wind") {
+		t.Errorf("Expected an escaped snippet with the match marked, got %q", snippet)
+	}
+
+	s.mustDo("GET", "/search?q=election&category=weather", "", nil, http.StatusOK, &results)
+	if len(results) != 0 {
+		t.Errorf("Expected no weather forecast about elections, got %+v", results)
+	}
+	s.mustDo("POST", "/api/forecast-points", bob, map[string]any{"forecast_id": 1, "point_forecast": 0.5, "reason": "unsure"}, http.StatusCreated, nil)
+	s.mustDo("PUT", "/api/resolve", alice, map[string]any{"id": 1, "resolution": "yes", "comment": "Held on May 5"}, http.StatusOK, nil)
+	s.mustDo("GET", "/search?q=election&status=open", "", nil, http.StatusOK, &results)
+	if len(results) != 1 || results[0].Forecast.ID != 3 {
+		t.Errorf("Expected only the open forecast, got %+v", results)
+	}
+	s.mustDo("GET", "/search?q=held&status=resolved", "", nil, http.StatusOK, &results)
+	if len(results) != 1 || len(results[0].Matches) != 2 || results[0].Matches[1].Field != models.SearchFieldComment {
+		t.Errorf("Expected the question and comment of the resolved forecast, got %+v", results)
+	}
+
+	for _, path := range []string{"/search", "/search?q=+", "/search?q=x&status=done", "/search?q=x&limit=101"} {
+		s.mustDo("GET", path, "", nil, http.StatusBadRequest, nil)
+	}
+}
+
+func TestCategories(t *testing.T) {
+	s := newTestServer(t)
+	admin := s.registerAs("admin", models.RoleAdmin)
+	alice := s.register("alice")
+	bob := s.register("bob")
+
+	// a category named as before categories were a tree is found at the top
+	// level, and only admins create new ones
+	var politics, us, sports models.Category
+	s.mustDo("POST", "/api/forecasts/create", alice, map[string]any{"question": "q1", "category": "Politics", "resolution_criteria": "r"}, http.StatusCreated, nil)
+	s.mustDo("POST", "/api/forecasts/create", alice, map[string]any{"question": "q2", "category": " politics ", "resolution_criteria": "r"}, http.StatusCreated, nil)
+	s.mustDo("POST", "/api/forecasts/create", alice, map[string]any{"question": "q", "category": "Poltics", "resolution_criteria": "r"}, http.StatusBadRequest, nil)
+	var forecast models.Forecast
+	s.mustDo("GET", "/forecasts/2", "", nil, http.StatusOK, &forecast)
+	if forecast.CategoryID == nil || forecast.Category != "politics" {
+		t.Fatalf("Expected the existing politics category, got %+v", forecast)
+	}
+	politics.ID = *forecast.CategoryID
+
+	if status := s.do("POST", "/api/admin/categories", alice, map[string]any{"name": "US"}, nil); status != http.StatusForbidden {
+		t.Errorf("Expected a forecaster to be forbidden from creating categories, got %d", status)
+	}
+	s.mustDo("POST", "/api/admin/categories", admin, map[string]any{"name": "US", "parent_id": politics.ID}, http.StatusCreated, &us)
+	s.mustDo("POST", "/api/admin/categories", admin, map[string]any{"name": "us", "parent_id": politics.ID}, http.StatusConflict, nil)
+	s.mustDo("POST", "/api/admin/categories", admin, map[string]any{"name": "Sports"}, http.StatusCreated, &sports)
+
+	var tree []models.Category
+	s.mustDo("GET", "/categories", "", nil, http.StatusOK, &tree)
+	if len(tree) != 4 || tree[1].Name != "politics" || len(tree[1].Children) != 1 || tree[1].Children[0].ID != us.ID || tree[2].ID != sports.ID {
+		t.Fatalf("Expected US under politics and Sports beside the test categories, got %+v", tree)
+	}
+
+	s.mustDo("POST", "/api/forecasts/create", alice, map[string]any{"question": "q3", "category_id": us.ID, "tags": []string{"Elections", " elections", "Senate"}, "resolution_criteria": "r"}, http.StatusCreated, nil)
+	s.mustDo("GET", "/forecasts/3", "", nil, http.StatusOK, &forecast)
+	if forecast.Category != "US" || !slices.Equal(forecast.Tags, []string{"elections", "senate"}) {
+		t.Errorf("Expected forecast 3 in US with normalized tags, got %+v", forecast)
+	}
+	s.mustDo("POST", "/api/forecasts/create", alice, map[string]any{"question": "q4", "category_id": 99, "resolution_criteria": "r"}, http.StatusBadRequest, nil)
+
+	var forecasts []models.Forecast
+	for path, want := range map[string]int{
+		fmt.Sprintf("/forecasts?category_id=%d", politics.ID):                            2,
+		fmt.Sprintf("/forecasts?category_id=%d&include_subcategories=true", politics.ID): 3,
+		"/forecasts?category=politics":                                                   2,
+		"/forecasts?category=pol":                                                        0,
+		"/forecasts?tag=Elections":                                                       1,
+	} {
+		s.mustDo("GET", path, "", nil, http.StatusOK, &forecasts)
+		if len(forecasts) != want {
+			t.Errorf("Expected %d forecasts from %s, got %d", want, path, len(forecasts))
+		}
+	}
+
+	// scores and calibration filter on the category tree too
+	s.mustDo("POST", "/api/forecast-points", bob, map[string]any{"forecast_id": 3, "point_forecast": 0.8}, http.StatusCreated, nil)
+	s.mustDo("PUT", "/api/resolve", alice, map[string]any{"id": 3, "resolution": "yes", "comment": "c"}, http.StatusOK, nil)
+	var overall models.OverallScores
+	s.mustDo("GET", fmt.Sprintf("/scores/aggregate?category_id=%d", politics.ID), "", nil, http.StatusOK, &overall)
+	if overall.TotalForecasts != 0 {
+		t.Errorf("Expected no scores directly in Politics, got %+v", overall)
+	}
+	s.mustDo("GET", fmt.Sprintf("/scores/aggregate?category_id=%d&include_subcategories=true", politics.ID), "", nil, http.StatusOK, &overall)
+	if overall.TotalForecasts != 1 {
+		t.Errorf("Expected the scores of US under Politics, got %+v", overall)
+	}
+	var calibration models.CalibrationData
+	s.mustDo("GET", "/calibration?category=us", "", nil, http.StatusOK, &calibration)
+	if calibration.TotalForecasts != 1 {
+		t.Errorf("Expected the calibration of US, got %+v", calibration)
+	}
+
+	// renaming a category renames its forecasts' category, cached or not
+	s.mustDo("PUT", "/api/admin/categories", admin, map[string]any{"id": us.ID, "name": "United States", "parent_id": politics.ID}, http.StatusOK, nil)
+	s.mustDo("GET", "/forecasts/3", "", nil, http.StatusOK, &forecast)
+	if forecast.Category != "United States" {
+		t.Errorf("Expected the renamed category, got %q", forecast.Category)
+	}
+	s.mustDo("PUT", "/api/admin/categories", admin, map[string]any{"id": politics.ID, "name": "Politics", "parent_id": us.ID}, http.StatusBadRequest, nil)
+
+	s.mustDo("DELETE", fmt.Sprintf("/api/admin/categories?id=%d", politics.ID), admin, nil, http.StatusConflict, nil)
+	s.mustDo("DELETE", fmt.Sprintf("/api/admin/categories?id=%d", sports.ID), admin, nil, http.StatusOK, nil)
+
+	// personal forecasts, by category or tag, are never handed to bots
+	s.mustDo("POST", "/api/admin/categories", admin, map[string]any{"name": "Personal"}, http.StatusCreated, nil)
+	s.mustDo("POST", "/api/forecasts/create", alice, map[string]any{"question": "q4", "category": "Personal", "resolution_criteria": "r"}, http.StatusCreated, nil)
+	if status := s.do("PUT", "/api/forecasts/categorize", bob, map[string]any{"forecast_id": 1, "category_id": us.ID, "tags": []string{"personal"}}, nil); status == http.StatusOK {
+		t.Errorf("Expected only the author to categorize their forecast")
+	}
+	s.mustDo("PUT", "/api/forecasts/categorize", alice, map[string]any{"forecast_id": 1, "category_id": us.ID, "tags": []string{"Personal"}}, http.StatusOK, nil)
+	s.mustDo("GET", "/forecasts/llm/3", "", nil, http.StatusOK, &forecasts)
+	if len(forecasts) != 1 || forecasts[0].ID != 2 {
+		t.Errorf("Expected only forecast 2 to be stale for bob, got %+v", forecasts)
+	}
+
+	var tags []models.Tag
+	s.mustDo("GET", "/tags", "", nil, http.StatusOK, &tags)
+	if len(tags) != 3 || tags[0].Name != "elections" || tags[1].Name != "personal" || tags[1].Forecasts != 1 {
+		t.Errorf("Expected the tags in use with their counts, got %+v", tags)
+	}
+}
+
+func TestVisibility(t *testing.T) {
+	s := newTestServer(t)
+	alice := s.register("alice")
+	bob := s.register("bob")
+	carol := s.register("carol")
+
+	for _, visibility := range []string{"public", "private", "unlisted"} {
+		s.mustDo("POST", "/api/forecasts/create", alice, map[string]any{"question": "Will it rain, " + visibility + "?", "category": "weather", "resolution_criteria": "r", "visibility": visibility}, http.StatusCreated, nil)
+	}
+	s.mustDo("POST", "/api/forecasts/create", alice, map[string]any{"question": "Will it rain, shared?", "category": "weather", "resolution_criteria": "r", "visibility": "shared", "shared_with": []int64{3}}, http.StatusCreated, nil)
+	s.mustDo("POST", "/api/forecasts/create", alice, map[string]any{"question": "q", "category": "weather", "resolution_criteria": "r", "visibility": "secret"}, http.StatusBadRequest, nil)
+	s.mustDo("POST", "/api/forecasts/create", alice, map[string]any{"question": "q", "category": "weather", "resolution_criteria": "r", "shared_with": []int64{3}}, http.StatusBadRequest, nil)
+
+	// lists show public forecasts, and the caller's own or shared with them
+	var forecasts []models.Forecast
+	for token, want := range map[string]int{"": 1, alice: 4, bob: 1, carol: 2} {
+		s.mustDo("GET", "/forecasts", token, nil, http.StatusOK, &forecasts)
+		if len(forecasts) != want {
+			t.Errorf("Expected %d forecasts to be listed, got %+v", want, forecasts)
+		}
+	}
+	var results []models.SearchResult
+	s.mustDo("GET", "/search?q=rain", carol, nil, http.StatusOK, &results)
+	if len(results) != 2 {
+		t.Errorf("Expected carol to find the public and shared forecasts, got %+v", results)
+	}
+
+	// hidden forecasts are not found, but unlisted ones are open to anyone
+	var forecast models.Forecast
+	s.mustDo("GET", "/forecasts/2", "", nil, http.StatusNotFound, nil)
+	s.mustDo("GET", "/forecasts/2", bob, nil, http.StatusNotFound, nil)
+	s.mustDo("GET", "/forecasts/2", alice, nil, http.StatusOK, &forecast)
+	if forecast.Visibility != models.VisibilityPrivate {
+		t.Errorf("Expected a private forecast, got %q", forecast.Visibility)
+	}
+	s.mustDo("GET", "/forecasts/3", "", nil, http.StatusOK, nil)
+	s.mustDo("GET", "/forecasts/4", bob, nil, http.StatusNotFound, nil)
+	s.mustDo("GET", "/forecasts/4", carol, nil, http.StatusOK, &forecast)
+	if !slices.Equal(forecast.SharedWith, []int64{3}) {
+		t.Errorf("Expected the forecast to be shared with carol, got %v", forecast.SharedWith)
+	}
+	s.mustDo("GET", "/forecasts/1", "not-a-token", nil, http.StatusUnauthorized, nil)
+
+	// only those who see a forecast can forecast on it
+	s.mustDo("POST", "/api/forecast-points", bob, map[string]any{"forecast_id": 2, "point_forecast": 0.5}, http.StatusNotFound, nil)
+	s.mustDo("POST", "/api/forecast-points", bob, map[string]any{"forecast_id": 3, "point_forecast": 0.5}, http.StatusCreated, nil)
+	s.mustDo("POST", "/api/forecast-points", carol, map[string]any{"forecast_id": 4, "point_forecast": 0.5}, http.StatusCreated, nil)
+	s.mustDo("POST", "/api/forecast-points", alice, map[string]any{"forecast_id": 2, "point_forecast": 0.9}, http.StatusCreated, nil)
+
+	var points []models.ForecastPoint
+	for _, c := range []struct {
+		path  string
+		token string
+		want  int
+	}{
+		{"/forecast-points?forecast_id=4", "", 0},
+		{"/forecast-points?forecast_id=4", carol, 1},
+		{"/forecast-points?forecast_id=3", "", 1},
+		{"/forecast-points?user_id=2", "", 0},
+		{"/forecast-points?user_id=2", bob, 0},
+		{"/forecast-points?user_id=1", alice, 1},
+	} {
+		s.mustDo("GET", c.path, c.token, nil, http.StatusOK, &points)
+		if len(points) != c.want {
+			t.Errorf("Expected %d points from %s, got %+v", c.want, c.path, points)
+		}
+	}
+
+	// scores and calibration of private forecasts are their owner's alone
+	s.mustDo("PUT", "/api/resolve", alice, map[string]any{"id": 2, "resolution": "yes", "comment": "c"}, http.StatusOK, nil)
+	var scores []models.Scores
+	s.mustDo("GET", "/scores?user_id=1", "", nil, http.StatusOK, &scores)
+	if len(scores) != 0 {
+		t.Errorf("Expected no public scores, got %+v", scores)
+	}
+	s.mustDo("GET", "/scores?user_id=1", alice, nil, http.StatusOK, &scores)
+	if len(scores) != 1 {
+		t.Errorf("Expected alice to see her score, got %+v", scores)
+	}
+	var calibration models.CalibrationData
+	s.mustDo("GET", "/calibration?user_id=1", bob, nil, http.StatusOK, &calibration)
+	if calibration.TotalForecasts != 0 {
+		t.Errorf("Expected no calibration for bob to see, got %+v", calibration)
+	}
+	s.mustDo("GET", "/calibration?user_id=1", alice, nil, http.StatusOK, &calibration)
+	if calibration.TotalForecasts != 1 {
+		t.Errorf("Expected alice to see her calibration, got %+v", calibration)
+	}
+	s.mustDo("GET", "/resolution-audits?forecast_id=2", bob, nil, http.StatusNotFound, nil)
+
+	// owners change who sees their forecasts
+	if status := s.do("PUT", "/api/forecasts/visibility", bob, map[string]any{"forecast_id": 2, "visibility": "public"}, nil); status == http.StatusOK {
+		t.Errorf("Expected only the author to change the visibility of their forecast")
+	}
+	s.mustDo("PUT", "/api/forecasts/visibility", alice, map[string]any{"forecast_id": 2, "visibility": "public", "shared_with": []int64{2}}, http.StatusBadRequest, nil)
+	s.mustDo("PUT", "/api/forecasts/visibility", alice, map[string]any{"forecast_id": 2, "visibility": "shared", "shared_with": []int64{2}}, http.StatusOK, nil)
+	s.mustDo("GET", "/forecasts/2", bob, nil, http.StatusOK, nil)
+	s.mustDo("GET", "/scores?user_id=1", bob, nil, http.StatusOK, &scores)
+	if len(scores) != 1 {
+		t.Errorf("Expected bob to see the scores of the shared forecast, got %+v", scores)
+	}
+	s.mustDo("PUT", "/api/forecasts/visibility", alice, map[string]any{"forecast_id": 4, "visibility": "private"}, http.StatusOK, nil)
+	s.mustDo("GET", "/forecasts/4", carol, nil, http.StatusNotFound, nil)
+}
+
+func TestForecastEdits(t *testing.T) {
+	s := newTestServer(t)
+	alice := s.register("alice")
+	bob := s.register("bob")
+	closing := time.Now().AddDate(0, 1, 0).UTC().Truncate(time.Second)
+	s.mustDo("POST", "/api/forecasts/create", alice, map[string]any{"question": "Will it rain?", "category": "weather", "resolution_criteria": "Any rain", "closing_date": closing}, http.StatusCreated, nil)
+	s.mustDo("POST", "/api/forecast-points", bob, map[string]any{"forecast_id": 1, "point_forecast": 0.4}, http.StatusCreated, nil)
+
+	var revision models.ForecastRevision
+	s.mustDo("PUT", "/api/forecasts/edit", alice, map[string]any{"forecast_id": 1, "resolution_criteria": "Any rain at the airport"}, http.StatusOK, &revision)
+	if revision.Version != 2 || len(revision.Changes) != 1 || revision.Changes[0].Old != "Any rain" {
+		t.Errorf("Expected version 2 changing the criteria, got %+v", revision)
+	}
+	extended := closing.AddDate(0, 1, 0)
+	s.mustDo("PUT", "/api/forecasts/edit", alice, map[string]any{"forecast_id": 1, "closing_date": extended}, http.StatusOK, &revision)
+	if revision.Version != 3 {
+		t.Errorf("Expected version 3, got %+v", revision)
+	}
+
+	var forecast models.Forecast
+	s.mustDo("GET", "/forecasts/1", "", nil, http.StatusOK, &forecast)
+	if forecast.ResolutionCriteria != "Any rain at the airport" || !forecast.ClosingDate.Equal(extended) {
+		t.Errorf("Expected the edited forecast, got %+v", forecast)
+	}
+
+	if status := s.do("PUT", "/api/forecasts/edit", bob, map[string]any{"forecast_id": 1, "question": "Will it snow?"}, nil); status == http.StatusOK {
+		t.Errorf("Expected only the author to edit their forecast")
+	}
+	s.mustDo("PUT", "/api/forecasts/edit", alice, map[string]any{"forecast_id": 1, "closing_date": closing}, http.StatusBadRequest, nil)
+	s.mustDo("PUT", "/api/forecasts/edit", alice, map[string]any{"forecast_id": 1, "question": "Will it rain?"}, http.StatusBadRequest, nil)
+
+	// bob sees the edits made since his forecast
+	var history []models.ForecastRevision
+	s.mustDo("GET", "/forecasts/1/history", bob, nil, http.StatusOK, &history)
+	if len(history) != 2 || history[0].Version != 2 || !history[0].AfterLastPoint || !history[1].AfterLastPoint {
+		t.Fatalf("Expected both edits after bob's point, got %+v", history)
+	}
+	s.mustDo("POST", "/api/forecast-points", bob, map[string]any{"forecast_id": 1, "point_forecast": 0.5}, http.StatusCreated, nil)
+	s.mustDo("PUT", "/api/forecasts/edit", alice, map[string]any{"forecast_id": 1, "question": "Will it rain at the airport?"}, http.StatusOK, nil)
+	// decoding into the old slice would keep marks the response omits
+	history = nil
+	s.mustDo("GET", "/forecasts/1/history", bob, nil, http.StatusOK, &history)
+	if len(history) != 3 || history[1].AfterLastPoint || !history[2].AfterLastPoint {
+		t.Errorf("Expected only the last edit after bob's last point, got %+v", history)
+	}
+	history = nil
+	s.mustDo("GET", "/forecasts/1/history", "", nil, http.StatusOK, &history)
+	if len(history) != 3 || history[2].AfterLastPoint {
+		t.Errorf("Expected the history without marks for anonymous callers, got %+v", history)
+	}
+
+	s.mustDo("PUT", "/api/resolve", alice, map[string]any{"id": 1, "resolution": "yes", "comment": "c"}, http.StatusOK, nil)
+	s.mustDo("PUT", "/api/forecasts/edit", alice, map[string]any{"forecast_id": 1, "question": "Will it hail?"}, http.StatusConflict, nil)
+
+	s.mustDo("PUT", "/api/forecasts/visibility", alice, map[string]any{"forecast_id": 1, "visibility": "private"}, http.StatusOK, nil)
+	s.mustDo("GET", "/forecasts/1/history", bob, nil, http.StatusNotFound, nil)
+}
+
+func TestComments(t *testing.T) {
+	s := newTestServer(t)
+	alice := s.register("alice")
+	bob := s.register("bob")
+	carol := s.register("carol")
+	s.mustDo("POST", "/api/forecasts/create", alice, map[string]any{"question": "Will it rain?", "category": "weather", "resolution_criteria": "r"}, http.StatusCreated, nil)
+	s.mustDo("POST", "/api/forecasts/create", alice, map[string]any{"question": "Private", "category": "weather", "resolution_criteria": "r", "visibility": "private"}, http.StatusCreated, nil)
+
+	var first, reply models.Comment
+	s.mustDo("POST", "/api/comments", alice, map[string]any{"forecast_id": 1, "body": "What do you think, @bob and @nobody?"}, http.StatusCreated, &first)
+	if first.UserName == nil || *first.UserName != "alice" || !slices.Equal(first.Mentions, []int64{2}) {
+		t.Errorf("Expected alice's comment mentioning bob, got %+v", first)
+	}
+	s.mustDo("POST", "/api/comments", bob, map[string]any{"forecast_id": 1, "parent_id": first.ID, "body": "**Likely**, see the radar"}, http.StatusCreated, &reply)
+	s.mustDo("POST", "/api/comments", carol, map[string]any{"forecast_id": 1, "body": "Agreed"}, http.StatusCreated, nil)
+
+	s.mustDo("POST", "/api/comments", bob, map[string]any{"forecast_id": 1, "body": "  "}, http.StatusBadRequest, nil)
+	s.mustDo("POST", "/api/comments", bob, map[string]any{"forecast_id": 1, "parent_id": 99, "body": "b"}, http.StatusBadRequest, nil)
+	s.mustDo("POST", "/api/comments", bob, map[string]any{"forecast_id": 2, "body": "b"}, http.StatusNotFound, nil)
+	s.mustDo("POST", "/api/comments", "", map[string]any{"forecast_id": 1, "body": "b"}, http.StatusUnauthorized, nil)
+
+	var comments []models.Comment
+	s.mustDo("GET", "/forecasts/1/comments", "", nil, http.StatusOK, &comments)
+	if len(comments) != 3 || comments[0].ID != first.ID || comments[1].ParentID == nil || *comments[1].ParentID != first.ID {
+		t.Fatalf("Expected the thread oldest first, got %+v", comments)
+	}
+	var page models.Paged[models.Comment]
+	s.mustDo("GET", "/forecasts/1/comments?limit=2", "", nil, http.StatusOK, &page)
+	if len(page.Items) != 2 || page.Items[0].Body != "Agreed" || page.NextCursor == nil {
+		t.Fatalf("Expected the newest two comments and a cursor, got %+v", page)
+	}
+	s.mustDo("GET", "/forecasts/1/comments?cursor="+*page.NextCursor, "", nil, http.StatusOK, &page)
+	if len(page.Items) != 1 || page.Items[0].ID != first.ID || page.NextCursor != nil {
+		t.Errorf("Expected the first comment last, got %+v", page)
+	}
+	s.mustDo("GET", "/forecasts/2/comments", bob, nil, http.StatusNotFound, nil)
+	s.mustDo("GET", "/forecasts/2/comments", alice, nil, http.StatusOK, nil)
+	s.mustDo("GET", "/forecasts/1/other", "", nil, http.StatusNotFound, nil)
+
+	// only authors edit and delete their comments
+	var edited models.Comment
+	s.mustDo("PUT", "/api/comments", alice, map[string]any{"id": reply.ID, "body": "mine now"}, http.StatusForbidden, nil)
+	s.mustDo("PUT", "/api/comments", bob, map[string]any{"id": reply.ID, "body": "Likely, cc @carol"}, http.StatusOK, &edited)
+	if edited.EditedAt == nil || edited.Body != "Likely, cc @carol" || !slices.Equal(edited.Mentions, []int64{3}) {
+		t.Errorf("Expected the edited reply mentioning carol, got %+v", edited)
+	}
+	s.mustDo("DELETE", fmt.Sprintf("/api/comments?id=%d", first.ID), bob, nil, http.StatusForbidden, nil)
+	s.mustDo("DELETE", fmt.Sprintf("/api/comments?id=%d", first.ID), alice, nil, http.StatusOK, nil)
+	s.mustDo("DELETE", fmt.Sprintf("/api/comments?id=%d", first.ID), alice, nil, http.StatusConflict, nil)
+	s.mustDo("PUT", "/api/comments", alice, map[string]any{"id": first.ID, "body": "back"}, http.StatusConflict, nil)
+	s.mustDo("POST", "/api/comments", carol, map[string]any{"forecast_id": 1, "parent_id": first.ID, "body": "c"}, http.StatusConflict, nil)
+
+	// a deleted comment keeps its place in the thread
+	comments = nil
+	s.mustDo("GET", "/forecasts/1/comments", "", nil, http.StatusOK, &comments)
+	if len(comments) != 3 || comments[0].DeletedAt == nil || comments[0].Body != "" || comments[0].Mentions != nil {
+		t.Errorf("Expected the deleted comment without its body, got %+v", comments[0])
+	}
+}
+
+func TestAggregates(t *testing.T) {
+	s := newTestServer(t)
+	alice := s.register("alice")
+	bob := s.register("bob")
+	carol := s.register("carol")
+	for _, question := range []string{"Will it rain?", "Will it snow?"} {
+		s.mustDo("POST", "/api/forecasts/create", alice, map[string]any{"question": question, "category": "weather", "resolution_criteria": "r"}, http.StatusCreated, nil)
+	}
+
+	// bob gets the second forecast right and carol gets it wrong
+	s.mustDo("POST", "/api/forecast-points", bob, map[string]any{"forecast_id": 2, "point_forecast": 0.9}, http.StatusCreated, nil)
+	s.mustDo("POST", "/api/forecast-points", carol, map[string]any{"forecast_id": 2, "point_forecast": 0.1}, http.StatusCreated, nil)
+	s.mustDo("PUT", "/api/resolve", alice, map[string]any{"id": 2, "resolution": "yes"}, http.StatusOK, nil)
+
+	var aggregate models.Aggregate
+	s.mustDo("GET", "/forecasts/1/aggregate", "", nil, http.StatusOK, &aggregate)
+	if aggregate.Forecasters != 0 || aggregate.PointForecast != nil || aggregate.Method != models.DefaultAggregateMethod {
+		t.Errorf("Expected an empty aggregate, got %+v", aggregate)
+	}
+
+	s.mustDo("POST", "/api/forecast-points", bob, map[string]any{"forecast_id": 1, "point_forecast": 0.2}, http.StatusCreated, nil)
+	s.mustDo("POST", "/api/forecast-points", carol, map[string]any{"forecast_id": 1, "point_forecast": 0.8}, http.StatusCreated, nil)
+	s.mustDo("POST", "/api/forecast-points", bob, map[string]any{"forecast_id": 1, "point_forecast": 0.4}, http.StatusCreated, nil)
+
+	odds := math.Sqrt(0.4 / 0.6 * 0.8 / 0.2)
+	extremized := math.Pow(odds, 2)
+	wBob, wCarol := 1/(0.01+0.05), 1/(0.81+0.05)
+	for query, want := range map[string]float64{
+		"":                               odds / (1 + odds),
+		"?method=mean":                   0.6,
+		"?method=median":                 0.6,
+		"?method=extremized&extremize=2": extremized / (1 + extremized),
+		"?method=mean&weighted=true":     (0.4*wBob + 0.8*wCarol) / (wBob + wCarol),
+	} {
+		aggregate = models.Aggregate{}
+		s.mustDo("GET", "/forecasts/1/aggregate"+query, "", nil, http.StatusOK, &aggregate)
+		if aggregate.Forecasters != 2 || aggregate.PointForecast == nil || math.Abs(*aggregate.PointForecast-want) > 1e-9 {
+			t.Errorf("%s: expected %v from 2 forecasters, got %+v", query, want, aggregate)
+		}
+	}
+	s.mustDo("GET", "/forecasts/1/aggregate?method=mode", "", nil, http.StatusBadRequest, nil)
+	s.mustDo("GET", "/forecasts/1/aggregate?weighted=no", "", nil, http.StatusBadRequest, nil)
+	s.mustDo("GET", "/forecasts/1/aggregate?method=mean&extremize=2", "", nil, http.StatusBadRequest, nil)
+
+	var series models.AggregateSeries
+	s.mustDo("GET", "/forecasts/1/aggregate/series?method=mean", "", nil, http.StatusOK, &series)
+	if len(series.Points) != 3 || *series.Points[0].PointForecast != 0.2 || *series.Points[1].PointForecast != 0.5 || math.Abs(*series.Points[2].PointForecast-0.6) > 1e-9 {
+		t.Errorf("Expected the mean after each point, got %+v", series)
+	}
+
+	// a new point refreshes the cached aggregate
+	s.mustDo("POST", "/api/forecast-points", alice, map[string]any{"forecast_id": 1, "point_forecast": 0.9}, http.StatusCreated, nil)
+	s.mustDo("GET", "/forecasts/1/aggregate?method=median", "", nil, http.StatusOK, &aggregate)
+	if aggregate.Forecasters != 3 || *aggregate.PointForecast != 0.8 {
+		t.Errorf("Expected the median of 3 forecasters, got %+v", aggregate)
+	}
+
+	// the crowd is scored on the default aggregate, and its name is reserved
+	s.mustDo("PUT", "/api/resolve", alice, map[string]any{"id": 1, "resolution": "no"}, http.StatusOK, nil)
+	var users []models.User
+	s.mustDo("GET", "/users", "", nil, http.StatusOK, &users)
+	if len(users) != 4 || users[3].Username != models.CrowdUsername || users[3].Role != models.RoleBot {
+		t.Fatalf("Expected the crowd user, got %+v", users)
+	}
+	var scores []models.Scores
+	s.mustDo("GET", "/scores?user_id=4", "", nil, http.StatusOK, &scores)
+	if len(scores) != 2 {
+		t.Errorf("Expected the crowd scored on both forecasts, got %+v", scores)
+	}
+	// the crowd ranks with the users it pools but is not averaged with them
+	var overall models.OverallScores
+	s.mustDo("GET", "/scores/aggregate", "", nil, http.StatusOK, &overall)
+	if overall.TotalUsers != 3 || overall.TotalForecasts != 2 {
+		t.Errorf("Expected the overall scores of alice, bob and carol, got %+v", overall)
+	}
+	var leaderboard []models.UserScores
+	s.mustDo("GET", "/scores/aggregate/users", "", nil, http.StatusOK, &leaderboard)
+	if len(leaderboard) != 4 {
+		t.Errorf("Expected the crowd on the leaderboard, got %+v", leaderboard)
+	}
+	s.mustDo("POST", "/users", "", map[string]string{"username": models.CrowdUsername, "password": "password123"}, http.StatusBadRequest, nil)
+	s.mustDo("POST", "/users/login", "", map[string]string{"username": models.CrowdUsername, "password": "password123"}, http.StatusUnauthorized, nil)
+
+	s.mustDo("POST", "/api/forecasts/create", alice, map[string]any{"question": "Which?", "category": "weather", "resolution_criteria": "r", "question_type": "multiple_choice", "options": []string{"a", "b", "c"}}, http.StatusCreated, nil)
+	s.mustDo("POST", "/api/forecast-points", bob, map[string]any{"forecast_id": 3, "probabilities": []float64{0.6, 0.3, 0.1}}, http.StatusCreated, nil)
+	s.mustDo("POST", "/api/forecast-points", carol, map[string]any{"forecast_id": 3, "probabilities": []float64{0.2, 0.2, 0.6}}, http.StatusCreated, nil)
+	aggregate = models.Aggregate{}
+	s.mustDo("GET", "/forecasts/3/aggregate", "", nil, http.StatusOK, &aggregate)
+	if err := models.ValidateProbabilities(aggregate.Probabilities, 3); err != nil || aggregate.PointForecast != nil {
+		t.Errorf("Expected pooled probabilities for each option, got %+v", aggregate)
+	}
+
+	s.mustDo("POST", "/api/forecasts/create", alice, map[string]any{"question": "How much?", "category": "weather", "resolution_criteria": "r", "question_type": "numeric", "range_min": 0, "range_max": 10}, http.StatusCreated, nil)
+	s.mustDo("GET", "/forecasts/4/aggregate", "", nil, http.StatusBadRequest, nil)
+
+	s.mustDo("PUT", "/api/forecasts/visibility", alice, map[string]any{"forecast_id": 3, "visibility": "private"}, http.StatusOK, nil)
+	s.mustDo("GET", "/forecasts/3/aggregate", bob, nil, http.StatusNotFound, nil)
+	s.mustDo("GET", "/forecasts/3/aggregate/series", "", nil, http.StatusNotFound, nil)
+	s.mustDo("GET", "/forecasts/3/aggregate", alice, nil, http.StatusOK, nil)
+}
+
+func TestCalibrationMetrics(t *testing.T) {
+	s := newTestServer(t)
+	alice := s.register("alice")
+	bob := s.register("bob")
+	for i, p := range []float64{0.25, 0.75, 0.75} {
+		s.mustDo("POST", "/api/forecasts/create", alice, map[string]any{"question": "q", "category": "c", "resolution_criteria": "r"}, http.StatusCreated, nil)
+		s.mustDo("POST", "/api/forecast-points", bob, map[string]any{"forecast_id": i + 1, "point_forecast": p}, http.StatusCreated, nil)
+	}
+	s.mustDo("PUT", "/api/resolve", alice, map[string]any{"id": 1, "resolution": "no"}, http.StatusOK, nil)
+	s.mustDo("PUT", "/api/resolve", alice, map[string]any{"id": 2, "resolution": "yes"}, http.StatusOK, nil)
+	s.mustDo("PUT", "/api/resolve", alice, map[string]any{"id": 3, "resolution": "no"}, http.StatusOK, nil)
+
+	var metrics models.CalibrationMetrics
+	s.mustDo("GET", "/calibration/metrics?user_id=2", "", nil, http.StatusOK, &metrics)
+	if metrics.TotalPredictions != 3 || math.Abs(metrics.BrierScore-(0.0625+0.0625+0.5625)/3) > 1e-9 {
+		t.Fatalf("Expected bob's 3 predictions, got %+v", metrics)
+	}
+	// the 0.75 bucket came true half the time
+	if math.Abs(metrics.ExpectedCalibrationError-(0.25/3+2*0.25/3)) > 1e-9 || math.Abs(metrics.Uncertainty-2.0/9) > 1e-9 {
+		t.Errorf("Expected the calibration error of the 0.75 bucket, got %+v", metrics)
+	}
+
+	// hidden forecasts leave the metrics
+	s.mustDo("PUT", "/api/forecasts/visibility", alice, map[string]any{"forecast_id": 3, "visibility": "private"}, http.StatusOK, nil)
+	metrics = models.CalibrationMetrics{}
+	s.mustDo("GET", "/calibration/metrics?user_id=2", "", nil, http.StatusOK, &metrics)
+	if metrics.TotalPredictions != 2 || math.Abs(metrics.BrierScore-0.0625) > 1e-9 {
+		t.Errorf("Expected the private forecast left out, got %+v", metrics)
+	}
+	s.mustDo("GET", "/calibration/metrics?start_date=yesterday", "", nil, http.StatusBadRequest, nil)
+}
+
+func TestCalibrationOptions(t *testing.T) {
+	s := newTestServer(t)
+	alice := s.register("alice")
+	bob := s.register("bob")
+	s.mustDo("POST", "/api/forecasts/create", alice, map[string]any{"question": "q", "category": "c", "resolution_criteria": "r"}, http.StatusCreated, nil)
+	for _, p := range []float64{0.3, 0.7, 0.9} {
+		s.mustDo("POST", "/api/forecast-points", bob, map[string]any{"forecast_id": 1, "point_forecast": p}, http.StatusCreated, nil)
+	}
+	s.mustDo("PUT", "/api/resolve", alice, map[string]any{"id": 1, "resolution": "yes"}, http.StatusOK, nil)
+
+	var data models.CalibrationData
+	s.mustDo("GET", "/calibration?bins=5", "", nil, http.StatusOK, &data)
+	if len(data.Buckets) != 3 || math.Abs(data.Buckets[2].BucketStart-0.8) > 1e-9 || math.Abs(data.Buckets[2].BucketEnd-1) > 1e-9 {
+		t.Fatalf("Expected three buckets of 0.2, got %+v", data.Buckets)
+	}
+	if b := data.Buckets[0]; b.ActualRateLow == nil || *b.ActualRateHigh != 1 {
+		t.Errorf("Expected a Wilson interval, got %+v", b)
+	}
+
+	data = models.CalibrationData{}
+	s.mustDo("GET", "/calibration?points=last&interval=bootstrap", "", nil, http.StatusOK, &data)
+	if data.TotalPredictions != 1 || len(data.Buckets) != 1 || data.Buckets[0].ActualRateLow == nil {
+		t.Errorf("Expected bob's last point with a bootstrap interval, got %+v", data)
+	}
+
+	var byUsers []models.UserCalibrationData
+	s.mustDo("GET", "/calibration/users?edges=0,0.5,1&interval=none", "", nil, http.StatusOK, &byUsers)
+	if len(byUsers) != 1 || len(byUsers[0].Buckets) != 2 || byUsers[0].Buckets[1].PredictionCount != 2 || byUsers[0].Buckets[1].ActualRateLow != nil {
+		t.Errorf("Expected bob's points in two custom buckets, got %+v", byUsers)
+	}
+
+	for _, query := range []string{"bins=0.5", "bins=101", "edges=0,x,1", "binning=quantile&edges=0,1", "points=first", "confidence=1"} {
+		s.mustDo("GET", "/calibration/metrics?"+query, "", nil, http.StatusBadRequest, nil)
+	}
+}
+
+func TestRelativeScores(t *testing.T) {
+	s := newTestServer(t)
+	alice := s.register("alice")
+	bob := s.register("bob")
+	carol := s.register("carol")
+	s.mustDo("POST", "/api/forecasts/create", alice, map[string]any{"question": "q", "category": "c", "resolution_criteria": "r"}, http.StatusCreated, nil)
+	s.mustDo("POST", "/api/forecast-points", bob, map[string]any{"forecast_id": 1, "point_forecast": 0.9}, http.StatusCreated, nil)
+	s.mustDo("POST", "/api/forecast-points", carol, map[string]any{"forecast_id": 1, "point_forecast": 0.1}, http.StatusCreated, nil)
+	s.mustDo("PUT", "/api/resolve", alice, map[string]any{"id": 1, "resolution": "yes"}, http.StatusOK, nil)
+
+	// the points are made at once, so the latest are compared; the crowd
+	// pools them into 0.5, after holding bob's 0.9
+	gap := math.Log(0.9) - math.Log(0.1)
+	want := map[int64][2]float64{
+		2: {gap, math.Log(0.9) - math.Log(0.5)},
+		3: {-gap, math.Log(0.1) - math.Log(0.5)},
+		4: {math.Log(0.5) - (math.Log(0.9)+math.Log(0.1))/2, (math.Log(0.9) - math.Log(0.5)) / 2},
+	}
+	var leaderboard []models.UserScores
+	s.mustDo("GET", "/scores/aggregate/users", "", nil, http.StatusOK, &leaderboard)
+	if len(leaderboard) != len(want) {
+		t.Fatalf("Expected bob, carol and the crowd on the leaderboard, got %+v", leaderboard)
+	}
+	for _, u := range leaderboard {
+		w := want[u.UserID]
+		if u.PeerScore == nil || u.BaselineScore == nil || math.Abs(*u.PeerScore-w[0]) > 1e-9 || math.Abs(*u.BaselineScore-w[1]) > 1e-9 {
+			t.Errorf("Expected user %d's peer and baseline scores %v, got %v and %v", u.UserID, w, u.PeerScore, u.BaselineScore)
+		}
+	}
+
+	var scores []models.Scores
+	s.mustDo("GET", "/scores?user_id=2", "", nil, http.StatusOK, &scores)
+	if len(scores) != 1 || scores[0].PeerScore == nil || math.Abs(*scores[0].PeerScore-gap) > 1e-9 {
+		t.Errorf("Expected bob's score to carry his peer score, got %+v", scores)
+	}
+	if len(scores) == 1 && (scores[0].BrierScoreLifetime == nil || scores[0].LogNScoreLifetime == nil) {
+		t.Errorf("Expected bob's score to carry lifetime scores, got %+v", scores[0])
+	}
+}
+
+func TestScoringRuleAggregates(t *testing.T) {
+	s := newTestServer(t)
+	alice := s.register("alice")
+	bob := s.register("bob")
+	carol := s.register("carol")
+	s.mustDo("POST", "/api/forecasts/create", alice, map[string]any{"question": "q", "category": "c", "resolution_criteria": "r"}, http.StatusCreated, nil)
+	s.mustDo("POST", "/api/forecast-points", bob, map[string]any{"forecast_id": 1, "point_forecast": 0.9}, http.StatusCreated, nil)
+	s.mustDo("POST", "/api/forecast-points", carol, map[string]any{"forecast_id": 1, "point_forecast": 0.1}, http.StatusCreated, nil)
+	s.mustDo("PUT", "/api/resolve", alice, map[string]any{"id": 1, "resolution": "yes"}, http.StatusOK, nil)
+
+	var rules []models.ScoringRuleInfo
+	s.mustDo("GET", "/scores/rules", "", nil, http.StatusOK, &rules)
+	if len(rules) != 5 || rules[0].Name != "brier" || !rules[0].LowerIsBetter {
+		t.Errorf("Expected the five built-in rules, got %+v", rules)
+	}
+
+	// the crowd holds bob's 0.9, then the pooled 0.5
+	spherical := func(p float64) float64 { return p / math.Sqrt(p*p+(1-p)*(1-p)) }
+	want := map[int64]float64{
+		2: spherical(0.9),
+		3: spherical(0.1),
+		4: (spherical(0.9) + spherical(0.5)) / 2,
+	}
+	var leaderboard []models.UserScores
+	s.mustDo("GET", "/scores/aggregate/users?rule=spherical", "", nil, http.StatusOK, &leaderboard)
+	if len(leaderboard) != len(want) {
+		t.Fatalf("Expected bob, carol and the crowd on the leaderboard, got %+v", leaderboard)
+	}
+	for _, u := range leaderboard {
+		if u.Rule != "spherical" || u.RuleScore == nil || math.Abs(*u.RuleScore-want[u.UserID]) > 1e-9 {
+			t.Errorf("Expected user %d's spherical score %v, got %+v", u.UserID, want[u.UserID], u.ScoreMetrics)
+		}
+	}
+
+	var overall models.OverallScores
+	s.mustDo("GET", "/scores/aggregate?rule=spherical", "", nil, http.StatusOK, &overall)
+	if mean := (want[2] + want[3]) / 2; overall.RuleScore == nil || math.Abs(*overall.RuleScore-mean) > 1e-9 {
+		t.Errorf("Expected the average spherical score %v, got %+v", mean, overall.ScoreMetrics)
+	}
+	overall = models.OverallScores{}
+	s.mustDo("GET", "/scores/aggregate", "", nil, http.StatusOK, &overall)
+	if overall.RuleScore != nil {
+		t.Errorf("Expected no rule without rule=, got %+v", overall.ScoreMetrics)
+	}
+	s.mustDo("GET", "/scores/aggregate/users?rule=ranked_probability", "", nil, http.StatusBadRequest, nil)
+
+	var scores []models.Scores
+	s.mustDo("GET", "/scores?user_id=2", "", nil, http.StatusOK, &scores)
+	if len(scores) != 1 || len(scores[0].Rules) != 5 {
+		t.Errorf("Expected bob's score under every rule, got %+v", scores)
+	}
+}
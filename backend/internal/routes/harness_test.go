@@ -0,0 +1,178 @@
+package routes
+
+import (
+	"backend/internal/auth"
+	"backend/internal/cache"
+	"backend/internal/models"
+	"backend/internal/repository/memory"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// testServer wires the in-memory repositories through the real services,
+// handlers and routes, so requests exercise everything but the database
+type testServer struct {
+	*httptest.Server
+	t     *testing.T
+	store *memory.Store
+}
+
+// newTestServer starts a server on a fresh store. Overrides can swap
+// repositories, e.g. to inject failures.
+func newTestServer(t *testing.T, overrides ...func(*Repositories)) *testServer {
+	t.Helper()
+	return newTestNode(t, newTestStore(t), cache.NewCache(), overrides...)
+}
+
+// testCategories are the top-level categories tests name their forecasts by
+var testCategories = []string{"c", "politics", "weather"}
+
+// newTestStore returns a fresh store with the test categories, as forecasts
+// can only name categories an admin created
+func newTestStore(t *testing.T) *memory.Store {
+	t.Helper()
+
+	store := memory.NewStore()
+	categories := memory.NewCategoryRepository(store)
+	for _, name := range testCategories {
+		if err := categories.CreateCategory(context.Background(), &models.Category{Name: name}); err != nil {
+			t.Fatalf("Error creating category %q: %v", name, err)
+		}
+	}
+	return store
+}
+
+// newTestNode starts a server on the store and cache, so that several servers
+// can share them like instances of the backend share a database
+func newTestNode(t *testing.T, store *memory.Store, c cache.Cache, overrides ...func(*Repositories)) *testServer {
+	t.Helper()
+
+	if err := auth.Init([]byte("test-secret-that-is-at-least-32-bytes")); err != nil {
+		t.Fatalf("Error initializing auth: %v", err)
+	}
+
+	repositories := &Repositories{
+		Forecast:         memory.NewForecastRepository(store),
+		ForecastPoint:    memory.NewForecastPointRepository(store),
+		User:             memory.NewUserRepository(store),
+		Score:            memory.NewScoreRepository(store),
+		Calibration:      memory.NewCalibrationRepository(store),
+		Category:         memory.NewCategoryRepository(store),
+		Comment:          memory.NewCommentRepository(store),
+		ResolutionAudit:  memory.NewResolutionAuditRepository(store),
+		ForecastRevision: memory.NewForecastRevisionRepository(store),
+		APIKey:           memory.NewAPIKeyRepository(store),
+		Session:          memory.NewSessionRepository(store),
+		Transactor:       memory.NewTransactor(store),
+	}
+	for _, override := range overrides {
+		override(repositories)
+	}
+
+	mux := http.NewServeMux()
+	Setup(mux, NewHandlers(NewServices(repositories, c)))
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	return &testServer{Server: server, t: t, store: store}
+}
+
+// do sends a request with an optional JSON body and bearer token or API key, and decodes
+// the response into out when it is not nil. It returns the status code.
+func (s *testServer) do(method string, path string, token string, body any, out any) int {
+	s.t.Helper()
+
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			s.t.Fatalf("Error encoding request body: %v", err)
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequest(method, s.URL+path, reader)
+	if err != nil {
+		s.t.Fatalf("Error creating request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	switch {
+	case strings.HasPrefix(token, "fk_"):
+		req.Header.Set(auth.APIKeyHeader, token)
+	case token != "":
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := s.Client().Do(req)
+	if err != nil {
+		s.t.Fatalf("Error making request to %s: %v", path, err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		s.t.Fatalf("Error reading response from %s: %v", path, err)
+	}
+
+	if out != nil && resp.StatusCode < 300 {
+		if err := json.Unmarshal(respBody, out); err != nil {
+			s.t.Fatalf("Error decoding response from %s: %v (body: %s)", path, err, respBody)
+		}
+	}
+	if resp.StatusCode >= 300 {
+		s.t.Logf("%s %s returned %d: %s", method, path, resp.StatusCode, respBody)
+	}
+	return resp.StatusCode
+}
+
+// mustDo is do for requests that are expected to return the given status
+func (s *testServer) mustDo(method string, path string, token string, body any, want int, out any) {
+	s.t.Helper()
+
+	if status := s.do(method, path, token, body, out); status != want {
+		s.t.Fatalf("%s %s: expected status %d, got %d", method, path, want, status)
+	}
+}
+
+// register creates a user and logs them in, returning their token
+func (s *testServer) register(username string) string {
+	s.t.Helper()
+
+	credentials := map[string]string{"username": username, "password": "password123"}
+	s.mustDo("POST", "/users", "", credentials, http.StatusCreated, nil)
+	return s.login(username)
+}
+
+// registerAs creates a user with the given role and returns their token
+func (s *testServer) registerAs(username string, role models.Role) string {
+	s.t.Helper()
+
+	s.register(username)
+	user, err := memory.NewUserRepository(s.store).GetUserByUsername(context.Background(), username)
+	if err != nil {
+		s.t.Fatalf("Error getting user %s: %v", username, err)
+	}
+	if err := memory.NewUserRepository(s.store).UpdateRole(context.Background(), user.ID, role); err != nil {
+		s.t.Fatalf("Error setting role of %s: %v", username, err)
+	}
+	return s.login(username)
+}
+
+// login logs a registered user in, returning their token
+func (s *testServer) login(username string) string {
+	s.t.Helper()
+
+	credentials := map[string]string{"username": username, "password": "password123"}
+	var login struct {
+		Token string `json:"token"`
+	}
+	s.mustDo("POST", "/users/login", "", credentials, http.StatusOK, &login)
+	return login.Token
+}
@@ -509,7 +509,7 @@ func TestProtectedEndpointsIntegration(t *testing.T) {
 			endpoint: "/api/forecasts/create",
 			body: `{
 				"question": "Will this test pass?",
-				"category": "testing",
+				"category": "politics",
 				"resolution_criteria": "the test will pass"
 			}`,
 			expectedStatus: http.StatusCreated,
@@ -2,7 +2,10 @@ package routes
 
 import (
 	"backend/internal/auth"
+	"backend/internal/cache"
+	"backend/internal/events"
 	"backend/internal/handlers"
+	"backend/internal/models"
 	"backend/internal/repository"
 	"backend/internal/services"
 	"net/http"
@@ -14,6 +17,14 @@ type Handlers struct {
 	User          *handlers.UserHandler
 	Score         *handlers.ScoreHandler
 	Calibration   *handlers.CalibrationHandler
+	Category      *handlers.CategoryHandler
+	Comment       *handlers.CommentHandler
+	APIKey        *handlers.APIKeyHandler
+	Cache         *handlers.CacheHandler
+	// Keys authenticates requests made with an API key
+	Keys auth.KeyAuthenticator
+	// Sessions revokes tokens after a logout or password change
+	Sessions auth.Sessions
 }
 
 type Services struct {
@@ -22,14 +33,66 @@ type Services struct {
 	User          *services.UserService
 	Score         *services.ScoreService
 	Calibration   *services.CalibrationService
+	Category      *services.CategoryService
+	Comment       *services.CommentService
+	APIKey        *services.APIKeyService
+	Session       *services.SessionService
+	// Cache is shared by the services
+	Cache cache.Cache
+	// Events carries what the services change, and invalidates the cache
+	Events *events.Bus
 }
 
 type Repositories struct {
-	Forecast      repository.ForecastRepository
-	ForecastPoint repository.ForecastPointRepository
-	User          repository.UserRepository
-	Score         repository.ScoreRepository
-	Calibration   repository.CalibrationRepository
+	Forecast         repository.ForecastRepository
+	ForecastPoint    repository.ForecastPointRepository
+	User             repository.UserRepository
+	Score            repository.ScoreRepository
+	Calibration      repository.CalibrationRepository
+	Category         repository.CategoryRepository
+	Comment          repository.CommentRepository
+	ResolutionAudit  repository.ResolutionAuditRepository
+	ForecastRevision repository.ForecastRevisionRepository
+	APIKey           repository.APIKeyRepository
+	Session          repository.SessionRepository
+	Transactor       repository.Transactor
+}
+
+// NewServices wires the services on top of the repositories
+func NewServices(repositories *Repositories, cache cache.Cache) *Services {
+	bus := events.NewBus()
+	bus.Subscribe(services.CacheInvalidator(cache))
+
+	return &Services{
+		Forecast:      services.NewForecastService(repositories.Forecast, repositories.ForecastPoint, repositories.Score, repositories.ResolutionAudit, repositories.ForecastRevision, repositories.Category, repositories.User, repositories.Transactor, cache, bus),
+		ForecastPoint: services.NewForecastPointService(repositories.ForecastPoint, repositories.Forecast, repositories.Transactor, cache, bus),
+		User:          services.NewUserService(repositories.User, cache, bus),
+		Score:         services.NewScoreService(repositories.Score, cache, bus),
+		Calibration:   services.NewCalibrationService(repositories.Calibration, cache),
+		Category:      services.NewCategoryService(repositories.Category, bus),
+		Comment:       services.NewCommentService(repositories.Comment, repositories.Forecast, repositories.Transactor),
+		APIKey:        services.NewAPIKeyService(repositories.APIKey, repositories.User),
+		Session:       services.NewSessionService(repositories.Session, repositories.User, repositories.Transactor),
+		Cache:         cache,
+		Events:        bus,
+	}
+}
+
+// NewHandlers wires the HTTP handlers on top of the services
+func NewHandlers(services *Services) *Handlers {
+	return &Handlers{
+		Forecast:      handlers.NewForecastHandler(services.Forecast, services.Category),
+		ForecastPoint: handlers.NewForecastPointHandler(services.ForecastPoint),
+		User:          handlers.NewUserHandler(services.User, services.Session),
+		Score:         handlers.NewScoreHandler(services.Score, services.Category),
+		Calibration:   handlers.NewCalibrationHandler(services.Calibration, services.Category),
+		Category:      handlers.NewCategoryHandler(services.Category),
+		Comment:       handlers.NewCommentHandler(services.Comment),
+		APIKey:        handlers.NewAPIKeyHandler(services.APIKey),
+		Cache:         handlers.NewCacheHandler(services.Cache),
+		Keys:          services.APIKey,
+		Sessions:      services.Session,
+	}
 }
 
 func Setup(mux *http.ServeMux, handlers *Handlers) {
@@ -40,53 +103,129 @@ func Setup(mux *http.ServeMux, handlers *Handlers) {
 	protected := http.NewServeMux()
 	setupProtectedRoutes(protected, handlers)
 
+	authenticated := auth.AuthMiddleware(handlers.Keys, handlers.Sessions)
 	apiHandler := http.StripPrefix("/api", protected)
-	mux.Handle("/api/", auth.AuthMiddleware(apiHandler))
+	mux.Handle("/api/", authenticated(apiHandler))
+
+	// logging out needs the token being revoked, so it is authenticated
+	// though it lives next to login
+	mux.Handle("POST /users/logout", authenticated(auth.RequireToken(handlers.User.Logout)))
 }
 
 func setupPublicRoutes(mux *http.ServeMux, handlers *Handlers) {
+	// forecasts, their points, scores and calibration are filtered by who is
+	// asking, so these routes read the caller's credentials when there are any
+	optional := auth.OptionalAuth(handlers.Keys, handlers.Sessions)
+	viewer := func(h http.HandlerFunc) http.Handler { return optional(h) }
+
 	// forecasts
-	mux.HandleFunc("GET /forecasts", handlers.Forecast.ListForecasts)
-	mux.HandleFunc("GET /forecasts/{id}", handlers.Forecast.GetForecast)
-	mux.HandleFunc("GET /forecasts/llm/{user_id}", handlers.Forecast.GetStaleAndNewForecasts)
+	mux.Handle("GET /forecasts", viewer(handlers.Forecast.ListForecasts))
+	mux.Handle("GET /forecasts/{id}", viewer(handlers.Forecast.GetForecast))
+	mux.Handle("GET /forecasts/{id}/{resource}", viewer(forecastResources(map[string]http.HandlerFunc{
+		"history":   handlers.Forecast.GetForecastHistory,
+		"comments":  handlers.Comment.ListComments,
+		"aggregate": handlers.Forecast.GetAggregate,
+	})))
+	mux.Handle("GET /forecasts/{id}/aggregate/series", viewer(handlers.Forecast.GetAggregateSeries))
+	mux.Handle("GET /forecasts/llm/{user_id}", viewer(handlers.Forecast.GetStaleAndNewForecasts))
+	mux.Handle("GET /resolution-audits", viewer(handlers.Forecast.GetResolutionAudits))
+	mux.Handle("GET /search", viewer(handlers.Forecast.Search))
+
+	// categories and tags
+	mux.HandleFunc("GET /categories", handlers.Category.ListCategories)
+	mux.HandleFunc("GET /tags", handlers.Category.ListTags)
 
 	// forecast points
-	mux.HandleFunc("GET /forecast-points", handlers.ForecastPoint.ListForecastPoints)
+	mux.Handle("GET /forecast-points", viewer(handlers.ForecastPoint.ListForecastPoints))
 
 	// scores (single-score)
-	mux.HandleFunc("GET /scores", handlers.Score.GetScores)
-	mux.HandleFunc("GET /scores/average", handlers.Score.GetAverageScores)
+	mux.Handle("GET /scores", viewer(handlers.Score.GetScores))
+	mux.Handle("GET /scores/average", viewer(handlers.Score.GetAverageScores))
 
 	// scores (aggregate)
-	mux.HandleFunc("GET /scores/aggregate", handlers.Score.GetAggregateScores)
-	mux.HandleFunc("GET /scores/aggregate/users", handlers.Score.GetAggregateScoresGroupedByUsers)
+	mux.Handle("GET /scores/aggregate", viewer(handlers.Score.GetAggregateScores))
+	mux.Handle("GET /scores/aggregate/users", viewer(handlers.Score.GetAggregateScoresGroupedByUsers))
+	mux.HandleFunc("GET /scores/rules", handlers.Score.ListScoringRules)
 
 	// users
 	mux.HandleFunc("GET /users", handlers.User.ListUsers)
 	mux.HandleFunc("POST /users", handlers.User.CreateUser)
 	mux.HandleFunc("POST /users/login", handlers.User.Login)
-	// mux.HandleFunc("POST /users/reset-password", handlers.User.AdminResetPassword)
+	mux.HandleFunc("POST /users/refresh", handlers.User.Refresh)
 
 	// calibration
-	mux.HandleFunc("GET /calibration", handlers.Calibration.GetCalibration)
-	mux.HandleFunc("GET /calibration/users", handlers.Calibration.GetCalibrationByUsers)
+	mux.Handle("GET /calibration", viewer(handlers.Calibration.GetCalibration))
+	mux.Handle("GET /calibration/users", viewer(handlers.Calibration.GetCalibrationByUsers))
+	mux.Handle("GET /calibration/metrics", viewer(handlers.Calibration.GetCalibrationMetrics))
+}
+
+// forecastResources routes GET /forecasts/{id}/{resource} to the handler of
+// the resource. The mux rejects e.g. /forecasts/{id}/history next to
+// /forecasts/llm/{user_id} as neither pattern is more specific, so the
+// resources share one pattern.
+func forecastResources(resources map[string]http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		handler, ok := resources[r.PathValue("resource")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		handler(w, r)
+	}
 }
 
 func setupProtectedRoutes(mux *http.ServeMux, handlers *Handlers) {
+	// bots are read-only, so writes need a forecaster or an admin
+	forecasters := auth.RequireRole(models.RoleForecaster, models.RoleAdmin)
+	admins := auth.RequireRole(models.RoleAdmin)
+
+	// API keys can only call routes their scopes allow
+	forecastsRead := auth.RequireScope(models.ScopeForecastsRead)
+	forecastsWrite := auth.RequireScope(models.ScopeForecastsWrite)
+	pointsWrite := auth.RequireScope(models.ScopePointsWrite)
+	scoresWrite := auth.RequireScope(models.ScopeScoresWrite)
+	commentsWrite := auth.RequireScope(models.ScopeCommentsWrite)
+	adminScope := auth.RequireScope(models.ScopeAdmin)
+
 	// forecasts
-	mux.HandleFunc("POST /forecasts/create", handlers.Forecast.CreateForecast)
-	mux.HandleFunc("DELETE /forecasts", handlers.Forecast.DeleteForecast)
-	mux.HandleFunc("PUT /resolve", handlers.Forecast.ResolveForecast)
+	mux.HandleFunc("GET /forecasts/llm", forecastsRead(handlers.Forecast.GetOwnStaleAndNewForecasts))
+	mux.HandleFunc("POST /forecasts/create", forecasters(forecastsWrite(handlers.Forecast.CreateForecast)))
+	mux.HandleFunc("DELETE /forecasts", forecasters(forecastsWrite(handlers.Forecast.DeleteForecast)))
+	mux.HandleFunc("PUT /forecasts/categorize", forecasters(forecastsWrite(handlers.Forecast.CategorizeForecast)))
+	mux.HandleFunc("PUT /forecasts/edit", forecasters(forecastsWrite(handlers.Forecast.EditForecast)))
+	mux.HandleFunc("PUT /forecasts/visibility", forecasters(forecastsWrite(handlers.Forecast.SetVisibility)))
+	mux.HandleFunc("PUT /resolve", forecasters(forecastsWrite(handlers.Forecast.ResolveForecast)))
+	mux.HandleFunc("PUT /unresolve", forecasters(forecastsWrite(handlers.Forecast.UnresolveForecast)))
 
 	// forecast points
-	mux.HandleFunc("POST /forecast-points", handlers.ForecastPoint.CreateForecastPoint)
+	mux.HandleFunc("POST /forecast-points", forecasters(pointsWrite(handlers.ForecastPoint.CreateForecastPoint)))
 
 	// scores (single-score)
-	mux.HandleFunc("POST /scores", handlers.Score.CreateScore)
-	mux.HandleFunc("DELETE /scores", handlers.Score.DeleteScore)
-
-	// users
-	mux.HandleFunc("DELETE /users", handlers.User.DeleteUser)
-	mux.HandleFunc("PUT /users/password", handlers.User.ChangePassword)
-
+	mux.HandleFunc("POST /scores", forecasters(scoresWrite(handlers.Score.CreateScore)))
+	mux.HandleFunc("DELETE /scores", forecasters(scoresWrite(handlers.Score.DeleteScore)))
+
+	// comments
+	mux.HandleFunc("POST /comments", forecasters(commentsWrite(handlers.Comment.CreateComment)))
+	mux.HandleFunc("PUT /comments", forecasters(commentsWrite(handlers.Comment.UpdateComment)))
+	mux.HandleFunc("DELETE /comments", forecasters(commentsWrite(handlers.Comment.DeleteComment)))
+
+	// users, which API keys cannot manage
+	mux.HandleFunc("DELETE /users", auth.RequireToken(handlers.User.DeleteUser))
+	mux.HandleFunc("PUT /users/password", auth.RequireToken(handlers.User.ChangePassword))
+
+	// api keys
+	mux.HandleFunc("POST /keys", auth.RequireToken(handlers.APIKey.CreateAPIKey))
+	mux.HandleFunc("GET /keys", auth.RequireToken(handlers.APIKey.ListAPIKeys))
+	mux.HandleFunc("DELETE /keys", auth.RequireToken(handlers.APIKey.RevokeAPIKey))
+
+	// admin
+	mux.HandleFunc("PUT /admin/users/password", admins(adminScope(handlers.User.AdminResetPassword)))
+	mux.HandleFunc("PUT /admin/users/role", admins(adminScope(handlers.User.AdminSetRole)))
+	mux.HandleFunc("DELETE /admin/forecasts", admins(adminScope(handlers.Forecast.AdminDeleteForecast)))
+	mux.HandleFunc("PUT /admin/resolve", admins(adminScope(handlers.Forecast.AdminResolveForecast)))
+	mux.HandleFunc("PUT /admin/unresolve", admins(adminScope(handlers.Forecast.AdminUnresolveForecast)))
+	mux.HandleFunc("GET /admin/cache", admins(adminScope(handlers.Cache.GetStats)))
+	mux.HandleFunc("POST /admin/categories", admins(adminScope(handlers.Category.CreateCategory)))
+	mux.HandleFunc("PUT /admin/categories", admins(adminScope(handlers.Category.UpdateCategory)))
+	mux.HandleFunc("DELETE /admin/categories", admins(adminScope(handlers.Category.DeleteCategory)))
 }
@@ -0,0 +1,166 @@
+package services
+
+import (
+	"backend/internal/events"
+	"backend/internal/logger"
+	"backend/internal/models"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+// GetAggregate returns the current community forecast of a forecast the viewer,
+// 0 when anonymous, may see
+func (s *ForecastService) GetAggregate(ctx context.Context, id int64, viewer int64, opts models.AggregateOptions) (*models.Aggregate, error) {
+	series, err := s.GetAggregateSeries(ctx, id, viewer, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	aggregate := &models.Aggregate{
+		ForecastID: series.ForecastID,
+		Method:     series.Method,
+		Extremize:  series.Extremize,
+		Weighted:   series.Weighted,
+	}
+	if len(series.Points) > 0 {
+		aggregate.AggregatePoint = series.Points[len(series.Points)-1]
+	}
+	return aggregate, nil
+}
+
+// GetAggregateSeries returns the community forecast after every point of a
+// forecast the viewer, 0 when anonymous, may see
+func (s *ForecastService) GetAggregateSeries(ctx context.Context, id int64, viewer int64, opts models.AggregateOptions) (*models.AggregateSeries, error) {
+	log := logger.FromContext(ctx)
+
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	forecast, err := s.GetVisibleForecast(ctx, id, viewer)
+	if err != nil {
+		return nil, err
+	}
+	if forecast.IsContinuous() {
+		return nil, models.ErrAggregateUnsupported
+	}
+
+	cacheKey := fmt.Sprintf("forecast:aggregate:%d:%s:%g:%t", id, opts.Method, opts.Extremize, opts.Weighted)
+	if cached, found := s.cache.Get(cacheKey); found {
+		log.Info("cache hit",
+			slog.String("cache_key", cacheKey),
+			slog.String("cache_type", "aggregate"))
+		return cached.(*models.AggregateSeries), nil
+	}
+
+	log.Info("cache miss",
+		slog.String("cache_key", cacheKey),
+		slog.String("cache_type", "aggregate"))
+	points, err := s.pointRepo.GetForecastPoints(ctx, models.PointFilters{ForecastID: &id})
+	if err != nil {
+		log.Error("failed to get forecast points", slog.Int64("id", id), slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	// points go with their users, whose deletion is not tagged on the forecast
+	tags := []string{forecastTag(id), forecastPointsTag(id), tagUsers}
+	var weights map[int64]float64
+	if opts.Weighted {
+		groupByUser := true
+		// Brier scores of other question types are on other scales
+		scores, err := s.scoreRepo.GetAggregateScoresByUsers(ctx, models.ScoreFilters{GroupByUserID: &groupByUser, QuestionType: &forecast.QuestionType, ExcludeCrowd: true})
+		if err != nil {
+			log.Error("failed to get user scores", slog.String("error", err.Error()))
+			return nil, err
+		}
+		weights = models.BrierWeights(scores, forecast.QuestionType)
+		tags = append(tags, tagScores)
+	}
+
+	aggregates, err := models.AggregatePoints(points, len(forecast.Options), weights, opts)
+	if err != nil {
+		return nil, err
+	}
+	series := &models.AggregateSeries{
+		ForecastID: id,
+		Method:     opts.Method,
+		Extremize:  opts.Extremize,
+		Weighted:   opts.Weighted,
+		Points:     aggregates,
+	}
+
+	s.cache.SetWithTags(cacheKey, series, tags...)
+	return series, nil
+}
+
+// scoreCrowd scores the default community forecast of a resolving forecast as
+// the crowd pseudo-user, so it ranks alongside the users it pools, when the
+// plan found the crowd for it. It returns nil otherwise.
+func (s *ForecastService) scoreCrowd(ctx context.Context, forecast *models.Forecast, plan *resolutionPlan) (*models.Scores, error) {
+	log := logger.FromContext(ctx)
+
+	crowdID := plan.crowdID
+	if forecast.IsContinuous() || crowdID == 0 {
+		return nil, nil
+	}
+
+	// the crowd's first point is everyone's first, so only the fixed prior
+	// comes before it in its lifetime scores
+	var score models.Scores
+	var err error
+	if forecast.IsMultipleChoice() {
+		points := models.ChoicePoints(plan.crowd)
+		score, err = models.CalcMultipleChoiceScore(points, plan.outcomeIndex, len(forecast.Options), crowdID, forecast.ID, forecast.CreatedAt, forecast.ClosingDate, forecast.ResolvedAt)
+		if err == nil {
+			prior := s.lifetimePrior.ChoicePoints(plan.crowd, len(forecast.Options), forecast.CreatedAt)
+			err = models.SetMultipleChoiceLifetimeScore(&score, points, prior, plan.outcomeIndex, len(forecast.Options), forecast.ClosingDate, forecast.ResolvedAt)
+		}
+	} else {
+		points := models.TimePoints(plan.crowd)
+		score, err = models.CalcBinaryScore(points, plan.binaryOutcome, crowdID, forecast.ID, forecast.CreatedAt, forecast.ClosingDate, forecast.ResolvedAt)
+		if err == nil {
+			prior := s.lifetimePrior.BinaryPoints(plan.crowd, forecast.CreatedAt)
+			err = models.SetBinaryLifetimeScore(&score, points, prior, plan.binaryOutcome, forecast.ClosingDate, forecast.ResolvedAt)
+		}
+	}
+	if err != nil {
+		log.Error("failed to calculate crowd score", slog.Int64("id", forecast.ID), slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	log.Info("scoring community forecast", slog.Int64("id", forecast.ID), slog.Int64("user_id", crowdID))
+	return &score, nil
+}
+
+// crowdUserID returns the id of the crowd pseudo-user, creating it on the first
+// resolution. It returns 0 when a real user holds the name, leaving the
+// community forecast unscored. It must not run inside a transaction, as a
+// failed create aborts it.
+func (s *ForecastService) crowdUserID(ctx context.Context) (int64, error) {
+	log := logger.FromContext(ctx)
+
+	user, err := s.userRepo.GetUserByUsername(ctx, models.CrowdUsername)
+	if errors.Is(err, sql.ErrNoRows) {
+		// without a password hash no one can log in as the crowd
+		user = &models.User{Username: models.CrowdUsername, Role: models.RoleBot}
+		if err = s.userRepo.CreateUser(ctx, user); err == nil {
+			log.Info("created crowd user", slog.Int64("user_id", user.ID))
+			s.bus.Publish(ctx, events.UserChanged{UserID: user.ID})
+			return user.ID, nil
+		}
+		// a concurrent resolution may have created it first
+		log.Warn("failed to create crowd user, looking it up again", slog.String("error", err.Error()))
+		user, err = s.userRepo.GetUserByUsername(ctx, models.CrowdUsername)
+	}
+	if err != nil {
+		log.Error("failed to get crowd user", slog.String("error", err.Error()))
+		return 0, err
+	}
+	if user.Role != models.RoleBot || user.Password != "" {
+		log.Warn("crowd username belongs to a user, not scoring the community forecast", slog.Int64("user_id", user.ID))
+		return 0, nil
+	}
+	return user.ID, nil
+}
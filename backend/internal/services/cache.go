@@ -0,0 +1,129 @@
+package services
+
+import (
+	"backend/internal/cache"
+	"backend/internal/events"
+	"backend/internal/logger"
+	"backend/internal/models"
+	"context"
+	"fmt"
+	"log/slog"
+)
+
+// the types the services cache, so backends that serialize values can read
+// them back; a value of a type missing here is not cached by those backends
+func init() {
+	cache.Register(
+		[]*models.User{},
+		&models.Forecast{},
+		[]*models.Forecast{},
+		[]*models.ForecastPoint{},
+		[]models.Scores{},
+		[]models.UserScores{},
+		&models.OverallScores{},
+		&models.CalibrationData{},
+		[]models.UserCalibrationData{},
+		&models.CalibrationMetrics{},
+		&models.AggregateSeries{},
+	)
+}
+
+// Cache entries are tagged with the data they are built from, and the
+// invalidator maps each event to the tags of the data it changed
+const (
+	tagUsers       = "users"
+	tagForecasts   = "forecasts"
+	tagPoints      = "points"
+	tagScores      = "scores"
+	tagCalibration = "calibration"
+)
+
+// forecastTag is on entries built from one forecast
+func forecastTag(id int64) string {
+	return fmt.Sprintf("forecast:%d", id)
+}
+
+// categoryTag is on entries built from forecasts in one category, which show
+// its name
+func categoryTag(id int64) string {
+	return fmt.Sprintf("category:%d", id)
+}
+
+// forecastPointsTag is on entries built from one forecast's points
+func forecastPointsTag(id int64) string {
+	return fmt.Sprintf("points:forecast:%d", id)
+}
+
+// categoriesKey is the part of a cache key naming the categories a list is
+// filtered on, which differs between no filter and an empty set
+func categoriesKey(ids []int64) string {
+	if ids == nil {
+		return ""
+	}
+	return fmt.Sprint(ids)
+}
+
+// viewerKey is the part of a cache key naming who a list is filtered for, as
+// lists differ with the forecasts each user may see
+func viewerKey(viewer *int64) string {
+	if viewer == nil {
+		return ""
+	}
+	return fmt.Sprintf(":viewer:%d", *viewer)
+}
+
+// ruleKey is the part of an aggregate's cache key naming the scoring rule it
+// also averages
+func ruleKey(rule *string) string {
+	if rule == nil {
+		return ""
+	}
+	return ":rule:" + *rule
+}
+
+// pageKey is the part of a list's cache key naming its page
+func pageKey(page *models.Page) string {
+	if page == nil {
+		return ""
+	}
+	return ":page:" + page.Key()
+}
+
+// invalidationTags returns the tags of the entries the event makes stale
+func invalidationTags(e events.Event) []string {
+	switch e := e.(type) {
+	case events.ForecastCreated:
+		return []string{tagForecasts}
+	case events.ForecastUpdated:
+		// scores and calibration are grouped by the forecast's category
+		return []string{forecastTag(e.ForecastID), tagForecasts, tagScores, tagCalibration}
+	case events.ForecastDeleted:
+		return []string{forecastTag(e.ForecastID), tagForecasts, forecastPointsTag(e.ForecastID), tagPoints, tagScores, tagCalibration}
+	case events.ForecastResolved:
+		return []string{forecastTag(e.ForecastID), tagForecasts, tagCalibration}
+	case events.ForecastVisibilityChanged:
+		return []string{forecastTag(e.ForecastID), tagForecasts, forecastPointsTag(e.ForecastID), tagPoints, tagScores, tagCalibration}
+	case events.CategoryChanged:
+		return []string{categoryTag(e.CategoryID), tagForecasts}
+	case events.PointCreated:
+		return []string{forecastPointsTag(e.ForecastID), tagPoints}
+	case events.ScoreChanged:
+		return []string{tagScores}
+	case events.UserChanged, events.UserDeleted:
+		return []string{tagUsers}
+	}
+	return nil
+}
+
+// CacheInvalidator returns a handler invalidating the cache entries each
+// event makes stale
+func CacheInvalidator(c cache.Cache) events.Handler {
+	return func(ctx context.Context, e events.Event) {
+		tags := invalidationTags(e)
+		if len(tags) == 0 {
+			return
+		}
+		logger.FromContext(ctx).Info("invalidating cache tags", slog.String("event", e.Name()), slog.Any("tags", tags))
+		c.InvalidateTags(tags...)
+	}
+}
@@ -12,79 +12,155 @@ import (
 
 type CalibrationService struct {
 	repo  repository.CalibrationRepository
-	cache *cache.Cache
+	cache cache.Cache
 }
 
-func NewCalibrationService(repo repository.CalibrationRepository, cache *cache.Cache) *CalibrationService {
+func NewCalibrationService(repo repository.CalibrationRepository, cache cache.Cache) *CalibrationService {
 	return &CalibrationService{repo: repo, cache: cache}
 }
 
-func (s *CalibrationService) GetCalibrationData(ctx context.Context, filters models.CalibrationFilters) (*models.CalibrationData, error) {
+// GetCalibrationData buckets the points matching the filters as the options say
+func (s *CalibrationService) GetCalibrationData(ctx context.Context, filters models.CalibrationFilters, opts models.CalibrationOptions) (*models.CalibrationData, error) {
 	log := logger.FromContext(ctx)
-	log.Info("getting calibration data", slog.Any("filters", filters))
+	log.Info("getting calibration data", slog.Any("filters", filters), slog.String("options", opts.Key()))
 
 	// Build cache key
 	dateRangeKey, cacheable := getCacheableDateRangeKey(filters.StartDate, filters.EndDate)
 	if cacheable {
-		cacheKey := s.buildCacheKey("calibration", filters, dateRangeKey)
+		cacheKey := s.buildCacheKey("calibration", filters, opts, dateRangeKey)
 		if cachedData, found := s.cache.Get(cacheKey); found {
 			log.Info("cache hit", slog.String("cache_key", cacheKey), slog.String("cache_type", "calibration data"))
 			return cachedData.(*models.CalibrationData), nil
 		}
 		log.Info("cache miss", slog.String("cache_key", cacheKey), slog.String("cache_type", "calibration data"))
 
-		data, err := s.repo.GetCalibrationData(ctx, filters)
+		data, err := s.calibrationData(ctx, filters, opts)
 		if err != nil {
 			log.Error("failed to get calibration data", slog.String("error", err.Error()))
 			return nil, err
 		}
-		s.cache.Set(cacheKey, data)
+		s.cache.SetWithTags(cacheKey, data, tagCalibration)
 		return data, nil
 	}
 
 	// Custom date range - don't cache
 	log.Info("custom date range - skipping cache")
-	return s.repo.GetCalibrationData(ctx, filters)
+	return s.calibrationData(ctx, filters, opts)
 }
 
-func (s *CalibrationService) GetCalibrationDataByUsers(ctx context.Context, filters models.CalibrationFilters) ([]models.UserCalibrationData, error) {
+// GetCalibrationDataByUsers buckets each user's points matching the filters as
+// the options say
+func (s *CalibrationService) GetCalibrationDataByUsers(ctx context.Context, filters models.CalibrationFilters, opts models.CalibrationOptions) ([]models.UserCalibrationData, error) {
 	log := logger.FromContext(ctx)
-	log.Info("getting calibration data by users", slog.Any("filters", filters))
+	log.Info("getting calibration data by users", slog.Any("filters", filters), slog.String("options", opts.Key()))
 
 	// Build cache key
 	dateRangeKey, cacheable := getCacheableDateRangeKey(filters.StartDate, filters.EndDate)
 	if cacheable {
-		cacheKey := s.buildCacheKey("calibration:users", filters, dateRangeKey)
+		cacheKey := s.buildCacheKey("calibration:users", filters, opts, dateRangeKey)
 		if cachedData, found := s.cache.Get(cacheKey); found {
 			log.Info("cache hit", slog.String("cache_key", cacheKey), slog.String("cache_type", "calibration data by users"))
 			return cachedData.([]models.UserCalibrationData), nil
 		}
 		log.Info("cache miss", slog.String("cache_key", cacheKey), slog.String("cache_type", "calibration data by users"))
 
-		data, err := s.repo.GetCalibrationDataByUsers(ctx, filters)
+		data, err := s.calibrationDataByUsers(ctx, filters, opts)
 		if err != nil {
 			log.Error("failed to get calibration data by users", slog.String("error", err.Error()))
 			return nil, err
 		}
-		s.cache.Set(cacheKey, data)
+		s.cache.SetWithTags(cacheKey, data, tagCalibration)
 		return data, nil
 	}
 
 	// Custom date range - don't cache
 	log.Info("custom date range - skipping cache")
-	return s.repo.GetCalibrationDataByUsers(ctx, filters)
+	return s.calibrationDataByUsers(ctx, filters, opts)
+}
+
+// GetCalibrationMetrics returns the Murphy decomposition, expected calibration
+// error and calibration fit of the points matching the filters, counted and
+// bucketed as the options say
+func (s *CalibrationService) GetCalibrationMetrics(ctx context.Context, filters models.CalibrationFilters, opts models.CalibrationOptions) (*models.CalibrationMetrics, error) {
+	log := logger.FromContext(ctx)
+	log.Info("getting calibration metrics", slog.Any("filters", filters), slog.String("options", opts.Key()))
+
+	dateRangeKey, cacheable := getCacheableDateRangeKey(filters.StartDate, filters.EndDate)
+	cacheKey := s.buildCacheKey("calibration:metrics", filters, opts, dateRangeKey)
+	if cacheable {
+		if cachedData, found := s.cache.Get(cacheKey); found {
+			log.Info("cache hit", slog.String("cache_key", cacheKey), slog.String("cache_type", "calibration metrics"))
+			return cachedData.(*models.CalibrationMetrics), nil
+		}
+		log.Info("cache miss", slog.String("cache_key", cacheKey), slog.String("cache_type", "calibration metrics"))
+	}
+
+	points, err := s.repo.GetCalibrationPoints(ctx, filters)
+	if err != nil {
+		log.Error("failed to get calibration points", slog.String("error", err.Error()))
+		return nil, err
+	}
+	metrics := models.CalcCalibrationMetrics(points, opts)
+
+	// custom date ranges are not cached
+	if cacheable {
+		s.cache.SetWithTags(cacheKey, &metrics, tagCalibration)
+	}
+	return &metrics, nil
+}
+
+// calibrationData lets the calibration query group the points when it can, and
+// buckets them here otherwise
+func (s *CalibrationService) calibrationData(ctx context.Context, filters models.CalibrationFilters, opts models.CalibrationOptions) (*models.CalibrationData, error) {
+	if opts.Grouped() {
+		filters.Bins = opts.Bins
+		data, err := s.repo.GetCalibrationData(ctx, filters)
+		if err != nil {
+			return nil, err
+		}
+		models.SetWilsonIntervals(data.Buckets, opts)
+		return data, nil
+	}
+
+	points, err := s.repo.GetCalibrationPoints(ctx, filters)
+	if err != nil {
+		return nil, err
+	}
+	data := models.Calibrate(points, opts)
+	return &data, nil
+}
+
+// calibrationDataByUsers is calibrationData per user
+func (s *CalibrationService) calibrationDataByUsers(ctx context.Context, filters models.CalibrationFilters, opts models.CalibrationOptions) ([]models.UserCalibrationData, error) {
+	if opts.Grouped() {
+		filters.Bins = opts.Bins
+		data, err := s.repo.GetCalibrationDataByUsers(ctx, filters)
+		if err != nil {
+			return nil, err
+		}
+		for i := range data {
+			models.SetWilsonIntervals(data[i].Buckets, opts)
+		}
+		return data, nil
+	}
+
+	points, err := s.repo.GetCalibrationPoints(ctx, filters)
+	if err != nil {
+		return nil, err
+	}
+	return models.CalibrateByUsers(points, opts), nil
 }
 
-func (s *CalibrationService) buildCacheKey(prefix string, filters models.CalibrationFilters, dateRangeKey string) string {
+func (s *CalibrationService) buildCacheKey(prefix string, filters models.CalibrationFilters, opts models.CalibrationOptions, dateRangeKey string) string {
 	key := prefix
 
 	if filters.UserID != nil {
 		key = fmt.Sprintf("%s:user:%d", key, *filters.UserID)
 	}
-	if filters.Category != nil {
-		key = fmt.Sprintf("%s:category:%s", key, *filters.Category)
+	if filters.CategoryIDs != nil {
+		key = fmt.Sprintf("%s:category:%s", key, categoriesKey(filters.CategoryIDs))
 	}
-	key = fmt.Sprintf("%s:%s", key, dateRangeKey)
+	key = fmt.Sprintf("%s:%s:%s", key, opts.Key(), dateRangeKey)
 
-	return key
+	return key + viewerKey(filters.Viewer)
 }
@@ -0,0 +1,199 @@
+package services
+
+import (
+	"backend/internal/events"
+	"backend/internal/logger"
+	"backend/internal/models"
+	"backend/internal/repository"
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"slices"
+	"strings"
+)
+
+type CategoryService struct {
+	repo repository.CategoryRepository
+	bus  *events.Bus
+}
+
+func NewCategoryService(repo repository.CategoryRepository, bus *events.Bus) *CategoryService {
+	return &CategoryService{repo: repo, bus: bus}
+}
+
+// GetCategoryTree returns the top-level categories with their subcategories
+func (s *CategoryService) GetCategoryTree(ctx context.Context) ([]*models.Category, error) {
+	categories, err := s.repo.GetCategories(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return models.CategoryTree(categories), nil
+}
+
+func (s *CategoryService) GetTags(ctx context.Context) ([]models.Tag, error) {
+	return s.repo.GetTags(ctx)
+}
+
+// CategoryIDs returns the categories a filter on a category id or name selects,
+// with their subcategories if asked. Names match categories at any level,
+// ignoring case. It returns nil when there is no filter, and an empty slice,
+// which matches nothing, when no category matches.
+func (s *CategoryService) CategoryIDs(ctx context.Context, id *int64, name string, descendants bool) ([]int64, error) {
+	if id == nil && name == "" {
+		return nil, nil
+	}
+
+	categories, err := s.repo.GetCategories(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := []int64{}
+	for _, c := range categories {
+		if (id != nil && c.ID != *id) || (name != "" && !strings.EqualFold(c.Name, name)) {
+			continue
+		}
+		if !descendants {
+			ids = append(ids, c.ID)
+			continue
+		}
+		for _, d := range models.CategoryDescendants(categories, c.ID) {
+			if !slices.Contains(ids, d) {
+				ids = append(ids, d)
+			}
+		}
+	}
+	slices.Sort(ids)
+	return ids, nil
+}
+
+func (s *CategoryService) CreateCategory(ctx context.Context, c *models.Category) error {
+	log := logger.FromContext(ctx)
+
+	categories, err := s.repo.GetCategories(ctx)
+	if err != nil {
+		return err
+	}
+	if err := validateCategory(categories, c); err != nil {
+		return err
+	}
+
+	log.Info("creating category", slog.String("name", c.Name), slog.Any("parent_id", c.ParentID))
+	return s.repo.CreateCategory(ctx, c)
+}
+
+// UpdateCategory renames a category or moves it under another parent, or to
+// the top level when it has none
+func (s *CategoryService) UpdateCategory(ctx context.Context, c *models.Category) error {
+	log := logger.FromContext(ctx)
+
+	categories, err := s.repo.GetCategories(ctx)
+	if err != nil {
+		return err
+	}
+	if !slices.ContainsFunc(categories, func(other *models.Category) bool { return other.ID == c.ID }) {
+		return models.ErrCategoryNotFound
+	}
+	if c.ParentID != nil && slices.Contains(models.CategoryDescendants(categories, c.ID), *c.ParentID) {
+		return models.ErrCategoryCycle
+	}
+	if err := validateCategory(categories, c); err != nil {
+		return err
+	}
+
+	log.Info("updating category", slog.Int64("id", c.ID), slog.String("name", c.Name), slog.Any("parent_id", c.ParentID))
+	if err := s.repo.UpdateCategory(ctx, c); err != nil {
+		return err
+	}
+
+	s.bus.Publish(ctx, events.CategoryChanged{CategoryID: c.ID})
+	return nil
+}
+
+// DeleteCategory deletes a category without forecasts or subcategories
+func (s *CategoryService) DeleteCategory(ctx context.Context, id int64) error {
+	log := logger.FromContext(ctx)
+
+	categories, err := s.repo.GetCategories(ctx)
+	if err != nil {
+		return err
+	}
+	if !slices.ContainsFunc(categories, func(c *models.Category) bool { return c.ID == id }) {
+		return models.ErrCategoryNotFound
+	}
+	if len(models.CategoryDescendants(categories, id)) > 1 {
+		return models.ErrCategoryInUse
+	}
+	count, err := s.repo.CountForecasts(ctx, id)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return models.ErrCategoryInUse
+	}
+
+	log.Info("deleting category", slog.Int64("id", id))
+	if err := s.repo.DeleteCategory(ctx, id); err != nil {
+		return err
+	}
+
+	s.bus.Publish(ctx, events.CategoryChanged{CategoryID: id})
+	return nil
+}
+
+// validateCategory trims the category's name and checks its parent exists and
+// has no other child of the same name
+func validateCategory(categories []*models.Category, c *models.Category) error {
+	c.Name = strings.TrimSpace(c.Name)
+	if c.Name == "" {
+		return models.ErrCategoryNameRequired
+	}
+	if c.ParentID != nil && !slices.ContainsFunc(categories, func(parent *models.Category) bool { return parent.ID == *c.ParentID }) {
+		return models.ErrCategoryNotFound
+	}
+	for _, other := range categories {
+		if other.ID != c.ID && sameParent(other.ParentID, c.ParentID) && strings.EqualFold(other.Name, c.Name) {
+			return models.ErrCategoryExists
+		}
+	}
+	return nil
+}
+
+func sameParent(a *int64, b *int64) bool {
+	return (a == nil && b == nil) || (a != nil && b != nil && *a == *b)
+}
+
+// resolveCategory points the forecast at its category. A forecast naming its
+// category instead of giving its id, as before categories were a tree, goes
+// in the top-level category of that name. Only admins create categories, so
+// an unknown name is not found.
+func resolveCategory(ctx context.Context, repo repository.CategoryRepository, f *models.Forecast) error {
+	if f.CategoryID != nil {
+		c, err := repo.GetCategoryByID(ctx, *f.CategoryID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.ErrCategoryNotFound
+		}
+		if err != nil {
+			return err
+		}
+		f.Category = c.Name
+		return nil
+	}
+
+	name := strings.TrimSpace(f.Category)
+	if name == "" {
+		return errors.New("category is required")
+	}
+	categories, err := repo.GetCategories(ctx)
+	if err != nil {
+		return err
+	}
+	for _, c := range categories {
+		if c.ParentID == nil && strings.EqualFold(c.Name, name) {
+			f.Category, f.CategoryID = c.Name, &c.ID
+			return nil
+		}
+	}
+	return models.ErrCategoryNotFound
+}
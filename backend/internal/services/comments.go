@@ -0,0 +1,173 @@
+package services
+
+import (
+	"backend/internal/logger"
+	"backend/internal/models"
+	"backend/internal/repository"
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"time"
+)
+
+// CommentService runs the discussion threads of forecasts. Comments are only
+// read and written by those who may see their forecast, and are not cached.
+type CommentService struct {
+	repo         repository.CommentRepository
+	forecastRepo repository.ForecastRepository
+	tx           repository.Transactor
+}
+
+func NewCommentService(repo repository.CommentRepository, forecastRepo repository.ForecastRepository, tx repository.Transactor) *CommentService {
+	return &CommentService{repo: repo, forecastRepo: forecastRepo, tx: tx}
+}
+
+// GetComments returns the comments of a forecast the viewer, 0 when anonymous,
+// may see
+func (s *CommentService) GetComments(ctx context.Context, filters models.CommentFilters, viewer int64) ([]*models.Comment, error) {
+	log := logger.FromContext(ctx)
+
+	if err := s.checkForecast(ctx, filters.ForecastID, viewer); err != nil {
+		return nil, err
+	}
+	log.Info("getting comments", slog.Int64("forecast_id", filters.ForecastID), slog.Any("page", filters.Page))
+	return s.repo.GetComments(ctx, filters)
+}
+
+// CreateComment adds a comment, or a reply when it has a parent, to a forecast
+// its author may see
+func (s *CommentService) CreateComment(ctx context.Context, c *models.Comment) error {
+	log := logger.FromContext(ctx)
+
+	if err := c.ValidateBody(); err != nil {
+		return err
+	}
+	if err := s.checkForecast(ctx, c.ForecastID, c.UserID); err != nil {
+		return err
+	}
+	if c.ParentID != nil {
+		parent, err := s.repo.GetCommentByID(ctx, *c.ParentID)
+		if errors.Is(err, sql.ErrNoRows) || (err == nil && parent.ForecastID != c.ForecastID) {
+			return models.ErrParentNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if parent.DeletedAt != nil {
+			return models.ErrCommentDeleted
+		}
+	}
+
+	log.Info("creating comment", slog.Int64("forecast_id", c.ForecastID), slog.Any("parent_id", c.ParentID), slog.Int64("user_id", c.UserID))
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.repo.CreateComment(ctx, c); err != nil {
+			return err
+		}
+		return s.repo.SetCommentMentions(ctx, c.ID, models.ParseMentions(c.Body))
+	})
+	if err != nil {
+		return err
+	}
+	return s.reload(ctx, c)
+}
+
+// UpdateComment replaces the body of one of the user's comments
+func (s *CommentService) UpdateComment(ctx context.Context, user_id int64, id int64, body string) (*models.Comment, error) {
+	log := logger.FromContext(ctx)
+
+	c, err := s.getOwnedComment(ctx, user_id, id)
+	if err != nil {
+		return nil, err
+	}
+	c.Body = body
+	if err := c.ValidateBody(); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	c.EditedAt = &now
+
+	log.Info("updating comment", slog.Int64("id", id), slog.Int64("user_id", user_id))
+	err = s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.repo.UpdateComment(ctx, c); err != nil {
+			return err
+		}
+		return s.repo.SetCommentMentions(ctx, c.ID, models.ParseMentions(c.Body))
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := s.reload(ctx, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// DeleteComment removes the body of one of the user's comments, keeping its
+// place in the thread for the replies
+func (s *CommentService) DeleteComment(ctx context.Context, user_id int64, id int64) error {
+	log := logger.FromContext(ctx)
+
+	c, err := s.getOwnedComment(ctx, user_id, id)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	c.Body, c.DeletedAt = "", &now
+
+	log.Info("deleting comment", slog.Int64("id", id), slog.Int64("user_id", user_id))
+	return s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.repo.UpdateComment(ctx, c); err != nil {
+			return err
+		}
+		return s.repo.SetCommentMentions(ctx, c.ID, nil)
+	})
+}
+
+// checkForecast returns ErrForecastNotFound unless the forecast exists and the
+// user may see it
+func (s *CommentService) checkForecast(ctx context.Context, forecastID int64, userID int64) error {
+	forecast, err := s.forecastRepo.GetForecastByID(ctx, forecastID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.ErrForecastNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if !forecast.CanView(userID) {
+		return models.ErrForecastNotFound
+	}
+	return nil
+}
+
+// getOwnedComment returns a comment the user wrote and can still change
+func (s *CommentService) getOwnedComment(ctx context.Context, user_id int64, id int64) (*models.Comment, error) {
+	c, err := s.repo.GetCommentByID(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, models.ErrCommentNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if c.UserID != user_id {
+		return nil, models.ErrNotCommentAuthor
+	}
+	if c.DeletedAt != nil {
+		return nil, models.ErrCommentDeleted
+	}
+	if err := s.checkForecast(ctx, c.ForecastID, user_id); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// reload refreshes the comment with what the repository filled in, such as the
+// author's name and the users it mentions
+func (s *CommentService) reload(ctx context.Context, c *models.Comment) error {
+	stored, err := s.repo.GetCommentByID(ctx, c.ID)
+	if err != nil {
+		return err
+	}
+	*c = *stored
+	return nil
+}
@@ -2,6 +2,7 @@ package services
 
 import (
 	"backend/internal/cache"
+	"backend/internal/events"
 	"backend/internal/logger"
 	"backend/internal/models"
 	"backend/internal/repository"
@@ -15,11 +16,13 @@ import (
 type ForecastPointService struct {
 	repo   repository.ForecastPointRepository
 	f_repo repository.ForecastRepository
-	cache  *cache.Cache
+	tx     repository.Transactor
+	cache  cache.Cache
+	bus    *events.Bus
 }
 
-func NewForecastPointService(fp_repo repository.ForecastPointRepository, f_repo repository.ForecastRepository, cache *cache.Cache) *ForecastPointService {
-	return &ForecastPointService{repo: fp_repo, f_repo: f_repo, cache: cache}
+func NewForecastPointService(fp_repo repository.ForecastPointRepository, f_repo repository.ForecastRepository, tx repository.Transactor, cache cache.Cache, bus *events.Bus) *ForecastPointService {
+	return &ForecastPointService{repo: fp_repo, f_repo: f_repo, tx: tx, cache: cache, bus: bus}
 }
 
 // routes handler requests to the associated service method based on filters
@@ -66,7 +69,7 @@ func (f *ForecastPointService) GetForecastPoints(ctx context.Context, filters mo
 func (f *ForecastPointService) GetForecastPointsByForecastID(ctx context.Context, filters models.PointFilters) ([]*models.ForecastPoint, error) {
 	log := logger.FromContext(ctx)
 	log.Info("fetching forecast points by forecast id", slog.Any("filters", filters))
-	cacheKey := fmt.Sprintf("point:list:%d", filters.ForecastID)
+	cacheKey := fmt.Sprintf("point:list:%d", *filters.ForecastID) + pageKey(filters.Page) + viewerKey(filters.Viewer)
 	if cachedPoints, found := f.cache.Get(cacheKey); found {
 		if data, ok := cachedPoints.([]*models.ForecastPoint); ok {
 			log.Info("cache hit",
@@ -84,7 +87,7 @@ func (f *ForecastPointService) GetForecastPointsByForecastID(ctx context.Context
 		log.Error("failed to fetch forecast points from database", slog.String("error", err.Error()))
 		return nil, err
 	}
-	f.cache.Set(cacheKey, points)
+	f.cache.SetWithTags(cacheKey, points, forecastPointsTag(*filters.ForecastID))
 
 	return points, nil
 }
@@ -92,7 +95,7 @@ func (f *ForecastPointService) GetForecastPointsByForecastID(ctx context.Context
 func (f *ForecastPointService) GetForecastPointsByForecastIDAndUser(ctx context.Context, filters models.PointFilters) ([]*models.ForecastPoint, error) {
 	log := logger.FromContext(ctx)
 
-	cacheKey := fmt.Sprintf("point:list:user:%d:%d", filters.UserID, filters.ForecastID)
+	cacheKey := fmt.Sprintf("point:list:user:%d:%d", *filters.UserID, *filters.ForecastID) + pageKey(filters.Page) + viewerKey(filters.Viewer)
 
 	if cachedPoints, found := f.cache.Get(cacheKey); found {
 		if data, ok := cachedPoints.([]*models.ForecastPoint); ok {
@@ -112,7 +115,7 @@ func (f *ForecastPointService) GetForecastPointsByForecastIDAndUser(ctx context.
 		log.Error("failed to fetch forecast points from database", slog.String("error", err.Error()))
 		return nil, err
 	}
-	f.cache.Set(cacheKey, points)
+	f.cache.SetWithTags(cacheKey, points, forecastPointsTag(*filters.ForecastID))
 	return points, nil
 }
 
@@ -125,9 +128,9 @@ func (f *ForecastPointService) CreateForecastPoint(ctx context.Context, fp *mode
 		log.Error("failed to get forecast", slog.String("error", err.Error()))
 		return err
 	}
-	if forecast == nil {
+	if forecast == nil || !forecast.CanView(fp.UserID) {
 		log.Error("forecast not found")
-		return errors.New("forecast not found")
+		return models.ErrForecastNotFound
 	}
 
 	// Check if forecast is already resolved
@@ -142,22 +145,68 @@ func (f *ForecastPointService) CreateForecastPoint(ctx context.Context, fp *mode
 		return errors.New("forecast has already closed")
 	}
 
-	log.Info("deleting cache keys",
-		slog.String("cache_key", fmt.Sprintf("point:list:%d", fp.ForecastID)),
-		slog.String("cache_key", "point:all:latest"),
-		slog.String("cache_key", "point:all"))
-	f.cache.Delete(fmt.Sprintf("point:list:%d", fp.ForecastID))
-	f.cache.Delete("point:all:latest")
-	f.cache.Delete("point:all")
+	// Check the point matches the question type
+	switch {
+	case forecast.IsMultipleChoice():
+		if fp.Distribution != nil {
+			log.Error("distribution given for a multiple choice forecast")
+			return errors.New("multiple choice forecasts take probabilities, not a distribution")
+		}
+		if err := models.ValidateProbabilities(fp.Probabilities, len(forecast.Options)); err != nil {
+			log.Error("invalid probabilities", slog.String("error", err.Error()))
+			return err
+		}
+		// point_forecast only carries meaning for binary questions
+		fp.PointForecast = 0
+	case forecast.IsContinuous():
+		if fp.Distribution == nil || len(fp.Probabilities) > 0 {
+			log.Error("numeric forecast point without a distribution")
+			return errors.New("numeric and date forecasts take a distribution")
+		}
+		if err := fp.Distribution.Validate(*forecast.RangeMin, *forecast.RangeMax); err != nil {
+			log.Error("invalid distribution", slog.String("error", err.Error()))
+			return err
+		}
+		fp.PointForecast = 0
+	default:
+		if len(fp.Probabilities) > 0 || fp.Distribution != nil {
+			log.Error("probabilities given for a binary forecast")
+			return errors.New("binary forecasts take a point_forecast, not probabilities")
+		}
+		if err := models.ValidateProbability(fp.PointForecast); err != nil {
+			log.Error("invalid point forecast", slog.String("error", err.Error()))
+			return err
+		}
+	}
 
 	log.Info("creating forecast point", slog.Any("forecast_point", fp))
-	return f.repo.CreateForecastPoint(ctx, fp)
+	err = f.tx.WithinTx(ctx, func(ctx context.Context) error {
+		// A resolution may have started since the forecast was read. Checking
+		// again holds the forecast, so the point is either scored by it or
+		// rejected.
+		open, err := f.f_repo.CheckForecastStatus(ctx, fp.ForecastID)
+		if err != nil {
+			log.Error("failed to check forecast status", slog.String("error", err.Error()))
+			return err
+		}
+		if !open {
+			log.Error("forecast has already been resolved")
+			return errors.New("forecast has already been resolved")
+		}
+		return f.repo.CreateForecastPoint(ctx, fp)
+	})
+	if err != nil {
+		return err
+	}
+
+	f.bus.Publish(ctx, events.PointCreated{ForecastID: fp.ForecastID, UserID: fp.UserID})
+	return nil
 }
 
 func (f *ForecastPointService) GetAllForecastPoints(ctx context.Context, filters models.PointFilters) ([]*models.ForecastPoint, error) {
 	log := logger.FromContext(ctx)
 
-	cacheKey := "point:all"
+	cacheKey := "point:all" + pageKey(filters.Page) + viewerKey(filters.Viewer)
 	if cachedPoints, found := f.cache.Get(cacheKey); found {
 		if data, ok := cachedPoints.([]*models.ForecastPoint); ok {
 			log.Info("cache hit",
@@ -177,14 +226,14 @@ func (f *ForecastPointService) GetAllForecastPoints(ctx context.Context, filters
 		return nil, err
 	}
 
-	f.cache.Set(cacheKey, points)
+	f.cache.SetWithTags(cacheKey, points, tagPoints)
 	return points, nil
 }
 
 func (f *ForecastPointService) GetLatestForecastPoints(ctx context.Context, filters models.PointFilters) ([]*models.ForecastPoint, error) {
 	log := logger.FromContext(ctx)
 
-	cacheKey := "point:all:latest"
+	cacheKey := "point:all:latest" + pageKey(filters.Page) + viewerKey(filters.Viewer)
 	if cachedPoints, found := f.cache.Get(cacheKey); found {
 		if data, ok := cachedPoints.([]*models.ForecastPoint); ok {
 			log.Info("cache hit",
@@ -204,14 +253,14 @@ func (f *ForecastPointService) GetLatestForecastPoints(ctx context.Context, filt
 		return nil, err
 	}
 
-	f.cache.Set(cacheKey, points)
+	f.cache.SetWithTags(cacheKey, points, tagPoints)
 	return points, nil
 }
 
 func (f *ForecastPointService) GetLatestForecastPointsByUser(ctx context.Context, filters models.PointFilters) ([]*models.ForecastPoint, error) {
 	log := logger.FromContext(ctx)
 
-	cacheKey := fmt.Sprintf("point:all:latest:%d", filters.UserID)
+	cacheKey := fmt.Sprintf("point:all:latest:%d", *filters.UserID) + pageKey(filters.Page) + viewerKey(filters.Viewer)
 	if cachedPoints, found := f.cache.Get(cacheKey); found {
 		if data, ok := cachedPoints.([]*models.ForecastPoint); ok {
 			log.Info("cache hit",
@@ -230,7 +279,7 @@ func (f *ForecastPointService) GetLatestForecastPointsByUser(ctx context.Context
 		log.Error("failed to fetch forecast points from database", slog.String("error", err.Error()))
 		return nil, err
 	}
-	f.cache.Set(cacheKey, points)
+	f.cache.SetWithTags(cacheKey, points, tagPoints)
 	return points, nil
 }
 
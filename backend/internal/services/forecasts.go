@@ -2,6 +2,7 @@ package services
 
 import (
 	"backend/internal/cache"
+	"backend/internal/events"
 	"backend/internal/logger"
 	"backend/internal/models"
 	"backend/internal/repository"
@@ -10,25 +11,50 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"strconv"
+	"strings"
 	"time"
 )
 
 type ForecastService struct {
-	repo      repository.ForecastRepository
-	pointRepo repository.ForecastPointRepository
-	scoreRepo repository.ScoreRepository
-	cache     *cache.Cache
+	repo         repository.ForecastRepository
+	pointRepo    repository.ForecastPointRepository
+	scoreRepo    repository.ScoreRepository
+	auditRepo    repository.ResolutionAuditRepository
+	revisionRepo repository.ForecastRevisionRepository
+	categoryRepo repository.CategoryRepository
+	userRepo     repository.UserRepository
+	tx           repository.Transactor
+	cache        cache.Cache
+	bus          *events.Bus
+	// lifetimePrior stands in for users before their first point in
+	// lifetime scores
+	lifetimePrior models.LifetimePrior
 }
 
-func NewForecastService(repo repository.ForecastRepository, pointRepo repository.ForecastPointRepository, scoreRepo repository.ScoreRepository, cache *cache.Cache) *ForecastService {
+func NewForecastService(repo repository.ForecastRepository, pointRepo repository.ForecastPointRepository, scoreRepo repository.ScoreRepository, auditRepo repository.ResolutionAuditRepository, revisionRepo repository.ForecastRevisionRepository, categoryRepo repository.CategoryRepository, userRepo repository.UserRepository, tx repository.Transactor, cache cache.Cache, bus *events.Bus) *ForecastService {
 	return &ForecastService{
-		repo:      repo,
-		pointRepo: pointRepo,
-		scoreRepo: scoreRepo,
-		cache:     cache,
+		repo:         repo,
+		pointRepo:    pointRepo,
+		scoreRepo:    scoreRepo,
+		auditRepo:    auditRepo,
+		revisionRepo: revisionRepo,
+		categoryRepo: categoryRepo,
+		userRepo:     userRepo,
+		tx:           tx,
+		cache:        cache,
+		bus:          bus,
+
+		lifetimePrior: models.LifetimePrior{Probability: models.DefaultLifetimePrior},
 	}
 }
 
+// SetLifetimePrior changes what lifetime scores count for users before their
+// first point on forecasts resolved from now on
+func (s *ForecastService) SetLifetimePrior(prior models.LifetimePrior) {
+	s.lifetimePrior = prior
+}
+
 // individual forecast operations
 func (s *ForecastService) GetForecastByID(ctx context.Context, id int64) (*models.Forecast, error) {
 	log := logger.FromContext(ctx)
@@ -51,11 +77,34 @@ func (s *ForecastService) GetForecastByID(ctx context.Context, id int64) (*model
 		return nil, err
 	}
 
-	s.cache.Set(cacheKey, forecast)
+	tags := []string{forecastTag(id)}
+	if forecast.CategoryID != nil {
+		tags = append(tags, categoryTag(*forecast.CategoryID))
+	}
+	s.cache.SetWithTags(cacheKey, forecast, tags...)
 
 	return forecast, nil
 }
 
+// GetVisibleForecast returns the forecast if the viewer, 0 when anonymous, may
+// see it, and ErrForecastNotFound otherwise
+func (s *ForecastService) GetVisibleForecast(ctx context.Context, id int64, viewer int64) (*models.Forecast, error) {
+	log := logger.FromContext(ctx)
+
+	forecast, err := s.GetForecastByID(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, models.ErrForecastNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !forecast.CanView(viewer) {
+		log.Warn("forecast hidden from viewer", slog.Int64("id", id), slog.Int64("viewer", viewer), slog.String("visibility", string(forecast.Visibility)))
+		return nil, models.ErrForecastNotFound
+	}
+	return forecast, nil
+}
+
 func (s *ForecastService) CheckForecastOwnership(ctx context.Context, id int64, user_id int64) (bool, error) {
 	log := logger.FromContext(ctx)
 
@@ -74,124 +123,668 @@ func (s *ForecastService) CreateForecast(ctx context.Context, f *models.Forecast
 	log := logger.FromContext(ctx)
 
 	log.Info("creating forecast", slog.Any("forecast", f))
-	s.cache.DeleteByPrefix("forecast:list:")
+	if err := f.ValidateVisibility(); err != nil {
+		return err
+	}
+	f.Tags = models.NormalizeTags(f.Tags)
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		if err := resolveCategory(ctx, s.categoryRepo, f); err != nil {
+			return err
+		}
+		return s.repo.CreateForecast(ctx, f)
+	})
+	if err != nil {
+		return err
+	}
 
-	return s.repo.CreateForecast(ctx, f)
+	s.bus.Publish(ctx, events.ForecastCreated{ForecastID: f.ID, UserID: f.UserID})
+	return nil
 }
 
 func (s *ForecastService) DeleteForecast(ctx context.Context, id int64, user_id int64) error {
 	log := logger.FromContext(ctx)
 
 	log.Info("deleting forecast", slog.Int64("id", id), slog.Int64("user_id", user_id))
-	s.cache.DeleteByPrefix("forecast:list:")
+	if err := s.repo.DeleteForecast(ctx, id, user_id); err != nil {
+		return err
+	}
 
-	return s.repo.DeleteForecast(ctx, id, user_id)
+	s.bus.Publish(ctx, events.ForecastDeleted{ForecastID: id})
+	return nil
+}
+
+// AdminDeleteForecast deletes any user's forecast
+func (s *ForecastService) AdminDeleteForecast(ctx context.Context, id int64, admin_id int64) error {
+	log := logger.FromContext(ctx)
+
+	forecast, err := s.repo.GetForecastByID(ctx, id)
+	if err != nil {
+		log.Error("failed to get forecast from database", slog.Int64("id", id), slog.String("error", err.Error()))
+		return err
+	}
+
+	log.Info("admin deleting forecast", slog.Int64("id", id), slog.Int64("admin_id", admin_id), slog.Int64("user_id", forecast.UserID))
+	if err := s.repo.DeleteForecast(ctx, id, forecast.UserID); err != nil {
+		return err
+	}
+
+	s.bus.Publish(ctx, events.ForecastDeleted{ForecastID: id})
+	return nil
 }
 
 func (s *ForecastService) UpdateForecast(ctx context.Context, f *models.Forecast) error {
 	log := logger.FromContext(ctx)
 
 	log.Info("updating forecast", slog.Any("forecast", f))
-	s.cache.DeleteByPrefix("forecast:list:")
+	if err := s.repo.UpdateForecast(ctx, f); err != nil {
+		return err
+	}
+
+	s.bus.Publish(ctx, events.ForecastUpdated{ForecastID: f.ID})
+	return nil
+}
+
+// EditForecast applies the user's edit of one of their open forecasts and
+// records it as the forecast's next revision
+func (s *ForecastService) EditForecast(ctx context.Context, user_id int64, edit models.ForecastEdit) (*models.ForecastRevision, error) {
+	log := logger.FromContext(ctx)
+
+	forecast, err := s.getOwnedForecast(ctx, user_id, edit.ForecastID)
+	if err != nil {
+		return nil, err
+	}
+	changes, err := forecast.ApplyEdit(edit, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	log.Info("editing forecast", slog.Int64("id", forecast.ID), slog.Any("changes", changes))
+	revision := &models.ForecastRevision{ForecastID: forecast.ID, UserID: user_id, Changes: changes}
+	err = s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.repo.UpdateForecast(ctx, forecast); err != nil {
+			return err
+		}
+		return s.revisionRepo.CreateRevision(ctx, revision)
+	})
+	if err != nil {
+		return nil, err
+	}
 
-	return s.repo.UpdateForecast(ctx, f)
+	s.bus.Publish(ctx, events.ForecastUpdated{ForecastID: forecast.ID})
+	return revision, nil
 }
 
-func (s *ForecastService) ResolveForecast(ctx context.Context, user_id int64, id int64, resolution string, comment string) error {
+// GetForecastHistory returns the edits of a forecast the viewer may see,
+// oldest first, marking those made after the viewer's last point on it
+func (s *ForecastService) GetForecastHistory(ctx context.Context, id int64, viewer int64) ([]models.ForecastRevision, error) {
 	log := logger.FromContext(ctx)
 
-	log.Info("resolving forecast", slog.Int64("id", id), slog.Int64("user_id", user_id), slog.String("resolution", resolution), slog.String("comment", comment))
+	log.Info("getting forecast history", slog.Int64("id", id))
+	if _, err := s.GetVisibleForecast(ctx, id, viewer); err != nil {
+		return nil, err
+	}
+	revisions, err := s.revisionRepo.GetRevisions(ctx, id)
+	if err != nil || viewer == 0 || len(revisions) == 0 {
+		return revisions, err
+	}
+
+	points, err := s.pointRepo.GetForecastPoints(ctx, models.PointFilters{ForecastID: &id, UserID: &viewer})
+	if err != nil {
+		return nil, err
+	}
+	var lastPoint time.Time
+	for _, p := range points {
+		if p.CreatedAt.After(lastPoint) {
+			lastPoint = p.CreatedAt
+		}
+	}
+	if lastPoint.IsZero() {
+		return revisions, nil
+	}
+	for i := range revisions {
+		revisions[i].AfterLastPoint = revisions[i].CreatedAt.After(lastPoint)
+	}
+	return revisions, nil
+}
+
+// CategorizeForecast moves one of the user's forecasts to another category and
+// replaces its tags
+func (s *ForecastService) CategorizeForecast(ctx context.Context, user_id int64, id int64, categoryID int64, tags []string) error {
+	log := logger.FromContext(ctx)
+
+	forecast, err := s.getOwnedForecast(ctx, user_id, id)
+	if err != nil {
+		return err
+	}
+
+	log.Info("categorizing forecast", slog.Int64("id", id), slog.Int64("category_id", categoryID), slog.Any("tags", tags))
+	forecast.CategoryID = &categoryID
+	err = s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		if err := resolveCategory(ctx, s.categoryRepo, forecast); err != nil {
+			return err
+		}
+		if err := s.repo.UpdateForecast(ctx, forecast); err != nil {
+			return err
+		}
+		return s.repo.SetForecastTags(ctx, id, models.NormalizeTags(tags))
+	})
+	if err != nil {
+		return err
+	}
+
+	s.bus.Publish(ctx, events.ForecastUpdated{ForecastID: id})
+	return nil
+}
+
+// SetVisibility changes who may see one of the user's forecasts, and who it
+// is shared with when shared
+func (s *ForecastService) SetVisibility(ctx context.Context, user_id int64, id int64, visibility models.Visibility, sharedWith []int64) error {
+	log := logger.FromContext(ctx)
+
+	forecast, err := s.getOwnedForecast(ctx, user_id, id)
+	if err != nil {
+		return err
+	}
+
+	forecast.Visibility, forecast.SharedWith = visibility, sharedWith
+	if err := forecast.ValidateVisibility(); err != nil {
+		return err
+	}
+
+	log.Info("setting forecast visibility", slog.Int64("id", id), slog.String("visibility", string(visibility)), slog.Any("shared_with", sharedWith))
+	err = s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.repo.UpdateForecast(ctx, forecast); err != nil {
+			return err
+		}
+		return s.repo.SetForecastShares(ctx, id, forecast.SharedWith)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.bus.Publish(ctx, events.ForecastVisibilityChanged{ForecastID: id})
+	return nil
+}
+
+// ResolveForecast resolves an open forecast and scores every user on it.
+// The resolution is parsed against the question, see Forecast.ParseResolution,
+// and the value is the fractional outcome of a probabilistic resolution.
+// Resolutions the question cannot take wrap models.ErrInvalidResolution.
+func (s *ForecastService) ResolveForecast(ctx context.Context, user_id int64, id int64, resolution string, value *float64, comment string) error {
+	forecast, err := s.getOwnedForecast(ctx, user_id, id)
+	if err != nil {
+		return err
+	}
+	return s.resolveForecast(ctx, user_id, forecast, resolution, value, comment)
+}
+
+// AdminResolveForecast resolves any user's forecast. The admin is recorded
+// as the user in the audit trail.
+func (s *ForecastService) AdminResolveForecast(ctx context.Context, admin_id int64, id int64, resolution string, value *float64, comment string) error {
 	forecast, err := s.repo.GetForecastByID(ctx, id)
 	if err != nil {
-		log.Error("failed to get forecast from database", slog.String("error", err.Error()))
+		return err
+	}
+	return s.resolveForecast(ctx, admin_id, forecast, resolution, value, comment)
+}
+
+func (s *ForecastService) resolveForecast(ctx context.Context, user_id int64, forecast *models.Forecast, input string, value *float64, comment string) error {
+	log := logger.FromContext(ctx)
+	id := forecast.ID
+
+	log.Info("resolving forecast", slog.Int64("id", id), slog.Int64("user_id", user_id), slog.String("resolution", input), slog.Any("value", value), slog.String("comment", comment))
+
+	resolution, err := forecast.ParseResolution(input)
+	if err != nil {
+		log.Error("invalid resolution", slog.Int64("id", id), slog.String("resolution", input), slog.String("error", err.Error()))
+		return err
+	}
+
+	plan, err := s.planResolution(ctx, forecast, resolution, value)
+	if err != nil {
 		return err
 	}
 
+	// The resolution, its audit record and every score commit together, so a
+	// failed score leaves the forecast open to be resolved again
+	err = s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		// Make sure the forecast is not already resolved, holding it until
+		// this resolution commits so a concurrent one waits and sees it
+		status, err := s.CheckForecastStatus(ctx, id)
+		if err != nil {
+			log.Error("failed to check forecast status", slog.Int64("id", id), slog.Int64("user_id", user_id), slog.String("error", err.Error()))
+			return err
+		}
+		if !status {
+			log.Error("forecast is already resolved", slog.Int64("id", id), slog.Int64("user_id", user_id))
+			return errors.New("forecast is already resolved")
+		}
+		if err := s.loadResolutionPoints(ctx, forecast, plan); err != nil {
+			return err
+		}
+
+		audit := models.ResolutionAudit{
+			ForecastID:      forecast.ID,
+			UserID:          user_id,
+			Action:          models.ResolutionActionResolve,
+			Resolution:      &plan.resolution,
+			ResolutionValue: plan.value,
+			Comment:         &comment,
+		}
+		if err := s.auditRepo.CreateResolutionAudit(ctx, &audit); err != nil {
+			log.Error("failed to create resolution audit", slog.Int64("id", id), slog.String("error", err.Error()))
+			return err
+		}
+
+		return s.applyResolution(ctx, forecast, plan, time.Now(), comment)
+	})
+	if err != nil {
+		log.Error("resolution rolled back", slog.Int64("id", id), slog.Int64("user_id", user_id), slog.String("error", err.Error()))
+		return err
+	}
+
+	s.bus.Publish(ctx, events.ForecastResolved{ForecastID: id})
+	s.bus.Publish(ctx, events.ScoreChanged{ForecastID: id})
+	return nil
+}
+
+// UnresolveForecast reverts a resolved forecast, archiving its scores. With a
+// new resolution it re-resolves in the same transaction and recomputes every
+// user's score; the original resolution time is kept, as the outcome was
+// known then, so time-weighted scores do not change for the correction alone.
+func (s *ForecastService) UnresolveForecast(ctx context.Context, user_id int64, id int64, resolution *string, value *float64, comment string) error {
+	forecast, err := s.getOwnedForecast(ctx, user_id, id)
+	if err != nil {
+		return err
+	}
+	return s.unresolveForecast(ctx, user_id, forecast, resolution, value, comment)
+}
+
+// AdminUnresolveForecast unresolves or re-resolves any user's forecast
+func (s *ForecastService) AdminUnresolveForecast(ctx context.Context, admin_id int64, id int64, resolution *string, value *float64, comment string) error {
+	forecast, err := s.repo.GetForecastByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	return s.unresolveForecast(ctx, admin_id, forecast, resolution, value, comment)
+}
+
+func (s *ForecastService) unresolveForecast(ctx context.Context, user_id int64, forecast *models.Forecast, input *string, value *float64, comment string) error {
+	log := logger.FromContext(ctx)
+	id := forecast.ID
+
+	log.Info("unresolving forecast", slog.Int64("id", id), slog.Int64("user_id", user_id), slog.Any("resolution", input), slog.Any("value", value), slog.String("comment", comment))
+	var err error
+
+	if !forecast.IsResolved() {
+		log.Error("forecast is not resolved", slog.Int64("id", id), slog.Int64("user_id", user_id))
+		return errors.New("forecast is not resolved")
+	}
+
+	var plan *resolutionPlan
+	if input != nil {
+		resolution, err := forecast.ParseResolution(*input)
+		if err != nil {
+			log.Error("invalid resolution", slog.Int64("id", id), slog.String("resolution", *input), slog.String("error", err.Error()))
+			return err
+		}
+		if plan, err = s.planResolution(ctx, forecast, resolution, value); err != nil {
+			return err
+		}
+	}
+
+	err = s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		// a concurrent unresolution may have reopened it since it was read
+		status, err := s.CheckForecastStatus(ctx, id)
+		if err != nil {
+			log.Error("failed to check forecast status", slog.Int64("id", id), slog.Int64("user_id", user_id), slog.String("error", err.Error()))
+			return err
+		}
+		if status {
+			log.Error("forecast is not resolved", slog.Int64("id", id), slog.Int64("user_id", user_id))
+			return errors.New("forecast is not resolved")
+		}
+
+		audit := models.ResolutionAudit{
+			ForecastID:              forecast.ID,
+			UserID:                  user_id,
+			Action:                  models.ResolutionActionUnresolve,
+			PreviousResolution:      forecast.Resolution,
+			PreviousResolutionValue: forecast.ResolutionValue,
+			PreviousResolvedAt:      forecast.ResolvedAt,
+			Comment:                 &comment,
+		}
+		if plan != nil {
+			if err := s.loadResolutionPoints(ctx, forecast, plan); err != nil {
+				return err
+			}
+			audit.Action = models.ResolutionActionReresolve
+			audit.Resolution = &plan.resolution
+			audit.ResolutionValue = plan.value
+		}
+		if err := s.auditRepo.CreateResolutionAudit(ctx, &audit); err != nil {
+			log.Error("failed to create resolution audit", slog.Int64("id", id), slog.String("error", err.Error()))
+			return err
+		}
+
+		archived, err := s.scoreRepo.ArchiveScores(ctx, forecast.ID, audit.ID)
+		if err != nil {
+			log.Error("failed to archive scores", slog.Int64("id", id), slog.String("error", err.Error()))
+			return err
+		}
+		log.Info("archived scores", slog.Int64("id", id), slog.Int64("count", archived))
+
+		if plan != nil {
+			return s.applyResolution(ctx, forecast, plan, *forecast.ResolvedAt, comment)
+		}
+
+		forecast.Resolution = nil
+		forecast.ResolutionValue = nil
+		forecast.ResolvedAt = nil
+		forecast.ResolutionComment = nil
+		return s.repo.UpdateForecast(ctx, forecast)
+	})
+	if err != nil {
+		log.Error("unresolution rolled back", slog.Int64("id", id), slog.Int64("user_id", user_id), slog.String("error", err.Error()))
+		return err
+	}
+
+	s.bus.Publish(ctx, events.ForecastResolved{ForecastID: id})
+	s.bus.Publish(ctx, events.ScoreChanged{ForecastID: id})
+	return nil
+}
+
+// GetResolutionAudits returns the resolution history of a forecast the viewer
+// may see, oldest first
+func (s *ForecastService) GetResolutionAudits(ctx context.Context, id int64, viewer int64) ([]models.ResolutionAudit, error) {
+	log := logger.FromContext(ctx)
+
+	log.Info("getting resolution audits", slog.Int64("id", id))
+	if _, err := s.GetVisibleForecast(ctx, id, viewer); err != nil {
+		return nil, err
+	}
+	return s.auditRepo.GetResolutionAudits(ctx, id)
+}
+
+// ErrEmptySearch is returned for a search without a query
+var ErrEmptySearch = errors.New("search query is empty")
+
+// Search finds the forecasts whose question, resolution criteria, comment or
+// point reasons match the query, best matches first. Results are not cached
+// as queries rarely repeat.
+func (s *ForecastService) Search(ctx context.Context, filters models.SearchFilters) ([]models.SearchResult, error) {
+	log := logger.FromContext(ctx)
+
+	if strings.TrimSpace(filters.Query) == "" {
+		return nil, ErrEmptySearch
+	}
+	if filters.Limit <= 0 {
+		filters.Limit = models.DefaultSearchLimit
+	}
+	filters.Limit = min(filters.Limit, models.MaxSearchLimit)
+
+	log.Info("searching forecasts", slog.String("query", filters.Query), slog.Int("limit", filters.Limit))
+	results, err := s.repo.SearchForecasts(ctx, filters)
+	if err != nil {
+		log.Error("failed to search forecasts", slog.String("error", err.Error()))
+		return nil, err
+	}
+	return results, nil
+}
+
+// getOwnedForecast loads a forecast and checks the user owns it
+func (s *ForecastService) getOwnedForecast(ctx context.Context, user_id int64, id int64) (*models.Forecast, error) {
+	log := logger.FromContext(ctx)
+
+	forecast, err := s.repo.GetForecastByID(ctx, id)
+	if err != nil {
+		log.Error("failed to get forecast from database", slog.String("error", err.Error()))
+		return nil, err
+	}
+
 	// Make sure the forecast exists and the user owns it
 	log.Info("checking forecast ownership", slog.Int64("id", id), slog.Int64("user_id", user_id))
 	ownership, err := s.CheckForecastOwnership(ctx, id, user_id)
 	if err == sql.ErrNoRows {
 		log.Error("forecast does not exist", slog.Int64("id", id), slog.Int64("user_id", user_id))
-		return errors.New("forecast does not exist")
+		return nil, errors.New("forecast does not exist")
 	}
 
 	if !ownership {
 		log.Error("user does not own this forecast", slog.Int64("id", id), slog.Int64("user_id", user_id))
-		return errors.New("user does not own this forecast")
+		return nil, errors.New("user does not own this forecast")
 	}
+	return forecast, nil
+}
 
-	// Make sure the forecast is not already resolved
-	status, err := s.CheckForecastStatus(ctx, id)
+// resolutionPlan is a validated resolution together with the points to score
+type resolutionPlan struct {
+	resolution models.Resolution
+	// value is stored with probabilistic resolutions
+	value                  *float64
+	binaryOutcome          float64
+	outcomeIndex           int
+	outcomeValue           float64
+	userPoints             map[int64][]models.TimePoint
+	userChoicePoints       map[int64][]models.ChoicePoint
+	userDistributionPoints map[int64][]models.DistributionPoint
+	// points are pooled into the community forecast, crowd, after each point
+	// of a binary or multiple choice forecast
+	points []*models.ForecastPoint
+	crowd  []models.AggregatePoint
+	// crowdID is the user the community forecast is scored as, 0 when it is
+	// not scored
+	crowdID int64
+}
+
+// planResolution validates the resolution for the forecast's question type and
+// finds the crowd to score, before the resolution's transaction starts
+func (s *ForecastService) planResolution(ctx context.Context, forecast *models.Forecast, resolution models.Resolution, value *float64) (*resolutionPlan, error) {
+	log := logger.FromContext(ctx)
+	id := forecast.ID
+
+	plan := &resolutionPlan{
+		resolution:             resolution,
+		userPoints:             make(map[int64][]models.TimePoint),
+		userChoicePoints:       make(map[int64][]models.ChoicePoint),
+		userDistributionPoints: make(map[int64][]models.DistributionPoint),
+	}
+
+	var err error
+	switch {
+	case !resolution.IsScored():
+	case forecast.IsMultipleChoice():
+		plan.outcomeIndex, err = forecast.ParseOptionResolution(string(resolution))
+	case forecast.IsContinuous():
+		plan.outcomeValue, err = forecast.ParseValueResolution(string(resolution))
+		plan.resolution = models.Resolution(strconv.FormatFloat(plan.outcomeValue, 'f', -1, 64))
+	default:
+		plan.binaryOutcome, err = resolution.BinaryOutcome(value)
+		if resolution == models.ResolutionProbabilistic {
+			plan.value = value
+		}
+	}
+	// only probabilistic resolutions keep their value
+	if err == nil && value != nil && plan.value == nil {
+		err = fmt.Errorf("%w: resolution %q does not take a value", models.ErrInvalidResolution, resolution)
+	}
 	if err != nil {
-		log.Error("failed to check forecast status", slog.Int64("id", id), slog.Int64("user_id", user_id), slog.String("error", err.Error()))
-		return err
+		log.Error("invalid resolution", slog.Int64("id", id), slog.String("resolution", string(resolution)), slog.String("error", err.Error()))
+		return nil, err
 	}
 
-	if !status {
-		log.Error("forecast is already resolved", slog.Int64("id", id), slog.Int64("user_id", user_id))
-		return errors.New("forecast is already resolved")
+	if !resolution.IsScored() || forecast.IsContinuous() {
+		return plan, nil
 	}
 
+	// The crowd is created outside the resolution's transaction, so it is
+	// committed before it is announced or scored. The points are read again
+	// once the forecast is locked; these only tell whether it is needed.
 	points, err := s.pointRepo.GetForecastPoints(ctx, models.PointFilters{ForecastID: &id})
 	if err != nil {
-		log.Error("failed to get forecast points", slog.Int64("id", id), slog.Int64("user_id", user_id), slog.String("error", err.Error()))
+		log.Error("failed to get forecast points", slog.Int64("id", id), slog.String("error", err.Error()))
+		return nil, err
+	}
+	if countForecasters(points) >= models.MinCrowdForecasters {
+		if plan.crowdID, err = s.crowdUserID(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return plan, nil
+}
+
+// loadResolutionPoints groups the forecast's points by user into the plan. It
+// must run inside the resolution's transaction, after the forecast is locked,
+// so no point is added between scoring and committing.
+func (s *ForecastService) loadResolutionPoints(ctx context.Context, forecast *models.Forecast, plan *resolutionPlan) error {
+	log := logger.FromContext(ctx)
+	id := forecast.ID
+
+	points, err := s.pointRepo.GetForecastPoints(ctx, models.PointFilters{ForecastID: &id})
+	if err != nil {
+		log.Error("failed to get forecast points", slog.Int64("id", id), slog.String("error", err.Error()))
 		return err
 	}
 
 	if len(points) == 0 {
-		log.Error("no forecast points found", slog.Int64("id", id), slog.Int64("user_id", user_id))
+		log.Error("no forecast points found", slog.Int64("id", id))
 		return errors.New("no forecast points found")
 	}
+	plan.points = points
 
 	// Group points and created at by user
-	userPoints := make(map[int64][]models.TimePoint)
 	for _, point := range points {
-		userPoints[point.UserID] = append(userPoints[point.UserID], models.TimePoint{
-			PointForecast: point.PointForecast,
-			CreatedAt:     point.CreatedAt,
-		})
+		switch {
+		case forecast.IsMultipleChoice():
+			plan.userChoicePoints[point.UserID] = append(plan.userChoicePoints[point.UserID], models.ChoicePoint{
+				Probabilities: point.Probabilities,
+				CreatedAt:     point.CreatedAt,
+			})
+		case forecast.IsContinuous():
+			if point.Distribution == nil {
+				return fmt.Errorf("forecast point %d has no distribution", point.ID)
+			}
+			plan.userDistributionPoints[point.UserID] = append(plan.userDistributionPoints[point.UserID], models.DistributionPoint{
+				Distribution: *point.Distribution,
+				CreatedAt:    point.CreatedAt,
+			})
+		default:
+			plan.userPoints[point.UserID] = append(plan.userPoints[point.UserID], models.TimePoint{
+				PointForecast: point.PointForecast,
+				CreatedAt:     point.CreatedAt,
+			})
+		}
+	}
+
+	if !forecast.IsContinuous() {
+		plan.crowd, err = models.AggregatePoints(points, len(forecast.Options), nil, models.AggregateOptions{Method: models.DefaultAggregateMethod})
+		if err != nil {
+			log.Error("failed to aggregate forecast points", slog.Int64("id", id), slog.String("error", err.Error()))
+			return err
+		}
+	}
+	// too few forecasters for the community forecast to be scored
+	if len(plan.userPoints)+len(plan.userChoicePoints) < models.MinCrowdForecasters {
+		plan.crowdID = 0
+	}
+	return nil
+}
+
+// countForecasters returns how many users made the points
+func countForecasters(points []*models.ForecastPoint) int {
+	users := make(map[int64]bool)
+	for _, point := range points {
+		users[point.UserID] = true
 	}
+	return len(users)
+}
+
+// applyResolution marks the forecast resolved and writes every user's score.
+// It must run inside a transaction.
+func (s *ForecastService) applyResolution(ctx context.Context, forecast *models.Forecast, plan *resolutionPlan, resolvedAt time.Time, comment string) error {
+	log := logger.FromContext(ctx)
+	id := forecast.ID
 
 	// Update the forecast with resolution status
-	now := time.Now()
-	forecast.ResolvedAt = &now
-	forecast.Resolution = &resolution
+	forecast.ResolvedAt = &resolvedAt
+	forecast.Resolution = &plan.resolution
+	forecast.ResolutionValue = plan.value
 	forecast.ResolutionComment = &comment
 
-	// Update the forecast in the database
 	if err := s.repo.UpdateForecast(ctx, forecast); err != nil {
-		log.Error("failed to update forecast in database", slog.Int64("id", id), slog.Int64("user_id", user_id), slog.String("error", err.Error()))
+		log.Error("failed to update forecast in database", slog.Int64("id", id), slog.String("error", err.Error()))
 		return err
 	}
 
-	if resolution == "-" {
+	// annulled and ambiguous forecasts close without scores
+	if !plan.resolution.IsScored() {
 		return nil
 	}
 
-	outcome := resolution == "1"
-	for userID, probabilities := range userPoints {
+	var scores []*models.Scores
+	for userID, choicePoints := range plan.userChoicePoints {
+		log.Info("calculating multiple choice forecast score")
+		score, err := models.CalcMultipleChoiceScore(choicePoints, plan.outcomeIndex, len(forecast.Options), userID, forecast.ID, forecast.CreatedAt, forecast.ClosingDate, forecast.ResolvedAt)
+		if err == nil {
+			prior := s.lifetimePrior.ChoicePoints(plan.crowd, len(forecast.Options), forecast.CreatedAt)
+			err = models.SetMultipleChoiceLifetimeScore(&score, choicePoints, prior, plan.outcomeIndex, len(forecast.Options), forecast.ClosingDate, forecast.ResolvedAt)
+		}
+		if err != nil {
+			log.Error("failed to calculate forecast score", slog.Int64("id", id), slog.Int64("user_id", userID), slog.String("error", err.Error()))
+			return err
+		}
+		scores = append(scores, &score)
+	}
+
+	for userID, distributionPoints := range plan.userDistributionPoints {
+		log.Info("calculating continuous forecast score")
+		score, err := models.CalcContinuousScore(distributionPoints, plan.outcomeValue, *forecast.RangeMin, *forecast.RangeMax, userID, forecast.ID, forecast.CreatedAt, forecast.ClosingDate, forecast.ResolvedAt)
+		if err != nil {
+			log.Error("failed to calculate forecast score", slog.Int64("id", id), slog.Int64("user_id", userID), slog.String("error", err.Error()))
+			return err
+		}
+		scores = append(scores, &score)
+	}
+
+	for userID, probabilities := range plan.userPoints {
 		if len(probabilities) == 0 {
 			continue
 		}
 		log.Info("calculating forecast score")
-		score, err := models.CalcForecastScore(probabilities, outcome, userID, forecast.ID, forecast.CreatedAt, forecast.ClosingDate, forecast.ResolvedAt)
+		score, err := models.CalcBinaryScore(probabilities, plan.binaryOutcome, userID, forecast.ID, forecast.CreatedAt, forecast.ClosingDate, forecast.ResolvedAt)
+		if err == nil {
+			prior := s.lifetimePrior.BinaryPoints(plan.crowd, forecast.CreatedAt)
+			err = models.SetBinaryLifetimeScore(&score, probabilities, prior, plan.binaryOutcome, forecast.ClosingDate, forecast.ResolvedAt)
+		}
 		if err != nil {
-			log.Error("failed to calculate forecast score", slog.Int64("id", id), slog.Int64("user_id", user_id), slog.String("error", err.Error()))
+			log.Error("failed to calculate forecast score", slog.Int64("id", id), slog.Int64("user_id", userID), slog.String("error", err.Error()))
 			return err
 		}
+		scores = append(scores, &score)
+	}
 
-		if err := s.scoreRepo.CreateScore(ctx, &score); err != nil {
-			log.Error("failed to create score", slog.Int64("id", id), slog.Int64("user_id", user_id), slog.String("error", err.Error()))
-			return err
-		}
+	crowd, err := s.scoreCrowd(ctx, forecast, plan)
+	if err != nil {
+		return err
+	}
+	var crowdID int64
+	if crowd != nil {
+		scores = append(scores, crowd)
+		crowdID = crowd.UserID
 	}
 
-	// invalidate affected cache keys
-	deleteKey := fmt.Sprintf("forecast:detail:%d", forecast.ID)
-	s.cache.Delete(deleteKey)
-	s.cache.DeleteByPrefix("forecast:list:")
-	s.cache.DeleteByPrefix("scores:")
+	// relative scores compare every user's points with everyone else's
+	if err := models.SetRelativeScores(forecast, plan.points, scores, crowdID); err != nil {
+		log.Error("failed to calculate relative scores", slog.Int64("id", id), slog.String("error", err.Error()))
+		return err
+	}
 
+	for _, score := range scores {
+		if err := s.scoreRepo.CreateScore(ctx, score); err != nil {
+			log.Error("failed to create score", slog.Int64("id", id), slog.Int64("user_id", score.UserID), slog.String("error", err.Error()))
+			return err
+		}
+	}
 	return nil
 }
 
@@ -205,12 +798,12 @@ func (s *ForecastService) GetForecasts(ctx context.Context, filters models.Forec
 		status = *filters.Status
 	}
 
-	category := ""
-	if filters.Category != nil {
-		category = *filters.Category
+	tag := ""
+	if filters.Tag != nil {
+		tag = *filters.Tag
 	}
 
-	cacheKey := fmt.Sprintf("forecast:list:%s:%s", status, category)
+	cacheKey := fmt.Sprintf("forecast:list:%s:%s:%s", status, categoriesKey(filters.CategoryIDs), tag) + pageKey(filters.Page) + viewerKey(filters.Viewer)
 
 	if cachedList, found := s.cache.Get(cacheKey); found {
 		log.Info("cache hit",
@@ -231,15 +824,17 @@ func (s *ForecastService) GetForecasts(ctx context.Context, filters models.Forec
 		return nil, err
 	}
 
-	s.cache.Set(cacheKey, forecasts)
+	s.cache.SetWithTags(cacheKey, forecasts, tagForecasts)
 	return forecasts, nil
 }
 
-func (s *ForecastService) GetStaleAndNewForecasts(ctx context.Context, userID int64) ([]*models.Forecast, error) {
+// GetStaleAndNewForecasts returns the open forecasts the user has not
+// forecast on lately, among those listed for the viewer
+func (s *ForecastService) GetStaleAndNewForecasts(ctx context.Context, userID int64, viewer int64) ([]*models.Forecast, error) {
 	log := logger.FromContext(ctx)
 
-	log.Info("getting stale and new forecasts", slog.Int64("user_id", userID))
-	forecasts, err := s.repo.GetStaleAndNewForecasts(ctx, userID)
+	log.Info("getting stale and new forecasts", slog.Int64("user_id", userID), slog.Int64("viewer", viewer))
+	forecasts, err := s.repo.GetStaleAndNewForecasts(ctx, userID, viewer)
 	if err != nil {
 		return nil, err
 	}
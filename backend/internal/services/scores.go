@@ -2,6 +2,7 @@ package services
 
 import (
 	"backend/internal/cache"
+	"backend/internal/events"
 	"backend/internal/logger"
 	"backend/internal/models"
 	"backend/internal/repository"
@@ -14,11 +15,12 @@ import (
 
 type ScoreService struct {
 	repo  repository.ScoreRepository
-	cache *cache.Cache
+	cache cache.Cache
+	bus   *events.Bus
 }
 
-func NewScoreService(repo repository.ScoreRepository, cache *cache.Cache) *ScoreService {
-	return &ScoreService{repo: repo, cache: cache}
+func NewScoreService(repo repository.ScoreRepository, cache cache.Cache, bus *events.Bus) *ScoreService {
+	return &ScoreService{repo: repo, cache: cache, bus: bus}
 }
 
 // getCacheableDateRangeKey returns a cache key suffix if the date range matches a predefined
@@ -68,12 +70,12 @@ func getCacheableDateRangeKey(startDate *time.Time, endDate *time.Time) (string,
 }
 
 // multiple-score methods
-func (s *ScoreService) GetScores(ctx context.Context, user_id int64, forecast_id int64) ([]models.Scores, error) {
+func (s *ScoreService) GetScores(ctx context.Context, user_id int64, forecast_id int64, page *models.Page, viewer *int64) ([]models.Scores, error) {
 	log := logger.FromContext(ctx)
 	switch {
 	case user_id != 0 && forecast_id != 0:
 		log.Info("getting scores by user and forecast", slog.Int64("user_id", user_id), slog.Int64("forecast_id", forecast_id))
-		cacheKey := fmt.Sprintf("score:by_user_and_forecast:%d:%d", user_id, forecast_id)
+		cacheKey := fmt.Sprintf("score:by_user_and_forecast:%d:%d", user_id, forecast_id) + pageKey(page) + viewerKey(viewer)
 		if cachedData, found := s.cache.Get(cacheKey); found {
 			if data, ok := cachedData.([]models.Scores); ok {
 				log.Info("cache hit", slog.String("cache_key", cacheKey), slog.String("cache_type", "scores by user and forecast"))
@@ -82,16 +84,16 @@ func (s *ScoreService) GetScores(ctx context.Context, user_id int64, forecast_id
 			log.Warn("cache type mismatch, refetching", slog.String("cache_key", cacheKey))
 		}
 		log.Info("cache miss", slog.String("cache_key", cacheKey), slog.String("cache_type", "scores by user and forecast"))
-		scores, err := s.repo.GetScores(ctx, models.ScoreFilters{UserID: &user_id, ForecastID: &forecast_id})
+		scores, err := s.repo.GetScores(ctx, models.ScoreFilters{UserID: &user_id, ForecastID: &forecast_id, Page: page, Viewer: viewer})
 		if err != nil {
 			return nil, err
 		}
-		s.cache.Set(cacheKey, scores)
+		s.cache.SetWithTags(cacheKey, scores, tagScores)
 		return scores, nil
 
 	case user_id != 0 && forecast_id == 0:
 		log.Info("getting scores by user", slog.Int64("user_id", user_id))
-		cacheKey := fmt.Sprintf("score:by_user:%d", user_id)
+		cacheKey := fmt.Sprintf("score:by_user:%d", user_id) + pageKey(page) + viewerKey(viewer)
 		if cachedData, found := s.cache.Get(cacheKey); found {
 			if data, ok := cachedData.([]models.Scores); ok {
 				log.Info("cache hit", slog.String("cache_key", cacheKey), slog.String("cache_type", "scores by user"))
@@ -100,15 +102,15 @@ func (s *ScoreService) GetScores(ctx context.Context, user_id int64, forecast_id
 			log.Warn("cache type mismatch, refetching", slog.String("cache_key", cacheKey))
 		}
 		log.Info("cache miss", slog.String("cache_key", cacheKey), slog.String("cache_type", "scores by user"))
-		scores, err := s.repo.GetScores(ctx, models.ScoreFilters{UserID: &user_id})
+		scores, err := s.repo.GetScores(ctx, models.ScoreFilters{UserID: &user_id, Page: page, Viewer: viewer})
 		if err != nil {
 			return nil, err
 		}
-		s.cache.Set(cacheKey, scores)
+		s.cache.SetWithTags(cacheKey, scores, tagScores)
 		return scores, nil
 	case forecast_id != 0 && user_id == 0:
 		log.Info("getting scores by forecast", slog.Int64("forecast_id", forecast_id))
-		cacheKey := fmt.Sprintf("score:by_forecast:%d", forecast_id)
+		cacheKey := fmt.Sprintf("score:by_forecast:%d", forecast_id) + pageKey(page) + viewerKey(viewer)
 		if cachedData, found := s.cache.Get(cacheKey); found {
 			if data, ok := cachedData.([]models.Scores); ok {
 				log.Info("cache hit", slog.String("cache_key", cacheKey), slog.String("cache_type", "scores by forecast"))
@@ -117,15 +119,15 @@ func (s *ScoreService) GetScores(ctx context.Context, user_id int64, forecast_id
 			log.Warn("cache type mismatch, refetching", slog.String("cache_key", cacheKey))
 		}
 		log.Info("cache miss", slog.String("cache_key", cacheKey), slog.String("cache_type", "scores by forecast"))
-		scores, err := s.repo.GetScores(ctx, models.ScoreFilters{ForecastID: &forecast_id})
+		scores, err := s.repo.GetScores(ctx, models.ScoreFilters{ForecastID: &forecast_id, Page: page, Viewer: viewer})
 		if err != nil {
 			return nil, err
 		}
-		s.cache.Set(cacheKey, scores)
+		s.cache.SetWithTags(cacheKey, scores, tagScores)
 		return scores, nil
 	case user_id == 0 && forecast_id == 0:
 		log.Info("getting all scores")
-		cacheKey := "score:all"
+		cacheKey := "score:all" + pageKey(page) + viewerKey(viewer)
 		if cachedData, found := s.cache.Get(cacheKey); found {
 			if data, ok := cachedData.([]models.Scores); ok {
 				log.Info("cache hit", slog.String("cache_key", cacheKey), slog.String("cache_type", "all scores"))
@@ -134,11 +136,11 @@ func (s *ScoreService) GetScores(ctx context.Context, user_id int64, forecast_id
 			log.Warn("cache type mismatch, refetching", slog.String("cache_key", cacheKey))
 		}
 		log.Info("cache miss", slog.String("cache_key", cacheKey), slog.String("cache_type", "all scores"))
-		scores, err := s.repo.GetScores(ctx, models.ScoreFilters{})
+		scores, err := s.repo.GetScores(ctx, models.ScoreFilters{Page: page, Viewer: viewer})
 		if err != nil {
 			return nil, err
 		}
-		s.cache.Set(cacheKey, scores)
+		s.cache.SetWithTags(cacheKey, scores, tagScores)
 		return scores, nil
 	}
 	return nil, errors.New("no scores found")
@@ -146,26 +148,37 @@ func (s *ScoreService) GetScores(ctx context.Context, user_id int64, forecast_id
 
 // manipulate score model
 func (s *ScoreService) CreateScore(ctx context.Context, score *models.Scores) error {
-	s.cache.DeleteByPrefix("score:")
+	if err := s.repo.CreateScore(ctx, score); err != nil {
+		return err
+	}
 
-	return s.repo.CreateScore(ctx, score)
+	s.bus.Publish(ctx, events.ScoreChanged{ForecastID: score.ForecastID})
+	return nil
 }
 
 func (s *ScoreService) UpdateScore(ctx context.Context, score *models.Scores) error {
-	return s.repo.UpdateScore(ctx, score)
+	if err := s.repo.UpdateScore(ctx, score); err != nil {
+		return err
+	}
+
+	s.bus.Publish(ctx, events.ScoreChanged{ForecastID: score.ForecastID})
+	return nil
 }
 
 func (s *ScoreService) DeleteScore(ctx context.Context, score_id int64) error {
-	s.cache.DeleteByPrefix("score:")
+	if err := s.repo.DeleteScore(ctx, score_id); err != nil {
+		return err
+	}
 
-	return s.repo.DeleteScore(ctx, score_id)
+	s.bus.Publish(ctx, events.ScoreChanged{})
+	return nil
 }
 
-func (s *ScoreService) GetAverageScores(ctx context.Context) ([]models.Scores, error) {
+func (s *ScoreService) GetAverageScores(ctx context.Context, viewer *int64) ([]models.Scores, error) {
 	log := logger.FromContext(ctx)
 
 	log.Info("getting average scores")
-	cacheKey := "score:all:average"
+	cacheKey := "score:all:average" + viewerKey(viewer)
 
 	// Try to get from cache first
 	if cachedData, found := s.cache.Get(cacheKey); found {
@@ -176,36 +189,38 @@ func (s *ScoreService) GetAverageScores(ctx context.Context) ([]models.Scores, e
 		log.Warn("cache type mismatch, refetching", slog.String("cache_key", cacheKey))
 	}
 	log.Info("cache miss", slog.String("cache_key", cacheKey), slog.String("cache_type", "average scores"))
-	scores, err := s.repo.GetAverageScores(ctx)
+	scores, err := s.repo.GetAverageScores(ctx, viewer)
 	if err != nil {
 		log.Error("failed to get average scores", slog.String("error", err.Error()))
 		return nil, err
 	}
-	s.cache.Set(cacheKey, scores)
+	s.cache.SetWithTags(cacheKey, scores, tagScores)
 	return scores, nil
 }
 
 // Aggregate Scores router
-func (s *ScoreService) GetAggregateScores(ctx context.Context, user_id *int64, forecast_id *int64, category *string, startDate *time.Time, endDate *time.Time) (*models.OverallScores, error) {
+func (s *ScoreService) GetAggregateScores(ctx context.Context, user_id *int64, forecast_id *int64, categoryIDs []int64, startDate *time.Time, endDate *time.Time, rule *string, viewer *int64) (*models.OverallScores, error) {
 	log := logger.FromContext(ctx)
 
-	log.Info("getting aggregate scores", slog.Any("user_id", user_id), slog.Any("forecast_id", forecast_id), slog.Any("category", category), slog.Any("start_date", startDate), slog.Any("end_date", endDate))
+	log.Info("getting aggregate scores", slog.Any("user_id", user_id), slog.Any("forecast_id", forecast_id), slog.Any("category_ids", categoryIDs), slog.Any("start_date", startDate), slog.Any("end_date", endDate), slog.Any("rule", rule))
+	// the crowd pools the users it would be averaged with, so only its own
+	// aggregates include it
 	switch {
-	case user_id != nil && category != nil:
-		log.Info("getting aggregate scores by user and category", slog.Any("user_id", user_id), slog.Any("category", category))
-		return s.GetAggregateScoresByUserIDAndCategory(ctx, models.ScoreFilters{UserID: user_id, Category: category, StartDate: startDate, EndDate: endDate})
+	case user_id != nil && categoryIDs != nil:
+		log.Info("getting aggregate scores by user and category", slog.Any("user_id", user_id), slog.Any("category_ids", categoryIDs))
+		return s.GetAggregateScoresByUserIDAndCategory(ctx, models.ScoreFilters{UserID: user_id, CategoryIDs: categoryIDs, StartDate: startDate, EndDate: endDate, Rule: rule, Viewer: viewer})
 	case user_id != nil:
 		log.Info("getting aggregate scores by user", slog.Any("user_id", user_id))
-		return s.GetAggregateScoresByUserID(ctx, models.ScoreFilters{UserID: user_id, StartDate: startDate, EndDate: endDate})
-	case category != nil:
-		log.Info("getting aggregate scores by category", slog.Any("category", category))
-		return s.GetAggregateScoresByCategory(ctx, models.ScoreFilters{Category: category, StartDate: startDate, EndDate: endDate})
+		return s.GetAggregateScoresByUserID(ctx, models.ScoreFilters{UserID: user_id, StartDate: startDate, EndDate: endDate, Rule: rule, Viewer: viewer})
+	case categoryIDs != nil:
+		log.Info("getting aggregate scores by category", slog.Any("category_ids", categoryIDs))
+		return s.GetAggregateScoresByCategory(ctx, models.ScoreFilters{CategoryIDs: categoryIDs, StartDate: startDate, EndDate: endDate, Rule: rule, Viewer: viewer, ExcludeCrowd: true})
 	case forecast_id != nil:
 		log.Info("getting aggregate scores by forecast", slog.Any("forecast_id", forecast_id))
-		return s.GetAggregateScoresByForecastID(ctx, models.ScoreFilters{ForecastID: forecast_id, StartDate: startDate, EndDate: endDate})
+		return s.GetAggregateScoresByForecastID(ctx, models.ScoreFilters{ForecastID: forecast_id, StartDate: startDate, EndDate: endDate, Rule: rule, Viewer: viewer, ExcludeCrowd: true})
 	default:
 		log.Info("getting overall scores")
-		return s.GetOverallScores(ctx, models.ScoreFilters{StartDate: startDate, EndDate: endDate})
+		return s.GetOverallScores(ctx, models.ScoreFilters{StartDate: startDate, EndDate: endDate, Rule: rule, Viewer: viewer, ExcludeCrowd: true})
 	}
 }
 
@@ -215,7 +230,7 @@ func (s *ScoreService) GetAggregateScoresByUserID(ctx context.Context, filters m
 
 	dateRangeKey, cacheable := getCacheableDateRangeKey(filters.StartDate, filters.EndDate)
 	if cacheable {
-		cacheKey := fmt.Sprintf("score:aggregate:%d:%s", *filters.UserID, dateRangeKey)
+		cacheKey := fmt.Sprintf("score:aggregate:%d:%s", *filters.UserID, dateRangeKey) + ruleKey(filters.Rule) + viewerKey(filters.Viewer)
 		if cachedData, found := s.cache.Get(cacheKey); found {
 			if data, ok := cachedData.(*models.OverallScores); ok {
 				log.Info("cache hit", slog.String("cache_key", cacheKey), slog.String("cache_type", "aggregate scores by user"))
@@ -229,7 +244,7 @@ func (s *ScoreService) GetAggregateScoresByUserID(ctx context.Context, filters m
 			log.Error("failed to get aggregate scores by user", slog.Any("user_id", filters.UserID), slog.String("error", err.Error()))
 			return nil, err
 		}
-		s.cache.Set(cacheKey, scores)
+		s.cache.SetWithTags(cacheKey, scores, tagScores)
 		return scores, nil
 	}
 
@@ -240,20 +255,17 @@ func (s *ScoreService) GetAggregateScoresByUserID(ctx context.Context, filters m
 
 func (s *ScoreService) GetAggregateScoresByUserIDAndCategory(ctx context.Context, filters models.ScoreFilters) (*models.OverallScores, error) {
 	log := logger.FromContext(ctx)
-	log.Info("getting aggregate scores by user and category", slog.Any("user_id", filters.UserID), slog.Any("category", filters.Category))
+	log.Info("getting aggregate scores by user and category", slog.Any("user_id", filters.UserID), slog.Any("category_ids", filters.CategoryIDs))
 
 	userID := int64(0)
 	if filters.UserID != nil {
 		userID = *filters.UserID
 	}
-	category := ""
-	if filters.Category != nil {
-		category = *filters.Category
-	}
+	category := categoriesKey(filters.CategoryIDs)
 
 	dateRangeKey, cacheable := getCacheableDateRangeKey(filters.StartDate, filters.EndDate)
 	if cacheable {
-		cacheKey := fmt.Sprintf("score:aggregate:%d:%s:%s", userID, category, dateRangeKey)
+		cacheKey := fmt.Sprintf("score:aggregate:%d:%s:%s", userID, category, dateRangeKey) + ruleKey(filters.Rule) + viewerKey(filters.Viewer)
 		if cachedData, found := s.cache.Get(cacheKey); found {
 			if data, ok := cachedData.(*models.OverallScores); ok {
 				log.Info("cache hit", slog.String("cache_key", cacheKey), slog.String("cache_type", "aggregate scores by user and category"))
@@ -264,10 +276,10 @@ func (s *ScoreService) GetAggregateScoresByUserIDAndCategory(ctx context.Context
 		log.Info("cache miss", slog.String("cache_key", cacheKey), slog.String("cache_type", "aggregate scores by user and category"))
 		scores, err := s.repo.GetAggregateScores(ctx, filters)
 		if err != nil {
-			log.Error("failed to get aggregate scores by user and category", slog.Any("user_id", filters.UserID), slog.Any("category", filters.Category), slog.String("error", err.Error()))
+			log.Error("failed to get aggregate scores by user and category", slog.Any("user_id", filters.UserID), slog.Any("category_ids", filters.CategoryIDs), slog.String("error", err.Error()))
 			return nil, err
 		}
-		s.cache.Set(cacheKey, scores)
+		s.cache.SetWithTags(cacheKey, scores, tagScores)
 		return scores, nil
 	}
 
@@ -282,7 +294,7 @@ func (s *ScoreService) GetAggregateScoresByForecastID(ctx context.Context, filte
 
 	dateRangeKey, cacheable := getCacheableDateRangeKey(filters.StartDate, filters.EndDate)
 	if cacheable {
-		cacheKey := fmt.Sprintf("score:aggregate:forecast:%d:%s", *filters.ForecastID, dateRangeKey)
+		cacheKey := fmt.Sprintf("score:aggregate:forecast:%d:%s", *filters.ForecastID, dateRangeKey) + ruleKey(filters.Rule) + viewerKey(filters.Viewer)
 		if cachedData, found := s.cache.Get(cacheKey); found {
 			if data, ok := cachedData.(*models.OverallScores); ok {
 				log.Info("cache hit", slog.String("cache_key", cacheKey), slog.String("cache_type", "aggregate scores by forecast"))
@@ -296,7 +308,7 @@ func (s *ScoreService) GetAggregateScoresByForecastID(ctx context.Context, filte
 			log.Error("failed to get aggregate scores by forecast", slog.Any("forecast_id", filters.ForecastID), slog.String("error", err.Error()))
 			return nil, err
 		}
-		s.cache.Set(cacheKey, score)
+		s.cache.SetWithTags(cacheKey, score, tagScores)
 		return score, nil
 	}
 
@@ -307,16 +319,13 @@ func (s *ScoreService) GetAggregateScoresByForecastID(ctx context.Context, filte
 
 func (s *ScoreService) GetAggregateScoresByCategory(ctx context.Context, filters models.ScoreFilters) (*models.OverallScores, error) {
 	log := logger.FromContext(ctx)
-	log.Info("getting aggregate scores by category", slog.Any("category", filters.Category))
+	log.Info("getting aggregate scores by category", slog.Any("category_ids", filters.CategoryIDs))
 
-	category := ""
-	if filters.Category != nil {
-		category = *filters.Category
-	}
+	category := categoriesKey(filters.CategoryIDs)
 
 	dateRangeKey, cacheable := getCacheableDateRangeKey(filters.StartDate, filters.EndDate)
 	if cacheable {
-		cacheKey := fmt.Sprintf("score:aggregate:%s:%s", category, dateRangeKey)
+		cacheKey := fmt.Sprintf("score:aggregate:%s:%s", category, dateRangeKey) + ruleKey(filters.Rule) + viewerKey(filters.Viewer)
 		if cachedData, found := s.cache.Get(cacheKey); found {
 			if data, ok := cachedData.(*models.OverallScores); ok {
 				log.Info("cache hit", slog.String("cache_key", cacheKey), slog.String("cache_type", "aggregate scores by category"))
@@ -327,10 +336,10 @@ func (s *ScoreService) GetAggregateScoresByCategory(ctx context.Context, filters
 		log.Info("cache miss", slog.String("cache_key", cacheKey), slog.String("cache_type", "aggregate scores by category"))
 		scores, err := s.repo.GetAggregateScores(ctx, filters)
 		if err != nil {
-			log.Error("failed to get aggregate scores by category", slog.Any("category", filters.Category), slog.String("error", err.Error()))
+			log.Error("failed to get aggregate scores by category", slog.Any("category_ids", filters.CategoryIDs), slog.String("error", err.Error()))
 			return nil, err
 		}
-		s.cache.Set(cacheKey, scores)
+		s.cache.SetWithTags(cacheKey, scores, tagScores)
 		return scores, nil
 	}
 
@@ -345,7 +354,7 @@ func (s *ScoreService) GetOverallScores(ctx context.Context, filters models.Scor
 
 	dateRangeKey, cacheable := getCacheableDateRangeKey(filters.StartDate, filters.EndDate)
 	if cacheable {
-		cacheKey := fmt.Sprintf("score:aggregate:overall:%s", dateRangeKey)
+		cacheKey := fmt.Sprintf("score:aggregate:overall:%s", dateRangeKey) + ruleKey(filters.Rule) + viewerKey(filters.Viewer)
 		if cachedData, found := s.cache.Get(cacheKey); found {
 			if data, ok := cachedData.(*models.OverallScores); ok {
 				log.Info("cache hit", slog.String("cache_key", cacheKey), slog.String("cache_type", "overall scores"))
@@ -359,7 +368,7 @@ func (s *ScoreService) GetOverallScores(ctx context.Context, filters models.Scor
 			log.Error("failed to get overall scores", slog.String("error", err.Error()))
 			return nil, err
 		}
-		s.cache.Set(cacheKey, scores)
+		s.cache.SetWithTags(cacheKey, scores, tagScores)
 		return scores, nil
 	}
 
@@ -369,17 +378,17 @@ func (s *ScoreService) GetOverallScores(ctx context.Context, filters models.Scor
 }
 
 // router for group by user aggregate scores
-func (s *ScoreService) GetAggregateScoresGroupedByUsers(ctx context.Context, category *string, startDate *time.Time, endDate *time.Time) ([]models.UserScores, error) {
+func (s *ScoreService) GetAggregateScoresGroupedByUsers(ctx context.Context, categoryIDs []int64, startDate *time.Time, endDate *time.Time, rule *string, viewer *int64) ([]models.UserScores, error) {
 	log := logger.FromContext(ctx)
 
-	log.Info("getting aggregate scores grouped by users", slog.Any("category", category))
+	log.Info("getting aggregate scores grouped by users", slog.Any("category_ids", categoryIDs))
 	groupByUserID := true
-	if category != nil {
-		log.Info("getting aggregate scores grouped by users and category", slog.Any("category", category))
-		return s.GetAggregateScoresByUsersAndCategory(ctx, models.ScoreFilters{Category: category, GroupByUserID: &groupByUserID, StartDate: startDate, EndDate: endDate})
+	if categoryIDs != nil {
+		log.Info("getting aggregate scores grouped by users and category", slog.Any("category_ids", categoryIDs))
+		return s.GetAggregateScoresByUsersAndCategory(ctx, models.ScoreFilters{CategoryIDs: categoryIDs, GroupByUserID: &groupByUserID, StartDate: startDate, EndDate: endDate, Rule: rule, Viewer: viewer})
 	} else {
 		log.Info("getting aggregate scores grouped by users")
-		return s.GetAggregateScoresByUsers(ctx, models.ScoreFilters{GroupByUserID: &groupByUserID, StartDate: startDate, EndDate: endDate})
+		return s.GetAggregateScoresByUsers(ctx, models.ScoreFilters{GroupByUserID: &groupByUserID, StartDate: startDate, EndDate: endDate, Rule: rule, Viewer: viewer})
 	}
 }
 
@@ -389,7 +398,7 @@ func (s *ScoreService) GetAggregateScoresByUsers(ctx context.Context, filters mo
 
 	dateRangeKey, cacheable := getCacheableDateRangeKey(filters.StartDate, filters.EndDate)
 	if cacheable {
-		cacheKey := fmt.Sprintf("score:aggregate:users:%s", dateRangeKey)
+		cacheKey := fmt.Sprintf("score:aggregate:users:%s", dateRangeKey) + ruleKey(filters.Rule) + viewerKey(filters.Viewer)
 		if cachedData, found := s.cache.Get(cacheKey); found {
 			if data, ok := cachedData.([]models.UserScores); ok {
 				log.Info("cache hit", slog.String("cache_key", cacheKey), slog.String("cache_type", "aggregate scores grouped by users"))
@@ -403,7 +412,7 @@ func (s *ScoreService) GetAggregateScoresByUsers(ctx context.Context, filters mo
 			log.Error("failed to get aggregate scores grouped by users", slog.Any("filters", filters), slog.String("error", err.Error()))
 			return nil, err
 		}
-		s.cache.Set(cacheKey, scores)
+		s.cache.SetWithTags(cacheKey, scores, tagScores)
 		return scores, nil
 	}
 
@@ -415,14 +424,11 @@ func (s *ScoreService) GetAggregateScoresByUsers(ctx context.Context, filters mo
 func (s *ScoreService) GetAggregateScoresByUsersAndCategory(ctx context.Context, filters models.ScoreFilters) ([]models.UserScores, error) {
 	log := logger.FromContext(ctx)
 
-	category := ""
-	if filters.Category != nil {
-		category = *filters.Category
-	}
+	category := categoriesKey(filters.CategoryIDs)
 
 	dateRangeKey, cacheable := getCacheableDateRangeKey(filters.StartDate, filters.EndDate)
 	if cacheable {
-		cacheKey := fmt.Sprintf("score:aggregate:users:%s:%s", category, dateRangeKey)
+		cacheKey := fmt.Sprintf("score:aggregate:users:%s:%s", category, dateRangeKey) + ruleKey(filters.Rule) + viewerKey(filters.Viewer)
 		if cachedData, found := s.cache.Get(cacheKey); found {
 			if data, ok := cachedData.([]models.UserScores); ok {
 				log.Info("cache hit", slog.String("cache_key", cacheKey), slog.String("cache_type", "aggregate scores grouped by users and category"))
@@ -436,7 +442,7 @@ func (s *ScoreService) GetAggregateScoresByUsersAndCategory(ctx context.Context,
 			log.Error("failed to get aggregate scores grouped by users and category", slog.Any("filters", filters), slog.String("error", err.Error()))
 			return nil, err
 		}
-		s.cache.Set(cacheKey, scores)
+		s.cache.SetWithTags(cacheKey, scores, tagScores)
 		return scores, nil
 	}
 
@@ -0,0 +1,179 @@
+package services
+
+import (
+	"backend/internal/auth"
+	"backend/internal/logger"
+	"backend/internal/models"
+	"backend/internal/repository"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"time"
+)
+
+// refreshTokenTTL is how long a session can go unused before logging in again
+const refreshTokenTTL = 30 * 24 * time.Hour
+
+var ErrInvalidRefreshToken = errors.New("invalid refresh token")
+
+type SessionService struct {
+	repo     repository.SessionRepository
+	userRepo repository.UserRepository
+	tx       repository.Transactor
+}
+
+func NewSessionService(repo repository.SessionRepository, userRepo repository.UserRepository, tx repository.Transactor) *SessionService {
+	return &SessionService{repo: repo, userRepo: userRepo, tx: tx}
+}
+
+// CreateSession starts a new refresh token family for a user who just logged in
+func (s *SessionService) CreateSession(ctx context.Context, user *models.User) (*models.Session, error) {
+	log := logger.FromContext(ctx)
+
+	familyID, err := randomHex(16)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Info("creating session", slog.Int64("user_id", user.ID))
+	return s.issue(ctx, user, familyID)
+}
+
+// Refresh swaps a refresh token for a new access and refresh token. Each
+// refresh token works once; presenting one again revokes its whole family, as
+// either the client or an attacker holds a stolen copy.
+func (s *SessionService) Refresh(ctx context.Context, refreshToken string) (*models.Session, error) {
+	log := logger.FromContext(ctx)
+
+	token, err := s.repo.GetRefreshTokenByHash(ctx, hashSecret(refreshToken))
+	if err != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	if token.RevokedAt != nil {
+		log.Warn("refresh token reused, revoking its family", slog.Int64("user_id", token.UserID), slog.String("family_id", token.FamilyID))
+		if err := s.repo.RevokeRefreshTokenFamily(ctx, token.FamilyID); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidRefreshToken
+	}
+	if time.Now().After(token.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	user, err := s.userRepo.GetUserByID(ctx, token.UserID)
+	if err != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+	// the password changed or the user logged out everywhere
+	if user.TokenVersion != token.TokenVersion {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	var session *models.Session
+	reused := false
+	err = s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		revoked, err := s.repo.RevokeRefreshToken(ctx, token.ID)
+		if err != nil {
+			return err
+		}
+		if !revoked {
+			reused = true
+			return ErrInvalidRefreshToken
+		}
+
+		session, err = s.issue(ctx, user, token.FamilyID)
+		return err
+	})
+	if reused {
+		log.Warn("refresh token reused concurrently, revoking its family", slog.Int64("user_id", token.UserID), slog.String("family_id", token.FamilyID))
+		if err := s.repo.RevokeRefreshTokenFamily(ctx, token.FamilyID); err != nil {
+			return nil, err
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return session, nil
+}
+
+// Logout revokes the access token in the claims and, if given, the refresh
+// token's family. Logging out everywhere invalidates every token the user holds.
+func (s *SessionService) Logout(ctx context.Context, claims *auth.Claims, refreshToken string, everywhere bool) error {
+	log := logger.FromContext(ctx)
+
+	log.Info("logging out", slog.Int64("user_id", claims.UserID), slog.Bool("everywhere", everywhere))
+	if everywhere {
+		return s.userRepo.BumpTokenVersion(ctx, claims.UserID)
+	}
+
+	if claims.ID != "" && claims.ExpiresAt != nil {
+		if err := s.repo.RevokeToken(ctx, claims.ID, claims.ExpiresAt.Time); err != nil {
+			return err
+		}
+	}
+
+	if refreshToken == "" {
+		return nil
+	}
+	token, err := s.repo.GetRefreshTokenByHash(ctx, hashSecret(refreshToken))
+	if err != nil || token.UserID != claims.UserID {
+		return ErrInvalidRefreshToken
+	}
+	return s.repo.RevokeRefreshTokenFamily(ctx, token.FamilyID)
+}
+
+// TokenVersion implements auth.Sessions
+func (s *SessionService) TokenVersion(ctx context.Context, userID int64) (int, error) {
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	return user.TokenVersion, nil
+}
+
+// IsTokenRevoked implements auth.Sessions
+func (s *SessionService) IsTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	return s.repo.IsTokenRevoked(ctx, jti)
+}
+
+// issue stores a new refresh token in the family and signs an access token
+// with the user's current role and token version
+func (s *SessionService) issue(ctx context.Context, user *models.User, familyID string) (*models.Session, error) {
+	refreshToken, err := randomHex(32)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.CreateRefreshToken(ctx, &models.RefreshToken{
+		UserID:       user.ID,
+		TokenHash:    hashSecret(refreshToken),
+		FamilyID:     familyID,
+		TokenVersion: user.TokenVersion,
+		ExpiresAt:    time.Now().Add(refreshTokenTTL),
+	}); err != nil {
+		return nil, err
+	}
+
+	accessToken, expiresAt, err := auth.GenerateToken(user.ID, user.Username, user.Role, user.TokenVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.Session{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresAt:    expiresAt,
+	}, nil
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -2,6 +2,7 @@ package services
 
 import (
 	"backend/internal/cache"
+	"backend/internal/events"
 	"backend/internal/models"
 	"backend/internal/repository"
 	"context"
@@ -13,13 +14,15 @@ import (
 
 type UserService struct {
 	repo  repository.UserRepository
-	cache *cache.Cache
+	cache cache.Cache
+	bus   *events.Bus
 }
 
-func NewUserService(repo repository.UserRepository, cache *cache.Cache) *UserService {
+func NewUserService(repo repository.UserRepository, cache cache.Cache, bus *events.Bus) *UserService {
 	return &UserService{
 		repo:  repo,
 		cache: cache,
+		bus:   bus,
 	}
 }
 
@@ -32,6 +35,10 @@ func (s *UserService) GetUserByUsername(ctx context.Context, username string) (*
 }
 
 func (s *UserService) CreateUser(ctx context.Context, user *models.User) error {
+	// the community forecast is scored under the crowd's name
+	if user.Username == models.CrowdUsername {
+		return models.ErrUsernameReserved
+	}
 	user.CreatedAt = time.Now()
 
 	// Hash password
@@ -43,14 +50,22 @@ func (s *UserService) CreateUser(ctx context.Context, user *models.User) error {
 	// Set the hashed password
 	user.Password = string(hashedPassword)
 
-	s.cache.Delete("users")
 	// Create the user with the hashed password
-	return s.repo.CreateUser(ctx, user)
+	if err := s.repo.CreateUser(ctx, user); err != nil {
+		return err
+	}
+
+	s.bus.Publish(ctx, events.UserChanged{UserID: user.ID})
+	return nil
 }
 
 func (s *UserService) DeleteUser(ctx context.Context, id int64) error {
-	s.cache.Delete("users")
-	return s.repo.DeleteUser(ctx, id)
+	if err := s.repo.DeleteUser(ctx, id); err != nil {
+		return err
+	}
+
+	s.bus.Publish(ctx, events.UserDeleted{UserID: id})
+	return nil
 }
 
 func (s *UserService) ValidateUser(ctx context.Context, id int64) (bool, error) {
@@ -89,6 +104,17 @@ func (s *UserService) AdminResetPassword(ctx context.Context, userID int64, newP
 	return s.repo.UpdatePassword(ctx, userID, string(hashedPassword))
 }
 
+// SetRole changes a user's role. Their tokens are revoked, as they carry the
+// old role, so the change applies from their next login.
+func (s *UserService) SetRole(ctx context.Context, userID int64, role models.Role) error {
+	if err := s.repo.UpdateRole(ctx, userID, role); err != nil {
+		return err
+	}
+
+	s.bus.Publish(ctx, events.UserChanged{UserID: userID})
+	return nil
+}
+
 func (s *UserService) VerifyPassword(ctx context.Context, username string, password string) (*models.User, error) {
 	user, err := s.repo.GetUserByUsername(ctx, username)
 	if err != nil {
@@ -112,6 +138,6 @@ func (s *UserService) ListUsers(ctx context.Context) ([]*models.User, error) {
 	if err != nil {
 		return nil, err
 	}
-	s.cache.Set("users", users)
+	s.cache.SetWithTags("users", users, tagUsers)
 	return users, nil
 }
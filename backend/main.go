@@ -5,11 +5,9 @@ import (
 	"backend/internal/cache"
 	"backend/internal/config"
 	"backend/internal/database"
-	"backend/internal/handlers"
 	"backend/internal/middleware"
 	"backend/internal/repository"
 	"backend/internal/routes"
-	"backend/internal/services"
 	"context"
 	"log"
 	"net/http"
@@ -23,7 +21,7 @@ func CORSMiddleware(allowedOrigin string) func(http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			w.Header().Set("Access-Control-Allow-Origin", allowedOrigin)
 			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-API-Key")
 
 			// Handle preflight requests
 			if r.Method == http.MethodOptions {
@@ -36,6 +34,25 @@ func CORSMiddleware(allowedOrigin string) func(http.Handler) http.Handler {
 	}
 }
 
+// newCache creates the configured cache backend
+func newCache(cfg *config.Config) (cache.Cache, error) {
+	local := cache.NewMemoryCache(cache.Options{
+		MaxEntries: cfg.CacheMaxEntries,
+		MaxBytes:   cfg.CacheMaxBytes,
+		DefaultTTL: cfg.CacheTTL,
+	})
+	redis := cache.RedisOptions{Addr: cfg.RedisAddr, Password: cfg.RedisPassword}
+
+	switch cfg.CacheBackend {
+	case "redis":
+		return cache.NewRedisCache(redis, "", cfg.CacheTTL), nil
+	case "synced":
+		return cache.NewSyncedCache(local, redis, "")
+	default:
+		return local, nil
+	}
+}
+
 func main() {
 	ctx := context.Background()
 
@@ -49,6 +66,9 @@ func main() {
 	if err := auth.Init(cfg.JWTSecret); err != nil {
 		log.Fatalf("Error initializing auth: %v", err)
 	}
+	if err := auth.SetAccessTokenTTL(cfg.AccessTokenTTL); err != nil {
+		log.Fatalf("Error initializing auth: %v", err)
+	}
 
 	db, err := database.NewDB(cfg.DBConnString)
 	if err != nil {
@@ -60,32 +80,38 @@ func main() {
 		log.Fatalf("Error connecting to the database: %v", err)
 	}
 
-	repositories := &routes.Repositories{
-		Forecast:      repository.NewForecastRepository(db),
-		ForecastPoint: repository.NewForecastPointRepository(db),
-		User:          repository.NewUserRepository(db),
-		Score:         repository.NewScoreRepository(db),
-		Calibration:   repository.NewCalibrationRepository(db),
+	if cfg.AutoMigrate {
+		applied, err := db.MigrateUp(ctx)
+		if err != nil {
+			log.Fatalf("Error migrating the database: %v", err)
+		}
+		log.Printf("Applied %d migrations", len(applied))
 	}
 
-	cache := cache.NewCache()
-
-	services := &routes.Services{
-		Forecast:      services.NewForecastService(repositories.Forecast, repositories.ForecastPoint, repositories.Score, cache),
-		ForecastPoint: services.NewForecastPointService(repositories.ForecastPoint, repositories.Forecast, cache),
-		User:          services.NewUserService(repositories.User, cache),
-		Score:         services.NewScoreService(repositories.Score, cache),
-		Calibration:   services.NewCalibrationService(repositories.Calibration, cache),
+	repositories := &routes.Repositories{
+		Forecast:         repository.NewForecastRepository(db),
+		ForecastPoint:    repository.NewForecastPointRepository(db),
+		User:             repository.NewUserRepository(db),
+		Score:            repository.NewScoreRepository(db),
+		Calibration:      repository.NewCalibrationRepository(db),
+		Category:         repository.NewCategoryRepository(db),
+		Comment:          repository.NewCommentRepository(db),
+		ResolutionAudit:  repository.NewResolutionAuditRepository(db),
+		ForecastRevision: repository.NewForecastRevisionRepository(db),
+		APIKey:           repository.NewAPIKeyRepository(db),
+		Session:          repository.NewSessionRepository(db),
+		Transactor:       repository.NewTransactor(db),
 	}
 
-	handlers := &routes.Handlers{
-		Forecast:      handlers.NewForecastHandler(services.Forecast),
-		ForecastPoint: handlers.NewForecastPointHandler(services.ForecastPoint),
-		User:          handlers.NewUserHandler(services.User),
-		Score:         handlers.NewScoreHandler(services.Score),
-		Calibration:   handlers.NewCalibrationHandler(services.Calibration),
+	cache, err := newCache(cfg)
+	if err != nil {
+		log.Fatalf("Error creating the cache: %v", err)
 	}
 
+	services := routes.NewServices(repositories, cache)
+	services.Forecast.SetLifetimePrior(cfg.LifetimePrior)
+	handlers := routes.NewHandlers(services)
+
 	mux := http.NewServeMux()
 	routes.Setup(mux, handlers)
 	handler := CORSMiddleware(cfg.AllowedOrigin)(mux)
@@ -57,6 +57,12 @@ function LeaderboardBase({
 
   // Sort items by selected metric
   const sortedItems = [...items].sort((a, b) => {
+    // Missing averages (no scores of that kind) go last, whichever way is better
+    const aMissing = a[selectedMetric] == null;
+    const bMissing = b[selectedMetric] == null;
+    if (aMissing || bMissing) {
+      return aMissing - bMissing;
+    }
     // For Brier score, lower is better
     if (selectedMetric.includes('brier')) {
       return a[selectedMetric] - b[selectedMetric];
@@ -17,6 +17,7 @@ export const login = async (username, password) => {
     const data = await response.json();
     // Store token and user info in local storage
     localStorage.setItem('token', data.token);
+    localStorage.setItem('refreshToken', data.refresh_token);
     localStorage.setItem('username', data.username);
     
     return data;
@@ -49,12 +50,65 @@ export const register = async (username, password) => {
   }
 };
 
-// Logout user by removing token
+// Logout user by revoking their tokens on the server and removing them
 export const logout = () => {
+  const token = localStorage.getItem('token');
+  const refreshToken = localStorage.getItem('refreshToken');
+  if (token) {
+    fetch(`${API_BASE_URL}/users/logout`, {
+      method: 'POST',
+      headers: {
+        'Content-Type': 'application/json',
+        'Authorization': `Bearer ${token}`,
+      },
+      body: JSON.stringify({ refresh_token: refreshToken }),
+    }).catch((error) => console.error('Logout error:', error));
+  }
+
   localStorage.removeItem('token');
+  localStorage.removeItem('refreshToken');
   localStorage.removeItem('username');
 };
 
+// Swap the refresh token for new tokens, returning whether it worked
+export const refreshSession = async () => {
+  const refreshToken = localStorage.getItem('refreshToken');
+  if (!refreshToken) {
+    return false;
+  }
+
+  const response = await fetch(`${API_BASE_URL}/users/refresh`, {
+    method: 'POST',
+    headers: {
+      'Content-Type': 'application/json',
+    },
+    body: JSON.stringify({ refresh_token: refreshToken }),
+  });
+
+  if (!response.ok) {
+    return false;
+  }
+
+  const data = await response.json();
+  localStorage.setItem('token', data.token);
+  localStorage.setItem('refreshToken', data.refresh_token);
+  return true;
+};
+
+// Fetch with the access token, refreshing it once if it has expired
+export const authFetch = async (url, options = {}) => {
+  const send = () => fetch(url, {
+    ...options,
+    headers: { ...options.headers, ...authHeader() },
+  });
+
+  const response = await send();
+  if (response.status === 401 && await refreshSession()) {
+    return send();
+  }
+  return response;
+};
+
 // Get current user info from local storage
 export const getCurrentUser = () => {
   return {
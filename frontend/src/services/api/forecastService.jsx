@@ -1,4 +1,5 @@
 import { API_BASE_URL } from './index';
+import { authFetch } from './authService';
 
 export const fetchForecasts = async (list_type = null, category = null) => {
   // Build query parameters (list_type maps to status in backend)
@@ -49,11 +50,10 @@ export const resolveForecast = async (forecast_id, resolution, comment) => {
   if (!token) {
     throw new Error('User needs to login to resolve a forecast');
   }
-  const response = await fetch(`${API_BASE_URL}/api/resolve`, {
+  const response = await authFetch(`${API_BASE_URL}/api/resolve`, {
     method: 'PUT',
     headers: { 
-      "Accept": "application/json",
-      "Authorization": `Bearer ${token}`
+      "Accept": "application/json"
     },
     body: JSON.stringify({
       id: forecast_id,
@@ -74,9 +74,9 @@ export const createForecast = async (forecast) => {
   if (!token) {
     throw new Error('User needs to login to create a forecast');
   }
-  const response = await fetch(`${API_BASE_URL}/api/forecasts/create`, {
+  const response = await authFetch(`${API_BASE_URL}/api/forecasts/create`, {
     method: 'POST',
-    headers: { "Accept": "application/json", "Authorization": `Bearer ${token}` },
+    headers: { "Accept": "application/json" },
     body: JSON.stringify(forecast)
   });
 
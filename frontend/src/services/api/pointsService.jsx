@@ -1,4 +1,5 @@
 import { API_BASE_URL } from './index';
+import { authFetch } from './authService';
 
 // Generic function to fetch forecast points with optional filters
 export const fetchForecastPoints = async (options = {}) => {
@@ -70,12 +71,11 @@ export const createPoint = async (forecast_id, point_forecast, reason) => {
     throw new Error('User needs to login to create a forecast point');
   }
   
-  const response = await fetch(`${API_BASE_URL}/api/forecast-points`, {
+  const response = await authFetch(`${API_BASE_URL}/api/forecast-points`, {
     method: 'POST',
     headers: { 
       "Accept": "application/json",
-      "Content-Type": "application/json",
-      "Authorization": `Bearer ${token}`
+      "Content-Type": "application/json"
     },
     body: JSON.stringify({
       forecast_id: forecast_id,